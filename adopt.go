@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// adoptExcludeCandidates are directories that often, but not always, hold
+// third-party code or build output. Unlike the default excludes they are
+// only suggested, for the ones a tree has.
+var adoptExcludeCandidates = map[string]bool{
+	"third_party": true,
+	"build":       true,
+	"out":         true,
+}
+
+// adoptPlan is what adopt proposes for a tree before anything is written.
+type adoptPlan struct {
+	root string
+	// languages counts the supported files of each language found.
+	languages  map[string]int
+	extensions []string
+	excludes   []string
+	copyright  string
+	// inferredFrom is the number of files whose existing header the
+	// notice was inferred from, 0 when it was given with --copyright.
+	inferredFrom int
+
+	// fixes holds the stamped content of every file with a missing or
+	// outdated header of ours, which is safe to rewrite.
+	fixes map[string]string
+	// risky lists files carrying another holder's notice, with that
+	// notice. They are excluded for a human to review.
+	risky map[string]string
+	stat  diffStat
+}
+
+// runAdopt implements the adopt subcommand, a guided first rollout: it
+// audits the tree, proposes a config, shows the projected diffstat and,
+// once confirmed, writes the config and applies the safe fixes.
+func runAdopt(cmd *cobra.Command, args []string) {
+	root := "."
+	if len(args) > 0 {
+		root = args[0]
+	}
+	copyrightText, _ := cmd.Flags().GetString("copyright")
+	yes, _ := cmd.Flags().GetBool("yes")
+
+	configPath := filepath.Join(root, defaultConfigFile)
+	if _, err := os.Stat(configPath); err == nil {
+		fmt.Fprintf(os.Stderr, "Error: %s already exists; adopt is for repositories without a config\n", configPath)
+		os.Exit(exitError)
+	}
+
+	plan, err := planAdoption(root, copyrightText)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	plan.print(os.Stdout)
+
+	if !yes && !confirm(os.Stdin, os.Stdout, fmt.Sprintf("Write %s and fix %d %s?", configPath, len(plan.fixes), plural(len(plan.fixes), "file", "files"))) {
+		fmt.Println("Nothing written")
+		return
+	}
+	if err := plan.apply(configPath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Wrote %s and fixed %d %s\n", configPath, len(plan.fixes), plural(len(plan.fixes), "file", "files"))
+	if len(plan.risky) > 0 {
+		fmt.Printf("%d %s with another holder's notice %s excluded in the config; review each one and remove it from the list\n",
+			len(plan.risky), plural(len(plan.risky), "file", "files"), plural(len(plan.risky), "is", "are"))
+	}
+}
+
+// planAdoption audits the tree at root. An empty copyrightText is inferred
+// from the most common notice at the top of the existing files.
+func planAdoption(root, copyrightText string) (*adoptPlan, error) {
+	plan := &adoptPlan{
+		root:      root,
+		languages: make(map[string]int),
+		copyright: strings.TrimSpace(copyrightText),
+		fixes:     make(map[string]string),
+		risky:     make(map[string]string),
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && slices.Contains(defaultExcludes, d.Name()) {
+				return filepath.SkipDir
+			}
+			if path != root && adoptExcludeCandidates[d.Name()] {
+				plan.excludes = append(plan.excludes, d.Name())
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if _, ok := copyrighter.StyleFor(path); ok && d.Type().IsRegular() {
+			files = append(files, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	plan.excludes = sortedUnique(plan.excludes)
+
+	contents := make(map[string]string, len(files))
+	notices := make(map[string]int)
+	extensions := make(map[string]bool)
+	for _, path := range files {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		content := string(data)
+		if copyrighter.IsGenerated(content, nil) {
+			continue
+		}
+		contents[path] = content
+		extensions[strings.ToLower(filepath.Ext(path))] = true
+		plan.languages[languageNames[strings.ToLower(filepath.Ext(path))]]++
+		if statements := copyrightStatements([]byte(headLines(content, adoptInferLines))); len(statements) > 0 {
+			notices[statements[0]]++
+		}
+	}
+	for ext := range extensions {
+		plan.extensions = append(plan.extensions, ext)
+	}
+	sort.Strings(plan.extensions)
+
+	if plan.copyright == "" {
+		for notice, n := range notices {
+			if n > plan.inferredFrom || n == plan.inferredFrom && notice < plan.copyright {
+				plan.copyright, plan.inferredFrom = notice, n
+			}
+		}
+		if plan.copyright == "" {
+			return nil, errors.New("no existing copyright notice to infer the header from; pass --copyright")
+		}
+	}
+
+	paths := make([]string, 0, len(contents))
+	for path := range contents {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		content := contents[path]
+		style, _ := copyrighter.StyleFor(path)
+		opts := copyrighter.Options{Copyright: plan.copyright, Style: style}
+		stamped, result, err := copyrighter.Stamp(content, opts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		rel, _ := filepath.Rel(root, path)
+		switch status, detail := classifyHeader(content, opts, result); status {
+		case auditForeign:
+			plan.risky[filepath.ToSlash(rel)] = detail
+			plan.stat.add(path, content, content)
+		default:
+			if result.Changed() {
+				plan.fixes[path] = stamped
+			}
+			plan.stat.add(path, content, stamped)
+		}
+	}
+	return plan, nil
+}
+
+// adoptInferLines is how far into a file adopt looks for an existing
+// notice to infer the header text from.
+const adoptInferLines = 20
+
+// headLines returns the first n lines of content.
+func headLines(content string, n int) string {
+	lines := strings.SplitN(content, "\n", n+1)
+	return strings.Join(lines[:min(n, len(lines))], "\n")
+}
+
+func sortedUnique(values []string) []string {
+	sort.Strings(values)
+	out := values[:0]
+	for i, v := range values {
+		if i == 0 || v != values[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func (p *adoptPlan) print(w io.Writer) {
+	names := make([]string, 0, len(p.languages))
+	for name := range p.languages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var counts []string
+	for _, name := range names {
+		counts = append(counts, fmt.Sprintf("%s (%d)", name, p.languages[name]))
+	}
+
+	fmt.Fprintln(w, "Proposed config")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Languages:  %s\n", strings.Join(counts, ", "))
+	if p.inferredFrom > 0 {
+		fmt.Fprintf(w, "Header:     %s (inferred from %d existing %s)\n", p.copyright, p.inferredFrom, plural(p.inferredFrom, "file", "files"))
+	} else {
+		fmt.Fprintf(w, "Header:     %s\n", p.copyright)
+	}
+	if len(p.excludes) > 0 {
+		fmt.Fprintf(w, "Excludes:   %s\n", strings.Join(p.excludes, ", "))
+	} else {
+		fmt.Fprintln(w, "Excludes:   none")
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Safe fixes: %d %s with a missing or outdated header\n", len(p.fixes), plural(len(p.fixes), "file", "files"))
+	if len(p.risky) > 0 {
+		fmt.Fprintf(w, "Risky:      %d %s with another holder's notice, to be excluded for review:\n", len(p.risky), plural(len(p.risky), "file", "files"))
+		for _, path := range sortedKeys(p.risky) {
+			fmt.Fprintf(w, "  %s (%s)\n", path, p.risky[path])
+		}
+	}
+	fmt.Fprintln(w)
+	p.stat.print(w)
+	fmt.Fprintln(w)
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// confirm asks a yes/no question and reports whether it was answered yes.
+func confirm(in io.Reader, out io.Writer, question string) bool {
+	fmt.Fprintf(out, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(in).ReadString('\n')
+	fmt.Fprintln(out)
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	}
+	return false
+}
+
+// apply writes the proposed config and the safe fixes. Files with another
+// holder's notice are listed as excludes, a baseline to burn down.
+func (p *adoptPlan) apply(configPath string) error {
+	var b strings.Builder
+	b.WriteString("# Written by copy-righter adopt.\n")
+	fmt.Fprintf(&b, "copyright: %s\n", strconv.Quote(p.copyright))
+	b.WriteString("extensions: [")
+	for i, ext := range p.extensions {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(strconv.Quote(ext))
+	}
+	b.WriteString("]\n")
+	if len(p.excludes) > 0 || len(p.risky) > 0 {
+		b.WriteString("exclude:\n")
+		for _, e := range p.excludes {
+			fmt.Fprintf(&b, "  - %s\n", strconv.Quote(e))
+		}
+		if len(p.risky) > 0 {
+			b.WriteString("  # Another holder's notice was found in these files. Review each one,\n")
+			b.WriteString("  # then remove it from the list so the policy applies to it.\n")
+			for _, path := range sortedKeys(p.risky) {
+				fmt.Fprintf(&b, "  - %s\n", strconv.Quote(path))
+			}
+		}
+	}
+	if err := os.WriteFile(configPath, []byte(b.String()), 0644); err != nil {
+		return err
+	}
+
+	for path, content := range p.fixes {
+		if err := writeFileAtomic(path, []byte(content)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
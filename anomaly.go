@@ -0,0 +1,62 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// licenseTextPattern matches lines that look like part of a copyright or
+// license notice.
+var licenseTextPattern = regexp.MustCompile(`(?i)copyright|©|\(c\)|licen[cs]ed under|permission is hereby granted|SPDX-License-Identifier|all rights reserved|general public license|apache license`)
+
+// anomaly is license or copyright text found outside the header and
+// footer, typically a license block pasted into the middle of a file.
+type anomaly struct {
+	Line int    `json:"line"` // 1-based line number in the file
+	Text string `json:"text"`
+}
+
+// findAnomalies returns the comment lines between the header and footer
+// positions that contain license or copyright text. They confuse license
+// scanners and reviewers, who expect the notice in one place.
+func findAnomalies(content string, opts copyrighter.Options) []anomaly {
+	_, body := copyrighter.SplitContent(content, opts)
+	offset := countLines(content) - len(body)
+	start, end := noticeBounds(body, opts)
+	// The line numbers past the middle of a large file, read as windows
+	// (see source), are not known
+	if i := slices.Index(body, copyrighter.MiddleMarker); i >= 0 {
+		end = min(end, i)
+	}
+
+	var found []anomaly
+	for i := start; i < end; i++ {
+		line := strings.TrimSpace(body[i])
+		if opts.Style.IsCommentLike(line) && licenseTextPattern.MatchString(line) {
+			found = append(found, anomaly{Line: offset + i + 1, Text: line})
+		}
+	}
+	return found
+}
+
+// noticeBounds returns where the header ends and the footer starts in the
+// body of a file: the expected notice where it is present, otherwise the
+// comment that stamping would replace.
+func noticeBounds(body []string, opts copyrighter.Options) (start, end int) {
+	header, footer := copyrighter.ExpectedNotice(body, opts)
+	start = len(header)
+	if len(body) < start || copyrighter.NoticeHash(body[:start]) != copyrighter.NoticeHash(header) {
+		start = opts.Style.LeadingComment(body, len(header))
+	}
+	if opts.NoFooter {
+		return start, len(body)
+	}
+	end = len(body) - len(footer)
+	if end < start || copyrighter.NoticeHash(body[end:]) != copyrighter.NoticeHash(footer) {
+		end = len(body) - opts.Style.TrailingComment(body, len(footer))
+	}
+	return start, end
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// auditStatus classifies a file in an audit report.
+type auditStatus int
+
+const (
+	auditOK auditStatus = iota
+	auditMissing
+	auditOutdated
+	auditForeign
+	auditUnsupported
+	auditGenerated
+	auditIgnored
+)
+
+var auditStatusNames = map[auditStatus]string{
+	auditOK:          "ok",
+	auditMissing:     "missing header",
+	auditOutdated:    "outdated header",
+	auditForeign:     "foreign header",
+	auditUnsupported: "unsupported",
+	auditGenerated:   "generated",
+	auditIgnored:     "ignored",
+}
+
+func (s auditStatus) String() string {
+	return auditStatusNames[s]
+}
+
+// auditEntry is one file of an audit report. detail explains the status,
+// such as the notice found in a foreign header.
+type auditEntry struct {
+	path   string
+	status auditStatus
+	detail string
+}
+
+// auditReport collects the classification of every file visited by an
+// audit run.
+type auditReport struct {
+	entries []auditEntry
+}
+
+func (a *auditReport) add(path string, status auditStatus, detail string) {
+	a.entries = append(a.entries, auditEntry{path, status, detail})
+}
+
+// runAudit implements the audit subcommand: it classifies every file in the
+// tree and prints a summary table and per-file details, without writing.
+func runAudit(cmd *cobra.Command, args []string) {
+	r := newRunner(cmd, args)
+	r.audit = &auditReport{}
+
+	r.runArgs(args)
+	r.audit.print(r.out)
+	r.finish()
+
+	// An audit is a report: it only fails when files could not be read
+	if r.summary.Failed > 0 {
+		os.Exit(exitError)
+	}
+}
+
+// yearsOrSpace matches the parts of a notice that are ignored when deciding
+// whether an existing header belongs to the same holder.
+var yearsOrSpace = regexp.MustCompile(copyrighter.YearRangeSource + `|\s+`)
+
+// classifyHeader decides whether a file whose stamp result is not clean
+// lacks a header, carries an outdated version of ours, or carries a
+// notice from a different holder. The detail names what was found.
+func classifyHeader(content string, opts copyrighter.Options, result copyrighter.Result) (auditStatus, string) {
+	if !result.Changed() {
+		return auditOK, ""
+	}
+	if result.Header == copyrighter.UpToDate {
+		return auditOutdated, describeProblems(result)
+	}
+
+	_, lines := copyrighter.SplitContent(content, opts)
+	header, _ := copyrighter.ExpectedNotice(lines, opts)
+	existing := opts.Style.LeadingComment(lines, len(header))
+	found := strings.Join(lines[:existing], "\n")
+	if existing == 0 || !copyrighter.CopyrightPattern.MatchString(found) {
+		return auditMissing, describeProblems(result)
+	}
+
+	if holderText(found) == holderText(strings.Join(header, "\n")) {
+		return auditOutdated, describeProblems(result)
+	}
+	return auditForeign, strings.Trim(strings.Join(strings.Fields(found), " "), commentMarkers)
+}
+
+// holderText reduces a notice to what identifies its holder, dropping
+// years, whitespace and comment markers.
+func holderText(notice string) string {
+	return strings.ToLower(strings.Trim(yearsOrSpace.ReplaceAllString(copyrighter.NormalizeNotice(notice), ""), commentMarkers))
+}
+
+func (a *auditReport) print(w io.Writer) {
+	counts := make(map[auditStatus]int)
+	for _, e := range a.entries {
+		counts[e.status]++
+	}
+
+	fmt.Fprintln(w, "Copyright audit")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "%-16s %6s\n", "Status", "Files")
+	for s := auditOK; s <= auditIgnored; s++ {
+		fmt.Fprintf(w, "%-16s %6d\n", s, counts[s])
+	}
+	fmt.Fprintf(w, "%-16s %6d\n", "total", len(a.entries))
+
+	entries := append([]auditEntry(nil), a.entries...)
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].path < entries[j].path })
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Details:")
+	for _, e := range entries {
+		if e.detail != "" {
+			fmt.Fprintf(w, "  %-16s %s (%s)\n", e.status, e.path, e.detail)
+		} else {
+			fmt.Fprintf(w, "  %-16s %s\n", e.status, e.path)
+		}
+	}
+}
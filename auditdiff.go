@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// commentMarkers are trimmed from both ends of a line to recover the text
+// of a copyright statement regardless of the comment syntax around it.
+const commentMarkers = " \t/*#!<>-{}%;"
+
+// noticeChange is the difference in copyright statements for one file
+// between two snapshots of a tree.
+type noticeChange struct {
+	path    string
+	added   []string
+	removed []string
+}
+
+// kind classifies the change for the report.
+func (c noticeChange) kind() string {
+	switch {
+	case len(c.removed) == 0:
+		return "added"
+	case len(c.added) == 0:
+		return "removed"
+	default:
+		return "changed"
+	}
+}
+
+func runAuditDiff(cmd *cobra.Command, args []string) {
+	changes, err := auditDiff(args[0], args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	writeAuditDiff(os.Stdout, changes)
+}
+
+// auditDiff compares the copyright statements of every file under oldRoot
+// with the file at the same relative path under newRoot.
+func auditDiff(oldRoot, newRoot string) ([]noticeChange, error) {
+	before, err := collectNotices(oldRoot)
+	if err != nil {
+		return nil, err
+	}
+	after, err := collectNotices(newRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make(map[string]bool)
+	for p := range before {
+		paths[p] = true
+	}
+	for p := range after {
+		paths[p] = true
+	}
+
+	var changes []noticeChange
+	for p := range paths {
+		c := noticeChange{
+			path:    p,
+			added:   difference(after[p], before[p]),
+			removed: difference(before[p], after[p]),
+		}
+		if len(c.added) > 0 || len(c.removed) > 0 {
+			changes = append(changes, c)
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].path < changes[j].path })
+	return changes, nil
+}
+
+// collectNotices walks root and returns the copyright statements found in
+// each text file, keyed by slash-separated path relative to root.
+func collectNotices(root string) (map[string][]string, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	notices := make(map[string][]string)
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		if statements := copyrightStatements(content); len(statements) > 0 {
+			notices[filepath.ToSlash(rel)] = statements
+		}
+		return nil
+	})
+	return notices, err
+}
+
+// copyrightStatements returns the distinct lines of content that mention a
+// copyright, stripped of comment markers. Binary files have none.
+func copyrightStatements(content []byte) []string {
+	if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		return nil
+	}
+	var statements []string
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, len(content)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !copyrighter.CopyrightPattern.MatchString(line) {
+			continue
+		}
+		statement := copyrighter.NormalizeNotice(strings.Trim(line, commentMarkers))
+		if statement != "" && !seen[statement] {
+			seen[statement] = true
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
+
+// difference returns the entries of a that are not in b, in order.
+func difference(a, b []string) []string {
+	var out []string
+	for _, s := range a {
+		found := false
+		for _, t := range b {
+			if s == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func writeAuditDiff(w io.Writer, changes []noticeChange) {
+	counts := make(map[string]int)
+	for _, c := range changes {
+		counts[c.kind()]++
+		fmt.Fprintf(w, "%-7s %s\n", c.kind(), c.path)
+		for _, s := range c.removed {
+			fmt.Fprintf(w, "  - %s\n", s)
+		}
+		for _, s := range c.added {
+			fmt.Fprintf(w, "  + %s\n", s)
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No copyright statements differ")
+		return
+	}
+	fmt.Fprintf(w, "%d %s with different notices: %d added, %d removed, %d changed\n",
+		len(changes), plural(len(changes), "file", "files"), counts["added"], counts["removed"], counts["changed"])
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// defaultBaselineFile is the baseline check reads and baseline writes
+// when the baseline setting is not given.
+const defaultBaselineFile = ".copyrighter-baseline"
+
+// baselineComment heads a baseline file, so a reader of the repository
+// knows what it is and how to shrink it.
+const baselineComment = `# Files copy-righter check tolerates with a missing or outdated notice.
+# Fix them and run copy-righter baseline to drop them from the list; new
+# files are checked in full.
+`
+
+// baseline is the set of files recorded as non-compliant when enforcement
+// started. check reports them without failing, so a legacy repository
+// can enforce the notice on new code while the backlog is burnt down.
+// Each file is keyed by its path relative to the baseline file.
+type baseline struct {
+	path  string
+	files map[string]bool
+}
+
+// loadBaseline reads the baseline at path; a missing one is nil, which
+// tolerates nothing.
+func loadBaseline(path string) (*baseline, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	b := &baseline{path: path, files: make(map[string]bool)}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		b.files[line] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("reading baseline %s: %w", path, err)
+	}
+	return b, nil
+}
+
+// baselineKey returns the key of filePath in the baseline at path.
+func baselineKey(path, filePath string) (string, error) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+	dir, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// covers reports whether filePath is in the baseline.
+func (b *baseline) covers(filePath string) bool {
+	if b == nil {
+		return false
+	}
+	key, err := baselineKey(b.path, filePath)
+	return err == nil && b.files[key]
+}
+
+// runBaseline implements the baseline subcommand: it checks the tree like
+// check, ignoring any existing baseline, and records the files that fail
+// in the baseline file, which check then tolerates.
+func runBaseline(cmd *cobra.Command, args []string) {
+	r := newRunner(cmd, args)
+	r.check = true
+	r.baseline = nil
+	r.recordOutdated = true
+	r.runArgs(args)
+	r.finish()
+	if r.summary.Failed > 0 {
+		fmt.Fprintf(os.Stderr, "Error: %d file(s) could not be checked; baseline not written\n", r.summary.Failed)
+		os.Exit(exitError)
+	}
+
+	keys := make([]string, 0, len(r.outdated))
+	for _, filePath := range r.outdated {
+		key, err := baselineKey(r.baselinePath, filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	keys = slices.Compact(keys)
+
+	var b strings.Builder
+	b.WriteString(baselineComment)
+	for _, key := range keys {
+		b.WriteString(key + "\n")
+	}
+	if err := writeFileAtomic(r.baselinePath, []byte(b.String())); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Fprintf(r.out, "Wrote %s with %d %s\n", r.baselinePath, len(keys), plural(len(keys), "file", "files"))
+}
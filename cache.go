@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// cacheFormat is bumped whenever the layout of the cache file changes.
+const cacheFormat = 1
+
+// hashCache maps the path of every file last seen up to date to the
+// sha256 of its content, so a later run can skip files that have not
+// changed since. Entries are only trusted when the settings that decide
+// whether a file is up to date are the same as when they were written.
+type hashCache struct {
+	Format int `json:"format"`
+	// Settings fingerprints the notice, placement options and binary the
+	// entries were verified with.
+	Settings string            `json:"settings"`
+	Files    map[string]string `json:"files"`
+
+	path  string
+	dirty bool
+}
+
+// loadCache reads the cache file at path. A missing file, or one written
+// with other settings, starts an empty cache.
+func loadCache(path, settings string) (*hashCache, error) {
+	c := &hashCache{Format: cacheFormat, Settings: settings, Files: make(map[string]string), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var stored hashCache
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, fmt.Errorf("parsing cache %s: %w", path, err)
+	}
+	if stored.Format == cacheFormat && stored.Settings == settings && stored.Files != nil {
+		c.Files = stored.Files
+	} else {
+		c.dirty = true
+	}
+	return c, nil
+}
+
+// cacheKey returns the absolute form of path, so entries survive running
+// from another directory.
+func cacheKey(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// fresh reports whether path was up to date with exactly the content
+// hashing to sum. It is false for a nil cache.
+func (c *hashCache) fresh(path, sum string) bool {
+	return c != nil && c.Files[cacheKey(path)] == sum
+}
+
+// remember records that path is up to date with the content hashing to sum.
+func (c *hashCache) remember(path, sum string) {
+	if c == nil {
+		return
+	}
+	key := cacheKey(path)
+	if c.Files[key] != sum {
+		c.Files[key] = sum
+		c.dirty = true
+	}
+}
+
+// forget drops path, which is not up to date.
+func (c *hashCache) forget(path string) {
+	if c == nil {
+		return
+	}
+	key := cacheKey(path)
+	if _, ok := c.Files[key]; ok {
+		delete(c.Files, key)
+		c.dirty = true
+	}
+}
+
+// save writes the cache back if it changed. The file is replaced
+// atomically so an interrupted run never leaves a truncated cache.
+func (c *hashCache) save() error {
+	if c == nil || !c.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(c.path, append(data, '\n'))
+}
+
+// cacheSettings fingerprints everything besides a file's content that
+// decides whether it is up to date: the stamping options, the holders,
+// the footer and continuation settings, the notice template and the year
+// it is rendered with, and the running binary, whose comment styles may
+// differ between versions.
+func cacheSettings(p copyrighter.Processor, holders []holder, accepted []string) string {
+	opts := p.Options
+	year := 0
+	if p.Template != "" {
+		year = p.Year
+	}
+	exts := make([]string, 0, len(p.Footers))
+	for ext, enabled := range p.Footers {
+		exts = append(exts, fmt.Sprintf("%s=%t", ext, enabled))
+	}
+	sort.Strings(exts)
+	prefixes := make([]string, 0, len(p.Continuation))
+	for ext, prefix := range p.Continuation {
+		prefixes = append(prefixes, ext+"="+strconv.Quote(prefix))
+	}
+	sort.Strings(prefixes)
+	fingerprint, _ := json.Marshal(struct {
+		Copyright       string
+		Template        string
+		Year            int
+		SPDX            string
+		MaintainedBy    string
+		TemplateVersion string
+		UpdateYearRange bool
+		MergeHeader     bool
+		LeaveHeader     bool
+		LeaveFooter     bool
+		BlankLines      *copyrighter.BlankLines
+		MaxLineLength   int
+		Preamble        []string
+		Footers         []string
+		Continuation    []string
+		NoticePatterns  []string
+		MatchPatterns   []string
+		NoticeLines     int
+		Holders         []holder
+		Accepted        []string
+		Binary          string
+	}{opts.Copyright, p.Template, year, opts.SPDX, opts.MaintainedBy, opts.TemplateVersion, opts.UpdateYearRange, opts.MergeHeader, opts.LeaveHeader, opts.LeaveFooter, opts.BlankLines, opts.MaxLineLength, patternStrings(opts.Preamble), exts, prefixes, patternStrings(opts.NoticePatterns), patternStrings(opts.MatchPatterns), opts.NoticeLines, holders, accepted, executableHash()})
+	return hashString(string(fingerprint))
+}
+
+// patternStrings returns the source of each of patterns, keeping nil
+// apart from an empty list, which match notices differently.
+func patternStrings(patterns []*regexp.Regexp) []string {
+	if patterns == nil {
+		return nil
+	}
+	sources := make([]string, len(patterns))
+	for i, re := range patterns {
+		sources[i] = re.String()
+	}
+	return sources
+}
+
+// executableHash returns the sha256 of the running binary, or an empty
+// string if it cannot be read.
+func executableHash() string {
+	path, err := os.Executable()
+	if err != nil {
+		return ""
+	}
+	hash, err := hashFile(path)
+	if err != nil {
+		return ""
+	}
+	return hash
+}
+
+// skipCached counts a file whose content is unchanged since it was last
+// verified up to date. Check mode still reports an expired review, which
+// depends on the date rather than the content.
+func (r *runner) skipCached(src *source, opts copyrighter.Options) {
+	filePath, content := src.path, src.content
+	r.summary.Scanned++
+	r.summary.UpToDate++
+	stats := r.languageStats(filePath)
+	stats.Scanned++
+	stats.Compliant++
+	outcome := fileOutcome{Path: filePath, Status: "up_to_date", lines: src.lines}
+	if r.check {
+		if r.tracksTemplateVersions(r.check) {
+			outcome.TemplateVersion = r.countTemplateVersion(content, opts)
+		}
+		if m := r.reportExpiredReview(filePath, content, opts); m != nil {
+			outcome.Problems = append(outcome.Problems, "expired review")
+			outcome.Maintainer = m
+		}
+	} else {
+		r.logf(logVerbose, "Copyright already up to date in: %s (unchanged since the last run)\n", filePath)
+	}
+	r.record(outcome)
+}
+
+// rememberIfClean records content, whose hash is hash, as up to date for
+// path unless license text outside the notice would make check warn about
+// it.
+func (r *runner) rememberIfClean(filePath, content, hash string, opts copyrighter.Options) {
+	if r.cache == nil {
+		return
+	}
+	if len(findAnomalies(content, opts)) > 0 {
+		r.cache.forget(filePath)
+		return
+	}
+	r.cache.remember(filePath, hash)
+}
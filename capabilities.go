@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// languageFor looks up the language of an extension; tests replace it to
+// declare languages with other capabilities.
+var languageFor = copyrighter.LanguageFor
+
+// normalizeKeys normalizes the extension keys of a per-extension setting
+// such as footers.
+func normalizeKeys[V any](byExt map[string]V) map[string]V {
+	if byExt == nil {
+		return nil
+	}
+	normalized := make(map[string]V, len(byExt))
+	for ext, v := range byExt {
+		if n := normalizeExtensions([]string{ext}); len(n) > 0 {
+			normalized[n[0]] = v
+		}
+	}
+	return normalized
+}
+
+// checkCapabilities refuses settings the capability matrix rules out: a
+// footer turned on for a language that cannot carry one, or an enabled
+// extension whose language cannot carry a header.
+func checkCapabilities(s settings) error {
+	exts := make([]string, 0, len(s.Footers))
+	for ext := range s.Footers {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		lang, ok := languageFor(ext)
+		if !ok {
+			return fmt.Errorf("footers: unsupported extension %q (supported: %s)", ext, strings.Join(copyrighter.Extensions, ", "))
+		}
+		if s.Footers[ext] && lang.Caps.Footer == copyrighter.FooterUnsupported {
+			return fmt.Errorf("footers are not supported for %s", lang.Name)
+		}
+	}
+	for _, ext := range s.Extensions {
+		if lang, _ := languageFor(ext); !lang.Caps.Header {
+			return fmt.Errorf("headers are not supported for %s", lang.Name)
+		}
+	}
+	return nil
+}
+
+// checkContinuations refuses a continuation prefix that would not keep
+// the lines of a notice inside the comment.
+func checkContinuations(continuation map[string]string) error {
+	exts := make([]string, 0, len(continuation))
+	for ext := range continuation {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	for _, ext := range exts {
+		lang, ok := languageFor(ext)
+		if !ok {
+			return fmt.Errorf("continuation: unsupported extension %q (supported: %s)", ext, strings.Join(copyrighter.Extensions, ", "))
+		}
+		prefix, style := continuation[ext], lang.Style
+		switch {
+		case prefix == "":
+		case style.LinePrefix() != "" && !strings.HasPrefix(prefix, style.LinePrefix()):
+			return fmt.Errorf("continuation for %s must start with %q to stay a %s comment", ext, style.LinePrefix(), lang.Name)
+		case style.LinePrefix() == "" && strings.Contains(prefix, style.BlockEnd()):
+			return fmt.Errorf("continuation for %s must not contain %q, which would end the %s comment", ext, style.BlockEnd(), lang.Name)
+		case strings.ContainsAny(prefix, "\r\n"):
+			return fmt.Errorf("continuation for %s must not contain a line break", ext)
+		}
+	}
+	return nil
+}
+
+// runLanguages implements the languages subcommand, printing the
+// capability matrix.
+func runLanguages(cmd *cobra.Command, args []string) {
+	printLanguages(os.Stdout)
+}
+
+func printLanguages(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LANGUAGE\tEXTENSIONS\tHEADER\tFOOTER\tBLOCK COMMENTS")
+	for _, lang := range copyrighter.Languages {
+		header := "no"
+		if lang.Caps.Header {
+			header = "yes"
+		}
+		block := "no"
+		if lang.Style.BlockStart() != "" {
+			block = "yes"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", lang.Name, strings.Join(lang.Extensions, " "), header, lang.Caps.Footer, block)
+	}
+	tw.Flush()
+}
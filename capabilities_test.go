@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+func TestCapabilityMatrixEnforced(t *testing.T) {
+	fakes := map[string]copyrighter.Language{
+		".test":  {Name: "Test", Extensions: []string{".test"}, Caps: copyrighter.Capabilities{Header: true, Footer: copyrighter.FooterUnsupported}},
+		".nohdr": {Name: "NoHeader", Extensions: []string{".nohdr"}, Caps: copyrighter.Capabilities{Footer: copyrighter.FooterUnsupported}},
+	}
+	languageFor = func(ext string) (copyrighter.Language, bool) {
+		lang, ok := fakes[ext]
+		return lang, ok
+	}
+	t.Cleanup(func() { languageFor = copyrighter.LanguageFor })
+
+	if err := checkCapabilities(settings{Footers: map[string]bool{".test": true}}); err == nil || err.Error() != "footers are not supported for Test" {
+		t.Errorf("unsupported footer not refused: %v", err)
+	}
+	if err := checkCapabilities(settings{Footers: map[string]bool{".test": false}}); err != nil {
+		t.Errorf("turning off an unsupported footer refused: %v", err)
+	}
+	if err := checkCapabilities(settings{Extensions: []string{".nohdr"}}); err == nil || err.Error() != "headers are not supported for NoHeader" {
+		t.Errorf("unsupported header not refused: %v", err)
+	}
+}
+
+func TestContinuationChecked(t *testing.T) {
+	if err := checkContinuations(map[string]string{".py": " * "}); err == nil {
+		t.Error("continuation that leaves the comment not refused")
+	}
+	if err := checkContinuations(map[string]string{".c": " */ "}); err == nil {
+		t.Error("continuation that ends the block not refused")
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// runCheck implements the check subcommand: it lists every file whose
+// header or footer is missing or outdated and exits non-zero if there are
+// any, without writing to the tree.
+func runCheck(cmd *cobra.Command, args []string) {
+	r := newRunner(cmd, args)
+	r.check = true
+	r.summary.EnforcementSkipped = getenv(skipEnv, r.noEnv) == "1"
+	if r.summary.EnforcementSkipped {
+		warnSkipped()
+	}
+
+	r.reportExpiredExceptions()
+	r.runArgs(args)
+	r.finish()
+
+	if r.summary.EnforcementSkipped {
+		warnSkipped()
+		r.logf(logNormal, "%d file(s) have a missing or outdated copyright header or footer (not enforced)\n", r.summary.Outdated)
+		return
+	}
+
+	if r.summary.Failed > 0 {
+		r.logf(logNormal, "%d file(s) could not be checked\n", r.summary.Failed)
+	}
+	if r.summary.Outdated > 0 {
+		r.logf(logNormal, "%d file(s) have a missing or outdated copyright header or footer\n", r.summary.Outdated)
+	}
+	if r.summary.Foreign > 0 {
+		r.logf(logNormal, "%d file(s) have another party's copyright or license header; leave them to their owners with holders or a %s comment, or replace them with --overwrite-foreign\n", r.summary.Foreign, copyrighter.IgnoreDirective)
+	}
+	if r.summary.Baselined > 0 {
+		r.logf(logNormal, "%d file(s) in %s still have a missing or outdated copyright header or footer\n", r.summary.Baselined, r.baselinePath)
+	}
+	if r.baselineFixed > 0 {
+		r.logf(logNormal, "%d file(s) in %s are now up to date; run copy-righter baseline to drop them\n", r.baselineFixed, r.baselinePath)
+	}
+	if r.summary.PackagesWithoutNotice > 0 {
+		r.logf(logNormal, "%d package(s) have no %s to carry the copyright notice\n", r.summary.PackagesWithoutNotice, r.packageNotice)
+	}
+	if r.summary.Warnings > 0 {
+		r.logf(logNormal, "%d finding(s) reported as warnings\n", r.summary.Warnings)
+	}
+	if r.summary.ExpiredExceptions > 0 {
+		r.logf(logNormal, "%d exception(s) have expired\n", r.summary.ExpiredExceptions)
+	}
+	if r.summary.ExpiredReviews > 0 {
+		r.logf(logNormal, "%d file(s) have a copyright header whose review date has passed\n", r.summary.ExpiredReviews)
+	}
+	if r.summary.Anomalies > 0 {
+		r.logf(logNormal, "%d line(s) of license text found outside the header and footer\n", r.summary.Anomalies)
+	}
+	if r.summary.LegacyHeaders > 0 {
+		r.logf(logNormal, "%d file(s) accepted with a legacy header, to be migrated\n", r.summary.LegacyHeaders)
+	}
+	if len(r.summary.TemplateVersions) > 0 {
+		r.logf(logNormal, "Template versions: %s\n", templateAdoption(r.summary.TemplateVersions))
+	}
+	if !r.summary.ok() {
+		os.Exit(r.summary.exitCode())
+	}
+	r.logf(logNormal, "All files have up-to-date copyright headers and footers\n")
+}
+
+// reportWarnings prints the findings of a file that were left alone
+// because of their severity.
+func (r *runner) reportWarnings(filePath string, warnings []string) {
+	suffix := ""
+	if !r.check {
+		suffix = ", below the fix severity, left as is"
+	}
+	for _, w := range warnings {
+		r.logf(logNormal, "%s: warning: %s%s\n", filePath, w, suffix)
+	}
+	r.summary.Warnings += len(warnings)
+}
+
+// reportAnomalies warns about license text outside the header and footer
+// of a checked file. Anomalies are reported but do not fail the check.
+func (r *runner) reportAnomalies(filePath, content string, opts copyrighter.Options) []anomaly {
+	anomalies := findAnomalies(content, opts)
+	for _, a := range anomalies {
+		r.logf(logNormal, "%s:%d: warning: license text outside the header and footer: %s\n", filePath, a.Line, a.Text)
+	}
+	r.summary.Anomalies += len(anomalies)
+	return anomalies
+}
+
+// skipEnv names the environment variable that lets an emergency release
+// bypass the check. Only check mode honours it, and every run that does
+// says so on stderr and in its summary so the bypass stays traceable.
+const skipEnv = "COPYRIGHTER_SKIP"
+
+func warnSkipped() {
+	fmt.Fprintf(os.Stderr, "WARNING: %s=1 is set, copyright enforcement is DISABLED for this run.\n", skipEnv)
+	fmt.Fprintln(os.Stderr, "WARNING: problems are reported but the check will pass; unset it once the emergency release is out.")
+}
+
+// describeProblems summarises why a file failed the check.
+func describeProblems(result copyrighter.Result) string {
+	return strings.Join(problemList(result), ", ")
+}
+
+// problemList lists the problems found in a file, such as "missing header".
+func problemList(result copyrighter.Result) []string {
+	var problems []string
+	switch result.Header {
+	case copyrighter.Added:
+		problems = append(problems, "missing header")
+	case copyrighter.Updated:
+		problems = append(problems, "outdated header")
+	}
+	switch result.Footer {
+	case copyrighter.Added:
+		problems = append(problems, "missing footer")
+	case copyrighter.Updated:
+		problems = append(problems, "outdated footer")
+	}
+	return problems
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sourceDateEpoch is the reproducible-builds variable that pins the clock
+// of a build to a Unix timestamp.
+const sourceDateEpoch = "SOURCE_DATE_EPOCH"
+
+// runClock returns the time a run treats as now: --frozen-time when given,
+// otherwise SOURCE_DATE_EPOCH unless the environment is ignored, otherwise
+// the current time. frozen reports whether the clock was pinned.
+func runClock(frozenTime string, noEnv bool) (now time.Time, frozen bool, err error) {
+	if frozenTime != "" {
+		now, err = parseFrozenTime(frozenTime)
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		return now, true, nil
+	}
+	if epoch := getenv(sourceDateEpoch, noEnv); epoch != "" {
+		seconds, err := strconv.ParseInt(strings.TrimSpace(epoch), 10, 64)
+		if err != nil {
+			return time.Time{}, false, fmt.Errorf("invalid %s %q, want a Unix timestamp", sourceDateEpoch, epoch)
+		}
+		return time.Unix(seconds, 0).UTC(), true, nil
+	}
+	return time.Now(), false, nil
+}
+
+// parseFrozenTime accepts a date (YYYY-MM-DD, midnight UTC) or an RFC 3339
+// timestamp.
+func parseFrozenTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid --frozen-time %q, want YYYY-MM-DD or an RFC 3339 timestamp", value)
+}
+
+// getenv returns the environment variable key, or an empty string when
+// the environment is ignored with --no-env.
+func getenv(key string, noEnv bool) string {
+	if noEnv {
+		return ""
+	}
+	return os.Getenv(key)
+}
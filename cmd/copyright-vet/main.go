@@ -0,0 +1,16 @@
+// Command copyright-vet runs the copyright header check of copy-righter
+// as a standalone analysis tool or as a vet tool:
+//
+//	copyright-vet -copyright="Copyright (c) 2025 Example Corp." ./...
+//	go vet -vettool=$(which copyright-vet) -copyright="Copyright (c) 2025 Example Corp." ./...
+package main
+
+import (
+	"golang.org/x/tools/go/analysis/singlechecker"
+
+	"github.com/earik87/copy-righter/pkg/analyzer"
+)
+
+func main() {
+	singlechecker.Main(analyzer.Analyzer)
+}
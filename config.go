@@ -0,0 +1,466 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// defaultConfigFile is loaded from the working directory when --config is
+// not given. It is optional; flags alone are enough to run the tool.
+const defaultConfigFile = ".copyrighter.yaml"
+
+// settings are the values a config file or one of its profiles can set.
+type settings struct {
+	Copyright     copyrightNotice `yaml:"copyright"`
+	CopyrightFile string          `yaml:"copyright_file"`
+	// CopyrightURL is where the notice is fetched from instead, cached for
+	// CopyrightURLTTL, a duration such as "12h".
+	CopyrightURL    string `yaml:"copyright_url"`
+	CopyrightURLTTL string `yaml:"copyright_url_ttl"`
+	// CopyrightTemplate renders the notice as a Go template for each file,
+	// in the sandbox of copyrighter.Processor.Funcs.
+	CopyrightTemplate *bool `yaml:"copyright_template"`
+	// FromLicense is the license file, or "auto" to look for it, whose
+	// standard header text follows the copyright lines.
+	FromLicense string   `yaml:"from_license"`
+	Preamble    []string `yaml:"preamble"`
+	Extensions  []string `yaml:"extensions"`
+	Include     []string `yaml:"include"`
+	Exclude     []string `yaml:"exclude"`
+	Roots       []string `yaml:"roots"`
+	// Generated holds extra patterns marking a file as generated, in
+	// addition to the canonical "Code generated ... DO NOT EDIT." line.
+	Generated    []string `yaml:"generated"`
+	SPDX         string   `yaml:"spdx"`
+	MaintainedBy string   `yaml:"maintained_by"`
+	// TemplateVersion is added to the header; check accepts the headers of
+	// MinTemplateVersion or later while a new template is rolled out.
+	TemplateVersion    string `yaml:"template_version"`
+	MinTemplateVersion string `yaml:"min_template_version"`
+	// Baseline lists the outdated files check tolerates, see baseline.
+	Baseline string `yaml:"baseline"`
+	// Placement is where the header goes, see placementSeparate and
+	// placementMerge.
+	Placement string `yaml:"placement"`
+	// BlankLines is how many blank lines separate the notice from the
+	// code; unset, existing spacing is kept.
+	BlankLines blankLines `yaml:"blank_lines"`
+	// Reuse writes the header in the layout of the REUSE specification and
+	// leaves the files .reuse/dep5 covers alone.
+	Reuse *bool `yaml:"reuse"`
+	// Only restricts runs to the header or the footer, see onlyHeader and
+	// onlyFooter.
+	Only string `yaml:"only"`
+	// PackageNotice is the one file of each Go package that carries the
+	// notice, such as doc.go, or "every" for every file, the default.
+	PackageNotice string `yaml:"package_notice"`
+	// Footers turns the footer on or off per extension, overriding the
+	// language's default in the capability matrix.
+	Footers map[string]bool `yaml:"footers"`
+	// Continuation replaces the prefix of the lines of a multi-line
+	// notice per extension, such as " ** " or "#  ".
+	Continuation map[string]string `yaml:"continuation"`
+	Notify       notifySettings    `yaml:"notify"`
+	History      string            `yaml:"history"`
+	Cache        string            `yaml:"cache"`
+	// Sidecar is the manifest that records the stamps of the files of
+	// SidecarExtensions, such as strict JSON, which cannot carry a comment.
+	Sidecar           string   `yaml:"sidecar"`
+	SidecarExtensions []string `yaml:"sidecar_extensions"`
+	// Journal is the directory run journals are kept in for undo, or
+	// "off"; it defaults to the user cache directory.
+	Journal string `yaml:"journal"`
+	// JournalSync is when the journal is flushed to disk, see
+	// journalSyncFile.
+	JournalSync string `yaml:"journal_sync"`
+	// Severity maps findings such as "stale-year" to off, warning or
+	// error; FixSeverity is the lowest severity fix mode acts on.
+	Severity    map[string]string `yaml:"severity"`
+	FixSeverity string            `yaml:"fix_severity"`
+	// UpdateYearRange, IncludeGenerated, IncludeVendored,
+	// DefaultExcludes, NestedRepos and FollowSymlinks are pointers so a
+	// profile can turn them off again.
+	UpdateYearRange  *bool `yaml:"update_year_range"`
+	IncludeGenerated *bool `yaml:"include_generated"`
+	IncludeVendored  *bool `yaml:"include_vendored"`
+	DefaultExcludes  *bool `yaml:"default_excludes"`
+	NestedRepos      *bool `yaml:"nested_repos"`
+	FollowSymlinks   *bool `yaml:"follow_symlinks"`
+	// Exceptions suppress the findings of matching files until they
+	// expire.
+	Exceptions []exception `yaml:"exceptions"`
+	// NoticePatterns recognise an existing comment at the top or bottom of
+	// a file as a notice to update when one of its first NoticeLines lines
+	// matches; other comments are kept. Unset, they match copyright lines;
+	// an empty list treats every such comment as a notice.
+	NoticePatterns []string `yaml:"notice_patterns"`
+	NoticeLines    int      `yaml:"notice_lines"`
+	// MatchPatterns recognise legacy header formats whose whole leading
+	// comment block is replaced by the header.
+	MatchPatterns []string `yaml:"match_patterns"`
+	// Holders map path patterns to the copyright holder of the matching
+	// files, or to none.
+	Holders []holder `yaml:"holders"`
+	// LegacyHeaders are the header formats of the tool the config was
+	// imported from, accepted by check until they expire.
+	LegacyHeaders []legacyHeader `yaml:"legacy_headers"`
+	// OverwriteForeign replaces headers that belong to another party
+	// instead of leaving them in place.
+	OverwriteForeign *bool `yaml:"overwrite_foreign"`
+	// AcceptedCopyright are other approved notices, such as last year's or
+	// a former company name, that files may carry instead of Copyright.
+	AcceptedCopyright []copyrightNotice `yaml:"accepted_copyright"`
+	// MaxLineLength is the column notices are wrapped at, 0 for none. It
+	// defaults to copyrighter.DefaultMaxLineLength.
+	MaxLineLength *int `yaml:"max_line_length"`
+	// MaxFileSize is the size in MiB above which a file is refused
+	// instead of loaded, 0 for no limit. It defaults to no limit, and to
+	// defaultServeMaxFileSize for the request bodies of serve.
+	MaxFileSize *int64 `yaml:"max_file_size"`
+}
+
+// copyrightNotice is the copyright setting: the notice, or a list of
+// holder lines, such as the original author and the current maintainer,
+// that together form it.
+type copyrightNotice string
+
+func (n *copyrightNotice) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.SequenceNode {
+		var text string
+		if err := node.Decode(&text); err != nil {
+			return err
+		}
+		*n = copyrightNotice(text)
+		return nil
+	}
+	var lines []string
+	if err := node.Decode(&lines); err != nil {
+		return err
+	}
+	*n = joinHolders(lines)
+	return nil
+}
+
+// joinHolders makes one notice of the holder lines.
+func joinHolders(lines []string) copyrightNotice {
+	trimmed := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			trimmed = append(trimmed, line)
+		}
+	}
+	return copyrightNotice(strings.Join(trimmed, "\n"))
+}
+
+// blankLines is the blank_lines setting. Its fields are pointers so either
+// can be set alone; the other defaults to one blank line.
+type blankLines struct {
+	Header *int `yaml:"header"`
+	Footer *int `yaml:"footer"`
+}
+
+// check refuses a count the style guides this is for never ask for.
+func (b blankLines) check() error {
+	for _, n := range []*int{b.Header, b.Footer} {
+		if n != nil && (*n < 0 || *n > copyrighter.MaxBlankLines) {
+			return fmt.Errorf("invalid blank_lines %d, want 0 to %d", *n, copyrighter.MaxBlankLines)
+		}
+	}
+	return nil
+}
+
+// policy returns the blank-line policy to stamp with, nil when neither
+// field is set.
+func (b blankLines) policy() *copyrighter.BlankLines {
+	if b.Header == nil && b.Footer == nil {
+		return nil
+	}
+	p := &copyrighter.BlankLines{Header: 1, Footer: 1}
+	if b.Header != nil {
+		p.Header = *b.Header
+	}
+	if b.Footer != nil {
+		p.Footer = *b.Footer
+	}
+	return p
+}
+
+// defaultServeMaxFileSize is the default max_file_size of serve in MiB.
+// Whoever can reach it sends the bodies, and a source file is far
+// smaller. The CLI reads files from a checkout its user chose, and large
+// files are only read in part, so it sets no limit.
+const defaultServeMaxFileSize = 64
+
+// maxFileSize returns the size limit in bytes, 0 for none, or def MiB
+// when max_file_size is not set.
+func (s settings) maxFileSize(def int64) int64 {
+	if s.MaxFileSize == nil {
+		return def << 20
+	}
+	return max(*s.MaxFileSize, 0) << 20
+}
+
+// maxLineLength returns the column notices are wrapped at, 0 for none.
+func (s settings) maxLineLength() int {
+	if s.MaxLineLength == nil {
+		return copyrighter.DefaultMaxLineLength
+	}
+	return *s.MaxLineLength
+}
+
+// defaultExcludes are directories holding version control data,
+// third-party code or build output, which carry their own licenses. They
+// are skipped unless default_excludes is set to false.
+var defaultExcludes = []string{".git", "vendor", "node_modules", "dist", "target"}
+
+// defaultExcludes returns the built-in exclusions that apply, if any.
+func (s settings) defaultExcludes() []string {
+	if s.DefaultExcludes != nil && !*s.DefaultExcludes {
+		return nil
+	}
+	return defaultExcludes
+}
+
+// config is the on-disk configuration. Top-level settings apply to every
+// run; a profile selected with --profile overrides them field by field.
+type config struct {
+	settings `yaml:",inline"`
+	Profiles map[string]settings `yaml:"profiles"`
+
+	// path and sha256 identify the file the config was loaded from; both
+	// are empty when no config file exists.
+	path   string
+	sha256 string
+}
+
+// loadConfig reads the config file at path. When path is empty the default
+// file is used if it exists, and a missing default is not an error.
+func loadConfig(path string) (*config, error) {
+	explicit := path != ""
+	if !explicit {
+		path = defaultConfigFile
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if !explicit && errors.Is(err, os.ErrNotExist) {
+			return &config{}, nil
+		}
+		return nil, err
+	}
+
+	var cfg config
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	cfg.path = path
+	sum := sha256.Sum256(data)
+	cfg.sha256 = hex.EncodeToString(sum[:])
+	return &cfg, nil
+}
+
+// verifyHash refuses a config whose contents do not match the expected
+// sha256, so a shared pipeline cannot be redirected by a repo-local config.
+func (c *config) verifyHash(expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if c.path == "" {
+		return fmt.Errorf("--config-hash given but no config file was loaded")
+	}
+	if c.sha256 != expected {
+		return fmt.Errorf("config %s has sha256 %s, expected %s; refusing to run", c.path, c.sha256, expected)
+	}
+	return nil
+}
+
+// resolve returns the effective settings for the named profile. An empty
+// name selects the top-level settings.
+func (c *config) resolve(profile string) (settings, error) {
+	s := c.settings
+	if profile == "" {
+		return s, nil
+	}
+	p, ok := c.Profiles[profile]
+	if !ok {
+		names := make([]string, 0, len(c.Profiles))
+		for name := range c.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return s, fmt.Errorf("unknown profile %q: the config defines no profiles", profile)
+		}
+		return s, fmt.Errorf("unknown profile %q (available: %s)", profile, strings.Join(names, ", "))
+	}
+
+	if p.Copyright != "" {
+		s.Copyright, s.CopyrightFile, s.CopyrightURL = p.Copyright, "", ""
+	}
+	if p.CopyrightFile != "" {
+		s.Copyright, s.CopyrightFile, s.CopyrightURL = "", p.CopyrightFile, ""
+	}
+	if p.CopyrightURL != "" {
+		s.Copyright, s.CopyrightFile, s.CopyrightURL = "", "", p.CopyrightURL
+	}
+	if p.CopyrightURLTTL != "" {
+		s.CopyrightURLTTL = p.CopyrightURLTTL
+	}
+	if p.FromLicense != "" {
+		s.FromLicense = p.FromLicense
+	}
+	if p.Preamble != nil {
+		s.Preamble = p.Preamble
+	}
+	if p.Extensions != nil {
+		s.Extensions = p.Extensions
+	}
+	if p.Include != nil {
+		s.Include = p.Include
+	}
+	if p.Exclude != nil {
+		s.Exclude = p.Exclude
+	}
+	if p.Exceptions != nil {
+		s.Exceptions = p.Exceptions
+	}
+	if p.NoticePatterns != nil {
+		s.NoticePatterns = p.NoticePatterns
+	}
+	if p.NoticeLines != 0 {
+		s.NoticeLines = p.NoticeLines
+	}
+	if p.MatchPatterns != nil {
+		s.MatchPatterns = p.MatchPatterns
+	}
+	if p.Holders != nil {
+		s.Holders = p.Holders
+	}
+	if p.LegacyHeaders != nil {
+		s.LegacyHeaders = p.LegacyHeaders
+	}
+	if p.OverwriteForeign != nil {
+		s.OverwriteForeign = p.OverwriteForeign
+	}
+	if p.AcceptedCopyright != nil {
+		s.AcceptedCopyright = p.AcceptedCopyright
+	}
+	if p.Roots != nil {
+		s.Roots = p.Roots
+	}
+	if p.Generated != nil {
+		s.Generated = p.Generated
+	}
+	if p.DefaultExcludes != nil {
+		s.DefaultExcludes = p.DefaultExcludes
+	}
+	if p.IncludeGenerated != nil {
+		s.IncludeGenerated = p.IncludeGenerated
+	}
+	if p.IncludeVendored != nil {
+		s.IncludeVendored = p.IncludeVendored
+	}
+	if p.CopyrightTemplate != nil {
+		s.CopyrightTemplate = p.CopyrightTemplate
+	}
+	if p.NestedRepos != nil {
+		s.NestedRepos = p.NestedRepos
+	}
+	if p.FollowSymlinks != nil {
+		s.FollowSymlinks = p.FollowSymlinks
+	}
+	if p.MaxFileSize != nil {
+		s.MaxFileSize = p.MaxFileSize
+	}
+	if p.MaxLineLength != nil {
+		s.MaxLineLength = p.MaxLineLength
+	}
+	if p.History != "" {
+		s.History = p.History
+	}
+	if p.Sidecar != "" {
+		s.Sidecar = p.Sidecar
+	}
+	if p.SidecarExtensions != nil {
+		s.SidecarExtensions = p.SidecarExtensions
+	}
+	if p.Journal != "" {
+		s.Journal = p.Journal
+	}
+	if p.JournalSync != "" {
+		s.JournalSync = p.JournalSync
+	}
+	if p.Cache != "" {
+		s.Cache = p.Cache
+	}
+	if p.Severity != nil {
+		s.Severity = p.Severity
+	}
+	if p.FixSeverity != "" {
+		s.FixSeverity = p.FixSeverity
+	}
+	if p.SPDX != "" {
+		s.SPDX = p.SPDX
+	}
+	if p.MaintainedBy != "" {
+		s.MaintainedBy = p.MaintainedBy
+	}
+	if p.TemplateVersion != "" {
+		s.TemplateVersion = p.TemplateVersion
+	}
+	if p.MinTemplateVersion != "" {
+		s.MinTemplateVersion = p.MinTemplateVersion
+	}
+	if p.Baseline != "" {
+		s.Baseline = p.Baseline
+	}
+	if p.Placement != "" {
+		s.Placement = p.Placement
+	}
+	if p.BlankLines.Header != nil {
+		s.BlankLines.Header = p.BlankLines.Header
+	}
+	if p.BlankLines.Footer != nil {
+		s.BlankLines.Footer = p.BlankLines.Footer
+	}
+	if p.Reuse != nil {
+		s.Reuse = p.Reuse
+	}
+	if p.Only != "" {
+		s.Only = p.Only
+	}
+	if p.PackageNotice != "" {
+		s.PackageNotice = p.PackageNotice
+	}
+	if p.Footers != nil {
+		s.Footers = p.Footers
+	}
+	if p.Continuation != nil {
+		s.Continuation = p.Continuation
+	}
+	if p.UpdateYearRange != nil {
+		s.UpdateYearRange = p.UpdateYearRange
+	}
+	if p.Notify.Command != "" {
+		s.Notify.Command = p.Notify.Command
+	}
+	if p.Notify.Webhook != "" {
+		s.Notify.Webhook = p.Notify.Webhook
+	}
+	return s, nil
+}
+
+// isTrue reports whether an optional boolean setting is set and true.
+func isTrue(b *bool) bool {
+	return b != nil && *b
+}
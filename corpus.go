@@ -0,0 +1,174 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// corpusWord matches a word of code or prose, the unit record-corpus
+// anonymizes.
+var corpusWord = regexp.MustCompile(`[\p{L}_][\p{L}\p{N}_]*`)
+
+// corpusKeptWords are left as they are in recorded fixtures: keywords that
+// give a file its structure, and the words header detection, directives
+// and preambles look for. They are compared case-insensitively.
+var corpusKeptWords = func() map[string]bool {
+	kept := make(map[string]bool)
+	for _, w := range strings.Fields(`
+		package import func var const type return if else for range struct
+		interface map class def from public private static void int include
+		define ifdef ifndef endif pragma code_page build go coding utf
+		html head body script style template xml version encoding doctype
+		copyright c license licensed licence spdx identifier all rights
+		reserved code generated do not edit maintained by review true false
+		null nil none env bin bash sh python python3 node
+		apache mit gpl bsd see for details under the`) {
+		kept[w] = true
+	}
+	return kept
+}()
+
+// sanitizeCorpusLines replaces every word of lines that is not in
+// corpusKeptWords with a placeholder, the same one for every occurrence
+// of the word, so identifiers, names and prose cannot be recovered while
+// comment markers, punctuation, indentation, years and blank lines stay
+// as they were.
+func sanitizeCorpusLines(lines []string) []string {
+	placeholders := make(map[string]string)
+	sanitized := make([]string, len(lines))
+	for i, line := range lines {
+		if copyrighter.Shebang.MatchString(line) {
+			sanitized[i] = line
+			continue
+		}
+		sanitized[i] = corpusWord.ReplaceAllStringFunc(line, func(w string) string {
+			if corpusKeptWords[strings.ToLower(w)] {
+				return w
+			}
+			p, ok := placeholders[w]
+			if !ok {
+				p = "w" + strconv.Itoa(len(placeholders)+1)
+				if r, _ := utf8.DecodeRuneInString(w); unicode.IsUpper(r) {
+					p = "W" + p[1:]
+				}
+				placeholders[w] = p
+			}
+			return p
+		})
+	}
+	return sanitized
+}
+
+// recordFixture returns a sanitized fixture for content: its first and
+// last n lines, anonymized.
+func recordFixture(content string, n int) string {
+	trailingNewline := strings.HasSuffix(content, "\n")
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if len(lines) > 2*n {
+		lines = slices.Concat(lines[:n], lines[len(lines)-n:])
+	}
+	fixture := strings.Join(sanitizeCorpusLines(lines), "\n")
+	if trailingNewline {
+		fixture += "\n"
+	}
+	return fixture
+}
+
+// corpusDir names the fixture directory of a language, such as
+// "go_template".
+func corpusDir(language string) string {
+	return strings.ReplaceAll(strings.ToLower(language), " ", "_")
+}
+
+// runRecordCorpus implements the hidden record-corpus command. It writes
+// a sanitized fixture for every file named, to attach to a bug report
+// about wrong placement; replay-corpus runs the selfcheck on them.
+func runRecordCorpus(cmd *cobra.Command, args []string) {
+	out, _ := cmd.Flags().GetString("output")
+	n, _ := cmd.Flags().GetInt("lines")
+	failed := false
+	for _, path := range args {
+		ext := strings.ToLower(filepath.Ext(path))
+		language, ok := languageNames[ext]
+		if !ok {
+			fmt.Fprintf(os.Stderr, "Error: %s: %v %q\n", path, errUnsupportedType, ext)
+			failed = true
+			continue
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failed = true
+			continue
+		}
+		fixture := recordFixture(string(data), n)
+		// Name the fixture after its content, never the original path
+		sum := sha256.Sum256([]byte(fixture))
+		dest := filepath.Join(out, corpusDir(language), hex.EncodeToString(sum[:6])+ext)
+		if err := os.MkdirAll(filepath.Dir(dest), 0755); err == nil {
+			err = os.WriteFile(dest, []byte(fixture), 0644)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			failed = true
+			continue
+		}
+		fmt.Printf("Recorded %s as %s\n", path, dest)
+	}
+	if failed {
+		os.Exit(exitError)
+	}
+}
+
+// runReplayCorpus implements the hidden replay-corpus command: the
+// selfcheck, run on recorded fixtures instead of the embedded corpus.
+func runReplayCorpus(cmd *cobra.Command, args []string) {
+	failures := 0
+	err := filepath.WalkDir(args[0], func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if _, ok := copyrighter.StyleFor(p); !ok {
+			return nil
+		}
+		src, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		for i, header := range selfcheckHeaders {
+			if err := checkStamping(p, src, header); err != nil {
+				fmt.Printf("FAIL %s (header %d): %v\n", p, i+1, err)
+				failures++
+				return nil
+			}
+		}
+		fmt.Printf("ok   %s\n", p)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading corpus: %v\n", err)
+		os.Exit(exitError)
+	}
+	if failures > 0 {
+		fmt.Printf("replay failed: %d problem(s)\n", failures)
+		os.Exit(exitError)
+	}
+	fmt.Println("replay passed")
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b      string
+		ins, dels int
+	}{
+		{"", "", 0, 0},
+		{"a\nb", "a\nb", 0, 0},
+		{"package main", "// c\n\npackage main\n\n// c", 4, 0},
+		{"// old\n\npackage main\n\n// old", "// new\n\npackage main\n\n// new", 2, 2},
+		{"a\nb\nc", "x\ny\nz", 3, 3},
+	}
+	for _, tt := range tests {
+		a := strings.Split(tt.a, "\n")
+		b := strings.Split(tt.b, "\n")
+		ops := diffLines(a, b)
+
+		var gotA, gotB []string
+		ins, dels := 0, 0
+		for _, op := range ops {
+			switch op.kind {
+			case ' ':
+				gotA = append(gotA, op.line)
+				gotB = append(gotB, op.line)
+			case '-':
+				gotA = append(gotA, op.line)
+				dels++
+			case '+':
+				gotB = append(gotB, op.line)
+				ins++
+			}
+		}
+		if strings.Join(gotA, "\n") != tt.a || strings.Join(gotB, "\n") != tt.b {
+			t.Errorf("diff of %q -> %q does not reproduce its inputs: %v", tt.a, tt.b, ops)
+		}
+		if ins != tt.ins || dels != tt.dels {
+			t.Errorf("diff of %q -> %q: got +%d -%d, want +%d -%d", tt.a, tt.b, ins, dels, tt.ins, tt.dels)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// sourceEncoding is the text encoding a source file is in. Files that are
+// not UTF-8 are stamped as UTF-8 and written back in their encoding, so
+// the notice is not added as mojibake.
+type sourceEncoding struct {
+	name string
+	// enc is nil for UTF-8, which is read and written as it is.
+	enc encoding.Encoding
+}
+
+var (
+	utf8Encoding    = sourceEncoding{name: "UTF-8"}
+	utf16LEEncoding = sourceEncoding{name: "UTF-16LE", enc: unicode.UTF16(unicode.LittleEndian, unicode.ExpectBOM)}
+	utf16BEEncoding = sourceEncoding{name: "UTF-16BE", enc: unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM)}
+	latin1Encoding  = sourceEncoding{name: "Latin-1", enc: charmap.ISO8859_1}
+)
+
+// detectEncoding tells the encoding of data: UTF-16 by its byte order
+// mark, UTF-8 when it is valid, and otherwise Latin-1, the usual encoding
+// of older sources. Binary data, with NUL bytes, is left to be read as it
+// is.
+func detectEncoding(data []byte) sourceEncoding {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return utf16LEEncoding
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		return utf16BEEncoding
+	case utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0:
+		return utf8Encoding
+	}
+	return latin1Encoding
+}
+
+// decodeSource returns data, the content of a source file, as UTF-8 and
+// the encoding it is in. Data that would not be written back byte for
+// byte, such as UTF-16 with unpaired surrogates, is refused.
+func decodeSource(data []byte) ([]byte, sourceEncoding, error) {
+	e := detectEncoding(data)
+	if e.enc == nil {
+		return data, e, nil
+	}
+	decoded, err := e.enc.NewDecoder().Bytes(data)
+	if err == nil {
+		var encoded []byte
+		if encoded, err = e.encode(string(decoded)); err == nil && !bytes.Equal(encoded, data) {
+			err = fmt.Errorf("%w: %s that does not decode cleanly", errEncoding, e.name)
+		}
+	}
+	if err != nil {
+		return nil, e, err
+	}
+	return decoded, e, nil
+}
+
+// encode converts content back to the encoding. A notice with characters
+// the encoding lacks, such as a Latin-1 file stamped with a curly quote,
+// is an error.
+func (e sourceEncoding) encode(content string) ([]byte, error) {
+	if e.enc == nil {
+		return []byte(content), nil
+	}
+	data, err := e.enc.NewEncoder().String(content)
+	if err != nil {
+		return nil, fmt.Errorf("%w: the notice cannot be written in %s: %v", errEncoding, e.name, err)
+	}
+	return []byte(data), nil
+}
+
+// readSource reads the file at path as UTF-8, see decodeSource.
+func (r *runner) readSource(path string) ([]byte, sourceEncoding, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, utf8Encoding, err
+	}
+	content, e, err := decodeSource(data)
+	if err != nil {
+		return nil, e, err
+	}
+	if e.enc != nil {
+		r.logf(logVerbose, "Reading %s as %s\n", path, e.name)
+	}
+	return content, e, nil
+}
+
+// writeSource writes after over the file at path, whose content was
+// before, in the encoding the file was read in.
+func (r *runner) writeSource(path string, e sourceEncoding, before, after string) error {
+	original, err := e.encode(before)
+	if err != nil {
+		return err
+	}
+	content, err := e.encode(after)
+	if err != nil {
+		return err
+	}
+	return r.writeFile(path, original, content)
+}
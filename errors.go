@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// Causes a fileError can wrap, so failures can be classified with
+// errors.Is instead of by their message.
+var (
+	errUnsupportedType = copyrighter.ErrUnsupported
+	errSymlink         = errors.New("is a symlink")
+	errOutsideRoots    = errors.New("outside the allowed roots")
+	errNotIdempotent   = errors.New("not idempotent")
+	errTooLarge        = errors.New("file too large")
+	errEncoding        = errors.New("unsupported encoding")
+	errChanged         = errors.New("changed while it was being processed")
+)
+
+// errWindowLost is returned when the notice of a large file cannot be
+// stamped from its head and tail alone; the file is then processed again,
+// read whole.
+var errWindowLost = errors.New("the notice runs into the middle of the file")
+
+// fileError is the failure of one file or path argument in a run.
+type fileError struct {
+	Path string
+	Err  error
+}
+
+// Error prefixes the cause with the path, unless the cause names it
+// already.
+func (e *fileError) Error() string {
+	msg := e.Err.Error()
+	if strings.Contains(msg, e.Path) {
+		return msg
+	}
+	return e.Path + ": " + msg
+}
+
+func (e *fileError) Unwrap() error {
+	return e.Err
+}
+
+// err returns every failure of the run joined with errors.Join, each a
+// *fileError, or nil if nothing failed.
+func (r *runner) err() error {
+	return errors.Join(r.errs...)
+}
+
+// countFailures counts the failures joined in err whose cause is target.
+func countFailures(err error, target error) int {
+	joined, ok := err.(interface{ Unwrap() []error })
+	if !ok {
+		if err != nil && errors.Is(err, target) {
+			return 1
+		}
+		return 0
+	}
+	n := 0
+	for _, e := range joined.Unwrap() {
+		if errors.Is(e, target) {
+			n++
+		}
+	}
+	return n
+}
+
+// printFailures lists the failures in err one per line, unwrapping an
+// errors.Join of several.
+func printFailures(w io.Writer, err error) {
+	errs := []error{err}
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		errs = joined.Unwrap()
+	}
+	fmt.Fprintf(w, "%d %s failed:\n", len(errs), plural(len(errs), "path", "paths"))
+	for _, e := range errs {
+		fmt.Fprintf(w, "  %v\n", e)
+	}
+}
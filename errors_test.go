@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestRunErrorsAreTyped checks that a run's failures can be counted and
+// classified from the joined error alone.
+func TestRunErrorsAreTyped(t *testing.T) {
+	dir := t.TempDir()
+	unsupported := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(unsupported, []byte("notes\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	r := &runner{out: &out}
+	r.run(slices.Values([]string{unsupported, filepath.Join(dir, "missing.go")}))
+
+	err := r.err()
+	if got := countFailures(err, errUnsupportedType); got != 1 {
+		t.Errorf("unsupported failures = %d, want 1", got)
+	}
+	if got := countFailures(err, os.ErrNotExist); got != 1 {
+		t.Errorf("missing failures = %d, want 1", got)
+	}
+	// The console messages go to the runner's writer, not to stdout
+	if !strings.Contains(out.String(), "Error: lstat "+filepath.Join(dir, "missing.go")) {
+		t.Errorf("console output %q does not report the missing file", out.String())
+	}
+	var fe *fileError
+	if !errors.As(err, &fe) || fe.Path != unsupported {
+		t.Errorf("first failure is %v, want a *fileError for %s", err, unsupported)
+	}
+}
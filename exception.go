@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// exception suppresses the findings of the files matching a path pattern
+// until it expires. Every exception must say why it exists and who owns
+// it, and check fails once it has expired, so suppressions are
+// re-justified periodically instead of piling up.
+type exception struct {
+	Path   string `yaml:"path" json:"path"`
+	Reason string `yaml:"reason" json:"reason"`
+	Owner  string `yaml:"owner" json:"owner"`
+	// Expires is a month (YYYY-MM, which ends with that month) or a day
+	// (YYYY-MM-DD), like a Maintained-by review date.
+	Expires string `yaml:"expires" json:"expires"`
+}
+
+// checkExceptions refuses an exception that is missing a field or has an
+// unreadable expiry date.
+func checkExceptions(exceptions []exception) error {
+	for i, e := range exceptions {
+		switch {
+		case e.Path == "":
+			return fmt.Errorf("exceptions[%d]: path is required", i)
+		case e.Reason == "":
+			return fmt.Errorf("exceptions[%d] (%s): reason is required", i, e.Path)
+		case e.Owner == "":
+			return fmt.Errorf("exceptions[%d] (%s): owner is required", i, e.Path)
+		case e.Expires == "":
+			return fmt.Errorf("exceptions[%d] (%s): expires is required", i, e.Path)
+		}
+		if _, err := reviewDue(e.Expires); err != nil {
+			return fmt.Errorf("exceptions[%d] (%s): %w", i, e.Path, err)
+		}
+	}
+	return nil
+}
+
+// expired reports whether e no longer applies at now.
+func (e exception) expired(now time.Time) bool {
+	due, err := reviewDue(e.Expires)
+	return err != nil || !now.Before(due)
+}
+
+// matches reports whether the exception covers path.
+func (e exception) matches(path string) bool {
+	return matchesWorkPath(e.Path, path)
+}
+
+// matchesWorkPath reports whether pattern matches path, given as found or
+// relative to the working directory.
+func matchesWorkPath(pattern, path string) bool {
+	if matchGlob(pattern, path) {
+		return true
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return false
+	}
+	rel, err := filepath.Rel(wd, abs)
+	return err == nil && matchGlob(pattern, rel)
+}
+
+// exceptionFor returns the unexpired exception covering path, if any.
+func (r *runner) exceptionFor(path string) *exception {
+	for i, e := range r.exceptions {
+		if !e.expired(r.now) && e.matches(path) {
+			return &r.exceptions[i]
+		}
+	}
+	return nil
+}
+
+// skipExcepted records a file whose findings an exception suppresses.
+func (r *runner) skipExcepted(filePath string, e *exception) {
+	r.logf(logVerbose, "Skipping file covered by an exception (%s, owner %s, expires %s): %s\n", e.Reason, e.Owner, e.Expires, filePath)
+	r.skip(filePath)
+	r.summary.Excepted++
+	r.record(fileOutcome{Path: filePath, Status: "excepted", Exception: e})
+}
+
+// reportExpiredExceptions flags every exception that has expired, which
+// fails check until it is renewed or removed.
+func (r *runner) reportExpiredExceptions() {
+	for _, e := range r.exceptions {
+		if e.expired(r.now) {
+			r.logf(logQuiet, "exception for %s owned by %s expired %s (%s); renew or remove it\n", e.Path, e.Owner, e.Expires, e.Reason)
+			r.summary.ExpiredExceptions++
+		}
+	}
+}
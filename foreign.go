@@ -0,0 +1,109 @@
+package main
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// foreignHeader returns what identifies the header or footer stamping
+// would replace as another party's, such as an upstream project's
+// copyright line or the text of a license other than the notice's, or ""
+// when it is ours or nothing is replaced. Overwriting it would destroy an
+// attribution the file must keep, so runs leave such files alone unless
+// overwrite_foreign is set or a match pattern claims it as a legacy format
+// of ours. Checks, when check is set, only report the file as outdated.
+func (r *runner) foreignHeader(content string, opts copyrighter.Options, result copyrighter.Result, check bool) string {
+	if r.overwriteForeign || check || r.remove || r.audit != nil {
+		return ""
+	}
+	_, lines := copyrighter.SplitContent(content, opts)
+	header, footer := copyrighter.ExpectedNotice(lines, opts)
+	expected := strings.Join(append(slices.Clip(header), footer...), "\n")
+	if result.Header == copyrighter.Updated {
+		if existing := opts.Style.LeadingComment(lines, len(header)); existing > 0 {
+			if foreign := foreignNotice(lines[:existing], expected, opts); foreign != "" {
+				return foreign
+			}
+		}
+	}
+	if result.Footer == copyrighter.Updated {
+		if existing := opts.Style.TrailingComment(lines, len(footer)); existing > 0 {
+			return foreignNotice(lines[len(lines)-existing:], expected, opts)
+		}
+	}
+	return ""
+}
+
+// foreignNotice returns what identifies comment, the lines of a header or
+// footer, as another party's rather than a notice of ours like expected.
+func foreignNotice(comment []string, expected string, opts copyrighter.Options) string {
+	found := strings.Join(comment, "\n")
+	for _, re := range opts.MatchPatterns {
+		if re.MatchString(found) {
+			return ""
+		}
+	}
+
+	ours := make(map[string]bool)
+	for _, statement := range copyrightStatements([]byte(expected)) {
+		ours[holderName(statement)] = true
+	}
+	for _, statement := range copyrightStatements([]byte(found)) {
+		if attribution.MatchString(statement) && !ours[holderName(statement)] {
+			return statement
+		}
+	}
+	if l, ok := identifyLicense(commentText(found)); ok {
+		if own, ok := identifyLicense(commentText(expected)); !ok || own.id != l.id {
+			return l.id + " license text"
+		}
+	}
+	return ""
+}
+
+// attribution matches a copyright statement that credits someone: one
+// with a year or a copyright sign, unlike a bare "Old copyright" comment.
+var attribution = regexp.MustCompile(`©|\([cC]\)|\b` + copyrighter.YearRangeSource + `\b`)
+
+var (
+	// holderNoise matches the parts of a copyright statement around the
+	// name of its holder.
+	holderNoise = regexp.MustCompile(`(?i)spdx-filecopyrighttext:|copyright|©|\(c\)|all rights reserved|\bby\b|` + copyrighter.YearRangeSource)
+	// sentenceEnd ends the holder's name, as in "Example Corp. Licensed
+	// under ..." or "Example Corp. All rights reserved.".
+	sentenceEnd = regexp.MustCompile(`\.\s+[A-Z]`)
+)
+
+// holderName returns the name of the holder a copyright statement
+// credits, in lower case, so statements of one holder compare equal
+// whatever their years and wording.
+func holderName(statement string) string {
+	name := strings.Join(strings.Fields(holderNoise.ReplaceAllString(statement, " ")), " ")
+	if end := sentenceEnd.FindStringIndex(name); end != nil {
+		name = name[:end[0]]
+	}
+	return strings.ToLower(strings.Trim(name, " .,;:-"))
+}
+
+// commentText returns the text of a comment block without its markers.
+func commentText(block string) string {
+	var b strings.Builder
+	for line := range strings.Lines(block) {
+		b.WriteString(strings.Trim(line, commentMarkers+"\r\n") + "\n")
+	}
+	return b.String()
+}
+
+// skipForeign reports and records a file whose header belongs to another
+// party. It counts against the run, so the file gets a decision: a holders
+// entry, an ignore comment or --overwrite-foreign.
+func (r *runner) skipForeign(filePath, detail string) {
+	r.summary.Scanned++
+	r.summary.Foreign++
+	r.logf(logQuiet, "%s: foreign header (%s), not overwritten\n", filePath, detail)
+	r.cache.forget(filePath)
+	r.record(fileOutcome{Path: filePath, Status: "foreign", Problems: []string{"foreign header"}, ForeignHeader: detail})
+}
@@ -0,0 +1,24 @@
+package main
+
+import "github.com/earik87/copy-righter/pkg/copyrighter"
+
+// skipGenerated reports and records a generated file that is left alone.
+func (r *runner) skipGenerated(filePath string) {
+	r.logf(logVerbose, "Skipping generated file: %s\n", filePath)
+	r.skip(filePath)
+	if r.audit != nil {
+		r.audit.add(filePath, auditGenerated, "")
+	}
+	r.record(fileOutcome{Path: filePath, Status: "generated"})
+}
+
+// skipIgnored reports and records a file that opts out of stamping with
+// the ignore directive.
+func (r *runner) skipIgnored(filePath string) {
+	r.logf(logVerbose, "Skipping file with a %s comment: %s\n", copyrighter.IgnoreDirective, filePath)
+	r.skip(filePath)
+	if r.audit != nil {
+		r.audit.add(filePath, auditIgnored, "")
+	}
+	r.record(fileOutcome{Path: filePath, Status: "ignored"})
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// gitChangedFiles lists the files git reports as changed, relative to the
+// working directory. With staged it lists files added or modified in the
+// index; otherwise files changed since the ref, in commits or in the working
+// tree, plus untracked files that are not ignored. Deleted files are omitted.
+func gitChangedFiles(since string, staged bool) ([]string, error) {
+	var files []string
+	if staged {
+		out, err := git("diff", "--cached", "--name-only", "--relative", "--diff-filter=d", "-z")
+		if err != nil {
+			return nil, err
+		}
+		return splitNul(out), nil
+	}
+
+	out, err := git("diff", "--name-only", "--relative", "--diff-filter=d", "-z", since, "--")
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, splitNul(out)...)
+	out, err = git("ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, splitNul(out)...)
+	slices.Sort(files)
+	return slices.Compact(files), nil
+}
+
+// git runs a git command in the working directory and returns its stdout.
+func git(args ...string) ([]byte, error) {
+	cmd := exec.Command("git", args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("git %s: %s", args[0], msg)
+		}
+		return nil, fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return out, nil
+}
+
+func splitNul(out []byte) []string {
+	var fields []string
+	for _, f := range bytes.Split(out, []byte{0}) {
+		if len(f) > 0 {
+			fields = append(fields, string(f))
+		}
+	}
+	return fields
+}
+
+// isBeneathAny reports whether path is one of roots or inside one of them.
+// Both are made absolute first, so a path git reports relative to the
+// working directory is matched against an absolute argument too.
+func isBeneathAny(path string, roots []string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	for _, root := range roots {
+		root, err := filepath.Abs(trimRecursivePattern(root))
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(root, path)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// The attributes GitHub's linguist reads from .gitattributes to leave
+// generated and vendored code out of language statistics and diffs.
+const (
+	linguistGenerated = "linguist-generated"
+	linguistVendored  = "linguist-vendored"
+)
+
+// attributeRule is a line of a .gitattributes file: a pattern and the
+// values it gives the linguist attributes, "" for an attribute it resets
+// with "!attr".
+type attributeRule struct {
+	pattern string
+	values  map[string]string
+}
+
+// gitAttributes answers which files the .gitattributes files of their
+// repository mark with the linguist attributes, so the run agrees with
+// GitHub on what is real source. Each file is read once; lookups are safe
+// for concurrent use.
+type gitAttributes struct {
+	mu    sync.Mutex
+	rules map[string][]attributeRule
+}
+
+// set reports whether attr is set to true for the file at filePath. As
+// in git, the .gitattributes closest to the file win over those above it,
+// and later lines over earlier ones.
+func (a *gitAttributes) set(filePath, attr string) bool {
+	if a == nil {
+		return false
+	}
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return false
+	}
+	var dirs []string
+	for dir := filepath.Dir(abs); ; {
+		dirs = append(dirs, dir)
+		parent := filepath.Dir(dir)
+		if isRepoRoot(dir) || parent == dir {
+			break
+		}
+		dir = parent
+	}
+
+	value := ""
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rel, err := filepath.Rel(dirs[i], abs)
+		if err != nil {
+			continue
+		}
+		rel = filepath.ToSlash(rel)
+		for _, rule := range a.load(dirs[i]) {
+			if v, ok := rule.values[attr]; ok && matchAttributePattern(rule.pattern, rel) {
+				value = v
+			}
+		}
+	}
+	return value == "true"
+}
+
+// load returns the linguist rules of the .gitattributes in dir, if any.
+func (a *gitAttributes) load(dir string) []attributeRule {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if rules, ok := a.rules[dir]; ok {
+		return rules
+	}
+	if a.rules == nil {
+		a.rules = make(map[string][]attributeRule)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, ".gitattributes"))
+	if err != nil {
+		a.rules[dir] = nil
+		return nil
+	}
+	rules := parseGitAttributes(data)
+	a.rules[dir] = rules
+	return rules
+}
+
+// parseGitAttributes returns the lines of a .gitattributes file that
+// mention a linguist attribute. Macro definitions are not expanded.
+func parseGitAttributes(data []byte) []attributeRule {
+	var rules []attributeRule
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") || strings.HasPrefix(fields[0], "[attr]") {
+			continue
+		}
+		values := make(map[string]string)
+		for _, field := range fields[1:] {
+			name, value, hasValue := strings.Cut(field, "=")
+			switch {
+			case strings.HasPrefix(name, "-"):
+				name, value = name[1:], "false"
+			case strings.HasPrefix(name, "!"):
+				name, value = name[1:], ""
+			case !hasValue:
+				value = "true"
+			}
+			if name == linguistGenerated || name == linguistVendored {
+				values[name] = strings.ToLower(value)
+			}
+		}
+		if len(values) > 0 {
+			rules = append(rules, attributeRule{pattern: fields[0], values: values})
+		}
+	}
+	return rules
+}
+
+// matchAttributePattern reports whether a .gitattributes pattern matches
+// rel, a path relative to the directory of the .gitattributes. Unlike an
+// exclude pattern, one without a slash matches the file name only: as in
+// git, a pattern matching a directory does not apply to the files in it,
+// which takes "dir/**".
+func matchAttributePattern(pattern, rel string) bool {
+	if !strings.Contains(pattern, "/") {
+		ok, _ := path.Match(pattern, path.Base(rel))
+		return ok
+	}
+	return matchSegments(strings.Split(strings.TrimPrefix(pattern, "/"), "/"), strings.Split(rel, "/"))
+}
@@ -1,9 +1,21 @@
-module copy-righter
+module github.com/earik87/copy-righter
 
 go 1.25.0
 
 require (
-	github.com/inconshreveable/mousetrap v1.1.0 // indirect
+	github.com/fsnotify/fsnotify v1.9.0
+	github.com/golangci/plugin-module-register v0.1.2
 	github.com/spf13/cobra v1.10.1
+	golang.org/x/term v0.36.0
+	golang.org/x/text v0.30.0
+	golang.org/x/tools v0.38.0
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require (
+	github.com/inconshreveable/mousetrap v1.1.0 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
-)
\ No newline at end of file
+	golang.org/x/mod v0.29.0 // indirect
+	golang.org/x/sync v0.17.0 // indirect
+	golang.org/x/sys v0.37.0 // indirect
+)
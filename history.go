@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// historyRecord is one line of the run history file: when and where a run
+// happened and how compliant each language was.
+type historyRecord struct {
+	Date      time.Time       `json:"date"`
+	Repo      string          `json:"repo"`
+	Command   string          `json:"command"`
+	Languages []languageStats `json:"languages"`
+}
+
+// languageStats counts the files of one language seen by a run.
+type languageStats struct {
+	Language  string `json:"language"`
+	Scanned   int    `json:"scanned"`
+	Compliant int    `json:"compliant"`
+	Fixed     int    `json:"fixed"`
+}
+
+// languageNames maps extensions to the language names used in history.
+var languageNames = func() map[string]string {
+	names := make(map[string]string)
+	for _, lang := range copyrighter.Languages {
+		for _, ext := range lang.Extensions {
+			names[ext] = lang.Name
+		}
+	}
+	return names
+}()
+
+// languageStats returns the counters for the language of path, creating
+// them on first use.
+func (r *runner) languageStats(path string) *languageStats {
+	name := languageNames[strings.ToLower(filepath.Ext(path))]
+	if r.languages == nil {
+		r.languages = make(map[string]*languageStats)
+	}
+	stats, ok := r.languages[name]
+	if !ok {
+		stats = &languageStats{Language: name}
+		r.languages[name] = stats
+	}
+	return stats
+}
+
+// appendHistory adds a record of the finished run to the history file.
+func (r *runner) appendHistory() error {
+	record := historyRecord{
+		Date:    r.now.UTC(),
+		Repo:    repoName(),
+		Command: r.summary.Command,
+	}
+	for _, stats := range r.languages {
+		record.Languages = append(record.Languages, *stats)
+	}
+	sort.Slice(record.Languages, func(i, j int) bool { return record.Languages[i].Language < record.Languages[j].Language })
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(r.history, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// repoName names the repository a run happened in: the top level of the
+// enclosing git work tree, or else the working directory.
+func repoName() string {
+	if top, err := git("rev-parse", "--show-toplevel"); err == nil {
+		return filepath.Base(strings.TrimSpace(string(top)))
+	}
+	wd, _ := os.Getwd()
+	return filepath.Base(wd)
+}
+
+// readHistory reads every record of a history file.
+func readHistory(path string) ([]historyRecord, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []historyRecord
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for n := 1; scanner.Scan(); n++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var rec historyRecord
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		records = append(records, rec)
+	}
+	return records, scanner.Err()
+}
+
+// trendRow is one row of an exported compliance table: a language in a run.
+type trendRow struct {
+	date         time.Time
+	repo         string
+	language     string
+	scanned      int
+	compliantPct float64
+	fixesApplied int
+}
+
+func trendRows(records []historyRecord) []trendRow {
+	var rows []trendRow
+	for _, rec := range records {
+		for _, l := range rec.Languages {
+			pct := 100.0
+			if l.Scanned > 0 {
+				pct = float64(l.Compliant) * 100 / float64(l.Scanned)
+			}
+			rows = append(rows, trendRow{rec.Date, rec.Repo, l.Language, l.Scanned, pct, l.Fixed})
+		}
+	}
+	return rows
+}
+
+// trendColumns names the exported columns, in order.
+var trendColumns = []string{"date", "repo", "language", "scanned", "compliant_pct", "fixes_applied"}
+
+func writeTrendCSV(w io.Writer, rows []trendRow) error {
+	cw := csv.NewWriter(w)
+	cw.Write(trendColumns)
+	for _, row := range rows {
+		cw.Write([]string{
+			row.date.Format(time.DateOnly),
+			row.repo,
+			row.language,
+			strconv.Itoa(row.scanned),
+			strconv.FormatFloat(row.compliantPct, 'f', 1, 64),
+			strconv.Itoa(row.fixesApplied),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeTrendParquet(w io.Writer, rows []trendRow) error {
+	columns := []*parquetColumn{
+		newParquetColumn(trendColumns[0], parquetInt32, parquetDate),
+		newParquetColumn(trendColumns[1], parquetByteArray, parquetUTF8),
+		newParquetColumn(trendColumns[2], parquetByteArray, parquetUTF8),
+		newParquetColumn(trendColumns[3], parquetInt64, parquetNoConversion),
+		newParquetColumn(trendColumns[4], parquetDouble, parquetNoConversion),
+		newParquetColumn(trendColumns[5], parquetInt64, parquetNoConversion),
+	}
+	for _, row := range rows {
+		columns[0].appendInt32(int32(row.date.Unix() / (24 * 60 * 60)))
+		columns[1].appendString(row.repo)
+		columns[2].appendString(row.language)
+		columns[3].appendInt64(int64(row.scanned))
+		columns[4].appendDouble(row.compliantPct)
+		columns[5].appendInt64(int64(row.fixesApplied))
+	}
+	return writeParquet(w, columns, len(rows))
+}
+
+// runReportExport implements "report export": it converts the run history
+// into a table for analytics dashboards.
+func runReportExport(cmd *cobra.Command, args []string) {
+	historyPath, _ := cmd.Flags().GetString("history")
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	write := map[string]func(io.Writer, []trendRow) error{
+		"csv":     writeTrendCSV,
+		"parquet": writeTrendParquet,
+	}[format]
+	if write == nil {
+		fmt.Fprintf(os.Stderr, "Error: unsupported format %q (supported: csv, parquet)\n", format)
+		os.Exit(exitError)
+	}
+
+	records, err := readHistory(historyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading history: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	w := io.Writer(os.Stdout)
+	if output != "" && output != "-" {
+		f, err := os.Create(output)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		defer f.Close()
+		w = f
+	}
+	if err := write(w, trendRows(records)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing report: %v\n", err)
+		os.Exit(exitError)
+	}
+}
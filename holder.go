@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// holder maps the files matching a path pattern to a copyright holder
+// other than the top-level notice, or marks them as someone else's to
+// leave alone, so one run can stamp a tree with code from several owners.
+// The first entry matching a file applies.
+type holder struct {
+	Path string `yaml:"path"`
+	// Copyright or CopyrightFile is the notice of the matching files,
+	// stamped with SPDX if set, or the top-level spdx otherwise.
+	Copyright     copyrightNotice `yaml:"copyright"`
+	CopyrightFile string          `yaml:"copyright_file"`
+	SPDX          string          `yaml:"spdx"`
+	// Skip leaves the matching files untouched, such as vendored code
+	// under its authors' notice.
+	Skip bool `yaml:"skip"`
+}
+
+// loadHolders checks the holders setting and reads the notices given as
+// files.
+func loadHolders(holders []holder) ([]holder, error) {
+	loaded := make([]holder, len(holders))
+	for i, h := range holders {
+		notices := 0
+		for _, set := range []bool{h.Skip, h.Copyright != "", h.CopyrightFile != ""} {
+			if set {
+				notices++
+			}
+		}
+		switch {
+		case h.Path == "":
+			return nil, fmt.Errorf("holders[%d]: path is required", i)
+		case notices != 1:
+			return nil, fmt.Errorf("holders[%d] (%s): set exactly one of copyright, copyright_file and skip", i, h.Path)
+		case h.Skip && h.SPDX != "":
+			return nil, fmt.Errorf("holders[%d] (%s): spdx has no effect on skipped files", i, h.Path)
+		}
+		if h.CopyrightFile != "" {
+			data, err := os.ReadFile(h.CopyrightFile)
+			if err != nil {
+				return nil, fmt.Errorf("holders[%d] (%s): reading copyright file: %w", i, h.Path, err)
+			}
+			h.Copyright = copyrightNotice(data)
+		}
+		loaded[i] = h
+	}
+	return loaded, nil
+}
+
+// holderFor returns the holder entry covering path, if any.
+func (r *runner) holderFor(path string) *holder {
+	for i, h := range r.holders {
+		if matchesWorkPath(h.Path, path) {
+			return &r.holders[i]
+		}
+	}
+	return nil
+}
+
+// skipHolder records a file the holders setting leaves to its owners.
+func (r *runner) skipHolder(filePath string, h *holder) {
+	r.logf(logVerbose, "Skipping file left to its owners by holders (%s): %s\n", h.Path, filePath)
+	r.skip(filePath)
+	r.record(fileOutcome{Path: filePath, Status: "skipped"})
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// The lines that delimit the block install-hook adds to a pre-commit hook,
+// so it can be found and replaced by a later install.
+const (
+	hookBegin = "# >>> copy-righter >>>"
+	hookEnd   = "# <<< copy-righter <<<"
+)
+
+// Modes of the pre-commit hook.
+const (
+	hookCheck = "check"
+	hookFix   = "fix"
+)
+
+// shellShebang matches the first line of a hook written in a POSIX shell,
+// which install-hook can add its block to.
+var shellShebang = regexp.MustCompile(`^#!\s*(/usr/bin/env\s+)?(/bin/|/usr/bin/)?(sh|bash|dash|zsh|ksh)\b`)
+
+// runInstallHook implements the install-hook subcommand: it makes the
+// pre-commit hook of the current repository run copy-righter on the staged
+// files, keeping whatever the hook already does.
+func runInstallHook(cmd *cobra.Command, args []string) {
+	mode, _ := cmd.Flags().GetString("mode")
+	if mode != hookCheck && mode != hookFix {
+		fmt.Fprintf(os.Stderr, "Error: invalid --mode %q, want %s or %s\n", mode, hookCheck, hookFix)
+		os.Exit(exitError)
+	}
+	hook, err := gitHookPath("pre-commit")
+	if err == nil {
+		err = installHook(hook, hookBlock(mode, hookCommand(), args))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Installed the copy-righter %s hook in %s\n", mode, hook)
+}
+
+// gitHookPath returns the path of the git hook name, honouring
+// core.hooksPath.
+func gitHookPath(name string) (string, error) {
+	out, err := git("rev-parse", "--git-path", "hooks/"+name)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(strings.TrimSpace(string(out))), nil
+}
+
+// hookCommand returns how the hook runs copy-righter: by name when the
+// binary on the PATH is this one, otherwise by its absolute path.
+func hookCommand() string {
+	self, err := os.Executable()
+	if err != nil {
+		return "copy-righter"
+	}
+	if onPath, err := exec.LookPath("copy-righter"); err == nil && sameFile(onPath, self) {
+		return "copy-righter"
+	}
+	return self
+}
+
+func sameFile(a, b string) bool {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	return err == nil && os.SameFile(ia, ib)
+}
+
+// hookBlock returns the lines the hook runs for mode, passing extra
+// arguments such as --cache on to copy-righter. Fix mode stamps the
+// staged files but then stops the commit, since the stamps are not staged
+// yet and staging whole files could sweep in unrelated edits.
+func hookBlock(mode, command string, extra []string) string {
+	words := []string{shellQuote(command)}
+	if mode == hookCheck {
+		words = append(words, "check")
+	}
+	words = append(words, "--staged")
+	for _, arg := range extra {
+		words = append(words, shellQuote(arg))
+	}
+	run := strings.Join(words, " ")
+	var b strings.Builder
+	b.WriteString(hookBegin + "\n")
+	b.WriteString("# Installed by copy-righter install-hook; reinstalling replaces this block.\n")
+	if mode == hookCheck {
+		b.WriteString(run + " || exit $?\n")
+	} else {
+		b.WriteString(run + "\n")
+		b.WriteString("status=$?\n")
+		b.WriteString("if [ $status -eq 1 ]; then\n")
+		b.WriteString("\techo 'copy-righter stamped staged files: review the changes, git add them and commit again' >&2\n")
+		b.WriteString("fi\n")
+		b.WriteString("[ $status -eq 0 ] || exit $status\n")
+	}
+	b.WriteString(hookEnd + "\n")
+	return b.String()
+}
+
+// installHook writes block into the hook at path. A block installed
+// before is replaced. An existing shell hook gets the block right after
+// its shebang, so it runs even if the rest of the hook exits early; any
+// other hook is moved to path.local and chained from a new shell hook.
+func installHook(path, block string) error {
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return writeHook(path, "#!/bin/sh\n"+block)
+	case err != nil:
+		return err
+	}
+	content := string(data)
+	if begin := strings.Index(content, hookBegin+"\n"); begin >= 0 {
+		end := strings.Index(content[begin:], hookEnd+"\n")
+		if end < 0 {
+			return fmt.Errorf("%s has a copy-righter block without its end line %q", path, hookEnd)
+		}
+		return writeHook(path, content[:begin]+block+content[begin+end+len(hookEnd)+1:])
+	}
+	first, rest, _ := strings.Cut(content, "\n")
+	if shellShebang.MatchString(first) {
+		return writeHook(path, first+"\n"+block+rest)
+	}
+	local := path + ".local"
+	if _, err := os.Stat(local); err == nil {
+		return fmt.Errorf("%s is not a shell script and %s already exists; add copy-righter to it by hand", path, local)
+	}
+	if err := os.Rename(path, local); err != nil {
+		return err
+	}
+	return writeHook(path, "#!/bin/sh\n"+block+"exec \"$(dirname \"$0\")\"/"+shellQuote(filepath.Base(local))+" \"$@\"\n")
+}
+
+func writeHook(path, content string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	if err := writeFileAtomic(path, []byte(content)); err != nil {
+		return err
+	}
+	// A hook must be executable to run
+	return os.Chmod(path, 0755)
+}
+
+// shellQuote quotes s for a POSIX shell, leaving plain words as they are.
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_=./:,@+") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
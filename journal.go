@@ -0,0 +1,408 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// journalOff turns the journal off when given as its directory.
+const journalOff = "off"
+
+// journalRuns is how many run journals are kept; older ones are deleted
+// when a run starts a new one.
+const journalRuns = 20
+
+// journalExt ends the name of the journal of a run that can be undone. An
+// undone run's journal gets undoneExt appended so it is not undone twice.
+const (
+	journalExt = ".jsonl"
+	undoneExt  = ".undone"
+)
+
+// The values of the journal_sync setting: when the journal is flushed to
+// disk. With journalSyncFile, the default, every entry is on disk before
+// the file it records is overwritten, so an interrupted run can always be
+// undone; journalSyncRun flushes once at the end of the run, trading that
+// guarantee for speed on slow disks, and journalSyncNone leaves it to the
+// operating system.
+const (
+	journalSyncFile = "file"
+	journalSyncRun  = "run"
+	journalSyncNone = "none"
+)
+
+// checkJournalSync refuses an unknown journal_sync.
+func checkJournalSync(v string) error {
+	switch v {
+	case "", journalSyncFile, journalSyncRun, journalSyncNone:
+		return nil
+	}
+	return fmt.Errorf("invalid journal sync %q, want %s, %s or %s", v, journalSyncFile, journalSyncRun, journalSyncNone)
+}
+
+// journalEntry records one file a run modified, with its original
+// content, so undo can put it back. Seq numbers the entries of a run in
+// the order the files were written; undo reverts them in reverse.
+type journalEntry struct {
+	Seq      int    `json:"seq"`
+	Path     string `json:"path"`
+	Before   string `json:"sha256_before"`
+	After    string `json:"sha256_after"`
+	Original []byte `json:"original"`
+	// OriginalFile names the file, next to the journal, that holds the
+	// original content of a large file instead of Original.
+	OriginalFile string `json:"original_file,omitempty"`
+}
+
+// originalExt ends the name of a file holding the original content of a
+// large file a run modified.
+const originalExt = ".orig"
+
+// journal records the files a run modifies. Its file is created on the
+// first write, so a run that changes nothing leaves no journal behind.
+// Entries are written whole under mu, one line each, so the journal stays
+// readable however its writers are scheduled.
+type journal struct {
+	dir  string
+	id   string
+	sync string
+
+	mu  sync.Mutex
+	f   *os.File
+	seq int
+}
+
+// journalDir resolves the journal setting: the directory to keep run
+// journals in, by default in the user's cache directory. It returns an
+// empty string when the journal is off or there is nowhere to keep it.
+func journalDir(setting string) string {
+	switch setting {
+	case journalOff:
+		return ""
+	case "":
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		return filepath.Join(cache, "copy-righter", "journal")
+	}
+	return setting
+}
+
+// newJournal returns the journal of a run starting at now, flushed to
+// disk according to sync, or nil when dir is empty.
+func newJournal(dir, sync string, now time.Time) *journal {
+	if dir == "" {
+		return nil
+	}
+	return &journal{dir: dir, sync: sync, id: now.UTC().Format("20060102-150405.000000")}
+}
+
+// record appends the original content of path to the journal before the
+// file is overwritten with content. A nil journal records nothing.
+func (j *journal) record(path string, original, content []byte) error {
+	if j == nil {
+		return nil
+	}
+	return j.add(path, journalEntry{Before: hashString(string(original)), After: hashString(string(content)), Original: original}, nil)
+}
+
+// recordFrom is record for a large file, whose original content is read
+// from original and copied to a file of its own instead of being held in
+// memory; before and after are the hashes of its old and new content.
+func (j *journal) recordFrom(path string, original io.Reader, before, after string) error {
+	if j == nil {
+		return nil
+	}
+	return j.add(path, journalEntry{Before: before, After: after}, original)
+}
+
+// add appends e, for the file at path, to the journal, after copying
+// original, if any, to the file e names.
+func (j *journal) add(path string, e journalEntry, original io.Reader) error {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return err
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		if err := j.open(); err != nil {
+			return fmt.Errorf("recording the run journal: %w", err)
+		}
+	}
+	j.seq++
+	e.Seq, e.Path = j.seq, abs
+	if original != nil {
+		e.OriginalFile = fmt.Sprintf("%s-%d%s", j.id, j.seq, originalExt)
+		if err := j.copyOriginal(e.OriginalFile, original); err != nil {
+			return fmt.Errorf("recording the run journal: %w", err)
+		}
+	}
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := j.f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("recording the run journal: %w", err)
+	}
+	if j.sync == "" || j.sync == journalSyncFile {
+		if err := j.f.Sync(); err != nil {
+			return fmt.Errorf("recording the run journal: %w", err)
+		}
+	}
+	return nil
+}
+
+// copyOriginal writes the original content of a large file to the file
+// name in the journal directory.
+func (j *journal) copyOriginal(name string, original io.Reader) error {
+	f, err := os.OpenFile(filepath.Join(j.dir, name), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, original); err != nil {
+		f.Close()
+		return err
+	}
+	if j.sync == "" || j.sync == journalSyncFile {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	return f.Close()
+}
+
+// open creates the journal file and deletes the oldest journals beyond
+// journalRuns.
+func (j *journal) open() error {
+	if err := os.MkdirAll(j.dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(j.dir, j.id+journalExt), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	j.f = f
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), journalExt) || strings.HasSuffix(e.Name(), journalExt+undoneExt) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names[:max(len(names)-journalRuns, 0)] {
+		os.Remove(filepath.Join(j.dir, name))
+		id := strings.TrimSuffix(strings.TrimSuffix(name, undoneExt), journalExt)
+		originals, _ := filepath.Glob(filepath.Join(j.dir, id+"-*"+originalExt))
+		for _, o := range originals {
+			os.Remove(o)
+		}
+	}
+	return nil
+}
+
+// close closes the journal file, if the run wrote one.
+func (j *journal) close() error {
+	if j == nil {
+		return nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.f == nil {
+		return nil
+	}
+	if j.sync == journalSyncRun {
+		if err := j.f.Sync(); err != nil {
+			j.f.Close()
+			return err
+		}
+	}
+	return j.f.Close()
+}
+
+// written reports whether the run modified any file.
+func (j *journal) written() bool {
+	return j != nil && j.f != nil
+}
+
+// writeFile overwrites a file the run modifies with content, after
+// recording its original content in the run journal.
+func (r *runner) writeFile(path string, original, content []byte) error {
+	if err := r.journal.record(path, original, content); err != nil {
+		return err
+	}
+	return writeFileAtomic(path, content)
+}
+
+// runUndo implements the undo subcommand: it puts back the files a run
+// modified, as recorded in its journal. Files changed again since then are
+// left alone and reported.
+func runUndo(cmd *cobra.Command, args []string) {
+	configPath, _ := cmd.Flags().GetString("config")
+	profile, _ := cmd.Flags().GetString("profile")
+	cfg, err := loadConfig(configPath)
+	if err == nil && cmd.Flags().Changed("config-hash") {
+		expected, _ := cmd.Flags().GetString("config-hash")
+		err = cfg.verifyHash(expected)
+	}
+	var s settings
+	if err == nil {
+		s, err = cfg.resolve(profile)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if cmd.Flags().Changed("journal") {
+		s.Journal, _ = cmd.Flags().GetString("journal")
+	}
+	dir := journalDir(s.Journal)
+	if dir == "" {
+		fmt.Fprintln(os.Stderr, "Error: the run journal is off, there is nothing to undo")
+		os.Exit(exitError)
+	}
+	id, _ := cmd.Flags().GetString("run")
+	if id == "" {
+		if id, err = lastJournal(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	path := filepath.Join(dir, id+journalExt)
+	entries, err := readJournal(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = fmt.Errorf("no run %s to undo in %s", id, dir)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	restored, failed := 0, 0
+	for _, e := range slices.Backward(entries) {
+		current, err := hashFile(e.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", e.Path, err)
+			failed++
+			continue
+		}
+		switch current {
+		case e.Before:
+			// Already restored by an earlier, interrupted undo
+		case e.After:
+			if err := restoreOriginal(dir, e); err != nil {
+				fmt.Fprintf(os.Stderr, "%s: %v\n", e.Path, err)
+				failed++
+				continue
+			}
+			fmt.Printf("Restoring: %s\n", e.Path)
+			restored++
+		default:
+			fmt.Fprintf(os.Stderr, "%s: changed since run %s, not restored\n", e.Path, id)
+			failed++
+		}
+	}
+	fmt.Printf("Undid run %s: %d %s restored", id, restored, plural(restored, "file", "files"))
+	if failed > 0 {
+		fmt.Printf(", %d not restored\n", failed)
+		os.Exit(exitError)
+	}
+	fmt.Println()
+	if err := os.Rename(path, path+undoneExt); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// restoreOriginal writes the original content e records back to its file.
+func restoreOriginal(dir string, e journalEntry) error {
+	if e.OriginalFile == "" {
+		return writeFileAtomic(e.Path, e.Original)
+	}
+	f, err := os.Open(filepath.Join(dir, e.OriginalFile))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return writeFileAtomicFunc(e.Path, func(w io.Writer) error {
+		_, err := io.Copy(w, f)
+		return err
+	})
+}
+
+// hashFile returns the hash of the content of the file at path, like
+// hashString, without reading it into memory.
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// lastJournal returns the id of the most recent run that has not been
+// undone.
+func lastJournal(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return "", err
+	}
+	last := ""
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), journalExt); ok && id > last {
+			last = id
+		}
+	}
+	if last == "" {
+		return "", fmt.Errorf("no run to undo in %s", dir)
+	}
+	return last, nil
+}
+
+// readJournal reads the entries of a run journal in sequence order. A last
+// line without its newline was cut short by an interrupted run; the file
+// it was about to record had not been written yet, so it is dropped.
+func readJournal(path string) ([]journalEntry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if i := bytes.LastIndexByte(data, '\n'); i < len(data)-1 {
+		data = data[:i+1]
+	}
+	var entries []journalEntry
+	for line := range bytes.Lines(data) {
+		var e journalEntry
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("reading %s: %w", path, err)
+		}
+		entries = append(entries, e)
+	}
+	slices.SortStableFunc(entries, func(a, b journalEntry) int { return a.Seq - b.Seq })
+	return entries, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestJournalSurvivesATornEntry checks a journal cut short by an
+// interrupted run is still read, in sequence order, without the entry
+// that was being written.
+func TestJournalSurvivesATornEntry(t *testing.T) {
+	dir := t.TempDir()
+	j := newJournal(dir, journalSyncFile, time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC))
+	for _, name := range []string{"a.go", "b.go"} {
+		if err := j.record(filepath.Join(dir, name), []byte("package a\n"), []byte("// Copyright\npackage a\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := j.close(); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, j.id+journalExt)
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"seq":3,"path":"c.go","orig`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	entries, err := readJournal(path)
+	if err != nil {
+		t.Fatalf("torn journal not read: %v", err)
+	}
+	if len(entries) != 2 || entries[0].Seq != 1 || filepath.Base(entries[1].Path) != "b.go" {
+		t.Errorf("entries = %+v, want a.go and b.go in order", entries)
+	}
+}
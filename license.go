@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// licenseFiles are the names the license of a repository is looked for
+// under, in order, when --from-license names no file.
+var licenseFiles = []string{"LICENSE", "LICENSE.txt", "LICENSE.md", "LICENCE", "COPYING", "COPYING.txt", "COPYING.md"}
+
+// licenseTitleLength is how much of a license text its title is looked
+// for in. The GPL mentions the AGPL in its body, so a license is known by
+// its title where it has one.
+const licenseTitleLength = 300
+
+// knownLicense identifies a license by phrases of its text and gives the
+// standard notice for the header of the files it covers.
+type knownLicense struct {
+	id string
+	// title phrases must appear near the start of the text, body phrases
+	// anywhere.
+	title, body []string
+	// notice is the text below the copyright lines; %s is the name of the
+	// license file.
+	notice string
+}
+
+// gnuNotice is the notice the GNU licenses recommend.
+func gnuNotice(name, version string) string {
+	return "This program is free software: you can redistribute it and/or modify\n" +
+		"it under the terms of the " + name + " as published by\n" +
+		"the Free Software Foundation, either version " + version + " of the License, or\n" +
+		"(at your option) any later version.\n\n" +
+		"This program is distributed in the hope that it will be useful,\n" +
+		"but WITHOUT ANY WARRANTY; without even the implied warranty of\n" +
+		"MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the\n" +
+		name + " for more details.\n\n" +
+		"You should have received a copy of the " + name + "\n" +
+		"along with this program.  If not, see <https://www.gnu.org/licenses/>."
+}
+
+// knownLicenses are tried in order, so the more specific texts come first.
+var knownLicenses = []knownLicense{
+	{id: "AGPL-3.0-or-later", title: []string{"gnu affero general public license", "version 3"},
+		notice: gnuNotice("GNU Affero General Public License", "3")},
+	{id: "LGPL-3.0-or-later", title: []string{"gnu lesser general public license", "version 3"},
+		notice: gnuNotice("GNU Lesser General Public License", "3")},
+	{id: "LGPL-2.1-or-later", title: []string{"gnu lesser general public license", "version 2.1"},
+		notice: gnuNotice("GNU Lesser General Public License", "2.1")},
+	{id: "GPL-3.0-or-later", title: []string{"gnu general public license", "version 3"},
+		notice: gnuNotice("GNU General Public License", "3")},
+	{id: "GPL-2.0-or-later", title: []string{"gnu general public license", "version 2"},
+		notice: gnuNotice("GNU General Public License", "2")},
+	{id: "Apache-2.0", title: []string{"apache license", "version 2.0"},
+		notice: "Licensed under the Apache License, Version 2.0 (the \"License\");\n" +
+			"you may not use this file except in compliance with the License.\n" +
+			"You may obtain a copy of the License at\n\n" +
+			"    http://www.apache.org/licenses/LICENSE-2.0\n\n" +
+			"Unless required by applicable law or agreed to in writing, software\n" +
+			"distributed under the License is distributed on an \"AS IS\" BASIS,\n" +
+			"WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.\n" +
+			"See the License for the specific language governing permissions and\n" +
+			"limitations under the License."},
+	{id: "MPL-2.0", title: []string{"mozilla public license", "version 2.0"},
+		notice: "This Source Code Form is subject to the terms of the Mozilla Public\n" +
+			"License, v. 2.0. If a copy of the MPL was not distributed with this\n" +
+			"file, You can obtain one at https://mozilla.org/MPL/2.0/."},
+	{id: "BSD-3-Clause", body: []string{"redistribution and use in source and binary forms", "neither the name"},
+		notice: "Use of this source code is governed by a BSD-style license that can be\nfound in the %s file."},
+	{id: "BSD-2-Clause", body: []string{"redistribution and use in source and binary forms"},
+		notice: "Use of this source code is governed by a BSD-style license that can be\nfound in the %s file."},
+	{id: "MIT", body: []string{"permission is hereby granted, free of charge"},
+		notice: "Use of this source code is governed by the MIT license that can be\nfound in the %s file."},
+	{id: "ISC", body: []string{"permission to use, copy, modify, and/or distribute this software for any purpose"},
+		notice: "Use of this source code is governed by the ISC license that can be\nfound in the %s file."},
+	{id: "Unlicense", body: []string{"this is free and unencumbered software released into the public domain"},
+		notice: "This is free and unencumbered software released into the public domain.\nFor more information, please refer to <https://unlicense.org>."},
+}
+
+// identifyLicense returns the known license text is, if any.
+func identifyLicense(text string) (knownLicense, bool) {
+	normalized := strings.ToLower(strings.Join(strings.Fields(text), " "))
+	title := normalized[:min(len(normalized), licenseTitleLength)]
+	containsAll := func(s string, phrases []string) bool {
+		for _, phrase := range phrases {
+			if !strings.Contains(s, phrase) {
+				return false
+			}
+		}
+		return true
+	}
+	for _, l := range knownLicenses {
+		if containsAll(title, l.title) && containsAll(normalized, l.body) {
+			return l, true
+		}
+	}
+	return knownLicense{}, false
+}
+
+// findLicenseFile returns the license file of the directory dir.
+func findLicenseFile(dir string) (string, error) {
+	for _, name := range licenseFiles {
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("no license file found (looked for %s)", strings.Join(licenseFiles, ", "))
+}
+
+// licenseHolder returns the first copyright line of a license text, such
+// as the one at the top of an MIT license, skipping template lines with
+// placeholders like Apache's "Copyright [yyyy] [name of copyright owner]".
+func licenseHolder(text string) string {
+	for line := range strings.Lines(text) {
+		line = strings.TrimSpace(line)
+		if copyrighter.CopyrightPattern.MatchString(line) && !strings.ContainsAny(line, "[]{}<>") {
+			return line
+		}
+	}
+	return ""
+}
+
+// licenseAuto is the from_license value, and the --from-license default,
+// that looks for the license file of the working directory.
+const licenseAuto = "auto"
+
+// headerFromLicense builds the notice for the license file at path, or the
+// one found in the working directory for licenseAuto: the copyright lines
+// of holder, or of the license when holder is empty, followed by the
+// license's standard header text.
+func headerFromLicense(path string, holder copyrightNotice) (copyrightNotice, error) {
+	if path == licenseAuto {
+		var err error
+		if path, err = findLicenseFile("."); err != nil {
+			return "", err
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("reading license file: %w", err)
+	}
+	l, ok := identifyLicense(string(data))
+	if !ok {
+		return "", fmt.Errorf("%s: license not recognised; set the header with --copyright-file instead", path)
+	}
+	if strings.TrimSpace(string(holder)) == "" {
+		holder = copyrightNotice(licenseHolder(string(data)))
+	}
+	if holder == "" {
+		return "", errors.New(path + " names no copyright holder; pass it with --copyright")
+	}
+	notice := l.notice
+	if strings.Contains(notice, "%s") {
+		notice = fmt.Sprintf(notice, filepath.Base(path))
+	}
+	return copyrightNotice(strings.TrimSpace(string(holder)) + "\n\n" + notice), nil
+}
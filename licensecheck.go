@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// permissiveLicenses may be included in a project under any of the
+// licenses of compatibleLicenses.
+var permissiveLicenses = []string{"0BSD", "BSD-2-Clause", "BSD-3-Clause", "ISC", "MIT", "Unlicense", "Zlib"}
+
+// compatibleLicenses maps the license of a project, by family, to the
+// license families its files may carry. The table is coarse on purpose:
+// it catches a GPL file committed to an MIT project, not the fine print
+// of every combination, and anything it does not list is flagged for a
+// person to decide.
+var compatibleLicenses = func() map[string][]string {
+	withApache := append(slices.Clone(permissiveLicenses), "Apache-2.0")
+	gpl3 := append(slices.Clone(withApache), "LGPL-2.1", "LGPL-3.0", "GPL-2.0", "GPL-3.0")
+	m := map[string][]string{
+		"Apache-2.0": withApache,
+		"MPL-2.0":    append(slices.Clone(withApache), "MPL-2.0"),
+		"LGPL-2.1":   append(slices.Clone(permissiveLicenses), "LGPL-2.1"),
+		"LGPL-3.0":   append(slices.Clone(withApache), "LGPL-2.1", "LGPL-3.0"),
+		"GPL-2.0":    append(slices.Clone(permissiveLicenses), "LGPL-2.1", "GPL-2.0"),
+		"GPL-3.0":    gpl3,
+		"AGPL-3.0":   append(slices.Clone(gpl3), "AGPL-3.0"),
+	}
+	for _, id := range permissiveLicenses {
+		m[id] = permissiveLicenses
+	}
+	return m
+}()
+
+// licenseFamily returns the license an SPDX identifier names, without the
+// -only, -or-later or + suffix that picks its versions.
+func licenseFamily(id string) string {
+	id = strings.TrimSuffix(id, "+")
+	id = strings.TrimSuffix(id, "-only")
+	return strings.TrimSuffix(id, "-or-later")
+}
+
+// licenseCompatible reports whether a file under the SPDX expression expr
+// may be part of a project under the license family project: one of the
+// alternatives of an OR expression must be, with every license it
+// combines with AND. A license restricted to one version with -only is
+// only compatible with that license.
+func licenseCompatible(expr, project string) bool {
+	for alternative := range strings.SplitSeq(expr, " OR ") {
+		ok := true
+		for _, id := range licenseIDs(withoutExceptions(alternative)) {
+			family := licenseFamily(id)
+			if family == project {
+				continue
+			}
+			if strings.HasSuffix(id, "-only") || !slices.Contains(compatibleLicenses[project], family) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+// withoutExceptions drops the "WITH exception" parts of an SPDX
+// expression; exceptions only grant additional permissions.
+func withoutExceptions(expr string) string {
+	fields := strings.Fields(expr)
+	kept := fields[:0]
+	for i := 0; i < len(fields); i++ {
+		if fields[i] == "WITH" {
+			i++
+			continue
+		}
+		kept = append(kept, fields[i])
+	}
+	return strings.Join(kept, " ")
+}
+
+// licenseMismatch is a file whose header license the project's license
+// does not allow.
+type licenseMismatch struct {
+	path    string
+	license string
+}
+
+// licenseCheckReport is the outcome of license-check for a project.
+type licenseCheckReport struct {
+	licenseFile string
+	license     string
+	// licensed counts the files whose header names a license.
+	licensed   int
+	mismatches []licenseMismatch
+}
+
+// runLicenseCheck implements the license-check subcommand.
+func runLicenseCheck(cmd *cobra.Command, args []string) {
+	root := "."
+	if len(args) > 0 {
+		root = args[0]
+	}
+	licenseFile, _ := cmd.Flags().GetString("license")
+	rep, err := checkLicenses(root, licenseFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	rep.print(os.Stdout)
+	if len(rep.mismatches) > 0 {
+		os.Exit(exitChanged)
+	}
+}
+
+// checkLicenses compares the license in the header of every source file of
+// the project at root, given by an SPDX tag or recognised boilerplate,
+// with the license of the project in licenseFile, or the license file
+// found at root when it is empty. Files whose header names no license are
+// left to check and reuse-lint.
+func checkLicenses(root, licenseFile string) (*licenseCheckReport, error) {
+	if licenseFile == "" {
+		var err error
+		if licenseFile, err = findLicenseFile(root); err != nil {
+			return nil, err
+		}
+	}
+	data, err := os.ReadFile(licenseFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading license file: %w", err)
+	}
+	l, ok := identifyLicense(string(data))
+	if !ok {
+		return nil, fmt.Errorf("%s: license not recognised", licenseFile)
+	}
+	project := licenseFamily(l.id)
+
+	files, err := reuseFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	rep := &licenseCheckReport{licenseFile: licenseFile, license: l.id}
+	for _, rel := range files {
+		if _, ok := copyrighter.StyleFor(rel); !ok {
+			continue
+		}
+		expr, err := headerLicense(filepath.Join(root, filepath.FromSlash(rel)))
+		if err != nil {
+			return nil, err
+		}
+		if expr == "" {
+			continue
+		}
+		rep.licensed++
+		if !licenseCompatible(expr, project) {
+			rep.mismatches = append(rep.mismatches, licenseMismatch{path: rel, license: expr})
+		}
+	}
+	return rep, nil
+}
+
+// headerLicense returns the license the header of the file at path
+// declares: its SPDX expression, or the identifier of the license whose
+// boilerplate it carries. Generated and binary files declare none.
+func headerLicense(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	if bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0 || copyrighter.IsGenerated(string(data), nil) {
+		return "", nil
+	}
+	head := headLines(string(data), noticeHeaderLines)
+	for line := range strings.Lines(head) {
+		if m := reuseLicenseTag.FindStringSubmatch(line); m != nil {
+			if expr := strings.TrimRight(m[1], commentMarkers+"\r\n"); expr != "" {
+				return expr, nil
+			}
+		}
+	}
+	if l, ok := identifyLicense(commentText(head)); ok {
+		return l.id, nil
+	}
+	return "", nil
+}
+
+func (rep *licenseCheckReport) print(w io.Writer) {
+	for _, m := range rep.mismatches {
+		fmt.Fprintf(w, "%s: %s, not compatible with the %s license of %s\n", m.path, m.license, rep.license, rep.licenseFile)
+	}
+	if len(rep.mismatches) > 0 {
+		fmt.Fprintf(w, "Not compatible with the %s license of %s: %d of %d %s with a license in the header\n",
+			rep.license, rep.licenseFile, len(rep.mismatches), rep.licensed, plural(rep.licensed, "file", "files"))
+		return
+	}
+	fmt.Fprintf(w, "All %d %s with a license in the header are compatible with the %s license of %s\n",
+		rep.licensed, plural(rep.licensed, "file", "files"), rep.license, rep.licenseFile)
+}
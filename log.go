@@ -0,0 +1,35 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// logLevel selects how much a run prints to the console. Errors, which go
+// to stderr, are printed at every level.
+type logLevel int
+
+const (
+	// logQuiet prints only the findings that fail the run.
+	logQuiet logLevel = iota
+	// logNormal adds the changes made, warnings and totals.
+	logNormal
+	// logVerbose adds every per-file decision, such as a directory walked
+	// or a file skipped or already up to date.
+	logVerbose
+)
+
+// errorf prints an error to stderr, at every level, clearing the progress
+// line first so the two do not run together.
+func (r *runner) errorf(format string, args ...any) {
+	r.progress.clear()
+	fmt.Fprintf(os.Stderr, format, args...)
+}
+
+// logf prints a console message if the run's level includes level.
+func (r *runner) logf(level logLevel, format string, args ...any) {
+	if r.logLevel >= level {
+		r.progress.clear()
+		fmt.Fprintf(r.out, format, args...)
+	}
+}
@@ -1,15 +1,25 @@
 package main
 
 import (
-	"bufio"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
+	"iter"
+	"maps"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
 )
 
 func hashString(s string) string {
@@ -17,171 +27,1520 @@ func hashString(s string) string {
 	return hex.EncodeToString(h[:])
 }
 
-func formatCopyrightLine(copyrightText string) string {
-	trimmed := strings.TrimSpace(copyrightText)
-	if strings.HasPrefix(trimmed, "//") {
-		return trimmed
-	}
-	return "// " + trimmed
+// runner processes files for a single invocation and accumulates what the
+// run did across them.
+type runner struct {
+	// processor holds the notice and the per-extension footer and
+	// continuation settings every file is stamped with.
+	processor copyrighter.Processor
+	dryRun    bool
+	stat      *diffStat // nil unless --stat was given
+	// patch collects the changes instead of writing them; nil unless
+	// --patch was given.
+	patch *patchWriter
+	// check reports files that need changes instead of modifying them.
+	check bool
+	// verifyIdempotent stamps every fixed file a second time in memory and
+	// refuses to write it if that would change it again.
+	verifyIdempotent bool
+	// errs holds a *fileError for every path that failed.
+	errs []error
+	// remove strips the header and footer instead of stamping them.
+	remove bool
+	// audit classifies every file into a report instead of modifying it;
+	// nil unless running the audit subcommand.
+	audit *auditReport
+	// outputs receives per-file outcomes for the --output sinks; nil
+	// unless any were given.
+	outputs *outputSet
+	// out receives the console messages of the run: stdout, or stderr
+	// when stdout carries the JSON Lines of a bare --output json.
+	out io.Writer
+	// dereference processes the targets of symlinks given as arguments.
+	dereference bool
+	// followSymlinks follows the symlinks found while walking a directory
+	// instead of skipping them.
+	followSymlinks bool
+	// maxDepth limits how deep a directory walk goes, 0 for no limit.
+	maxDepth int
+	// attributes holds the linguist attributes of .gitattributes files,
+	// which mark generated and vendored files like the patterns above.
+	attributes *gitAttributes
+	// extensions, include and exclude decide which files a directory walk
+	// visits; see selectFile for their precedence.
+	extensions []string
+	include    []string
+	exclude    []string
+	// defaultExcludes are the built-in directory exclusions, nil when
+	// turned off.
+	defaultExcludes []string
+	// roots are the resolved directories files may be processed in; when
+	// empty, each path argument is its own root.
+	roots []string
+	// generated marks files that are skipped unless includeGenerated is
+	// set, in addition to the canonical generated-code marker.
+	generated        []*regexp.Regexp
+	includeGenerated bool
+	// includeVendored also selects the files .gitattributes marks
+	// linguist-vendored, which are skipped by default.
+	includeVendored bool
+	// cache skips files unchanged since they were last verified up to
+	// date; nil unless --cache was given.
+	cache *hashCache
+	// severity decides which findings check fails on and fix mode fixes.
+	severity severityPolicy
+	// now is the time the run treats as current, pinned by --frozen-time
+	// or SOURCE_DATE_EPOCH for reproducible runs. noEnv ignores every
+	// environment variable the tool would otherwise read.
+	now   time.Time
+	noEnv bool
+	// logLevel is how much the run prints, see logf.
+	logLevel logLevel
+	summary  runSummary
+	notify   notifySettings
+	// history is the JSON Lines file each run appends a record to, and
+	// languages holds the per-language counts for that record.
+	history   string
+	languages map[string]*languageStats
+
+	// maxFileSize is the size in bytes above which a file is refused, 0
+	// for no limit.
+	maxFileSize int64
+	// exceptions suppress the findings of matching files until they
+	// expire.
+	exceptions []exception
+	// holders give the files matching them another notice, or none.
+	holders []holder
+	// dep5 gives the copyright and license of the files it covers in
+	// reuse mode; nil otherwise.
+	dep5 *dep5
+	// legacyHeaders are header formats check accepts until they expire.
+	legacyHeaders []legacyHeader
+	// packageNotice names the one file of each Go package that carries
+	// the notice, empty when every file does; packageDirs holds the
+	// package directories already checked for it.
+	packageNotice string
+	packageDirs   map[string]bool
+	// sidecar keeps the stamps of the files of sidecarExtensions, which
+	// cannot carry a comment; nil unless configured.
+	sidecar           *sidecarManifest
+	sidecarExtensions []string
+	// review collects the candidate files of a dry run for the tui
+	// subcommand; nil otherwise.
+	review *reviewSession
+	// journal records the original content of the files the run
+	// modifies, for undo; nil when it is off.
+	journal *journal
+	// profiling stops the CPU profile and trace of the run, if any.
+	profiling func() error
+	// overwriteForeign lets runs replace the headers of other parties.
+	overwriteForeign bool
+	// acceptedCopyright are the other notices a file may carry instead of
+	// the configured one.
+	acceptedCopyright []string
+	// minTemplateVersion is the oldest template version check accepts.
+	minTemplateVersion string
+	// baseline lists the outdated files check tolerates, nil when there
+	// is none; baselineFixed counts the ones found up to date again.
+	baseline      *baseline
+	baselinePath  string
+	baselineFixed int
+	// recordOutdated collects the files check finds outdated in outdated,
+	// for the baseline subcommand.
+	recordOutdated bool
+	outdated       []string
+
+	// nestedRepos walks into git repositories nested in a walked
+	// directory, and repos groups the results by repository.
+	nestedRepos bool
+	repos       *repoIndex
+
+	// since and staged restrict the run to files changed according to git.
+	since  string
+	staged bool
+
+	// readStdin streams additional NUL-separated paths from stdin,
+	// filesFrom names a file, or "-" for stdin, listing more paths
+	// separated by NULs or newlines, and showProgress replaces silence on
+	// long lists with periodic totals.
+	readStdin    bool
+	filesFrom    string
+	showProgress bool
+	// progress is the progress line of a run on a terminal, nil otherwise.
+	progress *progressLine
 }
 
-func processFile(filePath, copyrightText string) (modified bool, err error) {
-	copyrightLine := formatCopyrightLine(copyrightText)
+func (r *runner) processFile(filePath string) (modified bool, err error) {
+	modified, err = r.processPath(filePath, false)
+	if errors.Is(err, errWindowLost) {
+		return r.processPath(filePath, true)
+	}
+	return modified, err
+}
 
-	// Read the entire file to check for trailing newline
-	originalContent, err := os.ReadFile(filePath)
+// processPath processes the file at filePath, reading it whole if whole
+// is set and otherwise as windows when it is large, see openSource.
+func (r *runner) processPath(filePath string, whole bool) (modified bool, err error) {
+	h := r.holderFor(filePath)
+	if h != nil && h.Skip {
+		r.skipHolder(filePath, h)
+		return false, nil
+	}
+	if r.dep5 != nil && r.dep5.covers(filePath) != nil {
+		r.skipDep5(filePath)
+		return false, nil
+	}
+	if r.usesSidecar(filePath) {
+		if e := r.exceptionFor(filePath); e != nil {
+			r.skipExcepted(filePath, e)
+			return false, nil
+		}
+		return r.processSidecar(filePath)
+	}
+	opts, err := r.processor.OptionsFor(filePath)
+	if errors.Is(err, copyrighter.ErrUnsupported) && r.audit != nil {
+		r.audit.add(filePath, auditUnsupported, "")
+		r.skip(filePath)
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
-	hadTrailingNewline := len(originalContent) > 0 && originalContent[len(originalContent)-1] == '\n'
+	if h != nil {
+		opts.Copyright = string(h.Copyright)
+		if h.SPDX != "" {
+			opts.SPDX = h.SPDX
+		}
+	}
 
-	file, err := os.Open(filePath)
+	if r.maxFileSize > 0 {
+		if info, err := os.Stat(filePath); err == nil && info.Size() > r.maxFileSize {
+			return false, fmt.Errorf("%w (%d bytes, the limit is %d MiB, see --max-file-size)", errTooLarge, info.Size(), r.maxFileSize>>20)
+		}
+	}
+	if !r.includeGenerated && r.attributes.set(filePath, linguistGenerated) {
+		r.skipGenerated(filePath)
+		return false, nil
+	}
+	src, err := r.openSource(filePath, whole)
 	if err != nil {
 		return false, err
 	}
-	defer func() {
-		if cerr := file.Close(); cerr != nil {
-			fmt.Fprintf(os.Stderr, "Error closing file %s: %v\n", filePath, cerr)
-		}
-	}()
+	original := src.content
 
-	scanner := bufio.NewScanner(file)
-	var lines []string
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	if !r.includeGenerated && copyrighter.IsGenerated(original, r.generated) {
+		r.skipGenerated(filePath)
+		return false, nil
 	}
-	if err := scanner.Err(); err != nil {
-		return false, fmt.Errorf("error reading file %s: %w", filePath, err)
+	if copyrighter.IsIgnored(original) {
+		r.skipIgnored(filePath)
+		return false, nil
+	}
+	if e := r.exceptionFor(filePath); e != nil {
+		r.skipExcepted(filePath, e)
+		return false, nil
+	}
+	if r.audit == nil && !r.remove {
+		r.checkPackageNoticeFile(filePath)
 	}
 
-	if len(lines) == 0 {
-		// Empty file, just add copyright header and footer
-		err := os.WriteFile(filePath, []byte(copyrightLine+"\n\n"+copyrightLine+"\n"), 0644)
-		return true, err
+	if r.audit == nil && !r.remove && r.sharesPackageNotice(filePath) {
+		return r.processSharedNotice(src, opts)
+	}
+	if r.audit == nil && !r.remove && r.cache.fresh(filePath, src.hash) {
+		r.skipCached(src, opts)
+		return false, nil
+	}
+	st, err := r.stamp(original, opts, r.check)
+	if err != nil {
+		return false, fmt.Errorf("error reading file %s: %w", filePath, err)
+	}
+	content, result, warnings, templateVersion := st.content, st.result, st.warnings, st.templateVersion
+	opts = st.opts
+	if st.foreign != "" {
+		r.skipForeign(filePath, st.foreign)
+		return false, nil
+	}
+	// Only the files that are written need their middle in place
+	if !r.check && r.audit == nil && !src.fits(content) {
+		return false, errWindowLost
+	}
+	if r.tracksTemplateVersions(r.check) {
+		r.tallyTemplateVersion(templateVersion)
+	}
+	if st.legacy != nil {
+		r.logf(logVerbose, "Accepting the %s header until %s in: %s\n", st.legacy.From, st.legacy.Until, filePath)
+		r.summary.LegacyHeaders++
+	}
+	if st.alternate {
+		r.logf(logVerbose, "Accepting an alternative notice in: %s\n", filePath)
+	}
+	r.summary.Scanned++
+	stats := r.languageStats(filePath)
+	stats.Scanned++
+	if !result.Changed() {
+		stats.Compliant++
 	}
 
-	headerUpdated := false
-	footerUpdated := false
-	footerAdded := false // Track if we're adding a new footer vs updating
-
-	// Check and update header
-	firstLine := lines[0]
-	currentHash := hashString(firstLine)
-	if currentHash == hashString(copyrightLine) {
-		fmt.Printf("Copyright header already up to date in: %s\n", filePath)
-		headerUpdated = true
-	} else if strings.HasPrefix(firstLine, "//") {
-		fmt.Printf("Updating copyright header in: %s (hash mismatch)\n", filePath)
-		lines[0] = copyrightLine
-		if len(lines) > 1 && lines[1] == "" {
-			// Keep blank line after header
+	outcome := fileOutcome{Path: filePath, Problems: problemList(result), Warnings: warnings, TemplateVersion: templateVersion, lines: src.lines}
+	r.reportWarnings(filePath, warnings)
+	if r.audit != nil {
+		status, detail := classifyHeader(original, opts, result)
+		r.audit.add(filePath, status, detail)
+		outcome.Status = status.String()
+		r.record(outcome)
+		if status == auditOK {
+			r.summary.UpToDate++
 		} else {
-			lines = append([]string{copyrightLine, ""}, lines[1:]...)
+			r.summary.Outdated++
 		}
-		headerUpdated = true
-	} else {
-		// No copyright found, add at top
-		fmt.Printf("Adding copyright header to: %s\n", filePath)
-		lines = append([]string{copyrightLine, ""}, lines...)
-		headerUpdated = true
-	}
-
-	// Check and update footer
-	lastLine := lines[len(lines)-1]
-	lastLineHash := hashString(lastLine)
-	if lastLineHash == hashString(copyrightLine) {
-		fmt.Printf("Copyright footer already up to date in: %s\n", filePath)
-		footerUpdated = true
-	} else if strings.HasPrefix(lastLine, "//") {
-		fmt.Printf("Updating copyright footer in: %s (hash mismatch)\n", filePath)
-		// Check if there's a blank line before the footer comment
-		if len(lines) > 1 && lines[len(lines)-2] == "" {
-			lines[len(lines)-1] = copyrightLine
+		return false, nil
+	}
+
+	if r.check {
+		outcome.Status = "up_to_date"
+		switch {
+		case !result.Changed():
+			r.summary.UpToDate++
+			if r.baseline.covers(filePath) {
+				r.baselineFixed++
+			}
+		case r.baseline.covers(filePath):
+			r.summary.Baselined++
+			outcome.Status = "baselined"
+			r.logf(logVerbose, "%s: %s (in the baseline)\n", filePath, describeProblems(result))
+		default:
+			r.summary.Outdated++
+			outcome.Status = "outdated"
+			r.logf(logQuiet, "%s: %s\n", filePath, describeProblems(result))
+		}
+		if r.recordOutdated && result.Changed() {
+			r.outdated = append(r.outdated, filePath)
+		}
+		outcome.Anomalies = r.reportAnomalies(filePath, original, opts)
+		// A header accepted for its template version or legacy format is
+		// not up to date, so it stays out of the cache
+		if result.Changed() || len(outcome.Anomalies) > 0 || len(warnings) > 0 || st.acceptedVersion || st.legacy != nil {
+			r.cache.forget(filePath)
 		} else {
-			lines[len(lines)-1] = copyrightLine
-			lines = append(lines[:len(lines)-1], "", copyrightLine)
+			r.cache.remember(filePath, src.hash)
+		}
+		if m := r.reportExpiredReview(filePath, original, opts); m != nil {
+			outcome.Problems = append(outcome.Problems, "expired review")
+			outcome.Maintainer = m
 		}
-		footerUpdated = true
+		r.record(outcome)
+		return false, nil
+	}
+
+	if r.remove {
+		r.reportRemoval(filePath, result)
 	} else {
-		// No copyright footer found, add at bottom
-		fmt.Printf("Adding copyright footer to: %s\n", filePath)
-		lines = append(lines, "", copyrightLine)
-		footerUpdated = true
-		footerAdded = true
+		r.reportStamp(filePath, result)
 	}
 
-	if !headerUpdated && !footerUpdated {
-		fmt.Printf("Copyright already up to date in: %s\n", filePath)
+	if r.stat != nil {
+		r.stat.add(filePath, original, content)
+	}
+	if r.patch != nil {
+		r.patch.add(filePath, original, content)
+	}
+	if r.review != nil {
+		r.review.add(filePath, original, content, result)
+	}
+
+	if !result.Changed() {
+		if r.remove {
+			r.logf(logVerbose, "No copyright notice to remove in: %s\n", filePath)
+		} else if len(warnings) == 0 {
+			r.logf(logVerbose, "Copyright already up to date in: %s\n", filePath)
+			r.rememberIfClean(filePath, original, src.hash, opts)
+		}
+		r.summary.UpToDate++
+		outcome.Status = "up_to_date"
+		r.record(outcome)
 		return false, nil
 	}
 
-	// Determine if we should add trailing newline:
-	// - If adding a new footer: always add trailing newline (Go idiomatic)
-	// - If updating existing footer: preserve original format (developer's responsibility)
-	content := strings.Join(lines, "\n")
-	if footerAdded {
-		// New footer - add trailing newline
-		content += "\n"
-	} else if hadTrailingNewline {
-		// Updating footer and original had trailing newline - preserve it
-		content += "\n"
+	outcome.Actions = actionList(result)
+	if result.Header == copyrighter.Updated || result.Header == copyrighter.Removed {
+		outcome.PreviousHeader = replacedHeader(original, opts)
+	}
+	if !r.remove {
+		if result.Header == copyrighter.Updated || result.Footer == copyrighter.Updated {
+			r.summary.Updated++
+		} else {
+			r.summary.Added++
+		}
+	}
+	if r.dryRun {
+		r.logf(logNormal, "Dry run, not writing: %s\n", filePath)
+		r.summary.Modified++
+		outcome.Status = "would_modify"
+		r.record(outcome)
+		return true, nil
+	}
+
+	hash, err := r.write(src, content)
+	if err != nil {
+		return true, err
 	}
-	// Otherwise: updating footer without original trailing newline - don't add one
+	if !r.remove && len(warnings) == 0 {
+		r.rememberIfClean(filePath, content, hash, opts)
+	}
+	r.summary.Modified++
+	stats.Fixed++
+	outcome.Status = "modified"
+	r.record(outcome)
+	return true, nil
+}
 
-	err = os.WriteFile(filePath, []byte(content), 0644)
-	return true, err
+// stamping is what processing a file comes to once the severity policy
+// and the headers accepted in place of the notice are applied.
+type stamping struct {
+	content  string
+	result   copyrighter.Result
+	opts     copyrighter.Options
+	warnings []string
+	// templateVersion is the template version of the header, when the
+	// run tracks them, and acceptedVersion whether it passed for it.
+	templateVersion string
+	acceptedVersion bool
+	// legacy is the legacy header format accepted, if any, and alternate
+	// whether the file carries one of the accepted notices.
+	legacy    *legacyHeader
+	alternate bool
+	// foreign identifies the other party's header the file is left alone
+	// for, see foreignHeader.
+	foreign string
 }
 
-func runCopyright(cmd *cobra.Command, args []string) {
-	copyrightText, _ := cmd.Flags().GetString("copyright")
-	if copyrightText == "" || len(args) == 0 {
-		fmt.Println("Usage: copy-righter --copyright='Your copyright' file1 [file2 ...]")
-		os.Exit(1)
+// stamp stamps content, or removes its notice, for a check run when check
+// is set and a fix run otherwise, with everything that decides whether
+// the file passes. It records nothing, so the CLI and serve share it and
+// come to the same verdict on the same content.
+func (r *runner) stamp(content string, opts copyrighter.Options, check bool) (stamping, error) {
+	transform := copyrighter.Stamp
+	if r.remove {
+		transform = copyrighter.Remove
+	}
+	st := stamping{opts: opts}
+	var err error
+	if st.content, st.result, err = transform(content, opts); err != nil {
+		return st, err
+	}
+	restamp := func() error {
+		st.content, st.result, err = copyrighter.Stamp(content, st.opts)
+		return err
+	}
+	if !r.remove && r.audit == nil {
+		threshold := r.severity.fix
+		if check {
+			threshold = severityError
+		}
+		st.opts, st.warnings = r.severity.triage(content, st.opts, threshold, st.result)
+		if st.opts.LeaveHeader || st.opts.LeaveFooter {
+			if err := restamp(); err != nil {
+				return st, err
+			}
+		}
+	}
+	if r.tracksTemplateVersions(check) {
+		st.templateVersion, _ = findTemplateVersion(content, st.opts)
+		if st.opts, st.acceptedVersion = r.acceptTemplateVersion(st.templateVersion, st.opts, st.result); st.acceptedVersion {
+			if err := restamp(); err != nil {
+				return st, err
+			}
+		}
+	}
+	if st.opts, st.legacy = r.acceptLegacyHeader(content, st.opts, st.result, check); st.legacy != nil {
+		if err := restamp(); err != nil {
+			return st, err
+		}
+	}
+	if alt, ok := r.acceptAlternateNotice(content, st.opts, st.result); ok {
+		st.opts, st.alternate = alt, true
+		if err := restamp(); err != nil {
+			return st, err
+		}
+	}
+	st.foreign = r.foreignHeader(content, st.opts, st.result, check)
+	if st.foreign == "" && r.verifyIdempotent && !r.remove && st.result.Changed() {
+		if err := verifyIdempotent(st.content, st.opts); err != nil {
+			return st, err
+		}
+	}
+	return st, nil
+}
+
+// reportStamp prints what copyrighter.Stamp did to a file.
+func (r *runner) reportStamp(filePath string, result copyrighter.Result) {
+	switch {
+	case result.LeftHeader:
+	case result.Header == copyrighter.UpToDate:
+		r.logf(logVerbose, "Copyright header already up to date in: %s\n", filePath)
+	case result.Header == copyrighter.Updated:
+		r.logf(logNormal, "Updating copyright header in: %s (hash mismatch)\n", filePath)
+	case result.Header == copyrighter.Added:
+		r.logf(logNormal, "Adding copyright header to: %s\n", filePath)
+	}
+	switch {
+	case result.NoFooter, result.LeftFooter:
+	case result.Footer == copyrighter.UpToDate:
+		r.logf(logVerbose, "Copyright footer already up to date in: %s\n", filePath)
+	case result.Footer == copyrighter.Updated:
+		r.logf(logNormal, "Updating copyright footer in: %s (hash mismatch)\n", filePath)
+	case result.Footer == copyrighter.Added:
+		r.logf(logNormal, "Adding copyright footer to: %s\n", filePath)
+	}
+	if result.KeptTrailingComment {
+		r.logf(logNormal, "Keeping trailing comment attached to code in: %s (footer added below it)\n", filePath)
+	}
+}
+
+// progressInterval is how many listed paths are processed between
+// aggregate progress lines on long runs.
+const progressInterval = 1000
+
+// run processes every path yielded by paths, walking directories
+// recursively. Paths are consumed one at a time, so lists streamed from
+// stdin never have to be held in memory. On a terminal a progress line
+// counts the files processed; unless the paths are streamed, the walk runs
+// ahead of the processing, see progressLookahead, so it can show the total.
+func (r *runner) run(paths iter.Seq[string]) {
+	r.progress = newProgressLine(r.logLevel)
+	defer r.progress.clear()
+	if r.progress != nil && !r.readStdin && r.filesFrom == "" {
+		ahead := &lookahead{progress: r.progress, n: progressLookahead}
+		for arg := range paths {
+			r.processArg(arg, ahead.schedule)
+		}
+		ahead.finish()
+		return
+	}
+
+	count := 0
+	for arg := range paths {
+		r.processArg(arg, func(task func()) {
+			task()
+			r.progress.step()
+		})
+		count++
+		if r.progress == nil && r.showProgress && r.logLevel >= logNormal && count%progressInterval == 0 {
+			fmt.Fprintf(os.Stderr, "Progress: %d paths processed (%d files scanned, %d modified, %d failed)\n",
+				count, r.summary.Scanned, r.summary.Modified, r.summary.Failed)
+		}
+	}
+}
+
+// verifyIdempotent checks that stamping already stamped content is a
+// no-op, catching templates and placements that oscillate between runs.
+func verifyIdempotent(content string, opts copyrighter.Options) error {
+	again, result, err := copyrighter.Stamp(content, opts)
+	if err != nil {
+		return err
+	}
+	if result.Changed() || again != content {
+		problems := describeProblems(result)
+		if problems == "" {
+			problems = "whitespace differs"
+		}
+		return fmt.Errorf("%w, a second pass would change the file again (%s); not writing it", errNotIdempotent, problems)
 	}
-	for _, file := range args {
-		info, err := os.Stat(file)
+	return nil
+}
+
+// fail counts a file that could not be processed and keeps its error for
+// the end of the run.
+func (r *runner) fail(path string, err error) {
+	r.summary.Failed++
+	r.errs = append(r.errs, &fileError{Path: path, Err: err})
+	r.record(fileOutcome{Path: path, Status: "failed", Error: err.Error()})
+}
+
+// countLines returns the number of lines in content.
+func countLines(content string) int {
+	n := strings.Count(content, "\n")
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		n++
+	}
+	return n
+}
+
+// processArg processes one listed path, walking it if it is a directory.
+// Go-style "dir/..." patterns are accepted as a synonym for "dir". The
+// processing of each file found is handed to schedule, which runs it
+// right away or queues it until every path has been walked.
+func (r *runner) processArg(arg string, schedule func(task func())) {
+	file, err := r.resolveArg(trimRecursivePattern(arg))
+	if err != nil {
+		r.logf(logQuiet, "Error: %v\n", err)
+		r.fail(arg, err)
+		return
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		r.logf(logQuiet, "Error: %v\n", err)
+		r.fail(file, err)
+		return
+	}
+	if !info.IsDir() {
+		if err := r.checkContained(file, file); err != nil {
+			r.errorf("Error processing file %s: %v\n", file, err)
+			r.fail(file, err)
+			return
+		}
+		schedule(func() {
+			if _, err := r.processFile(file); err != nil {
+				r.errorf("Error processing file %s: %v\n", file, err)
+				r.fail(file, err)
+			}
+		})
+		return
+	}
+
+	if err := r.walk(file, file, make(map[string]bool), schedule); err != nil {
+		r.errorf("Error walking directory %s: %v\n", file, err)
+	}
+}
+
+// walk visits the files beneath path, root or a path found under it.
+// Symlinks are skipped unless followSymlinks is set; seen holds the
+// resolved paths visited so far, so a file or directory reached through
+// several links is visited once and a link to an ancestor does not loop.
+func (r *runner) walk(root, path string, seen map[string]bool, schedule func(task func())) error {
+	return filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
 		if err != nil {
-			fmt.Printf("Error: %v\n", err)
-			continue
+			r.errorf("Error accessing path %s: %v\n", path, err)
+			return nil // Continue walking
+		}
+		if d.Type()&fs.ModeSymlink != 0 {
+			r.visitSymlink(root, path, seen, schedule)
+			return nil
 		}
-		if info.IsDir() {
-			err := filepath.Walk(file, func(path string, info os.FileInfo, err error) error {
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Error accessing path %s: %v\n", path, err)
-					return nil // Continue walking
-				}
-
-				if info.IsDir() {
-					fmt.Printf("Skipping directory: %s\n", path)
-					return nil
-				}
-
-				if !isSupportedFile(path) {
-					fmt.Printf("Skipping unsupported file: %s\n", path)
-					return nil
-				}
-
-				fmt.Printf("Processing file: %s\n", path)
-				if _, err := processFile(path, copyrightText); err != nil {
-					fmt.Fprintf(os.Stderr, "Error processing file %s: %v\n", path, err)
-				}
-				return nil
-			})
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error walking directory %s: %v\n", file, err)
+		if r.followSymlinks && visited(path, seen) {
+			r.logf(logVerbose, "Skipping path already visited through a symlink: %s\n", path)
+			if d.IsDir() {
+				return filepath.SkipDir
 			}
-		} else {
-			if _, err := processFile(file, copyrightText); err != nil {
-				fmt.Fprintf(os.Stderr, "Error processing file %s: %v\n", file, err)
+			return nil
+		}
+
+		if d.IsDir() {
+			if path != root && r.tooDeep(root, path) {
+				r.logf(logVerbose, "Skipping directory below --max-depth: %s\n", path)
+				return filepath.SkipDir
+			}
+			if path != root && r.isExcluded(root, path) {
+				r.logf(logVerbose, "Skipping excluded path: %s\n", path)
+				return filepath.SkipDir
+			}
+			if path != root && !r.nestedRepos && isRepoRoot(path) {
+				r.logf(logVerbose, "Skipping nested git repository: %s\n", path)
+				return filepath.SkipDir
+			}
+			r.logf(logVerbose, "Skipping directory: %s\n", path)
+			return nil
+		}
+
+		schedule(func() { r.visitFile(root, path) })
+		return nil
+	})
+}
+
+// visitSymlink handles a symlink found while walking root: it is skipped,
+// so a walk never rewrites a file through a link, unless followSymlinks
+// is set. A followed link to a directory is walked like one beneath root,
+// once, as long as its target is within the allowed roots.
+func (r *runner) visitSymlink(root, path string, seen map[string]bool, schedule func(task func())) {
+	if !r.followSymlinks {
+		r.logf(logVerbose, "Skipping symlink: %s (use --follow-symlinks to follow it)\n", path)
+		r.skip(path)
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		r.errorf("Error accessing path %s: %v\n", path, err)
+		return
+	}
+	if !info.IsDir() {
+		if visited(path, seen) {
+			r.logf(logVerbose, "Skipping path already visited through a symlink: %s\n", path)
+			return
+		}
+		schedule(func() { r.visitFile(root, path) })
+		return
+	}
+	if r.tooDeep(root, path) {
+		r.logf(logVerbose, "Skipping directory below --max-depth: %s\n", path)
+		return
+	}
+	if r.isExcluded(root, path) {
+		r.logf(logVerbose, "Skipping excluded path: %s\n", path)
+		return
+	}
+	if err := r.checkContained(path, root); err != nil {
+		r.errorf("Error walking %s: %v\n", path, err)
+		r.fail(path, err)
+		return
+	}
+	if visited(path, seen) {
+		r.logf(logVerbose, "Skipping symlink to a directory already walked: %s\n", path)
+		return
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		r.errorf("Error accessing path %s: %v\n", path, err)
+		return
+	}
+	for _, e := range entries {
+		if err := r.walk(root, filepath.Join(path, e.Name()), seen, schedule); err != nil {
+			r.errorf("Error walking directory %s: %v\n", path, err)
+		}
+	}
+}
+
+// tooDeep reports whether the files in dir, a directory found while
+// walking root, are below the --max-depth limit.
+func (r *runner) tooDeep(root, dir string) bool {
+	if r.maxDepth <= 0 {
+		return false
+	}
+	rel, err := filepath.Rel(root, dir)
+	if err != nil {
+		return false
+	}
+	return strings.Count(filepath.ToSlash(rel), "/")+1 >= r.maxDepth
+}
+
+// visited reports whether the resolved path of path is in seen, and adds
+// it.
+func visited(path string, seen map[string]bool) bool {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+	if seen[resolved] {
+		return true
+	}
+	seen[resolved] = true
+	return false
+}
+
+// visitFile processes a file discovered under root rather than named
+// explicitly, so it is subject to the selection rules of selectFile.
+func (r *runner) visitFile(root, path string) {
+	if err := r.visit(root, path); err != nil {
+		r.errorf("Error processing file %s: %v\n", path, err)
+		r.fail(path, err)
+	}
+}
+
+// visit is visitFile returning the error processing the file, for the
+// caller to record or retry.
+func (r *runner) visit(root, path string) error {
+	switch verdict, _ := r.selectFile(root, path); verdict {
+	case matchExcluded:
+		r.logf(logVerbose, "Skipping excluded path: %s\n", path)
+		r.skip(path)
+		return nil
+	case matchNotIncluded:
+		r.logf(logVerbose, "Skipping path not matched by include patterns: %s\n", path)
+		r.skip(path)
+		return nil
+	case matchUnsupported:
+		r.logf(logVerbose, "Skipping unsupported file: %s\n", path)
+		r.skip(path)
+		if r.audit != nil {
+			r.audit.add(path, auditUnsupported, "")
+		}
+		return nil
+	}
+
+	r.logf(logVerbose, "Processing file: %s\n", path)
+	if err := r.checkContained(path, root); err != nil {
+		return err
+	}
+	_, err := r.processFile(path)
+	return err
+}
+
+// runArgs processes the paths named on the command line, or with --since
+// and --staged only the files git reports as changed beneath them.
+func (r *runner) runArgs(args []string) {
+	if r.since == "" && !r.staged {
+		r.run(r.paths(args))
+		return
+	}
+
+	files, err := gitChangedFiles(r.since, r.staged)
+	if err != nil {
+		r.errorf("Error listing changed files: %v\n", err)
+		r.summary.Failed++
+		return
+	}
+	for _, file := range files {
+		if len(args) == 0 || isBeneathAny(file, args) {
+			r.visitFile(".", file)
+		}
+	}
+}
+
+// paths returns the listed paths for a run: the command-line arguments
+// followed, with -0, by the NUL-separated paths streamed from stdin or,
+// with --files-from, by the paths listed in that file.
+func (r *runner) paths(args []string) iter.Seq[string] {
+	if !r.readStdin && r.filesFrom == "" {
+		return slices.Values(args)
+	}
+	return func(yield func(string) bool) {
+		for _, arg := range args {
+			if !yield(arg) {
+				return
+			}
+		}
+		if r.readStdin {
+			if err := readNulSeparated(os.Stdin, yield); err != nil {
+				r.errorf("Error reading paths from stdin: %v\n", err)
+				r.summary.Failed++
+			}
+			return
+		}
+		if err := r.readFilesFrom(yield); err != nil {
+			r.errorf("Error reading paths from %s: %v\n", r.filesFrom, err)
+			r.summary.Failed++
+		}
+	}
+}
+
+// readFilesFrom streams the paths listed in the --files-from file, stdin
+// when it is "-".
+func (r *runner) readFilesFrom(yield func(string) bool) error {
+	if r.filesFrom == "-" {
+		return readPathList(os.Stdin, yield)
+	}
+	f, err := os.Open(r.filesFrom)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return readPathList(f, yield)
+}
+
+// resolveArg applies the symlink contract for explicit arguments: a symlink
+// is refused, so that a file outside the tree is never rewritten by
+// accident, unless --dereference is set, in which case its target is used.
+func (r *runner) resolveArg(arg string) (string, error) {
+	info, err := os.Lstat(arg)
+	if err != nil {
+		return "", err
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		return arg, nil
+	}
+	if !r.dereference {
+		return "", fmt.Errorf("%s %w, refusing to process it (use --dereference to process its target)", arg, errSymlink)
+	}
+	target, err := filepath.EvalSymlinks(arg)
+	if err != nil {
+		return "", fmt.Errorf("resolving symlink %s: %w", arg, err)
+	}
+	r.logf(logVerbose, "Following symlink %s -> %s\n", arg, target)
+	return target, nil
+}
+
+// isExcluded reports whether a directory found while walking root matches
+// one of the exclude patterns, so the walk can skip it entirely.
+func (r *runner) isExcluded(root, path string) bool {
+	for _, p := range slices.Concat(r.defaultExcludes, r.exclude) {
+		if matchesPath(p, root, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// trimRecursivePattern turns a Go-style "./..." argument into the
+// directory it names; directories are always walked recursively.
+func trimRecursivePattern(arg string) string {
+	if arg == "..." {
+		return "."
+	}
+	if trimmed, ok := strings.CutSuffix(arg, "/..."); ok {
+		if trimmed == "" {
+			return "/"
+		}
+		return trimmed
+	}
+	return arg
+}
+
+// addRunFlags registers the flags that control the stamped text and which
+// files are visited. They are shared by every command that compares files
+// against the template.
+func addRunFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArray("copyright", nil, "Copyright text to add (required unless set in the config file); repeat for a notice with several holder lines")
+	cmd.Flags().String("copyright-file", "", "File holding a multi-line copyright or license text to add as a comment block")
+	cmd.Flags().Bool("copyright-template", false, "Render the copyright text as a Go template for each file, with .Path, .Base, .Ext, .Language and .Year")
+	cmd.Flags().String("copyright-url", "", "URL of the copyright text to add, such as a header published by a central legal team; fetched copies are cached")
+	cmd.Flags().Duration("copyright-url-ttl", defaultCopyrightURLTTL, "How long a copy fetched from --copyright-url is used before it is fetched again")
+	cmd.MarkFlagsMutuallyExclusive("copyright", "copyright-file", "copyright-url")
+	cmd.Flags().Bool("overwrite-foreign", false, "Replace headers that carry another party's copyright or license instead of leaving them in place")
+	cmd.Flags().StringArray("accept-copyright", nil, "Another approved notice files may carry instead, such as last year's (repeatable)")
+	cmd.Flags().String("from-license", "", "Follow the copyright lines with the standard header text of the license in this file, or in LICENSE or COPYING when given without a value; the copyright lines default to the license's")
+	cmd.Flags().Lookup("from-license").NoOptDefVal = licenseAuto
+	cmd.MarkFlagsMutuallyExclusive("from-license", "copyright-file")
+	cmd.Flags().StringArray("preamble", nil, "Regular expression matching leading lines that must stay above the header (repeatable)")
+	cmd.Flags().StringArray("match-pattern", nil, "Regular expression recognising a legacy header format, whose whole comment block is replaced (repeatable)")
+	cmd.Flags().String("spdx", "", "SPDX license identifier to add to the header, e.g. Apache-2.0")
+	cmd.Flags().Bool("reuse", false, "Write SPDX-FileCopyrightText lines as the REUSE specification expects and skip the files .reuse/dep5 covers")
+	cmd.Flags().String("maintained-by", "", `Ownership annotation to add to the header, e.g. "team-payments (review 2026-01)"`)
+	cmd.Flags().String("template-version", "", `Template version to add to the header, e.g. "3", to track the rollout of a new notice`)
+	cmd.Flags().Int("header-blank-lines", 1, "Blank lines between the header and the code, 0 to 2; once this or --footer-blank-lines is given, existing notices are respaced too, else they keep their spacing")
+	cmd.Flags().Int("footer-blank-lines", 1, "Blank lines between the code and the footer, 0 to 2; once this or --header-blank-lines is given, existing notices are respaced too, else they keep their spacing")
+	cmd.Flags().String("only", "", "Only add, update and check the header or the footer, leaving the other as it is: header, footer or both (default)")
+	cmd.Flags().String("placement", "", "Where the header goes: separate, a comment block of its own (default), or merge, the top of the comment block describing the file")
+	cmd.Flags().String("package-notice", "", "Go file that alone carries the notice in each package, e.g. doc.go (default every file)")
+	cmd.Flags().Bool("update-year-range", false, "Extend the year of an existing notice into a range (2021 -> 2021-2025) instead of replacing it")
+	addConfigFlags(cmd)
+	cmd.Flags().String("notify-cmd", "", "Shell command to run at the end of the run with the JSON summary on stdin")
+	cmd.Flags().String("notify-webhook", "", "URL to POST the JSON run summary to at the end of the run (Slack/Teams compatible)")
+	cmd.Flags().Bool("allow-config-commands", false, "Run the notify.command of the config file, which is ignored otherwise since the config comes with the checkout")
+	cmd.Flags().String("since", "", "Only process files changed since this git ref (including uncommitted and untracked files)")
+	cmd.Flags().Bool("staged", false, "Only process files staged in the git index")
+	cmd.MarkFlagsMutuallyExclusive("since", "staged")
+	cmd.Flags().BoolP("null", "0", false, "Also read NUL-separated paths from stdin (e.g. from find -print0 or git ls-files -z)")
+	cmd.Flags().String("files-from", "", "Also read paths separated by newlines or NULs from this file, - for stdin (e.g. from git ls-files -z)")
+	cmd.MarkFlagsMutuallyExclusive("null", "files-from")
+	cmd.Flags().StringArray("output", nil, "Also write results to FORMAT=PATH (json, sarif); repeatable, the console output is always printed. A bare json streams one JSON object per file and a summary to stdout")
+	cmd.Flags().String("history", "", "Append a record of the run to this JSON Lines history file")
+	cmd.Flags().String("fix-severity", "", "Only fix findings at or above this severity (warning or error); the others are reported")
+	cmd.Flags().Bool("ignore-year", false, "Treat a notice that differs from the required one only in its years as up to date (sets the stale-year severity to off)")
+	cmd.Flags().String("sidecar", "", "Manifest to record the stamps of files that cannot carry a comment in (default "+defaultSidecar+" when --sidecar-ext is given)")
+	cmd.Flags().StringSlice("sidecar-ext", nil, "Extensions of files stamped in the sidecar manifest instead of in the file, e.g. .json")
+	cmd.Flags().String("journal", "", "Directory to record the original content of modified files in, for undo, or off (default in the user cache directory)")
+	cmd.Flags().String("journal-sync", "", "When to flush the run journal to disk: file, before each file is written (default), run, once at the end, or none")
+	cmd.Flags().String("cache", "", "Skip files unchanged since this cache file recorded them as up to date, and update it")
+	cmd.Flags().StringSlice("ext", nil, "File extensions to process when walking directories, e.g. .go,.py,.ts (default every supported extension)")
+	cmd.Flags().StringArray("include", nil, "Glob a file found while walking directories must match to be processed (repeatable)")
+	cmd.Flags().StringArray("exclude", nil, "Glob of paths to skip while walking directories (repeatable)")
+	cmd.Flags().String("debug-match", "", "Explain which include/exclude rules select the given path, then exit")
+	cmd.Flags().StringArray("generated-pattern", nil, "Regular expression marking a file as generated, in addition to \"Code generated ... DO NOT EDIT.\" (repeatable)")
+	cmd.Flags().Bool("include-generated", false, "Also process generated files, which are skipped by default")
+	cmd.Flags().Bool("include-vendored", false, "Also process files marked linguist-vendored in a .gitattributes, which are skipped by default")
+	cmd.Flags().Int64("max-file-size", 0, "Refuse files larger than this many MiB instead of loading them, 0 for no limit (serve: 64 MiB by default)")
+	cmd.Flags().Int("max-line-length", copyrighter.DefaultMaxLineLength, "Wrap a notice whose comment lines would be longer than this many columns, 0 to never wrap")
+	cmd.Flags().Bool("nested-repos", false, "Also walk into git repositories nested in a directory, which are skipped by default")
+	cmd.Flags().Bool("no-default-excludes", false, "Also walk "+strings.Join(defaultExcludes, ", ")+" directories, which are skipped by default")
+	cmd.Flags().StringArray("root", nil, "Directory the run may process files in (repeatable); files resolving outside every root are refused (default: each path argument)")
+	cmd.Flags().Bool("dereference", false, "Process the target of symlinks given as arguments instead of refusing them")
+	cmd.Flags().Bool("follow-symlinks", false, "Follow symlinks found while walking directories, which are skipped by default")
+	cmd.Flags().Int("max-depth", 0, "Descend at most this many directory levels below each directory argument, 1 for its files only; 0 for no limit")
+	cmd.Flags().BoolP("quiet", "q", false, "Only print errors and the findings that fail the run")
+	cmd.Flags().BoolP("verbose", "v", false, "Also print every per-file decision, such as files skipped or already up to date")
+	cmd.MarkFlagsMutuallyExclusive("quiet", "verbose")
+	cmd.Flags().String("frozen-time", "", "Treat this date (YYYY-MM-DD or RFC 3339) as the current time, for reproducible runs")
+	cmd.Flags().Bool("no-env", false, "Ignore environment variables such as SOURCE_DATE_EPOCH and "+skipEnv)
+	cmd.Flags().String("cpu-profile", "", "Write a CPU profile of the run to this file, for go tool pprof")
+	cmd.Flags().String("trace", "", "Write an execution trace of the run to this file, for go tool trace")
+}
+
+// addConfigFlags registers the flags that select and verify the config file.
+func addConfigFlags(cmd *cobra.Command) {
+	cmd.Flags().String("config", "", "Path to the config file (default "+defaultConfigFile+" if present)")
+	cmd.Flags().String("profile", "", "Named profile from the config file to apply")
+	cmd.Flags().String("config-hash", "", "Refuse to run unless the config file has this sha256 (as printed by sha256sum)")
+}
+
+// loadSettings resolves the effective settings for a command: the config
+// file and selected profile first, then any flags given on the command line.
+func loadSettings(cmd *cobra.Command) (settings, *config, error) {
+	configPath, _ := cmd.Flags().GetString("config")
+	profile, _ := cmd.Flags().GetString("profile")
+	cfg, err := loadConfig(configPath)
+	if err != nil {
+		return settings{}, nil, err
+	}
+	if cmd.Flags().Changed("config-hash") {
+		expected, _ := cmd.Flags().GetString("config-hash")
+		if err := cfg.verifyHash(expected); err != nil {
+			return settings{}, nil, err
+		}
+	}
+	s, err := cfg.resolve(profile)
+	if err != nil {
+		return settings{}, nil, err
+	}
+
+	if cmd.Flags().Changed("copyright") {
+		holders, _ := cmd.Flags().GetStringArray("copyright")
+		s.Copyright = joinHolders(holders)
+		s.CopyrightFile, s.CopyrightURL = "", ""
+	}
+	if cmd.Flags().Changed("overwrite-foreign") {
+		v, _ := cmd.Flags().GetBool("overwrite-foreign")
+		s.OverwriteForeign = &v
+	}
+	if cmd.Flags().Changed("accept-copyright") {
+		accepted, _ := cmd.Flags().GetStringArray("accept-copyright")
+		s.AcceptedCopyright = nil
+		for _, text := range accepted {
+			s.AcceptedCopyright = append(s.AcceptedCopyright, copyrightNotice(text))
+		}
+	}
+	if cmd.Flags().Changed("copyright-file") {
+		s.CopyrightFile, _ = cmd.Flags().GetString("copyright-file")
+		s.Copyright, s.CopyrightURL = "", ""
+	}
+	if cmd.Flags().Changed("copyright-url") {
+		s.CopyrightURL, _ = cmd.Flags().GetString("copyright-url")
+		s.Copyright, s.CopyrightFile = "", ""
+	}
+	if cmd.Flags().Changed("copyright-url-ttl") {
+		ttl, _ := cmd.Flags().GetDuration("copyright-url-ttl")
+		s.CopyrightURLTTL = ttl.String()
+	}
+	if s.CopyrightURL != "" {
+		if s.Copyright != "" || s.CopyrightFile != "" {
+			return settings{}, nil, fmt.Errorf("copyright_url cannot be set with copyright or copyright_file")
+		}
+		ttl := defaultCopyrightURLTTL
+		if s.CopyrightURLTTL != "" {
+			if ttl, err = time.ParseDuration(s.CopyrightURLTTL); err != nil || ttl < 0 {
+				return settings{}, nil, fmt.Errorf("invalid copyright_url_ttl %q", s.CopyrightURLTTL)
+			}
+		}
+		text, err := fetchCopyright(s.CopyrightURL, ttl)
+		if err != nil {
+			return settings{}, nil, err
+		}
+		s.Copyright = copyrightNotice(text)
+	}
+	if cmd.Flags().Changed("from-license") {
+		s.FromLicense, _ = cmd.Flags().GetString("from-license")
+	}
+	if s.CopyrightFile != "" && s.FromLicense != "" {
+		return settings{}, nil, fmt.Errorf("copyright_file and from_license cannot both be set")
+	}
+	if s.FromLicense != "" {
+		if s.Copyright, err = headerFromLicense(s.FromLicense, s.Copyright); err != nil {
+			return settings{}, nil, err
+		}
+	}
+	if s.CopyrightFile != "" {
+		if s.Copyright != "" {
+			return settings{}, nil, fmt.Errorf("copyright and copyright_file cannot both be set")
+		}
+		data, err := os.ReadFile(s.CopyrightFile)
+		if err != nil {
+			return settings{}, nil, fmt.Errorf("reading copyright file: %w", err)
+		}
+		s.Copyright = copyrightNotice(data)
+	}
+	if cmd.Flags().Changed("preamble") {
+		s.Preamble, _ = cmd.Flags().GetStringArray("preamble")
+	}
+	if cmd.Flags().Changed("match-pattern") {
+		s.MatchPatterns, _ = cmd.Flags().GetStringArray("match-pattern")
+	}
+	if cmd.Flags().Changed("no-default-excludes") {
+		v, _ := cmd.Flags().GetBool("no-default-excludes")
+		v = !v
+		s.DefaultExcludes = &v
+	}
+	if cmd.Flags().Changed("root") {
+		s.Roots, _ = cmd.Flags().GetStringArray("root")
+	}
+	if cmd.Flags().Changed("ext") {
+		s.Extensions, _ = cmd.Flags().GetStringSlice("ext")
+	}
+	if cmd.Flags().Changed("include") {
+		s.Include, _ = cmd.Flags().GetStringArray("include")
+	}
+	if cmd.Flags().Changed("exclude") {
+		s.Exclude, _ = cmd.Flags().GetStringArray("exclude")
+	}
+	if cmd.Flags().Changed("generated-pattern") {
+		s.Generated, _ = cmd.Flags().GetStringArray("generated-pattern")
+	}
+	if cmd.Flags().Changed("include-generated") {
+		v, _ := cmd.Flags().GetBool("include-generated")
+		s.IncludeGenerated = &v
+	}
+	if cmd.Flags().Changed("copyright-template") {
+		v, _ := cmd.Flags().GetBool("copyright-template")
+		s.CopyrightTemplate = &v
+	}
+	if cmd.Flags().Changed("include-vendored") {
+		v, _ := cmd.Flags().GetBool("include-vendored")
+		s.IncludeVendored = &v
+	}
+	if cmd.Flags().Changed("max-file-size") {
+		v, _ := cmd.Flags().GetInt64("max-file-size")
+		s.MaxFileSize = &v
+	}
+	if cmd.Flags().Changed("max-line-length") {
+		v, _ := cmd.Flags().GetInt("max-line-length")
+		s.MaxLineLength = &v
+	}
+	if s.MaxLineLength != nil && *s.MaxLineLength < 0 {
+		return settings{}, nil, fmt.Errorf("invalid max_line_length %d", *s.MaxLineLength)
+	}
+	if cmd.Flags().Changed("nested-repos") {
+		v, _ := cmd.Flags().GetBool("nested-repos")
+		s.NestedRepos = &v
+	}
+	if cmd.Flags().Changed("follow-symlinks") {
+		v, _ := cmd.Flags().GetBool("follow-symlinks")
+		s.FollowSymlinks = &v
+	}
+	if cmd.Flags().Changed("history") {
+		s.History, _ = cmd.Flags().GetString("history")
+	}
+	if cmd.Flags().Changed("sidecar") {
+		s.Sidecar, _ = cmd.Flags().GetString("sidecar")
+	}
+	if cmd.Flags().Changed("sidecar-ext") {
+		s.SidecarExtensions, _ = cmd.Flags().GetStringSlice("sidecar-ext")
+	}
+	s.SidecarExtensions = normalizeExtensions(s.SidecarExtensions)
+	if len(s.SidecarExtensions) > 0 && s.Sidecar == "" {
+		s.Sidecar = defaultSidecar
+	}
+	if cmd.Flags().Changed("journal") {
+		s.Journal, _ = cmd.Flags().GetString("journal")
+	}
+	if cmd.Flags().Changed("journal-sync") {
+		s.JournalSync, _ = cmd.Flags().GetString("journal-sync")
+	}
+	if err := checkJournalSync(s.JournalSync); err != nil {
+		return settings{}, nil, err
+	}
+	if cmd.Flags().Changed("cache") {
+		s.Cache, _ = cmd.Flags().GetString("cache")
+	}
+	if cmd.Flags().Changed("fix-severity") {
+		s.FixSeverity, _ = cmd.Flags().GetString("fix-severity")
+	}
+	if ignore, _ := cmd.Flags().GetBool("ignore-year"); ignore {
+		s.Severity = maps.Clone(s.Severity)
+		if s.Severity == nil {
+			s.Severity = map[string]string{}
+		}
+		s.Severity["stale-year"] = "off"
+	}
+	if _, err := newSeverityPolicy(s.Severity, s.FixSeverity); err != nil {
+		return settings{}, nil, err
+	}
+	if cmd.Flags().Changed("spdx") {
+		s.SPDX, _ = cmd.Flags().GetString("spdx")
+	}
+	if s.SPDX != "" && !copyrighter.SPDXExpression.MatchString(s.SPDX) {
+		return settings{}, nil, fmt.Errorf("invalid SPDX license expression %q", s.SPDX)
+	}
+	if cmd.Flags().Changed("reuse") {
+		v, _ := cmd.Flags().GetBool("reuse")
+		s.Reuse = &v
+	}
+	if isTrue(s.Reuse) && s.SPDX == "" {
+		return settings{}, nil, fmt.Errorf("reuse needs the license of the files, see --spdx")
+	}
+	if cmd.Flags().Changed("maintained-by") {
+		s.MaintainedBy, _ = cmd.Flags().GetString("maintained-by")
+	}
+	if s.MaintainedBy != "" {
+		if _, err := parseMaintainedBy(s.MaintainedBy); err != nil {
+			return settings{}, nil, err
+		}
+	}
+	if cmd.Flags().Changed("template-version") {
+		s.TemplateVersion, _ = cmd.Flags().GetString("template-version")
+	}
+	if cmd.Flags().Changed("min-template-version") {
+		s.MinTemplateVersion, _ = cmd.Flags().GetString("min-template-version")
+	}
+	if cmd.Flags().Changed("baseline") {
+		s.Baseline, _ = cmd.Flags().GetString("baseline")
+	}
+	if s.Baseline == "" {
+		s.Baseline = defaultBaselineFile
+	}
+	s.TemplateVersion = strings.TrimSpace(s.TemplateVersion)
+	s.MinTemplateVersion = strings.TrimSpace(s.MinTemplateVersion)
+	if err := checkExceptions(s.Exceptions); err != nil {
+		return settings{}, nil, err
+	}
+	if s.LegacyHeaders, err = compileLegacyHeaders(s.LegacyHeaders); err != nil {
+		return settings{}, nil, err
+	}
+	if s.Holders, err = loadHolders(s.Holders); err != nil {
+		return settings{}, nil, err
+	}
+	if isTrue(s.Reuse) {
+		s.Copyright = reuseNotice(s.Copyright)
+		for i, text := range s.AcceptedCopyright {
+			s.AcceptedCopyright[i] = reuseNotice(text)
+		}
+		for i, h := range s.Holders {
+			if !h.Skip {
+				s.Holders[i].Copyright = reuseNotice(h.Copyright)
 			}
 		}
 	}
+	if s.NoticeLines < 0 {
+		return settings{}, nil, fmt.Errorf("invalid notice_lines %d", s.NoticeLines)
+	}
+	if cmd.Flags().Changed("header-blank-lines") {
+		n, _ := cmd.Flags().GetInt("header-blank-lines")
+		s.BlankLines.Header = &n
+	}
+	if cmd.Flags().Changed("footer-blank-lines") {
+		n, _ := cmd.Flags().GetInt("footer-blank-lines")
+		s.BlankLines.Footer = &n
+	}
+	if err := s.BlankLines.check(); err != nil {
+		return settings{}, nil, err
+	}
+	if err := checkTemplateVersion("template version", s.TemplateVersion); err != nil {
+		return settings{}, nil, err
+	}
+	if err := checkTemplateVersion("minimum template version", s.MinTemplateVersion); err != nil {
+		return settings{}, nil, err
+	}
+	if cmd.Flags().Changed("placement") {
+		s.Placement, _ = cmd.Flags().GetString("placement")
+	}
+	if cmd.Flags().Changed("only") {
+		s.Only, _ = cmd.Flags().GetString("only")
+	}
+	if err := checkOnly(s.Only); err != nil {
+		return settings{}, nil, err
+	}
+	if err := checkPlacement(s.Placement); err != nil {
+		return settings{}, nil, err
+	}
+	if cmd.Flags().Changed("package-notice") {
+		s.PackageNotice, _ = cmd.Flags().GetString("package-notice")
+	}
+	if err := checkPackageNotice(s.PackageNotice); err != nil {
+		return settings{}, nil, err
+	}
+	if cmd.Flags().Changed("update-year-range") {
+		v, _ := cmd.Flags().GetBool("update-year-range")
+		s.UpdateYearRange = &v
+	}
+	// The config file comes with the checkout, so whoever can commit to
+	// it would otherwise run a shell command on every machine that runs
+	// copy-righter there
+	allow, err := cmd.Flags().GetBool("allow-config-commands")
+	switch {
+	case cmd.Flags().Changed("notify-cmd"):
+		s.Notify.Command, _ = cmd.Flags().GetString("notify-cmd")
+	case s.Notify.Command != "" && err == nil && !allow:
+		fmt.Fprintln(os.Stderr, "Warning: ignoring notify.command from the config file; pass --allow-config-commands to run it, or use --notify-cmd")
+		s.Notify.Command = ""
+	}
+	if cmd.Flags().Changed("notify-webhook") {
+		s.Notify.Webhook, _ = cmd.Flags().GetString("notify-webhook")
+	}
+	if len(s.Extensions) == 0 {
+		s.Extensions = copyrighter.Extensions
+	}
+	s.Extensions = normalizeExtensions(s.Extensions)
+	for _, ext := range s.Extensions {
+		if _, ok := copyrighter.LanguageFor(ext); !ok {
+			return settings{}, nil, fmt.Errorf("unsupported extension %q (supported: %s)", ext, strings.Join(copyrighter.Extensions, ", "))
+		}
+	}
+	s.Footers = normalizeKeys(s.Footers)
+	s.Continuation = normalizeKeys(s.Continuation)
+	if err := checkCapabilities(s); err != nil {
+		return settings{}, nil, err
+	}
+	if err := checkContinuations(s.Continuation); err != nil {
+		return settings{}, nil, err
+	}
+	for _, warning := range shadowedRules(s.Include, s.Exclude) {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	return s, cfg, nil
+}
+
+// newRunner builds a runner from the flags registered by addRunFlags,
+// exiting with a usage message when they are invalid.
+func newRunner(cmd *cobra.Command, args []string) *runner {
+	s, _, err := loadSettings(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if debugPath, _ := cmd.Flags().GetString("debug-match"); debugPath != "" {
+		r := &runner{
+			extensions:      s.Extensions,
+			include:         s.Include,
+			exclude:         s.Exclude,
+			defaultExcludes: s.defaultExcludes(),
+			attributes:      &gitAttributes{},
+			includeVendored: isTrue(s.IncludeVendored),
+		}
+		r.explainMatch(os.Stdout, debugPath)
+		os.Exit(0)
+	}
+	readStdin, _ := cmd.Flags().GetBool("null")
+	filesFrom, _ := cmd.Flags().GetString("files-from")
+	since, _ := cmd.Flags().GetString("since")
+	staged, _ := cmd.Flags().GetBool("staged")
+	if s.Copyright == "" || (len(args) == 0 && !readStdin && filesFrom == "" && since == "" && !staged) {
+		fmt.Printf("Usage: copy-righter %s--copyright='Your copyright' file1 [file2 ...]\n", subcommandPrefix(cmd))
+		os.Exit(exitError)
+	}
+	preamble, err := compilePatterns(s.Preamble)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid preamble pattern: %v\n", err)
+		os.Exit(exitError)
+	}
+	generated, err := compilePatterns(s.Generated)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid generated pattern: %v\n", err)
+		os.Exit(exitError)
+	}
+	noticePatterns := []*regexp.Regexp{copyrighter.CopyrightPattern}
+	if s.NoticePatterns != nil {
+		if noticePatterns, err = compilePatterns(s.NoticePatterns); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: invalid notice pattern: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	matchPatterns, err := compilePatterns(s.MatchPatterns)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid match pattern: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	outputValues, _ := cmd.Flags().GetStringArray("output")
+	sinks, stream, err := parseOutputs(outputValues)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	var outputs *outputSet
+	if len(sinks) > 0 || stream {
+		outputs = &outputSet{sinks: sinks}
+	}
+	out := io.Writer(os.Stdout)
+	if stream {
+		// stdout carries nothing but the JSON Lines
+		outputs.stream = json.NewEncoder(os.Stdout)
+		out = os.Stderr
+	}
+
+	roots, err := resolveRoots(s.Roots)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	dereference, _ := cmd.Flags().GetBool("dereference")
+	maxDepth, _ := cmd.Flags().GetInt("max-depth")
+	if maxDepth < 0 {
+		fmt.Fprintf(os.Stderr, "Error: invalid --max-depth %d\n", maxDepth)
+		os.Exit(exitError)
+	}
+	severity, _ := newSeverityPolicy(s.Severity, s.FixSeverity)
+	frozenTime, _ := cmd.Flags().GetString("frozen-time")
+	noEnv, _ := cmd.Flags().GetBool("no-env")
+	now, frozen, err := runClock(frozenTime, noEnv)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	level := logNormal
+	if quiet, _ := cmd.Flags().GetBool("quiet"); quiet {
+		level = logQuiet
+	}
+	if verbose, _ := cmd.Flags().GetBool("verbose"); verbose {
+		level = logVerbose
+	}
+	r := &runner{
+		logLevel:        level,
+		now:             now,
+		noEnv:           noEnv,
+		severity:        severity,
+		roots:           roots,
+		defaultExcludes: s.defaultExcludes(),
+		outputs:         outputs,
+		out:             out,
+		dereference:     dereference,
+		maxDepth:        maxDepth,
+		attributes:      &gitAttributes{},
+		readStdin:       readStdin,
+		filesFrom:       filesFrom,
+		since:           since,
+		staged:          staged,
+		showProgress:    readStdin || filesFrom != "" || len(args) > progressInterval,
+		processor: copyrighter.Processor{
+			Options: copyrighter.Options{
+				Copyright:       string(s.Copyright),
+				Preamble:        preamble,
+				NoticePatterns:  noticePatterns,
+				NoticeLines:     s.NoticeLines,
+				MatchPatterns:   matchPatterns,
+				SPDX:            strings.TrimSpace(s.SPDX),
+				MaintainedBy:    strings.TrimSpace(s.MaintainedBy),
+				TemplateVersion: s.TemplateVersion,
+				UpdateYearRange: isTrue(s.UpdateYearRange),
+				MergeHeader:     s.Placement == placementMerge,
+				MaxLineLength:   s.maxLineLength(),
+				BlankLines:      s.BlankLines.policy(),
+				LeaveHeader:     s.Only == onlyFooter,
+				LeaveFooter:     s.Only == onlyHeader,
+			},
+			Footers:      s.Footers,
+			Continuation: s.Continuation,
+			Year:         now.Year(),
+		},
+		extensions:         s.Extensions,
+		include:            s.Include,
+		exclude:            s.Exclude,
+		generated:          generated,
+		includeGenerated:   isTrue(s.IncludeGenerated),
+		includeVendored:    isTrue(s.IncludeVendored),
+		nestedRepos:        isTrue(s.NestedRepos),
+		followSymlinks:     isTrue(s.FollowSymlinks),
+		maxFileSize:        s.maxFileSize(maxFileSizeDefault(cmd)),
+		exceptions:         s.Exceptions,
+		holders:            s.Holders,
+		legacyHeaders:      s.LegacyHeaders,
+		acceptedCopyright:  acceptedNotices(s.AcceptedCopyright),
+		overwriteForeign:   isTrue(s.OverwriteForeign),
+		packageNotice:      packageNoticeFile(s.PackageNotice),
+		journal:            newJournal(journalDir(s.Journal), s.JournalSync, time.Now()),
+		minTemplateVersion: s.MinTemplateVersion,
+		baselinePath:       s.Baseline,
+		repos:              newRepoIndex(),
+		notify:             s.Notify,
+		history:            s.History,
+		summary:            runSummary{Command: commandName(cmd), started: time.Now(), frozen: frozen},
+	}
+	if isTrue(s.CopyrightTemplate) {
+		r.processor.Template = string(s.Copyright)
+		if _, err := r.processor.Notice("notice.go"); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if len(s.SidecarExtensions) > 0 {
+		r.sidecarExtensions = s.SidecarExtensions
+		if r.sidecar, err = loadSidecar(s.Sidecar); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if r.baseline, err = loadBaseline(s.Baseline); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if isTrue(s.Reuse) {
+		if r.dep5, err = loadDep5("."); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if s.Cache != "" {
+		r.cache, err = loadCache(s.Cache, cacheSettings(r.processor, r.holders, r.acceptedCopyright))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	cpuProfile, _ := cmd.Flags().GetString("cpu-profile")
+	tracePath, _ := cmd.Flags().GetString("trace")
+	if r.profiling, err = startProfiling(cpuProfile, tracePath); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	return r
+}
+
+// commandName names the mode of a run in summaries: "fix" for the root
+// command, otherwise the subcommand name.
+func commandName(cmd *cobra.Command) string {
+	if !cmd.HasParent() {
+		return "fix"
+	}
+	return cmd.Name()
+}
+
+// maxFileSizeDefault returns the max_file_size, in MiB, of cmd when it is
+// not set: a limit for serve only.
+func maxFileSizeDefault(cmd *cobra.Command) int64 {
+	if cmd.Name() == "serve" {
+		return defaultServeMaxFileSize
+	}
+	return 0
+}
+
+func subcommandPrefix(cmd *cobra.Command) string {
+	if !cmd.HasParent() {
+		return ""
+	}
+	return cmd.Name() + " "
+}
+
+func runCopyright(cmd *cobra.Command, args []string) {
+	r := newRunner(cmd, args)
+	r.dryRun, _ = cmd.Flags().GetBool("dry-run")
+	r.verifyIdempotent, _ = cmd.Flags().GetBool("verify-idempotent")
+	if stat, _ := cmd.Flags().GetBool("stat"); stat {
+		r.stat = &diffStat{}
+	}
+	r.setPatch(cmd)
+
+	r.runArgs(args)
+
+	if r.stat != nil {
+		r.stat.print(r.out)
+	}
+	r.writePatch()
+	r.finish()
+
+	if n := countFailures(r.err(), errNotIdempotent); n > 0 {
+		r.logf(logQuiet, "%d file(s) would change again on a second run\n", n)
+	}
+	os.Exit(r.summary.exitCode())
+}
+
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var compiled []*regexp.Regexp
+	for _, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, re)
+	}
+	return compiled, nil
+}
+
+// normalizeExtensions lower-cases extensions and adds a missing leading dot.
+func normalizeExtensions(exts []string) []string {
+	normalized := make([]string, 0, len(exts))
+	for _, ext := range exts {
+		ext = strings.ToLower(strings.TrimSpace(ext))
+		if ext == "" {
+			continue
+		}
+		if !strings.HasPrefix(ext, ".") {
+			ext = "." + ext
+		}
+		normalized = append(normalized, ext)
+	}
+	return normalized
 }
 
-func isSupportedFile(filePath string) bool {
-	supportedExtensions := []string{".go"}
+func isSupportedFile(filePath string, extensions []string) bool {
 	ext := strings.ToLower(filepath.Ext(filePath))
-	for _, supportedExt := range supportedExtensions {
+	for _, supportedExt := range extensions {
 		if ext == supportedExt {
 			return true
 		}
@@ -190,22 +1549,233 @@ func isSupportedFile(filePath string) bool {
 }
 
 func main() {
-	var copyrightText string
-
 	rootCmd := &cobra.Command{
 		Use:   "copy-righter [flags] file1 [file2 ...]",
 		Short: "A CLI tool to check and add copyright headers to files.",
-		Args:  cobra.MinimumNArgs(1),
+		Args:  cobra.ArbitraryArgs,
 		Run:   runCopyright,
 	}
-	rootCmd.Flags().StringVar(&copyrightText, "copyright", "", "Copyright text to add (required)")
-	if err := rootCmd.MarkFlagRequired("copyright"); err != nil {
-		fmt.Fprintf(os.Stderr, "Error marking flag required: %v\n", err)
-		os.Exit(1)
+	addRunFlags(rootCmd)
+	rootCmd.Flags().Bool("dry-run", false, "Report what would change without modifying any file")
+	rootCmd.Flags().Bool("stat", false, "Print a git-style diffstat and churn estimate at the end of the run")
+	rootCmd.Flags().String("patch", "", "Write the changes to this file as a patch for git apply instead of modifying any file")
+	rootCmd.Flags().Bool("verify-idempotent", false, "Re-stamp every fixed file in memory and fail instead of writing it if a second run would change it again")
+
+	checkCmd := &cobra.Command{
+		Use:   "check [flags] file1 [file2 ...]",
+		Short: "Report files with a missing or outdated copyright header or footer without modifying them.",
+		Args:  cobra.ArbitraryArgs,
+		Run:   runCheck,
+	}
+	addRunFlags(checkCmd)
+	checkCmd.Flags().String("min-template-version", "", "Accept outdated headers that carry this template version or a later one")
+	checkCmd.Flags().String("baseline", "", "File listing the outdated files to report without failing (default "+defaultBaselineFile+")")
+	rootCmd.AddCommand(checkCmd)
+
+	auditCmd := &cobra.Command{
+		Use:   "audit [flags] file1 [file2 ...]",
+		Short: "Classify every file as OK, missing, outdated, foreign or unsupported and print a compliance report.",
+		Args:  cobra.ArbitraryArgs,
+		Run:   runAudit,
+	}
+	addRunFlags(auditCmd)
+	rootCmd.AddCommand(auditCmd)
+
+	removeCmd := &cobra.Command{
+		Use:   "remove [flags] file1 [file2 ...]",
+		Short: "Strip the copyright header and footer from files.",
+		Args:  cobra.ArbitraryArgs,
+		Run:   runRemove,
+	}
+	addRunFlags(removeCmd)
+	removeCmd.Flags().Bool("dry-run", false, "Report what would change without modifying any file")
+	removeCmd.Flags().Bool("stat", false, "Print a git-style diffstat and churn estimate at the end of the run")
+	removeCmd.Flags().String("patch", "", "Write the changes to this file as a patch for git apply instead of modifying any file")
+	rootCmd.AddCommand(removeCmd)
+
+	adoptCmd := &cobra.Command{
+		Use:   "adopt [flags] [dir]",
+		Short: "Propose a config for a first rollout, show its impact and, once confirmed, write it and apply the safe fixes.",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runAdopt,
+	}
+	adoptCmd.Flags().String("copyright", "", "Copyright text to adopt (default: inferred from the most common existing notice)")
+	adoptCmd.Flags().BoolP("yes", "y", false, "Apply the proposal without asking for confirmation")
+	rootCmd.AddCommand(adoptCmd)
+
+	watchCmd := &cobra.Command{
+		Use:   "watch [flags] [dir ...]",
+		Short: "Stamp source files as they are created or saved, until interrupted.",
+		Args:  cobra.ArbitraryArgs,
+		Run:   runWatch,
+	}
+	addRunFlags(watchCmd)
+	watchCmd.Flags().Duration("debounce", defaultDebounce, "How long a file must be left alone after an event before it is stamped")
+	watchCmd.Flags().Int("max-watches", 0, "Most directories to give an inotify watch; the others are polled (default: as many as the system allows)")
+	watchCmd.Flags().Duration("poll-interval", defaultPollInterval, "How often the directories without an inotify watch are scanned for changes")
+	watchCmd.Flags().Bool("status", false, "Print the watches and queued files of the watch of the directories given, and exit")
+	rootCmd.AddCommand(watchCmd)
+
+	tuiCmd := &cobra.Command{
+		Use:   "tui [flags] [dir ...]",
+		Short: "Review the files a run would change in a terminal UI, preview their diffs and stamp only the ones selected.",
+		Args:  cobra.ArbitraryArgs,
+		Run:   runTUI,
+	}
+	addRunFlags(tuiCmd)
+	rootCmd.AddCommand(tuiCmd)
+
+	installHookCmd := &cobra.Command{
+		Use:   "install-hook [flags] [-- copy-righter flags]",
+		Short: "Make the git pre-commit hook check or fix the staged files, keeping any existing hook.",
+		Args:  cobra.ArbitraryArgs,
+		Run:   runInstallHook,
+	}
+	installHookCmd.Flags().String("mode", hookCheck, "What the hook does with the staged files: check, or fix and stop the commit to review the stamps")
+	rootCmd.AddCommand(installHookCmd)
+
+	reuseLintCmd := &cobra.Command{
+		Use:   "reuse-lint [dir]",
+		Short: "Check that every file of a project has a copyright and license as the REUSE specification requires, and that LICENSES holds the text of each license used.",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runReuseLint,
+	}
+	rootCmd.AddCommand(reuseLintCmd)
+
+	licenseCheckCmd := &cobra.Command{
+		Use:   "license-check [flags] [dir]",
+		Short: "Flag source files whose header declares a license, by SPDX tag or boilerplate, that the license of the project does not allow.",
+		Args:  cobra.MaximumNArgs(1),
+		Run:   runLicenseCheck,
+	}
+	licenseCheckCmd.Flags().String("license", "", "License file of the project (default: LICENSE, COPYING or similar in dir)")
+	rootCmd.AddCommand(licenseCheckCmd)
+
+	noticeCmd := &cobra.Command{
+		Use:   "notice [flags] [dir ...]",
+		Short: "Collect the distinct copyright statements of the file headers into a NOTICE file, keeping the rest of it.",
+		Args:  cobra.ArbitraryArgs,
+		Run:   runNotice,
+	}
+	noticeCmd.Flags().StringP("output", "o", defaultNoticeFile, "NOTICE file to write")
+	noticeCmd.Flags().Bool("check", false, "Exit with 1 instead of writing when the NOTICE file is out of date")
+	rootCmd.AddCommand(noticeCmd)
+
+	baselineCmd := &cobra.Command{
+		Use:   "baseline [flags] [dir ...]",
+		Short: "Record the files that fail check in a baseline, so check only fails on new problems while the backlog is fixed.",
+		Args:  cobra.ArbitraryArgs,
+		Run:   runBaseline,
+	}
+	addRunFlags(baselineCmd)
+	baselineCmd.Flags().String("baseline", "", "File to write the baseline to (default "+defaultBaselineFile+")")
+	rootCmd.AddCommand(baselineCmd)
+
+	serveCmd := &cobra.Command{
+		Use:   "serve [flags]",
+		Short: "Stamp and check file content sent over HTTP with the settings of the working directory, for bots and code review tools.",
+		Args:  cobra.NoArgs,
+		Run:   runServe,
+	}
+	addRunFlags(serveCmd)
+	serveCmd.Flags().String("addr", defaultServeAddr, "Address to listen on")
+	rootCmd.AddCommand(serveCmd)
+
+	importCmd := &cobra.Command{
+		Use:   "import --from TOOL [flags] CONFIG",
+		Short: "Convert the config of addlicense, license-eye or hawkeye into a .copyrighter.yaml that accepts their headers during a migration window.",
+		Args:  cobra.ExactArgs(1),
+		Run:   runImport,
 	}
+	importCmd.Flags().String("from", "", "Tool the config belongs to: addlicense (a script or Makefile running it), license-eye or hawkeye")
+	importCmd.Flags().String("until", "", "Month (YYYY-MM) or day check accepts the tool's headers until (default 3 months from now)")
+	importCmd.Flags().StringP("output", "o", defaultConfigFile, "Config file to write")
+	importCmd.MarkFlagRequired("from")
+	rootCmd.AddCommand(importCmd)
+
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Work with the history recorded by --history.",
+	}
+	reportExportCmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export the run history as a table of compliance per date, repository and language.",
+		Args:  cobra.NoArgs,
+		Run:   runReportExport,
+	}
+	reportExportCmd.Flags().String("history", "", "History file written by --history")
+	reportExportCmd.Flags().String("format", "csv", "Output format: csv or parquet")
+	reportExportCmd.Flags().StringP("output", "o", "", "Write to this file instead of stdout")
+	reportExportCmd.MarkFlagRequired("history")
+	reportCmd.AddCommand(reportExportCmd)
+	rootCmd.AddCommand(reportCmd)
+
+	policyCmd := &cobra.Command{
+		Use:   "policy",
+		Short: "Inspect the copyright policy enforced by the config.",
+	}
+	policyShowCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Render the effective policy as a document auditors can attach to compliance evidence.",
+		Args:  cobra.NoArgs,
+		Run:   runPolicyShow,
+	}
+	addConfigFlags(policyShowCmd)
+	policyCmd.AddCommand(policyShowCmd)
+	rootCmd.AddCommand(policyCmd)
+
+	undoCmd := &cobra.Command{
+		Use:   "undo",
+		Short: "Revert the files modified by the last run, or by the run given with --run, from the run journal.",
+		Args:  cobra.NoArgs,
+		Run:   runUndo,
+	}
+	addConfigFlags(undoCmd)
+	undoCmd.Flags().String("run", "", "Id of the run to undo (default the last run not undone)")
+	undoCmd.Flags().String("journal", "", "Directory the run journals are kept in (default in the user cache directory)")
+	rootCmd.AddCommand(undoCmd)
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "audit-diff old-dir new-dir",
+		Short: "Report copyright statements added, removed or changed between two snapshots of a tree.",
+		Args:  cobra.ExactArgs(2),
+		Run:   runAuditDiff,
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "languages",
+		Short: "List the supported languages and whether each gets a header, a footer and block comments.",
+		Args:  cobra.NoArgs,
+		Run:   runLanguages,
+	})
+
+	recordCorpusCmd := &cobra.Command{
+		Use:    "record-corpus [flags] file1 [file2 ...]",
+		Short:  "Write anonymized fixtures of files copy-righter handles wrongly, to attach to a bug report.",
+		Args:   cobra.MinimumNArgs(1),
+		Hidden: true,
+		Run:    runRecordCorpus,
+	}
+	recordCorpusCmd.Flags().StringP("output", "o", "corpus", "Directory to write the fixtures to")
+	recordCorpusCmd.Flags().Int("lines", 10, "Number of lines to keep from the start and from the end of each file")
+	rootCmd.AddCommand(recordCorpusCmd)
+	rootCmd.AddCommand(&cobra.Command{
+		Use:    "replay-corpus dir",
+		Short:  "Run the selfcheck on fixtures written by record-corpus.",
+		Args:   cobra.ExactArgs(1),
+		Hidden: true,
+		Run:    runReplayCorpus,
+	})
+
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "selfcheck",
+		Short: "Verify header placement against the embedded corpus of sample files.",
+		Args:  cobra.NoArgs,
+		Run:   runSelfcheck,
+	})
 
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		os.Exit(exitError)
 	}
 }
@@ -1,15 +1,52 @@
 package main
 
 import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/earik87/copy-righter/pkg/client"
+	"github.com/earik87/copy-righter/pkg/copyrighter"
 )
 
 const copyright = "Copyright (c) 2025 Example Corp. All rights reserved."
 
+// binPath is the CLI binary built once by TestMain and shared by all tests.
+var binPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "copy-righter-test")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "failed to create build dir: %v\n", err)
+		os.Exit(1)
+	}
+	binPath = filepath.Join(dir, "copy-righter")
+	if out, err := exec.Command("go", "build", "-o", binPath, ".").CombinedOutput(); err != nil {
+		fmt.Fprintf(os.Stderr, "failed to build CLI: %v\n%s", err, out)
+		os.Exit(1)
+	}
+	// Keep the run journals of the tests out of the user's cache
+	os.Setenv("XDG_CACHE_HOME", filepath.Join(dir, "cache"))
+	code := m.Run()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
 func writeTempFile(t *testing.T, content string) string {
 	t.Helper()
 	dir := t.TempDir()
@@ -22,13 +59,20 @@ func writeTempFile(t *testing.T, content string) string {
 
 func runCLI(t *testing.T, files ...string) string {
 	t.Helper()
-	args := append([]string{"run", "main.go", "--copyright=" + copyright}, files...)
-	cmd := exec.Command("go", args...)
-	out, err := cmd.CombinedOutput()
-	if err != nil {
-		t.Fatalf("CLI failed: %v\nOutput: %s", err, string(out))
+	args := append([]string{"--copyright=" + copyright}, files...)
+	out, code := runCmd(t, args...)
+	// Exit code 1 only reports that files were changed
+	if code != exitOK && code != exitChanged {
+		t.Fatalf("CLI failed with exit code %d\nOutput: %s", code, out)
 	}
-	return string(out)
+	return out
+}
+
+// runCmd runs the CLI with arbitrary arguments and returns its combined
+// output and exit code.
+func runCmd(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	return runCmdIn(t, "", args...)
 }
 
 func readFile(t *testing.T, file string) string {
@@ -111,24 +155,104 @@ func TestShebangFile(t *testing.T) {
 	file := writeTempFile(t, "#!/usr/bin/env bash\necho hi\n")
 	runCLI(t, file)
 	content := readFile(t, file)
-	if !strings.HasPrefix(content, "// "+copyright) {
+	if !strings.HasPrefix(content, "#!/usr/bin/env bash\n\n// "+copyright+"\n") {
 		t.Errorf("copyright not added after shebang: %q", content)
 	}
 }
 
+func TestBuildConstraintsKept(t *testing.T) {
+	file := writeTempFile(t, "//go:build linux\n// +build linux\n\npackage main\n")
+	runCLI(t, file)
+	content := readFile(t, file)
+	want := "// " + copyright + "\n\n//go:build linux\n// +build linux\n\npackage main\n"
+	if !strings.HasPrefix(content, want) {
+		t.Errorf("build constraints not kept below the header: %q", content)
+	}
+}
+
+func TestPackageDocCommentKept(t *testing.T) {
+	file := writeTempFile(t, "// Copyright 2019 Example Corp.\n// Package main is documented.\npackage main\n")
+	runCLI(t, file)
+	content := readFile(t, file)
+	want := "// " + copyright + "\n\n// Package main is documented.\npackage main\n"
+	if !strings.HasPrefix(content, want) {
+		t.Errorf("package doc comment not kept below the header: %q", content)
+	}
+}
+
+func TestGeneratedFilesSkipped(t *testing.T) {
+	generated := "// Code generated by mockgen. DO NOT EDIT.\n\npackage main\n"
+	file := writeTempFile(t, generated)
+	out := runCLI(t, "--verbose", file)
+	if !strings.Contains(out, "Skipping generated file: "+file) || readFile(t, file) != generated {
+		t.Fatalf("generated file not skipped:\n%s", out)
+	}
+
+	runCLI(t, "--include-generated", file)
+	if content := readFile(t, file); !strings.HasPrefix(content, "// "+copyright+"\n\n"+generated) {
+		t.Errorf("generated marker not kept below the header: %q", content)
+	}
+}
+
+func TestIgnoreDirectiveSkipsFile(t *testing.T) {
+	upstream := "// Copyright (c) 2019 Upstream Authors. MIT License.\n// copy-righter:ignore vendored from upstream\n\npackage lib\n"
+	file := writeTempFile(t, upstream)
+	out, code := runCmd(t, "check", "--copyright="+copyright, "--verbose", file)
+	if code != exitOK || !strings.Contains(out, "Skipping file with a copy-righter:ignore comment: "+file) {
+		t.Fatalf("check exit %d:\n%s", code, out)
+	}
+	runCLI(t, file)
+	if content := readFile(t, file); content != upstream {
+		t.Errorf("ignored file stamped: %q", content)
+	}
+}
+
+func TestResourceScriptKeepsCodePage(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "app.rc")
+	if err := os.WriteFile(file, []byte("#pragma code_page(65001)\n#include \"resource.h\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, file)
+	want := "#pragma code_page(65001)\n\n// " + copyright + "\n\n#include \"resource.h\"\n"
+	if content := readFile(t, file); !strings.HasPrefix(content, want) {
+		t.Errorf("code page pragma not kept above the header: %q", content)
+	}
+}
+
+func TestExtAndIncludeFlags(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"a.go", "b.py", "src/c.py"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("x = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runCLI(t, "--ext=.py,.ts", "--include=src/**", dir)
+	for name, stamped := range map[string]bool{"a.go": false, "b.py": false, "src/c.py": true} {
+		content := readFile(t, filepath.Join(dir, name))
+		if strings.Contains(content, copyright) != stamped {
+			t.Errorf("%s: stamped = %v, want %v: %q", name, !stamped, stamped, content)
+		}
+	}
+}
+
 func TestReadOnlyFile(t *testing.T) {
 	file := writeTempFile(t, "package main\n")
 	if err := os.Chmod(file, 0400); err != nil {
 		t.Fatalf("failed to chmod: %v", err)
 	}
-	cmd := exec.Command("go", "run", "main.go", "--copyright="+copyright, file)
+	cmd := exec.Command(binPath, "--copyright="+copyright, file)
 	_ = cmd.Run()
 	// Should not panic or crash; error is expected
 	_ = os.Chmod(file, 0600) // restore for cleanup
 }
 
 func TestNonExistentFile(t *testing.T) {
-	cmd := exec.Command("go", "run", "main.go", "--copyright="+copyright, "no_such_file.go")
+	cmd := exec.Command(binPath, "--copyright="+copyright, "no_such_file.go")
 	_ = cmd.Run() // Should not panic or crash
 }
 
@@ -563,3 +687,2744 @@ func TestIdempotencyPreservesTrailingNewline(t *testing.T) {
 		t.Errorf("third run changed file content")
 	}
 }
+
+func TestSelfcheckCorpus(t *testing.T) {
+	out, code := runCmd(t, "selfcheck")
+	if code != 0 {
+		t.Fatalf("selfcheck failed with exit code %d:\n%s", code, out)
+	}
+	if !strings.Contains(out, "ok   testdata/corpus/go/basic.go") {
+		t.Errorf("selfcheck did not report corpus files:\n%s", out)
+	}
+}
+
+func TestRecordAndReplayCorpus(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "billing.go")
+	os.WriteFile(src, []byte("//go:build linux\n\n// Copyright 2021 Megacorp Inc.\n\n// Package billing charges Zebra customers.\npackage billing\n\nfunc ChargeCustomer(id int) {}\n"), 0644)
+	fixtures := filepath.Join(dir, "fixtures")
+
+	out, code := runCmd(t, "record-corpus", "-o", fixtures, src)
+	if code != 0 {
+		t.Fatalf("record-corpus failed with exit code %d:\n%s", code, out)
+	}
+	recorded, _ := filepath.Glob(filepath.Join(fixtures, "go", "*.go"))
+	if len(recorded) != 1 {
+		t.Fatalf("recorded fixtures = %v, want one Go fixture", recorded)
+	}
+	fixture := readFile(t, recorded[0])
+	for _, secret := range []string{"billing", "Megacorp", "Zebra", "ChargeCustomer", "linux"} {
+		if strings.Contains(fixture, secret) {
+			t.Errorf("fixture still contains %q:\n%s", secret, fixture)
+		}
+	}
+	if !strings.HasPrefix(fixture, "//go:build w1\n\n// Copyright 2021 W2 W3.\n") || !strings.Contains(fixture, "\npackage w4\n") {
+		t.Errorf("fixture lost the file structure:\n%s", fixture)
+	}
+
+	out, code = runCmd(t, "replay-corpus", fixtures)
+	if code != 0 || !strings.Contains(out, "replay passed") {
+		t.Errorf("replay-corpus failed with exit code %d:\n%s", code, out)
+	}
+}
+
+func TestUTF8BOMStaysFirst(t *testing.T) {
+	const bom = "\ufeff"
+	file := writeTempFile(t, bom+"package main\n")
+	runCLI(t, file)
+	want := bom + "// " + copyright + "\n\npackage main\n\n// " + copyright + "\n"
+	if content := readFile(t, file); content != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, file); code != exitOK {
+		t.Errorf("header after a BOM not recognised (exit %d):\n%s", code, out)
+	}
+
+	generated := writeTempFile(t, bom+"// Code generated by tool. DO NOT EDIT.\n\npackage main\n")
+	runCLI(t, generated)
+	if content := readFile(t, generated); strings.Contains(content, copyright) {
+		t.Errorf("generated file after a BOM was stamped: %q", content)
+	}
+}
+
+func TestTrailingTicketCommentIsKept(t *testing.T) {
+	initial := "package main\n\nfunc main() {}\n\n// TODO(jira-123): remove once migrated\n"
+	file := writeTempFile(t, initial)
+	runCLI(t, file)
+	content := readFile(t, file)
+
+	if !strings.Contains(content, "// TODO(jira-123): remove once migrated\n") {
+		t.Errorf("ticket comment was overwritten: %q", content)
+	}
+	if !strings.HasSuffix(content, "\n\n// "+copyright+"\n") {
+		t.Errorf("footer not added below ticket comment: %q", content)
+	}
+}
+
+func TestTrailingCommentAttachedToCodeIsKept(t *testing.T) {
+	initial := "package main\n\nvar x = 1\n// x must stay in sync with y\n"
+	file := writeTempFile(t, initial)
+	runCLI(t, file)
+	content := readFile(t, file)
+
+	if !strings.Contains(content, "var x = 1\n// x must stay in sync with y\n\n// "+copyright+"\n") {
+		t.Errorf("attached trailing comment not preserved: %q", content)
+	}
+}
+
+func TestPreambleStaysAboveHeader(t *testing.T) {
+	initial := "// EXPORT CONTROLLED: ECCN 5D002\npackage main\n"
+	file := writeTempFile(t, initial)
+	args := []string{"--copyright=" + copyright, "--preamble=^// EXPORT CONTROLLED", file}
+	if out, code := runCmd(t, args...); code != exitChanged {
+		t.Fatalf("CLI failed: %s", out)
+	}
+	first := readFile(t, file)
+	expected := "// EXPORT CONTROLLED: ECCN 5D002\n\n// " + copyright + "\n\npackage main\n"
+	if !strings.HasPrefix(first, expected) {
+		t.Errorf("header not placed below preamble: %q", first)
+	}
+
+	runCmd(t, args...)
+	if second := readFile(t, file); second != first {
+		t.Errorf("preamble placement not idempotent:\nFirst:\n%q\nSecond:\n%q", first, second)
+	}
+}
+
+func TestMatchPatternReplacesLegacyHeaders(t *testing.T) {
+	dir := t.TempDir()
+	legacy := filepath.Join(dir, "legacy.py")
+	directive := filepath.Join(dir, "directive.py")
+	if err := os.WriteFile(legacy, []byte("# ACME PROPRIETARY\n# Do not distribute.\n\nimport os\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(directive, []byte("# pylint: disable=invalid-name\n\nimport os\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, code := runCmd(t, "--copyright="+copyright, "--match-pattern=^# ACME PROPRIETARY", legacy, directive); code != exitChanged {
+		t.Fatalf("CLI failed (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, legacy); content != "# "+copyright+"\n\nimport os\n\n# "+copyright+"\n" {
+		t.Errorf("legacy header not replaced: %q", content)
+	}
+	if content := readFile(t, directive); content != "# "+copyright+"\n\n# pylint: disable=invalid-name\n\nimport os\n\n# "+copyright+"\n" {
+		t.Errorf("lint directive not kept below the header: %q", content)
+	}
+}
+
+func TestDryRunLeavesFilesUntouched(t *testing.T) {
+	initial := "package main\n\nfunc main() {}\n"
+	file := writeTempFile(t, initial)
+	out, code := runCmd(t, "--copyright="+copyright, "--dry-run", file)
+	if code != exitChanged {
+		t.Fatalf("CLI failed: %s", out)
+	}
+	if content := readFile(t, file); content != initial {
+		t.Errorf("dry run modified the file: %q", content)
+	}
+	if !strings.Contains(out, "Dry run, not writing: "+file) {
+		t.Errorf("dry run did not report the pending change:\n%s", out)
+	}
+}
+
+func TestDryRunStat(t *testing.T) {
+	file := writeTempFile(t, "package main\n\nfunc main() {}\n")
+	out, code := runCmd(t, "--copyright="+copyright, "--dry-run", "--stat", file)
+	if code != exitChanged {
+		t.Fatalf("CLI failed: %s", out)
+	}
+	if !strings.Contains(out, file+" | 4 ++++") {
+		t.Errorf("per-file diffstat missing:\n%s", out)
+	}
+	if !strings.Contains(out, " 1 file changed, 4 insertions(+)") {
+		t.Errorf("diffstat summary missing:\n%s", out)
+	}
+	if !strings.Contains(out, "Estimated churn: 4 of 3 scanned lines") {
+		t.Errorf("churn estimate missing:\n%s", out)
+	}
+}
+
+func TestCheckReportsWithoutModifying(t *testing.T) {
+	initial := "package main\n\nfunc main() {}\n"
+	file := writeTempFile(t, initial)
+	out, code := runCmd(t, "check", "--copyright="+copyright, file)
+	if code == 0 {
+		t.Fatalf("check passed on a file without copyright:\n%s", out)
+	}
+	if !strings.Contains(out, file+": missing header, missing footer") {
+		t.Errorf("check did not list the offending file:\n%s", out)
+	}
+	if content := readFile(t, file); content != initial {
+		t.Errorf("check modified the file: %q", content)
+	}
+}
+
+func TestCheckPassesAfterFix(t *testing.T) {
+	file := writeTempFile(t, "package main\n")
+	runCLI(t, file)
+	out, code := runCmd(t, "check", "--copyright="+copyright, filepath.Dir(file)+"/...")
+	if code != 0 {
+		t.Fatalf("check failed on a stamped tree (exit %d):\n%s", code, out)
+	}
+}
+
+func TestConfigProfileSelection(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "copyrighter.yaml")
+	configContent := `copyright: "Copyright (c) 2025 Example Corp. Internal use only."
+profiles:
+  oss:
+    copyright: "Copyright (c) 2025 Example Corp. Licensed under Apache-2.0."
+    exclude: ["internal"]
+`
+	if err := os.WriteFile(config, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	public := filepath.Join(dir, "src", "public.go")
+	private := filepath.Join(dir, "src", "internal", "private.go")
+	for _, file := range []string{public, private} {
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(file, []byte("package src\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	out, code := runCmd(t, "--config="+config, "--profile=oss", filepath.Join(dir, "src"))
+	if code != exitChanged {
+		t.Fatalf("CLI failed: %s", out)
+	}
+	if content := readFile(t, public); !strings.HasPrefix(content, "// Copyright (c) 2025 Example Corp. Licensed under Apache-2.0.\n") {
+		t.Errorf("profile copyright not applied: %q", content)
+	}
+	if content := readFile(t, private); content != "package src\n" {
+		t.Errorf("profile exclude not applied: %q", content)
+	}
+
+	out, code = runCmd(t, "--config="+config, "--profile=missing", filepath.Join(dir, "src"))
+	if code == 0 || !strings.Contains(out, `unknown profile "missing" (available: oss)`) {
+		t.Errorf("unknown profile not rejected (exit %d):\n%s", code, out)
+	}
+}
+
+func TestBlockCommentStylesheet(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "styles.css")
+	if err := os.WriteFile(file, []byte(".a { color: red; }\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	runCLI(t, file)
+	expected := "/* " + copyright + " */\n\n.a { color: red; }\n\n/* " + copyright + " */\n"
+	if content := readFile(t, file); content != expected {
+		t.Errorf("css not stamped with block comments:\n%q\nwant:\n%q", content, expected)
+	}
+}
+
+func TestSVGHeaderAfterXMLDeclaration(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "icon.svg")
+	initial := "<?xml version=\"1.0\"?>\n<svg xmlns=\"http://www.w3.org/2000/svg\"/>\n"
+	if err := os.WriteFile(file, []byte(initial), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	runCLI(t, dir)
+	content := readFile(t, file)
+	if !strings.HasPrefix(content, "<?xml version=\"1.0\"?>\n\n<!-- "+copyright+" -->\n") {
+		t.Errorf("svg header not placed after the XML declaration: %q", content)
+	}
+}
+
+func TestTemplateFilesUseEngineComments(t *testing.T) {
+	dir := t.TempDir()
+	cases := map[string]string{
+		"page.tmpl":  "{{/* " + copyright + " */}}",
+		"card.hbs":   "{{!-- " + copyright + " --}}",
+		"list.ejs":   "<%# " + copyright + " %>",
+		"base.jinja": "{# " + copyright + " #}",
+	}
+	for name := range cases {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("<p>body</p>\n"), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	runCLI(t, dir)
+	for name, comment := range cases {
+		expected := comment + "\n\n<p>body</p>\n"
+		if content := readFile(t, filepath.Join(dir, name)); content != expected {
+			t.Errorf("%s not stamped with template comments:\n%q\nwant:\n%q", name, content, expected)
+		}
+	}
+
+	// Footers would add a line to every rendered page, so they are opt-in.
+	config := filepath.Join(t.TempDir(), "copyrighter.yaml")
+	if err := os.WriteFile(config, []byte("footers: {tmpl: true, hbs: true, ejs: true, jinja: true}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, "--config="+config, dir)
+	for name, comment := range cases {
+		expected := comment + "\n\n<p>body</p>\n\n" + comment + "\n"
+		if content := readFile(t, filepath.Join(dir, name)); content != expected {
+			t.Errorf("%s footer not stamped when turned on:\n%q\nwant:\n%q", name, content, expected)
+		}
+	}
+}
+
+func TestSymlinkArgumentRefusedWithoutDereference(t *testing.T) {
+	target := writeTempFile(t, "package main\n")
+	link := filepath.Join(t.TempDir(), "link.go")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	out, _ := runCmd(t, "--copyright="+copyright, link)
+	if !strings.Contains(out, "is a symlink, refusing to process it") {
+		t.Errorf("symlink argument not refused:\n%s", out)
+	}
+	if content := readFile(t, target); content != "package main\n" {
+		t.Errorf("symlink target modified without --dereference: %q", content)
+	}
+
+	runCLI(t, "--dereference", link)
+	if content := readFile(t, target); !strings.HasPrefix(content, "// "+copyright) {
+		t.Errorf("symlink target not stamped with --dereference: %q", content)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink itself was replaced: %v", err)
+	}
+}
+
+func TestSymlinkEscapingRootRefused(t *testing.T) {
+	outside := writeTempFile(t, "package main\n")
+	dir := t.TempDir()
+	if err := os.Symlink(outside, filepath.Join(dir, "escape.go")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	out, _ := runCmd(t, "--copyright="+copyright, "--follow-symlinks", dir)
+	if !strings.Contains(out, "outside the allowed roots") {
+		t.Errorf("symlink escape not reported:\n%s", out)
+	}
+	if content := readFile(t, outside); content != "package main\n" {
+		t.Errorf("file outside the root modified: %q", content)
+	}
+
+	out, _ = runCmd(t, "--copyright="+copyright, "--root="+dir, outside)
+	if !strings.Contains(out, "outside the allowed roots") || readFile(t, outside) != "package main\n" {
+		t.Errorf("explicit path outside --root not refused:\n%s", out)
+	}
+}
+
+func TestNotifyCommandReceivesSummary(t *testing.T) {
+	file := writeTempFile(t, "package main\n")
+	summaryFile := filepath.Join(t.TempDir(), "summary.json")
+	runCLI(t, "--notify-cmd=cat > "+summaryFile, file)
+
+	var summary struct {
+		Command  string `json:"command"`
+		Scanned  int    `json:"scanned"`
+		Modified int    `json:"modified"`
+		Text     string `json:"text"`
+	}
+	if err := json.Unmarshal([]byte(readFile(t, summaryFile)), &summary); err != nil {
+		t.Fatalf("notify command did not receive JSON: %v", err)
+	}
+	if summary.Command != "fix" || summary.Scanned != 1 || summary.Modified != 1 {
+		t.Errorf("unexpected summary: %+v", summary)
+	}
+	if !strings.Contains(summary.Text, "copy-righter fix succeeded") {
+		t.Errorf("summary text missing: %q", summary.Text)
+	}
+}
+
+func TestNotifyCommandFromConfigNeedsOptIn(t *testing.T) {
+	dir := t.TempDir()
+	summaryFile := filepath.Join(dir, "summary.json")
+	config := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(config, []byte("copyright: \""+copyright+"\"\nnotify:\n  command: cat > "+summaryFile+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(file, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := runCmd(t, "check", "--config="+config, file)
+	if _, err := os.Stat(summaryFile); err == nil || !strings.Contains(out, "ignoring notify.command") {
+		t.Errorf("notify.command from the config ran without --allow-config-commands:\n%s", out)
+	}
+	runCmd(t, "check", "--config="+config, "--allow-config-commands", file)
+	if _, err := os.Stat(summaryFile); err != nil {
+		t.Errorf("notify.command did not run with --allow-config-commands: %v", err)
+	}
+}
+
+func TestNotifyWebhookReceivesCheckSummary(t *testing.T) {
+	received := make(chan map[string]any, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		received <- body
+	}))
+	defer server.Close()
+
+	file := writeTempFile(t, "package main\n")
+	runCmd(t, "check", "--copyright="+copyright, "--notify-webhook="+server.URL, file)
+
+	select {
+	case body := <-received:
+		if body["command"] != "check" || body["outdated"] != float64(1) {
+			t.Errorf("unexpected webhook payload: %v", body)
+		}
+	default:
+		t.Fatal("webhook was not called")
+	}
+}
+
+func TestCopyrightFileMultiLineHeader(t *testing.T) {
+	dir := t.TempDir()
+	headerFile := filepath.Join(dir, "HEADER.txt")
+	header := "Copyright 2025 Example Corp.\n\nLicensed under the Apache License, Version 2.0.\n"
+	if err := os.WriteFile(headerFile, []byte(header), 0644); err != nil {
+		t.Fatalf("failed to write header file: %v", err)
+	}
+	file := filepath.Join(dir, "main.go")
+	initial := "// Copyright 2019 Example Corp.\n// All rights reserved.\n\npackage main\n"
+	if err := os.WriteFile(file, []byte(initial), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	args := []string{"--copyright-file=" + headerFile, file}
+	if out, code := runCmd(t, args...); code != exitChanged {
+		t.Fatalf("CLI failed: %s", out)
+	}
+	block := "// Copyright 2025 Example Corp.\n//\n// Licensed under the Apache License, Version 2.0."
+	expected := block + "\n\npackage main\n\n" + block + "\n"
+	first := readFile(t, file)
+	if first != expected {
+		t.Errorf("multi-line header not applied:\n%q\nwant:\n%q", first, expected)
+	}
+
+	runCmd(t, args...)
+	if second := readFile(t, file); second != first {
+		t.Errorf("multi-line header not idempotent:\n%q", second)
+	}
+}
+
+func TestCopyrightTemplate(t *testing.T) {
+	dir := t.TempDir()
+	goFile, pyFile := filepath.Join(dir, "main.go"), filepath.Join(dir, "tool.py")
+	for name, content := range map[string]string{goFile: "package main\n", pyFile: "print('hi')\n"} {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	args := []string{"--copyright={{.Language}} file {{.Base}}, Copyright (c) Example Corp.", "--copyright-template", "--only=header", goFile, pyFile}
+	if out, code := runCmd(t, args...); code != exitChanged {
+		t.Fatalf("CLI failed: %s", out)
+	}
+	if got := readFile(t, goFile); got != "// Go file main.go, Copyright (c) Example Corp.\n\npackage main\n" {
+		t.Errorf("main.go = %q", got)
+	}
+	if got := readFile(t, pyFile); !strings.HasPrefix(got, "# Python file tool.py, Copyright (c) Example Corp.\n") {
+		t.Errorf("tool.py = %q", got)
+	}
+	if out, code := runCmd(t, append([]string{"check"}, args...)...); code != exitOK {
+		t.Errorf("check after stamping: exit %d: %s", code, out)
+	}
+
+	out, code := runCmd(t, "--copyright={{range .Path}}x{{end}}", "--copyright-template", goFile)
+	if code != exitError || !strings.Contains(out, "range is not allowed") {
+		t.Errorf("range: exit %d: %s", code, out)
+	}
+}
+
+func TestConfigHashEnforcement(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "copyrighter.yaml")
+	configContent := "copyright: \"" + copyright + "\"\n"
+	if err := os.WriteFile(config, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	sum := sha256.Sum256([]byte(configContent))
+	goodHash := hex.EncodeToString(sum[:])
+	file := writeTempFile(t, "package main\n")
+
+	out, code := runCmd(t, "--config="+config, "--config-hash="+strings.Repeat("0", 64), file)
+	if code == 0 || !strings.Contains(out, "refusing to run") {
+		t.Errorf("mismatched config hash not refused (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, file); content != "package main\n" {
+		t.Errorf("file modified despite config hash mismatch: %q", content)
+	}
+
+	if out, code := runCmd(t, "--config="+config, "--config-hash="+goodHash, file); code != exitChanged {
+		t.Fatalf("matching config hash refused (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, file); !strings.HasPrefix(content, "// "+copyright) {
+		t.Errorf("file not stamped with trusted config: %q", content)
+	}
+}
+
+func TestNulSeparatedPathsFromStdin(t *testing.T) {
+	file1 := writeTempFile(t, "package a\n")
+	file2 := writeTempFile(t, "package b\n")
+	list := strings.Repeat(file1+"\x00"+file2+"\x00", progressInterval/2)
+
+	cmd := exec.Command(binPath, "--copyright="+copyright, "-0")
+	cmd.Stdin = strings.NewReader(list)
+	out, err := cmd.CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != exitChanged {
+		t.Fatalf("CLI failed: %v\n%s", err, out)
+	}
+	for _, file := range []string{file1, file2} {
+		if content := readFile(t, file); strings.Count(content, "// "+copyright) != 2 {
+			t.Errorf("file from stdin not stamped exactly once: %q", content)
+		}
+	}
+	if !strings.Contains(string(out), "Progress: 1000 paths processed") {
+		t.Errorf("aggregate progress not reported:\n%s", lastLines(string(out), 5))
+	}
+}
+
+func TestFilesFrom(t *testing.T) {
+	for _, sep := range []string{"\n", "\r\n", "\x00"} {
+		file1 := writeTempFile(t, "package a\n")
+		file2 := writeTempFile(t, "package b\n")
+
+		cmd := exec.Command(binPath, "--copyright="+copyright, "--files-from", "-")
+		cmd.Stdin = strings.NewReader(file1 + sep + file2 + sep)
+		out, err := cmd.CombinedOutput()
+		if exitErr, ok := err.(*exec.ExitError); !ok || exitErr.ExitCode() != exitChanged {
+			t.Fatalf("paths separated by %q: CLI failed: %v\n%s", sep, err, out)
+		}
+		for _, file := range []string{file1, file2} {
+			if content := readFile(t, file); strings.Count(content, "// "+copyright) != 2 {
+				t.Errorf("paths separated by %q: listed file not stamped exactly once: %q", sep, content)
+			}
+		}
+	}
+
+	file := writeTempFile(t, "package a\n")
+	list := filepath.Join(t.TempDir(), "files")
+	if err := os.WriteFile(list, []byte(file+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, "--files-from="+list)
+	if content := readFile(t, file); !strings.HasPrefix(content, "// "+copyright) {
+		t.Errorf("file listed in a file not stamped: %q", content)
+	}
+}
+
+func lastLines(s string, n int) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
+
+func TestUpdateYearRange(t *testing.T) {
+	initial := "// Copyright (c) 2021 Example Corp. All rights reserved.\n\npackage main\n"
+	file := writeTempFile(t, initial)
+	runCLI(t, "--update-year-range", file)
+	content := readFile(t, file)
+
+	expected := "// Copyright (c) 2021-2025 Example Corp. All rights reserved."
+	if !strings.HasPrefix(content, expected+"\n\npackage main\n") {
+		t.Errorf("year range not extended in header: %q", content)
+	}
+	if !strings.HasSuffix(content, "\n"+expected+"\n") {
+		t.Errorf("footer does not carry the same year range: %q", content)
+	}
+
+	runCLI(t, "--update-year-range", file)
+	if second := readFile(t, file); second != content {
+		t.Errorf("year range update not idempotent: %q", second)
+	}
+}
+
+func TestPolicyShow(t *testing.T) {
+	dir := t.TempDir()
+	config := filepath.Join(dir, "copyrighter.yaml")
+	configContent := `copyright: "Copyright (c) 2025 Example Corp. Internal."
+exclude: ["vendor"]
+profiles:
+  oss:
+    copyright: "Copyright (c) 2025 Example Corp. Apache-2.0."
+    extensions: [".go", ".css"]
+`
+	if err := os.WriteFile(config, []byte(configContent), 0644); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+
+	out, code := runCmd(t, "policy", "show", "--config="+config, "--profile=oss")
+	if code != 0 {
+		t.Fatalf("policy show failed: %s", out)
+	}
+	for _, want := range []string{
+		"- Profile: `oss`",
+		"    // Copyright (c) 2025 Example Corp. Apache-2.0.",
+		"    /* Copyright (c) 2025 Example Corp. Apache-2.0. */",
+		"- Excluded paths: `vendor`",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("policy document missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// runCmdIn is runCmd with the working directory set to dir; an empty dir
+// uses the test's working directory.
+func TestAdopt(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":               "// Copyright 2024 Acme\n\npackage a\n",
+		"pkg/b.go":           "package pkg\n",
+		"pkg/d.go":           "// Copyright 2024 Acme\n\npackage pkg\n",
+		"pkg/c.py":           "# Copyright 2019 Other Inc\nx = 1\n",
+		"third_party/v/v.go": "package v\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, code := runCmdIn(t, dir, "adopt")
+	if code != 0 || !strings.Contains(out, "Header:     Copyright 2024 Acme (inferred from 2 existing files)") || !strings.Contains(out, "Nothing written") {
+		t.Fatalf("adopt without confirmation (exit %d):\n%s", code, out)
+	}
+	if _, err := os.Stat(filepath.Join(dir, defaultConfigFile)); err == nil {
+		t.Fatal("config written without confirmation")
+	}
+
+	if out, code := runCmdIn(t, dir, "adopt", "--yes"); code != 0 {
+		t.Fatalf("adopt --yes failed (exit %d):\n%s", code, out)
+	}
+	config := readFile(t, filepath.Join(dir, defaultConfigFile))
+	for _, want := range []string{`copyright: "Copyright 2024 Acme"`, `extensions: [".go", ".py"]`, `- "third_party"`, `- "pkg/c.py"`} {
+		if !strings.Contains(config, want) {
+			t.Errorf("config missing %s:\n%s", want, config)
+		}
+	}
+	if content := readFile(t, filepath.Join(dir, "pkg/b.go")); !strings.HasPrefix(content, "// Copyright 2024 Acme\n") {
+		t.Errorf("safe fix not applied: %q", content)
+	}
+	if content := readFile(t, filepath.Join(dir, "pkg/c.py")); content != files["pkg/c.py"] {
+		t.Errorf("file with a foreign notice was modified: %q", content)
+	}
+	if out, code := runCmdIn(t, dir, "check", "."); code != 0 {
+		t.Errorf("check fails after adopt (exit %d):\n%s", code, out)
+	}
+}
+
+func runCmdIn(t *testing.T, dir string, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(binPath, args...)
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return string(out), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatalf("failed to run CLI: %v", err)
+	}
+	return string(out), 0
+}
+
+// initGitRepo creates a git repository in a temp dir with files committed.
+func initGitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	gitIn(t, dir, "init", "-q")
+	gitIn(t, dir, "add", ".")
+	gitIn(t, dir, "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func TestPatchAppliesWithGit(t *testing.T) {
+	files := map[string]string{
+		"main.go":      "package main\n\n" + strings.Repeat("// filler\n", 20) + "func main() {}",
+		"pkg/util.go":  "// Copyright (c) 2019 Example Corp. All rights reserved.\n\npackage pkg\n",
+		"pkg/other.py": "print('hi')\n",
+	}
+	dir := initGitRepo(t, files)
+	patch := filepath.Join(t.TempDir(), "out.patch")
+	if out, code := runCmdIn(t, dir, "--copyright="+copyright, "--patch="+patch, "."); code != exitChanged || !strings.Contains(out, "Wrote the changes to 3 files to "+patch) {
+		t.Fatalf("--patch exit %d:\n%s", code, out)
+	}
+	for name, content := range files {
+		if got := readFile(t, filepath.Join(dir, name)); got != content {
+			t.Errorf("%s modified by --patch: %q", name, got)
+		}
+	}
+
+	gitIn(t, dir, "apply", patch)
+	applied := make(map[string]string)
+	for name := range files {
+		applied[name] = readFile(t, filepath.Join(dir, name))
+	}
+	runCmdIn(t, dir, "--copyright="+copyright, ".")
+	for name, want := range applied {
+		if got := readFile(t, filepath.Join(dir, name)); got != want {
+			t.Errorf("%s: applied patch gives %q, stamping gives %q", name, want, got)
+		}
+	}
+}
+
+func gitIn(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v\n%s", args, err, out)
+	}
+}
+
+// startWatch starts watch in dir with args, checks its first line starts
+// with want and returns the process and a channel receiving the rest of
+// its output once it exits.
+func startWatch(t *testing.T, dir, want string, args ...string) (*exec.Cmd, chan string) {
+	t.Helper()
+	cmd := exec.Command(binPath, append([]string{"watch", "--copyright=" + copyright}, args...)...)
+	cmd.Dir = dir
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	out := bufio.NewReader(stdout)
+	if line, err := out.ReadString('\n'); err != nil || !strings.HasPrefix(line, want) {
+		t.Fatalf("watch did not start: %q, %v", line, err)
+	}
+	rest := make(chan string, 1)
+	go func() {
+		b, _ := io.ReadAll(out)
+		rest <- string(b)
+	}()
+	return cmd, rest
+}
+
+// waitStamped waits for the file at path to be stamped, for at most five
+// seconds.
+func waitStamped(t *testing.T, path string) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); !strings.HasPrefix(readFile(t, path), "// "+copyright) && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// waitWatchStatus waits for watch --status in dir to report want, which
+// the session saves shortly after a change, for at most five seconds, and
+// returns the last status.
+func waitWatchStatus(t *testing.T, dir, want string) string {
+	t.Helper()
+	var status string
+	for deadline := time.Now().Add(5 * time.Second); !strings.Contains(status, want) && time.Now().Before(deadline); {
+		time.Sleep(20 * time.Millisecond)
+		status, _ = runCmdIn(t, dir, "watch", "--status", ".")
+	}
+	return status
+}
+
+func TestWatchStampsNewFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(dir, "vendor"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	cmd, rest := startWatch(t, dir, "Watching 1 directory in .", "--debounce=50ms", ".")
+
+	files := map[string]string{"pkg/a/new.go": "package a\n", "vendor/v.go": "package v\n"}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	stamped := filepath.Join(dir, "pkg/a/new.go")
+	waitStamped(t, stamped)
+
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	summary := <-rest
+	cmd.Wait()
+	if content := readFile(t, stamped); !strings.HasPrefix(content, "// "+copyright) {
+		t.Errorf("new file in a new directory not stamped: %q\n%s", content, summary)
+	}
+	if content := readFile(t, filepath.Join(dir, "vendor/v.go")); content != files["vendor/v.go"] {
+		t.Errorf("excluded file stamped: %q", content)
+	}
+	if code := cmd.ProcessState.ExitCode(); code != exitChanged || !strings.Contains(summary, "1 added") {
+		t.Errorf("watch exit %d, summary:\n%s", code, summary)
+	}
+}
+
+func TestWatchPollsPastMaxWatches(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "pkg/a"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	startWatch(t, dir, "Watching 3 directories in . (2 polled every 50ms)", "--debounce=50ms", "--poll-interval=50ms", "--max-watches=1", ".")
+
+	files := []string{"pkg/a/saved.go", "pkg/b/new.go"}
+	for _, name := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package p\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range files {
+		path := filepath.Join(dir, name)
+		if waitStamped(t, path); !strings.HasPrefix(readFile(t, path), "// "+copyright) {
+			t.Errorf("%s in a polled directory not stamped", name)
+		}
+	}
+	if out := waitWatchStatus(t, dir, "1 directory watched, 3 polled, 0 files queued"); !strings.Contains(out, "1 directory watched, 3 polled, 0 files queued") {
+		t.Errorf("unexpected status: %s", out)
+	}
+}
+
+func TestWatchQueueSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	cmd, _ := startWatch(t, dir, "Watching 1 directory in .", "--debounce=1h", ".")
+	path := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(path, []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if status := waitWatchStatus(t, dir, "1 file queued"); !strings.Contains(status, "0 polled, 1 file queued") {
+		t.Fatalf("file not queued: %s", status)
+	}
+	// A session killed before the file settles leaves it queued
+	cmd.Process.Kill()
+	cmd.Wait()
+	if out, _ := runCmdIn(t, dir, "watch", "--status", "."); !strings.Contains(out, "No watch of . running; 1 file queued for the next one") {
+		t.Errorf("killed session reported running: %s", out)
+	}
+	if content := readFile(t, path); content != "package a\n" {
+		t.Fatalf("file stamped before it settled: %q", content)
+	}
+
+	cmd, rest := startWatch(t, dir, "Watching 1 directory in .", "--debounce=50ms", ".")
+	waitStamped(t, path)
+	if err := cmd.Process.Signal(os.Interrupt); err != nil {
+		t.Fatal(err)
+	}
+	summary := <-rest
+	cmd.Wait()
+	if !strings.Contains(summary, "Resuming 1 file queued by the last session") || !strings.HasPrefix(readFile(t, path), "// "+copyright) {
+		t.Errorf("queued file not stamped by the next session:\n%s", summary)
+	}
+	if out, _ := runCmdIn(t, dir, "watch", "--status", "."); !strings.Contains(out, "No watch of .") {
+		t.Errorf("state left after a clean stop: %s", out)
+	}
+}
+
+// startServe starts serve in dir with args and returns a function posting
+// a request body to one of its endpoints.
+func startServe(t *testing.T, dir string, args ...string) func(endpoint, body string) (int, map[string]any) {
+	t.Helper()
+	base := serveURL(t, dir, args...)
+	return func(endpoint, body string) (int, map[string]any) {
+		t.Helper()
+		resp, err := http.Post(base+endpoint, "application/json", strings.NewReader(body))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		var got map[string]any
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode, got
+	}
+}
+
+// serveURL starts serve in dir with args and returns its URL.
+func serveURL(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command(binPath, append([]string{"serve", "--addr=127.0.0.1:0"}, args...)...)
+	cmd.Dir = dir
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = cmd.Process.Signal(os.Interrupt)
+		_ = cmd.Wait()
+	})
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "Serving on ") {
+		t.Fatalf("serve did not start: %q, %v", line, err)
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, "Serving on "))
+}
+
+// TestServeClient checks the client package against serve: its requests
+// are understood, the verdicts decoded and refusals returned as errors,
+// and the server answers the OpenAPI document the client ships.
+func TestServeClient(t *testing.T) {
+	base := serveURL(t, t.TempDir(), "--copyright="+copyright)
+	c := client.New(base)
+	ctx := context.Background()
+
+	resp, err := c.Check(ctx, client.Request{Language: "Go", Content: "package main\n"})
+	if err != nil || resp.Status != "outdated" || resp.Content != nil {
+		t.Errorf("check: %+v, %v", resp, err)
+	}
+	resp, err = c.Stamp(ctx, client.Request{Path: "main.go", Content: "package main\n"})
+	if err != nil || resp.Status != "modified" || resp.Content == nil || !strings.HasPrefix(*resp.Content, "// "+copyright+"\n") {
+		t.Errorf("stamp: %+v, %v", resp, err)
+	}
+	_, err = c.Check(ctx, client.Request{Language: "cobol", Content: "x"})
+	if serr := (*client.Error)(nil); !errors.As(err, &serr) || serr.StatusCode != http.StatusUnprocessableEntity || serr.Message == "" {
+		t.Errorf("unsupported language: %v", err)
+	}
+
+	httpResp, err := http.Get(base + "/v1/openapi.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpResp.Body.Close()
+	if spec, _ := io.ReadAll(httpResp.Body); !bytes.Equal(spec, client.OpenAPI) {
+		t.Errorf("served OpenAPI document differs from the client's")
+	}
+}
+
+func TestServeStampsAndChecksContent(t *testing.T) {
+	post := startServe(t, t.TempDir(), "--copyright="+copyright)
+
+	code, got := post("/v1/check", `{"language": "Go", "content": "package main\n"}`)
+	if code != http.StatusOK || got["status"] != "outdated" || got["content"] != nil {
+		t.Errorf("check: %d %v", code, got)
+	}
+	code, got = post("/v1/stamp", `{"path": "cmd/main.go", "content": "package main\n"}`)
+	stamped, _ := got["content"].(string)
+	if code != http.StatusOK || got["status"] != "modified" || !strings.HasPrefix(stamped, "// "+copyright+"\n") {
+		t.Fatalf("stamp: %d %v", code, got)
+	}
+	body, _ := json.Marshal(map[string]string{"language": "go", "content": stamped})
+	if code, got = post("/v1/check", string(body)); code != http.StatusOK || got["status"] != "up_to_date" {
+		t.Errorf("check of stamped content: %d %v", code, got)
+	}
+	if code, got = post("/v1/stamp", `{"language": "cobol", "content": ""}`); code != http.StatusUnprocessableEntity || got["error"] == "" {
+		t.Errorf("unsupported language: %d %v", code, got)
+	}
+	if code, _ = post("/v1/stamp", `{"content": "x"}`); code != http.StatusBadRequest {
+		t.Errorf("request without path or language: %d", code)
+	}
+	for _, path := range []string{"/etc/main.go", "../main.go", "cmd/../../main.go"} {
+		body, _ := json.Marshal(map[string]string{"path": path, "content": "package main\n"})
+		if code, got = post("/v1/check", string(body)); code != http.StatusBadRequest || !strings.Contains(got["error"].(string), "not inside the working directory") {
+			t.Errorf("path %s: %d %v", path, code, got)
+		}
+	}
+	if code, got = post("/v1/check", `{"path": "cmd/../main.go", "content": "package main\n"}`); code != http.StatusOK {
+		t.Errorf("path that stays inside: %d %v", code, got)
+	}
+	if code, _ = post("/v1/check", `{"language": "../go", "content": "package main\n"}`); code != http.StatusBadRequest {
+		t.Errorf("language with a path separator: %d", code)
+	}
+}
+
+func TestServeVerdictsMatchCLI(t *testing.T) {
+	dir := t.TempDir()
+	config := "copyright: \"" + copyright + "\"\ntemplate_version: \"3\"\nmin_template_version: \"2\"\n" +
+		"legacy_headers:\n  - from: addlicense\n    pattern: Licensed to Legacy Corp\n    until: 2099-12\n"
+	files := map[string]string{
+		defaultConfigFile: config,
+		"legacy.go":       "// Copyright 2020 Legacy Corp\n// Licensed to Legacy Corp\n\npackage a\n",
+		"foreign.go":      "// Copyright (c) 2018 Upstream Authors\n\npackage a\n",
+		"versioned.go":    "package a\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	versioned := filepath.Join(dir, "versioned.go")
+	if out, code := runCmd(t, "--copyright=Copyright (c) 2025 Example Corp. Old terms.", "--template-version=2", versioned); code != exitChanged {
+		t.Fatalf("stamping failed with exit code %d:\n%s", code, out)
+	}
+	post := startServe(t, dir)
+
+	for _, name := range []string{"legacy.go", "foreign.go", "versioned.go"} {
+		body, _ := json.Marshal(map[string]string{"path": name, "content": readFile(t, filepath.Join(dir, name))})
+
+		_, code := runCmdIn(t, dir, "check", name)
+		_, got := post("/v1/check", string(body))
+		if want := map[int]string{exitOK: "up_to_date", exitChanged: "outdated"}[code]; got["status"] != want {
+			t.Errorf("check of %s: serve says %v, the CLI exits %d", name, got["status"], code)
+		}
+
+		out, _ := runCmdIn(t, dir, "--dry-run", name)
+		_, got = post("/v1/stamp", string(body))
+		if foreign := strings.Contains(out, "foreign header"); (got["status"] == "foreign") != foreign {
+			t.Errorf("stamp of %s: serve says %v, the CLI:\n%s", name, got["status"], out)
+		}
+	}
+
+	post = startServe(t, dir, "--max-file-size=1")
+	body, _ := json.Marshal(map[string]string{"path": "big.go", "content": strings.Repeat("x", 2<<20)})
+	if code, _ := post("/v1/stamp", string(body)); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("request over --max-file-size: %d", code)
+	}
+	// The limit is on the content, however much longer escaping makes it.
+	body, _ = json.Marshal(map[string]string{"path": "big.go", "content": "package big\n" + strings.Repeat("\n", 1<<20-100)})
+	if code, got := post("/v1/check", string(body)); code != http.StatusOK {
+		t.Errorf("content under --max-file-size: %d %v", code, got)
+	}
+}
+
+func TestInstallHook(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{
+		defaultConfigFile: "copyright: \"" + copyright + "\"\nextensions: [\".go\"]\n",
+	})
+	hook := filepath.Join(dir, ".git", "hooks", "pre-commit")
+	if err := os.WriteFile(hook, []byte("#!/usr/bin/env true\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for range 2 {
+		if out, code := runCmdIn(t, dir, "install-hook"); code != exitOK {
+			t.Fatalf("install-hook failed (exit %d):\n%s", code, out)
+		}
+	}
+	content := readFile(t, hook)
+	if strings.Count(content, hookBegin) != 1 || !strings.Contains(content, "check --staged || exit $?") || !strings.HasSuffix(content, "exec \"$(dirname \"$0\")\"/pre-commit.local \"$@\"\n") {
+		t.Errorf("hook not installed once and chained to the existing hook:\n%s", content)
+	}
+	if got := readFile(t, hook+".local"); got != "#!/usr/bin/env true\n" {
+		t.Errorf("existing hook not kept: %q", got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	gitIn(t, dir, "add", "new.go")
+	commit := exec.Command("git", "-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "-m", "new")
+	commit.Dir = dir
+	if out, err := commit.CombinedOutput(); err == nil || !strings.Contains(string(out), "new.go: missing header") {
+		t.Errorf("commit of a file without a header not stopped (%v):\n%s", err, out)
+	}
+}
+
+func TestSinceOnlyProcessesChangedFiles(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{
+		"old.go":     "package main\n",
+		"changed.go": "package main\n",
+	})
+	if err := os.WriteFile(filepath.Join(dir, "changed.go"), []byte("package main\n\nvar x = 1\n"), 0644); err != nil {
+		t.Fatalf("failed to modify file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), []byte("package main\n"), 0644); err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+
+	if out, code := runCmdIn(t, dir, "--copyright="+copyright, "--since=HEAD"); code != exitChanged {
+		t.Fatalf("CLI failed: %s", out)
+	}
+	for name, wantStamped := range map[string]bool{"old.go": false, "changed.go": true, "new.go": true} {
+		content := readFile(t, filepath.Join(dir, name))
+		if stamped := strings.HasPrefix(content, "// "+copyright); stamped != wantStamped {
+			t.Errorf("%s: stamped=%v, want %v: %q", name, stamped, wantStamped, content)
+		}
+	}
+}
+
+func TestSinceWithAbsolutePath(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{
+		"sub/a.go": "package sub\n",
+		"b.go":     "package main\n",
+	})
+	for _, name := range []string{"sub/a.go", "b.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package x\n\nvar x = 1\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if out, code := runCmdIn(t, dir, "--copyright="+copyright, "--since=HEAD", filepath.Join(dir, "sub")); code != exitChanged {
+		t.Fatalf("CLI failed (exit %d): %s", code, out)
+	}
+	if !strings.HasPrefix(readFile(t, filepath.Join(dir, "sub", "a.go")), "// "+copyright) {
+		t.Error("changed file beneath an absolute argument not stamped")
+	}
+	if strings.HasPrefix(readFile(t, filepath.Join(dir, "b.go")), "//") {
+		t.Error("changed file outside the absolute argument stamped")
+	}
+}
+
+func TestStagedOnlyProcessesIndex(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{"base.go": "package main\n"})
+	for _, name := range []string{"staged.go", "unstaged.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package main\n"), 0644); err != nil {
+			t.Fatalf("failed to add file: %v", err)
+		}
+	}
+	gitIn(t, dir, "add", "staged.go")
+
+	out, code := runCmdIn(t, dir, "check", "--copyright="+copyright, "--staged")
+	if code == 0 {
+		t.Fatalf("check --staged passed with an unstamped staged file:\n%s", out)
+	}
+	if !strings.Contains(out, "staged.go: missing header") || strings.Contains(out, "unstaged.go") {
+		t.Errorf("check --staged did not limit itself to the index:\n%s", out)
+	}
+}
+
+func TestSPDXIdentifierInHeader(t *testing.T) {
+	initial := "// Copyright (c) 2019 Example Corp.\n// SPDX-License-Identifier: MIT\n\npackage main\n"
+	file := writeTempFile(t, initial)
+	runCLI(t, "--spdx=Apache-2.0", file)
+	content := readFile(t, file)
+
+	expected := "// " + copyright + "\n// SPDX-License-Identifier: Apache-2.0\n\npackage main\n\n// " + copyright + "\n"
+	if content != expected {
+		t.Errorf("SPDX header not applied:\n%q\nwant:\n%q", content, expected)
+	}
+
+	runCLI(t, "--spdx=Apache-2.0", file)
+	if second := readFile(t, file); second != content {
+		t.Errorf("SPDX header not idempotent: %q", second)
+	}
+}
+
+func TestCopyrightURLIsFetchedAndCached(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		fmt.Fprintln(w, copyright)
+	}))
+	url := server.URL + "/headers/go.txt"
+
+	file := writeTempFile(t, "package main\n")
+	for range 2 {
+		if out, code := runCmd(t, "--copyright-url="+url, file); code != exitOK && code != exitChanged {
+			t.Fatalf("CLI failed (exit %d):\n%s", code, out)
+		}
+	}
+	if content := readFile(t, file); content != "// "+copyright+"\n\npackage main\n\n// "+copyright+"\n" {
+		t.Errorf("fetched notice not stamped: %q", content)
+	}
+	if n := requests.Load(); n != 1 {
+		t.Errorf("notice fetched %d times, want once within the TTL", n)
+	}
+
+	server.Close()
+	if out, code := runCmd(t, "check", "--copyright-url="+url, "--copyright-url-ttl=0s", file); code != exitOK || !strings.Contains(out, "Warning: fetching copyright notice") {
+		t.Errorf("cached copy should be used when the server is down (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmd(t, "check", "--copyright-url="+server.URL+"/other.txt", file); code != exitError || !strings.Contains(out, "fetching copyright notice") {
+		t.Errorf("unreachable URL without a cached copy should fail (exit %d):\n%s", code, out)
+	}
+}
+
+func TestFromLicense(t *testing.T) {
+	dir := t.TempDir()
+	mit := "MIT License\n\nCopyright (c) 2024 Jane Doe\n\nPermission is hereby granted, free of charge, to any person obtaining a copy\nof this software...\n"
+	apache := "\n                                 Apache License\n                           Version 2.0, January 2004\n...\n   Copyright [yyyy] [name of copyright owner]\n"
+	for name, content := range map[string]string{"LICENSE": mit, "APACHE.txt": apache, "OTHER.txt": "All rights reserved.\n", "a.go": "package a\n", "b.py": "import os\n"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if out, code := runCmdIn(t, dir, "--from-license", "a.go"); code != exitChanged {
+		t.Fatalf("CLI failed (exit %d):\n%s", code, out)
+	}
+	header := "// Copyright (c) 2024 Jane Doe\n//\n// Use of this source code is governed by the MIT license that can be\n// found in the LICENSE file.\n"
+	if content := readFile(t, filepath.Join(dir, "a.go")); !strings.HasPrefix(content, header+"\npackage a\n") {
+		t.Errorf("header not derived from the MIT license: %q", content)
+	}
+
+	if out, code := runCmdIn(t, dir, "--copyright="+copyright, "--from-license=APACHE.txt", "b.py"); code != exitChanged {
+		t.Fatalf("CLI failed (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, filepath.Join(dir, "b.py")); !strings.HasPrefix(content, "# "+copyright+"\n#\n# Licensed under the Apache License, Version 2.0 (the \"License\");\n") ||
+		!strings.Contains(content, "\n#     http://www.apache.org/licenses/LICENSE-2.0\n") {
+		t.Errorf("header not derived from the Apache license: %q", content)
+	}
+
+	if out, code := runCmdIn(t, dir, "check", "--from-license=OTHER.txt", "a.go"); code != exitError || !strings.Contains(out, "OTHER.txt: license not recognised") {
+		t.Errorf("unknown license not rejected (exit %d):\n%s", code, out)
+	}
+}
+
+func TestReuseLayoutAndLint(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":     "package main\n",
+		"docs/gen.go": "package docs\n",
+		"logo.png":    "\x89PNG\x00\x00",
+		".reuse/dep5": "Format: https://www.debian.org/doc/packaging-manuals/copyright-format/1.0/\n\nFiles: docs/*\n *.png\nCopyright: 2025 Example Corp.\nLicense: CC-BY-4.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if out, code := runCmdIn(t, dir, "--copyright="+copyright, "--reuse", "."); code != exitError || !strings.Contains(out, "reuse needs the license") {
+		t.Errorf("--reuse without --spdx not rejected (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmdIn(t, dir, "--copyright="+copyright, "--reuse", "--spdx=MIT", "."); code != exitChanged {
+		t.Fatalf("CLI failed (exit %d):\n%s", code, out)
+	}
+	tag := "// SPDX-FileCopyrightText: " + copyright + "\n"
+	if content := readFile(t, filepath.Join(dir, "main.go")); content != tag+"// SPDX-License-Identifier: MIT\n\npackage main\n\n"+tag {
+		t.Errorf("header not in the REUSE layout: %q", content)
+	}
+	if content := readFile(t, filepath.Join(dir, "docs/gen.go")); content != files["docs/gen.go"] {
+		t.Errorf("file covered by dep5 was stamped: %q", content)
+	}
+
+	out, code := runCmdIn(t, dir, "reuse-lint")
+	for _, want := range []string{"LICENSES/MIT.txt: missing, MIT is used by main.go", "LICENSES/CC-BY-4.0.txt: missing"} {
+		if code != exitChanged || !strings.Contains(out, want) {
+			t.Errorf("reuse-lint should report %q (exit %d):\n%s", want, code, out)
+		}
+	}
+	for _, id := range []string{"MIT", "CC-BY-4.0"} {
+		if err := os.MkdirAll(filepath.Join(dir, "LICENSES"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "LICENSES", id+".txt"), []byte(id+" license text\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, code := runCmdIn(t, dir, "reuse-lint"); code != exitOK || !strings.Contains(out, "All 3 files comply with the REUSE specification") {
+		t.Errorf("compliant project not accepted (exit %d):\n%s", code, out)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("Example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runCmdIn(t, dir, "reuse-lint"); code != exitChanged || !strings.Contains(out, "README: no copyright\nREADME: no license\n") {
+		t.Errorf("file without REUSE information not reported (exit %d):\n%s", code, out)
+	}
+}
+
+func TestNoticeCollectsHeaderStatements(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.go":          "// Copyright 2020 Vendor\npackage a\n\n// Copyright 2020 Vendor\n",
+		"lib/b.py":      "# Copyright (c) 2024 Example Corp.\nimport os\n",
+		"lib/c.go":      "// Copyright 2020 Vendor\npackage lib\n",
+		"gen.go":        "// Code generated by gen. DO NOT EDIT.\n// Copyright 2019 Generator\npackage a\n",
+		"vendor/v.go":   "// Copyright 2018 Third Party\npackage v\n",
+		"docs/notes.md": "Copyright 2017 Not Source\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	notice := filepath.Join(dir, "NOTICE")
+	if err := os.WriteFile(notice, []byte("Example Product\nThis product includes software developed at Example Corp.\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, code := runCmdIn(t, dir, "notice"); code != exitOK || !strings.Contains(out, "Wrote NOTICE with 2 copyright statements") {
+		t.Fatalf("notice failed (exit %d):\n%s", code, out)
+	}
+	want := "Example Product\nThis product includes software developed at Example Corp.\n\n" +
+		"=== Copyright notices collected by copy-righter notice; do not edit by hand ===\n" +
+		"Copyright (c) 2024 Example Corp.\nCopyright 2020 Vendor\n=== End of copyright notices ===\n"
+	if content := readFile(t, notice); content != want {
+		t.Errorf("NOTICE = %q, want %q", content, want)
+	}
+	if out, code := runCmdIn(t, dir, "notice", "--check"); code != exitOK {
+		t.Errorf("check of an up-to-date NOTICE failed (exit %d):\n%s", code, out)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "lib/d.go"), []byte("// Copyright 2025 Newcomer\npackage lib\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runCmdIn(t, dir, "notice", "--check"); code != exitChanged || !strings.Contains(out, "NOTICE is out of date") {
+		t.Errorf("check of an outdated NOTICE should fail (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, notice); content != want {
+		t.Errorf("--check wrote the NOTICE file: %q", content)
+	}
+	runCmdIn(t, dir, "notice")
+	if content := readFile(t, notice); !strings.Contains(content, "Copyright 2020 Vendor\nCopyright 2025 Newcomer\n===") || !strings.HasPrefix(content, "Example Product\n") {
+		t.Errorf("NOTICE section not updated in place: %q", content)
+	}
+}
+
+func TestAuditDiffReportsNoticeChanges(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"old/same.go":    "// Copyright 2020 Vendor\npackage a\n",
+		"new/same.go":    "// Copyright 2020 Vendor\npackage a\n",
+		"old/bumped.go":  "// Copyright 2020 Vendor\npackage a\n",
+		"new/bumped.go":  "// Copyright 2021 Vendor\npackage a\n",
+		"old/dropped.go": "/* Copyright (c) 2019 Other */\npackage a\n",
+		"new/dropped.go": "package a\n",
+		"new/lib/new.go": "// Copyright 2024 Newcomer\npackage lib\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, code := runCmd(t, "audit-diff", filepath.Join(dir, "old"), filepath.Join(dir, "new"))
+	if code != 0 {
+		t.Fatalf("audit-diff failed (exit %d):\n%s", code, out)
+	}
+	expected := "changed bumped.go\n  - Copyright 2020 Vendor\n  + Copyright 2021 Vendor\n" +
+		"removed dropped.go\n  - Copyright (c) 2019 Other\n" +
+		"added   lib/new.go\n  + Copyright 2024 Newcomer\n" +
+		"3 files with different notices: 1 added, 1 removed, 1 changed\n"
+	if out != expected {
+		t.Errorf("unexpected audit-diff report:\n%s\nwant:\n%s", out, expected)
+	}
+}
+
+func TestUnicodeVariantsOfHolderNameAreEquivalent(t *testing.T) {
+	nfc := "© 2025 Soci\u00e9t\u00e9 G\u00e9n\u00e9rale O\u2019Brien"
+	nfd := "© 2025 Socie\u0301te\u0301 Ge\u0301ne\u0301rale O'Brien"
+	file := writeTempFile(t, "// "+nfd+"\n\npackage main\n\n// "+nfd+"\n")
+
+	out, code := runCmd(t, "check", "--copyright="+nfc, file)
+	if code != 0 {
+		t.Errorf("NFD notice reported as non-compliant (exit %d):\n%s", code, out)
+	}
+
+	fresh := writeTempFile(t, "package main\n")
+	runCmd(t, "--copyright="+nfd, fresh)
+	if content := readFile(t, fresh); !strings.HasPrefix(content, "// © 2025 Soci\u00e9t\u00e9 G\u00e9n\u00e9rale O'Brien\n") {
+		t.Errorf("notice not written in NFC: %q", content)
+	}
+}
+
+func TestRemoveStripsHeaderAndFooter(t *testing.T) {
+	initial := "package main\n\nfunc main() {}\n"
+	file := writeTempFile(t, initial)
+	runCLI(t, file)
+	if readFile(t, file) == initial {
+		t.Fatal("file was not stamped")
+	}
+
+	out, code := runCmd(t, "remove", "--copyright="+copyright, file)
+	if code != exitChanged {
+		t.Fatalf("remove failed (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, file); content != initial {
+		t.Errorf("remove did not restore the original file:\n%q\nwant:\n%q", content, initial)
+	}
+
+	out, _ = runCmd(t, "remove", "--copyright="+copyright, "--verbose", file)
+	if !strings.Contains(out, "No copyright notice to remove in: "+file) {
+		t.Errorf("second remove should find nothing:\n%s", out)
+	}
+}
+
+func TestBlankLinesRespaceNotices(t *testing.T) {
+	file := writeTempFile(t, "// "+copyright+"\n\npackage main\n\n// "+copyright+"\n")
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--header-blank-lines=2", file); code != exitChanged || !strings.Contains(out, file+": outdated header") {
+		t.Errorf("check should fail on the header spacing (exit %d):\n%s", code, out)
+	}
+
+	config := filepath.Join(t.TempDir(), "copyrighter.yaml")
+	if err := os.WriteFile(config, []byte("blank_lines: {header: 2, footer: 0}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, "--config="+config, file)
+	if content := readFile(t, file); content != "// "+copyright+"\n\n\npackage main\n// "+copyright+"\n" {
+		t.Errorf("notice not respaced: %q", content)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--footer-blank-lines=3", file); code != exitError || !strings.Contains(out, "invalid blank_lines 3") {
+		t.Errorf("out of range blank lines not rejected (exit %d):\n%s", code, out)
+	}
+}
+
+func TestMultipleCopyrightHolders(t *testing.T) {
+	const original = "Copyright (c) 2019 Original Author"
+	file := writeTempFile(t, "// "+original+"\n\npackage main\n")
+
+	if out, code := runCmd(t, "--copyright="+original, "--copyright="+copyright, file); code != exitChanged {
+		t.Fatalf("CLI failed (exit %d):\n%s", code, out)
+	}
+	block := "// " + original + "\n// " + copyright + "\n"
+	if content := readFile(t, file); content != block+"\npackage main\n\n"+block {
+		t.Errorf("holder lines not stamped as one block: %q", content)
+	}
+
+	config := filepath.Join(t.TempDir(), "copyrighter.yaml")
+	if err := os.WriteFile(config, []byte("copyright:\n  - "+original+"\n  - "+copyright+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runCmd(t, "check", "--config="+config, file); code != exitOK {
+		t.Errorf("check with the holders listed in the config should pass (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, file); code != exitChanged {
+		t.Errorf("check for a single holder should fail (exit %d):\n%s", code, out)
+	}
+}
+
+func TestLongNoticeIsWrapped(t *testing.T) {
+	long := copyright + " Licensed under the Example Corp. Proprietary Software License, version 2."
+	file := writeTempFile(t, "// "+long+"\n\npackage main\n")
+	if out, code := runCmd(t, "check", "--copyright="+long, "--max-line-length=0", file); code != exitChanged || !strings.Contains(out, file+": missing footer\n") {
+		t.Errorf("unwrapped header should be accepted with --max-line-length=0 (exit %d):\n%s", code, out)
+	}
+
+	runCmd(t, "--copyright="+long, file)
+	wrapped := "// Copyright (c) 2025 Example Corp. All rights reserved. Licensed under the Example Corp.\n// Proprietary Software License, version 2.\n"
+	if content := readFile(t, file); content != wrapped+"\npackage main\n\n"+wrapped {
+		t.Errorf("notice not wrapped at 100 columns: %q", content)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+long, file); code != exitOK {
+		t.Errorf("wrapped notice should pass the check (exit %d):\n%s", code, out)
+	}
+}
+
+func TestOnlyFooterLeavesHeadersAlone(t *testing.T) {
+	header := "// Copyright (c) 2019 Old Corp.\n\n"
+	file := writeTempFile(t, header+"package main\n")
+
+	if out, code := runCmd(t, "--copyright="+copyright, "--only=footer", file); code != exitChanged {
+		t.Fatalf("CLI failed (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, file); content != header+"package main\n\n// "+copyright+"\n" {
+		t.Errorf("footer not added below the code, or header changed: %q", content)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--only=footer", file); code != exitOK {
+		t.Errorf("check --only=footer should pass (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--only=header", file); code != exitChanged {
+		t.Errorf("check --only=header should fail on the old header (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--only=top", file); code != exitError || !strings.Contains(out, `invalid --only "top"`) {
+		t.Errorf("unknown --only value not rejected (exit %d):\n%s", code, out)
+	}
+}
+
+func TestIncludeExcludePrecedenceAndDebugMatch(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"src/app.go", "src/vendor/lib.go", "tools/gen.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := filepath.Join(dir, "copyrighter.yaml")
+	configText := "copyright: \"" + copyright + "\"\ninclude: [\"src/**\", \"vendor/keep/*.go\"]\nexclude: [\"vendor\"]\n"
+	if err := os.WriteFile(config, []byte(configText), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, code := runCmdIn(t, dir, "--config="+config, ".")
+	if code != exitChanged {
+		t.Fatalf("run failed (exit %d):\n%s", code, out)
+	}
+	if !strings.Contains(out, `Warning: include pattern "vendor/keep/*.go" is shadowed by exclude pattern "vendor"`) {
+		t.Errorf("shadowed include not reported:\n%s", out)
+	}
+	if !strings.HasPrefix(readFile(t, filepath.Join(dir, "src/app.go")), "// "+copyright) {
+		t.Error("included file not stamped")
+	}
+	for _, name := range []string{"src/vendor/lib.go", "tools/gen.go"} {
+		if content := readFile(t, filepath.Join(dir, name)); content != "package x\n" {
+			t.Errorf("%s should not be stamped: %q", name, content)
+		}
+	}
+
+	out, _ = runCmdIn(t, dir, "--config="+config, "--debug-match=src/vendor/lib.go")
+	if !strings.Contains(out, `exclude "vendor"`) || !strings.Contains(out, "skipped: excluded by exclude \"vendor\"") {
+		t.Errorf("unexpected --debug-match output:\n%s", out)
+	}
+}
+
+func TestDefaultExcludes(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"app.go", "vendor/lib/lib.go", "node_modules/m/m.js", "target/gen.go"} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := runCLI(t, "--verbose", dir)
+	if !strings.Contains(out, "Skipping excluded path: "+filepath.Join(dir, "vendor")) {
+		t.Errorf("vendor not skipped:\n%s", out)
+	}
+	for name, stamped := range map[string]bool{"app.go": true, "vendor/lib/lib.go": false, "node_modules/m/m.js": false, "target/gen.go": false} {
+		if got := strings.Contains(readFile(t, filepath.Join(dir, name)), copyright); got != stamped {
+			t.Errorf("%s: stamped = %v, want %v", name, got, stamped)
+		}
+	}
+
+	runCLI(t, "--no-default-excludes", dir)
+	if !strings.Contains(readFile(t, filepath.Join(dir, "vendor/lib/lib.go")), copyright) {
+		t.Error("vendor not walked with --no-default-excludes")
+	}
+}
+
+func TestDefaultExcludesIgnoreDirectoriesAboveRoot(t *testing.T) {
+	for _, parent := range []string{"dist", "vendor"} {
+		root := filepath.Join(t.TempDir(), parent, "app")
+		if err := os.MkdirAll(root, 0755); err != nil {
+			t.Fatal(err)
+		}
+		file := filepath.Join(root, "main.go")
+		if err := os.WriteFile(file, []byte("package main\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		out, code := runCmd(t, "--copyright="+copyright, root)
+		if code != exitChanged || !strings.Contains(readFile(t, file), copyright) {
+			t.Errorf("root under %s/ not stamped (exit %d):\n%s", parent, code, out)
+		}
+	}
+}
+
+func TestAuditClassifiesFiles(t *testing.T) {
+	dir := t.TempDir()
+	holder := "Copyright (c) 2025 Example Corp."
+	files := map[string]string{
+		"ok.go":       "// " + holder + "\n\npackage x\n\n// " + holder + "\n",
+		"missing.go":  "package x\n",
+		"outdated.go": "// Copyright (c) 2019 Example Corp.\n\npackage x\n\n// " + holder + "\n",
+		"foreign.go":  "// Copyright 2010 Upstream Authors\n\npackage x\n",
+		"notes.txt":   "plain text\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, code := runCmdIn(t, dir, "audit", "--copyright="+holder, ".")
+	if code != 0 {
+		t.Fatalf("audit failed (exit %d):\n%s", code, out)
+	}
+	for _, want := range []string{
+		"ok                    1\n",
+		"missing header        1\n",
+		"outdated header       1\n",
+		"foreign header        1\n",
+		"unsupported           1\n",
+		"total                 5\n",
+		"  foreign header   foreign.go (Copyright 2010 Upstream Authors)\n",
+		"  outdated header  outdated.go (outdated header)\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("audit report missing %q:\n%s", want, out)
+		}
+	}
+	if content := readFile(t, filepath.Join(dir, "missing.go")); content != files["missing.go"] {
+		t.Errorf("audit modified a file: %q", content)
+	}
+}
+
+func TestSkipEnvBypassesCheckTraceably(t *testing.T) {
+	file := writeTempFile(t, "package main\n")
+	summaryFile := filepath.Join(t.TempDir(), "summary.json")
+	t.Setenv("COPYRIGHTER_SKIP", "1")
+
+	out, code := runCmd(t, "check", "--copyright="+copyright, "--notify-cmd=cat > "+summaryFile, file)
+	if code != 0 {
+		t.Errorf("check should pass with COPYRIGHTER_SKIP=1 (exit %d):\n%s", code, out)
+	}
+	if !strings.Contains(out, "copyright enforcement is DISABLED") || !strings.Contains(out, file+": missing header, missing footer") {
+		t.Errorf("bypass not logged loudly:\n%s", out)
+	}
+	if summary := readFile(t, summaryFile); !strings.Contains(summary, `"enforcement_skipped":true`) {
+		t.Errorf("bypass not recorded in the summary: %s", summary)
+	}
+
+	// Only check mode honours the variable
+	runCLI(t, file)
+	if !strings.HasPrefix(readFile(t, file), "// "+copyright) {
+		t.Error("fix mode should ignore COPYRIGHTER_SKIP")
+	}
+}
+
+func TestReportExportFromHistory(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	history := filepath.Join(dir, "history.jsonl")
+	runCmdIn(t, dir, "--copyright="+copyright, "--history="+history, "a.go")
+	runCmdIn(t, dir, "check", "--copyright="+copyright, "--history="+history, "a.go")
+
+	out, code := runCmd(t, "report", "export", "--history="+history)
+	if code != 0 {
+		t.Fatalf("report export failed (exit %d):\n%s", code, out)
+	}
+	rows := strings.Split(strings.TrimSpace(out), "\n")
+	if len(rows) != 3 || rows[0] != "date,repo,language,scanned,compliant_pct,fixes_applied" {
+		t.Fatalf("unexpected CSV export:\n%s", out)
+	}
+	if !strings.HasSuffix(rows[1], ",Go,1,0.0,1") || !strings.HasSuffix(rows[2], ",Go,1,100.0,0") {
+		t.Errorf("unexpected compliance rows:\n%s", out)
+	}
+
+	parquetFile := filepath.Join(dir, "trend.parquet")
+	if out, code := runCmd(t, "report", "export", "--history="+history, "--format=parquet", "-o", parquetFile); code != 0 {
+		t.Fatalf("parquet export failed (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, parquetFile); !strings.HasPrefix(content, "PAR1") || !strings.HasSuffix(content, "PAR1") {
+		t.Errorf("parquet export is not framed by the PAR1 magic")
+	}
+}
+
+func TestHashCommentLanguagesKeepShebang(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho hi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(config, []byte("name: example\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, dir)
+
+	if content, want := readFile(t, script), "#!/bin/sh\n\n# "+copyright+"\n\necho hi\n\n# "+copyright+"\n"; content != want {
+		t.Errorf("shell script not stamped below the shebang:\n%q\nwant:\n%q", content, want)
+	}
+	if content, want := readFile(t, config), "# "+copyright+"\n\nname: example\n"; content != want {
+		t.Errorf("yaml not stamped with hash comments:\n%q\nwant:\n%q", content, want)
+	}
+}
+
+func TestCStyleBlockHeaderReplacesExistingNotice(t *testing.T) {
+	dir := t.TempDir()
+	cFile := filepath.Join(dir, "main.c")
+	initial := "/*\n * Copyright (c) 2015 Example Corp.\n * All rights reserved.\n */\n\nint main(void) { return 0; }\n"
+	if err := os.WriteFile(cFile, []byte(initial), 0644); err != nil {
+		t.Fatal(err)
+	}
+	jsFile := filepath.Join(dir, "index.js")
+	if err := os.WriteFile(jsFile, []byte("// Copyright 2019 Example Corp.\n\nexport {};\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, dir)
+
+	want := "/* " + copyright + " */\n\nint main(void) { return 0; }\n\n/* " + copyright + " */\n"
+	if content := readFile(t, cFile); content != want {
+		t.Errorf("block header not replaced:\n%q\nwant:\n%q", content, want)
+	}
+	want = "/* " + copyright + " */\n\nexport {};\n\n/* " + copyright + " */\n"
+	if content := readFile(t, jsFile); content != want {
+		t.Errorf("line comment header not replaced:\n%q\nwant:\n%q", content, want)
+	}
+}
+
+func TestCheckReportsLicenseTextMidFile(t *testing.T) {
+	content := "// " + copyright + "\n\npackage main\n\n" +
+		"// Permission is hereby granted, free of charge, to any person\n" +
+		"func main() {}\n\n// " + copyright + "\n"
+	file := writeTempFile(t, content)
+
+	out, code := runCmd(t, "check", "--copyright="+copyright, file)
+	if code != 0 {
+		t.Errorf("anomalies should not fail the check (exit %d):\n%s", code, out)
+	}
+	want := file + ":5: warning: license text outside the header and footer: // Permission is hereby granted"
+	if !strings.Contains(out, want) || !strings.Contains(out, "1 line(s) of license text found outside the header and footer") {
+		t.Errorf("mid-file license text not reported:\n%s", out)
+	}
+}
+
+func TestCheckFlagsExpiredReview(t *testing.T) {
+	file := writeTempFile(t, "package main\n")
+	runCLI(t, "--maintained-by=team-payments (review 2020-01)", file)
+	content := readFile(t, file)
+	if !strings.HasPrefix(content, "// "+copyright+"\n// Maintained-by: team-payments (review 2020-01)\n\npackage main\n") {
+		t.Fatalf("annotation not added to the header: %q", content)
+	}
+
+	out, code := runCmd(t, "check", "--copyright="+copyright, "--maintained-by=team-payments (review 2020-01)", file)
+	if code != 1 || !strings.Contains(out, file+":2: review of the copyright header by team-payments was due 2020-01") {
+		t.Errorf("expired review not flagged (exit %d):\n%s", code, out)
+	}
+
+	runCLI(t, "--maintained-by=team-payments (review 2999-12)", file)
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--maintained-by=team-payments (review 2999-12)", file); code != 0 {
+		t.Errorf("renewed review should pass (exit %d):\n%s", code, out)
+	}
+}
+
+func TestMultipleOutputSinks(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(file, []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	sarif := filepath.Join(dir, "results.sarif")
+	jsonOut := filepath.Join(dir, "results.json")
+
+	out, code := runCmd(t, "check", "--copyright="+copyright, "--output=console", "--output=sarif="+sarif, "--output=json="+jsonOut, file)
+	if code != 1 {
+		t.Fatalf("check should fail (exit %d):\n%s", code, out)
+	}
+	if !strings.Contains(out, file+": missing header, missing footer") {
+		t.Errorf("console output missing:\n%s", out)
+	}
+
+	var log struct {
+		Version string
+		Runs    []struct {
+			Results []struct {
+				RuleID string
+			}
+		}
+	}
+	if err := json.Unmarshal([]byte(readFile(t, sarif)), &log); err != nil {
+		t.Fatalf("invalid SARIF: %v", err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 2 ||
+		log.Runs[0].Results[0].RuleID != "missing-header" || log.Runs[0].Results[1].RuleID != "missing-footer" {
+		t.Errorf("unexpected SARIF results: %+v", log)
+	}
+
+	var report struct {
+		Summary struct{ Outdated int }
+		Files   []struct{ Path, Status string }
+	}
+	if err := json.Unmarshal([]byte(readFile(t, jsonOut)), &report); err != nil {
+		t.Fatalf("invalid JSON output: %v", err)
+	}
+	if report.Summary.Outdated != 1 || len(report.Files) != 1 || report.Files[0].Status != "outdated" {
+		t.Errorf("unexpected JSON output: %+v", report)
+	}
+
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--output=xml=x.xml", file); code == 0 || !strings.Contains(out, `unsupported output format "xml"`) {
+		t.Errorf("unknown output format not rejected (exit %d):\n%s", code, out)
+	}
+}
+
+func TestHTMLHeaderAfterDoctype(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "index.html")
+	if err := os.WriteFile(file, []byte("<!DOCTYPE html>\n<html></html>\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCLI(t, file)
+	want := "<!DOCTYPE html>\n\n<!-- " + copyright + " -->\n\n<html></html>\n\n<!-- " + copyright + " -->\n"
+	if content := readFile(t, file); content != want {
+		t.Errorf("html header not placed after the DOCTYPE:\n%q\nwant:\n%q", content, want)
+	}
+}
+
+func TestVerifyIdempotentRefusesOscillatingConfig(t *testing.T) {
+	file := writeTempFile(t, "package main\n")
+	out, code := runCmd(t, "--copyright="+copyright, "--verify-idempotent", file)
+	if code != exitChanged {
+		t.Fatalf("idempotent run failed (exit %d):\n%s", code, out)
+	}
+
+	// A preamble pattern that also matches the header swallows it on the
+	// next run, so every run would add another header
+	other := writeTempFile(t, "package main\n")
+	out, code = runCmd(t, "--copyright="+copyright, "--preamble=^//", "--verify-idempotent", other)
+	if code != exitError || !strings.Contains(out, "not idempotent") || !strings.Contains(out, "1 file(s) would change again on a second run") {
+		t.Errorf("oscillating config not caught (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, other); content != "package main\n" {
+		t.Errorf("non-idempotent result was written: %q", content)
+	}
+}
+
+func TestFootersTurnedOffPerExtension(t *testing.T) {
+	dir := t.TempDir()
+	tomlFile := filepath.Join(dir, "app.toml")
+	goFile := filepath.Join(dir, "main.go")
+	if err := os.WriteFile(tomlFile, []byte("a = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(goFile, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "copyrighter.yaml")
+	if err := os.WriteFile(config, []byte("footers: {toml: false}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	runCLI(t, "--config="+config, tomlFile, goFile)
+	if content, want := readFile(t, tomlFile), "# "+copyright+"\n\na = 1\n"; content != want {
+		t.Errorf("footer stamped although turned off:\n%q\nwant:\n%q", content, want)
+	}
+	if !strings.HasSuffix(readFile(t, goFile), "// "+copyright+"\n") {
+		t.Error("footer missing from a language it was not turned off for")
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--config="+config, tomlFile); code != 0 {
+		t.Errorf("header-only file reported as outdated (exit %d):\n%s", code, out)
+	}
+
+	if err := os.WriteFile(config, []byte("footers: {.json: true}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runCmd(t, "--copyright="+copyright, "--config="+config, goFile); code == 0 || !strings.Contains(out, `footers: unsupported extension ".json"`) {
+		t.Errorf("footer setting for an unknown extension not rejected (exit %d):\n%s", code, out)
+	}
+}
+
+func TestCacheSkipsUnchangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	cache := filepath.Join(dir, "cache.json")
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	for _, path := range []string{a, b} {
+		if err := os.WriteFile(path, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runCLI(t, "--cache="+cache, a, b)
+	out := runCLI(t, "--cache="+cache, "--verbose", a, b)
+	for _, path := range []string{a, b} {
+		if !strings.Contains(out, "Copyright already up to date in: "+path+" (unchanged since the last run)") {
+			t.Errorf("%s not skipped from the cache:\n%s", path, out)
+		}
+	}
+
+	// A changed file is verified again, and a different notice invalidates
+	// the whole cache
+	if err := os.WriteFile(b, []byte("package x\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--cache="+cache, a, b); code == 0 || !strings.Contains(out, b+": missing header") {
+		t.Errorf("changed file not checked again (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmd(t, "check", "--copyright=Other", "--cache="+cache, a); code == 0 || !strings.Contains(out, a+": outdated header") {
+		t.Errorf("cache trusted after the notice changed (exit %d):\n%s", code, out)
+	}
+}
+
+func TestFrozenTimePinsTheClock(t *testing.T) {
+	file := writeTempFile(t, "package main\n")
+	maintained := "--maintained-by=team-payments (review 2020-01)"
+	runCLI(t, maintained, file)
+
+	if out, code := runCmd(t, "check", "--copyright="+copyright, maintained, "--frozen-time=2019-12-31", file); code != 0 {
+		t.Errorf("review expired before the frozen date (exit %d):\n%s", code, out)
+	}
+
+	// SOURCE_DATE_EPOCH pins the clock too, unless --no-env is given
+	t.Setenv("SOURCE_DATE_EPOCH", "1577836800") // 2020-01-01
+	if out, code := runCmd(t, "check", "--copyright="+copyright, maintained, file); code != 0 {
+		t.Errorf("SOURCE_DATE_EPOCH not honoured (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, maintained, "--no-env", file); code != 1 {
+		t.Errorf("SOURCE_DATE_EPOCH honoured despite --no-env (exit %d):\n%s", code, out)
+	}
+
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--frozen-time=yesterday", file); code == 0 || !strings.Contains(out, `invalid --frozen-time "yesterday"`) {
+		t.Errorf("invalid frozen time not rejected (exit %d):\n%s", code, out)
+	}
+}
+
+func TestJSONOutputStreamsToStdout(t *testing.T) {
+	dir := t.TempDir()
+	old := filepath.Join(dir, "a.go")
+	if err := os.WriteFile(old, []byte("// Copyright 2019 Example Corp.\n\npackage a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	bad := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(bad, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the JSON Lines go to stdout; the console messages go to stderr
+	stdout, err := exec.Command(binPath, "--copyright="+copyright, "--output", "json", old, bad).Output()
+	if _, exited := err.(*exec.ExitError); err != nil && !exited {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(stdout)), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("want one line per file and a summary, got:\n%s", stdout)
+	}
+
+	var file fileOutcome
+	if err := json.Unmarshal([]byte(lines[0]), &file); err != nil {
+		t.Fatalf("invalid JSON line %q: %v", lines[0], err)
+	}
+	if file.Path != old || file.Status != "modified" || file.PreviousHeader != "// Copyright 2019 Example Corp." ||
+		strings.Join(file.Actions, ", ") != "header updated, footer added" {
+		t.Errorf("unexpected outcome: %+v", file)
+	}
+	if err := json.Unmarshal([]byte(lines[1]), &file); err != nil || file.Path != bad || file.Status != "failed" || file.Error == "" {
+		t.Errorf("unexpected outcome for the failed file: %s", lines[1])
+	}
+
+	var last struct {
+		Summary runSummary `json:"summary"`
+	}
+	if err := json.Unmarshal([]byte(lines[2]), &last); err != nil || last.Summary.Modified != 1 || last.Summary.Failed != 1 {
+		t.Errorf("unexpected summary line: %s", lines[2])
+	}
+}
+
+func TestSeverityLeavesWarningsUnfixed(t *testing.T) {
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "stale.go")
+	staleContent := "// Copyright (c) 2019 Example Corp. All rights reserved.\n\npackage a\n\n// Copyright (c) 2019 Example Corp. All rights reserved.\n"
+	if err := os.WriteFile(stale, []byte(staleContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	missing := filepath.Join(dir, "missing.go")
+	if err := os.WriteFile(missing, []byte("package b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "copyrighter.yaml")
+	if err := os.WriteFile(config, []byte("severity: {stale-year: warning}\nfix_severity: error\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out := runCLI(t, "--config="+config, "--verify-idempotent", stale, missing)
+	if !strings.Contains(out, stale+": warning: stale year, below the fix severity, left as is") {
+		t.Errorf("stale year not reported:\n%s", out)
+	}
+	if content := readFile(t, stale); content != staleContent {
+		t.Errorf("file with a stale year was changed: %q", content)
+	}
+	if !strings.HasPrefix(readFile(t, missing), "// "+copyright) {
+		t.Error("missing header not fixed")
+	}
+
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--config="+config, stale, missing); code != 0 || !strings.Contains(out, "2 finding(s) reported as warnings") {
+		t.Errorf("warnings should not fail the check (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, stale); code != 1 || !strings.Contains(out, stale+": outdated header, outdated footer") {
+		t.Errorf("stale year should fail the check by default (exit %d):\n%s", code, out)
+	}
+
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--ignore-year", stale); code != 0 || !strings.Contains(out, "1 up to date") {
+		t.Errorf("--ignore-year should accept a stale year (exit %d):\n%s", code, out)
+	}
+	if err := os.WriteFile(missing, []byte("package b\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--ignore-year", missing); code != 1 {
+		t.Errorf("--ignore-year should still fail a missing header (exit %d):\n%s", code, out)
+	}
+
+	if err := os.WriteFile(config, []byte("severity: {old-year: warning}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--config="+config, stale); code == 0 || !strings.Contains(out, `severity: unknown finding "old-year"`) {
+		t.Errorf("unknown finding not rejected (exit %d):\n%s", code, out)
+	}
+}
+
+func TestLogLevels(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "sub", "a.go")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(file, []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The default level reports changes without per-file chatter
+	out := runCLI(t, dir)
+	if !strings.Contains(out, "Adding copyright header to: "+file) || strings.Contains(out, "Skipping directory") || strings.Contains(out, "Processing file") {
+		t.Errorf("unexpected default output:\n%s", out)
+	}
+	if out := runCLI(t, dir); strings.Contains(out, file) {
+		t.Errorf("up to date file reported at the default level:\n%s", out)
+	}
+	if out := runCLI(t, "--verbose", dir); !strings.Contains(out, "Processing file: "+file) || !strings.Contains(out, "Copyright already up to date in: "+file) {
+		t.Errorf("per-file decisions missing from verbose output:\n%s", out)
+	}
+
+	// Quiet still lists the findings that fail a check, and nothing else
+	out, code := runCmd(t, "check", "--copyright=Other", "--quiet", dir)
+	if code != 1 || strings.TrimSpace(out) != file+": outdated header, outdated footer" {
+		t.Errorf("unexpected quiet output (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "-q", dir); code != 0 || out != "" {
+		t.Errorf("quiet check of a compliant tree printed (exit %d):\n%s", code, out)
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	file := writeTempFile(t, "package main\n")
+	if out, code := runCmd(t, "check", "--copyright="+copyright, file); code != exitChanged {
+		t.Errorf("check of an outdated file exited %d, want %d:\n%s", code, exitChanged, out)
+	}
+	if out, code := runCmd(t, "--copyright="+copyright, file); code != exitChanged {
+		t.Errorf("fixing a file exited %d, want %d:\n%s", code, exitChanged, out)
+	}
+	if out, code := runCmd(t, "--copyright="+copyright, file); code != exitOK {
+		t.Errorf("run on an up to date file exited %d, want %d:\n%s", code, exitOK, out)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, file); code != exitOK {
+		t.Errorf("check of an up to date file exited %d, want %d:\n%s", code, exitOK, out)
+	}
+
+	other := writeTempFile(t, "package main\n")
+	missing := filepath.Join(t.TempDir(), "missing.go")
+	if out, code := runCmd(t, "--copyright="+copyright, other, missing); code != exitError {
+		t.Errorf("run with an unreadable file exited %d, want %d:\n%s", code, exitError, out)
+	}
+	if !strings.HasPrefix(readFile(t, other), "// "+copyright) {
+		t.Error("readable file not fixed next to an unreadable one")
+	}
+	if out, code := runCmd(t, "--copyright="+copyright, "--frozen-time=yesterday", file); code != exitError {
+		t.Errorf("invalid flag value exited %d, want %d:\n%s", code, exitError, out)
+	}
+}
+
+func TestRunSummary(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"missing.go":   "package a\n",
+		"stamped.go":   "// " + copyright + "\n\npackage a\n\n// " + copyright + "\n",
+		"outdated.go":  "// Copyright (c) 2020 Example Corp. All rights reserved.\n\npackage a\n",
+		"generated.go": "// Code generated by tool. DO NOT EDIT.\n\npackage a\n",
+		"notes.txt":    "notes\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := runCLI(t, "--frozen-time=2026-01-01", dir)
+	if !strings.Contains(out, "Summary: 3 scanned, 1 added, 1 updated, 1 already up to date, 2 skipped, 0 errored (0s)") {
+		t.Errorf("unexpected summary:\n%s", out)
+	}
+	if out := runCLI(t, "--quiet", dir); strings.Contains(out, "Summary:") {
+		t.Errorf("summary printed with --quiet:\n%s", out)
+	}
+}
+
+func TestNestedRepositories(t *testing.T) {
+	parent := t.TempDir()
+	nested := filepath.Join(parent, "example")
+	for _, dir := range []string{filepath.Join(parent, ".git"), filepath.Join(nested, ".git")} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	top, inner := filepath.Join(parent, "a.go"), filepath.Join(nested, "b.go")
+	for _, file := range []string{top, inner} {
+		if err := os.WriteFile(file, []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runCLI(t, parent)
+	if !strings.HasPrefix(readFile(t, top), "// "+copyright) {
+		t.Error("file of the parent repository not stamped")
+	}
+	if readFile(t, inner) != "package a\n" {
+		t.Error("file of a nested repository stamped by default")
+	}
+
+	out := runCLI(t, "--nested-repos", parent)
+	if !strings.HasPrefix(readFile(t, inner), "// "+copyright) {
+		t.Error("file of a nested repository not stamped with --nested-repos")
+	}
+	if !strings.Contains(out, "  "+parent+": 1 scanned, 0 modified, 1 up to date") || !strings.Contains(out, "  "+nested+": 1 scanned, 1 modified, 0 up to date") {
+		t.Errorf("summary not grouped by repository:\n%s", out)
+	}
+}
+
+func TestMinTemplateVersion(t *testing.T) {
+	oldText := "Copyright (c) 2025 Example Corp. Old terms."
+	current, previous, unversioned := writeTempFile(t, "package a\n"), writeTempFile(t, "package a\n"), writeTempFile(t, "package a\n")
+	if out, code := runCmd(t, "--copyright="+oldText, "--template-version=2", previous); code != exitChanged {
+		t.Fatalf("stamping failed with exit code %d:\n%s", code, out)
+	}
+	if out, code := runCmd(t, "--copyright="+oldText, unversioned); code != exitChanged {
+		t.Fatalf("stamping failed with exit code %d:\n%s", code, out)
+	}
+	runCLI(t, "--template-version=3", current)
+	if !strings.HasPrefix(readFile(t, current), "// "+copyright+"\n// Template-Version: 3\n\n") {
+		t.Errorf("template version not added to the header: %q", readFile(t, current))
+	}
+
+	out, code := runCmd(t, "check", "--copyright="+copyright, "--template-version=3", "--min-template-version=2", current, previous)
+	if code != 0 || !strings.Contains(out, "Template versions: 3: 1 file(s), 2: 1 file(s)") {
+		t.Errorf("header of an accepted template version failed the check (exit %d):\n%s", code, out)
+	}
+	out, code = runCmd(t, "check", "--copyright="+copyright, "--template-version=3", "--min-template-version=3", previous, unversioned)
+	if code != 1 || !strings.Contains(out, previous+": outdated header") || !strings.Contains(out, unversioned+": outdated header") {
+		t.Errorf("headers below the minimum template version passed the check (exit %d):\n%s", code, out)
+	}
+
+	if out, code := runCmd(t, "check", "--copyright="+copyright, "--min-template-version=v2", current); code == 0 || !strings.Contains(out, `invalid minimum template version "v2"`) {
+		t.Errorf("invalid minimum template version not rejected (exit %d):\n%s", code, out)
+	}
+}
+
+func TestBaselineToleratesRecordedFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"legacy.go", "old.go"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("package a\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, code := runCmdIn(t, dir, "baseline", "--copyright="+copyright, "."); code != exitOK || !strings.Contains(out, "Wrote .copyrighter-baseline with 2 files") {
+		t.Fatalf("baseline exit %d:\n%s", code, out)
+	}
+	if content := readFile(t, filepath.Join(dir, defaultBaselineFile)); !strings.HasSuffix(content, "\nlegacy.go\nold.go\n") {
+		t.Errorf("baseline: %q", content)
+	}
+	out, code := runCmdIn(t, dir, "check", "--copyright="+copyright, ".")
+	if code != exitOK || !strings.Contains(out, "0 outdated, 2 baselined") {
+		t.Errorf("check failed on baselined files (exit %d):\n%s", code, out)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "new.go"), []byte("package a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runCmdIn(t, dir, "--copyright="+copyright, "old.go")
+	out, code = runCmdIn(t, dir, "check", "--copyright="+copyright, ".")
+	if code != exitChanged || !strings.Contains(out, "new.go: missing header") || strings.Contains(out, "legacy.go:") ||
+		!strings.Contains(out, "1 file(s) in .copyrighter-baseline are now up to date") {
+		t.Errorf("check with a new file (exit %d):\n%s", code, out)
+	}
+}
+
+func TestAcceptedCopyright(t *testing.T) {
+	const lastYear = "Copyright (c) 2024 Example Corp. All rights reserved."
+	accepted, other := writeTempFile(t, "package a\n"), writeTempFile(t, "package a\n")
+	runCmd(t, "--copyright="+lastYear, accepted)
+	runCmd(t, "--copyright=Copyright (c) 2023 Example Corp. All rights reserved.", other)
+	stamped := readFile(t, accepted)
+
+	out, code := runCmd(t, "check", "--copyright="+copyright, "--accept-copyright="+lastYear, accepted)
+	if code != exitOK {
+		t.Errorf("accepted notice failed the check (exit %d):\n%s", code, out)
+	}
+	out, code = runCmd(t, "check", "--copyright="+copyright, "--accept-copyright="+lastYear, other)
+	if code != exitChanged || !strings.Contains(out, other+": outdated header") {
+		t.Errorf("other notice passed the check (exit %d):\n%s", code, out)
+	}
+	runCmd(t, "--copyright="+copyright, "--accept-copyright="+lastYear, accepted, other)
+	if content := readFile(t, accepted); content != stamped {
+		t.Errorf("file with an accepted notice rewritten: %q", content)
+	}
+	if content := readFile(t, other); !strings.HasPrefix(content, "// "+copyright+"\n") {
+		t.Errorf("file with another notice not updated: %q", content)
+	}
+}
+
+func TestForeignHeaderIsNotOverwritten(t *testing.T) {
+	upstream := "// Copyright (c) 2018 Upstream Authors\n// Use of this source code is governed by the MIT license.\n\npackage lib\n"
+	apache := "/*\n * Copyright The Kubernetes Authors.\n *\n * Licensed under the Apache License, Version 2.0 (the \"License\");\n * you may not use this file except in compliance with the License.\n */\n\nint x;\n"
+	ours := "// Copyright (c) 2019 Example Corp. All rights reserved.\n\npackage lib\n"
+	trailing := "// " + copyright + "\n\npackage lib\n\n// Copyright (c) 2018 Upstream Authors\n"
+	files := []string{writeTempFile(t, upstream), filepath.Join(t.TempDir(), "x.c"), writeTempFile(t, ours), writeTempFile(t, trailing)}
+	if err := os.WriteFile(files[1], []byte(apache), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, code := runCmd(t, "--copyright="+copyright, files[0], files[1], files[2], files[3])
+	if code != exitChanged || !strings.Contains(out, files[0]+": foreign header (Copyright (c) 2018 Upstream Authors), not overwritten") ||
+		!strings.Contains(out, files[1]+": foreign header (Apache-2.0 license text), not overwritten") ||
+		!strings.Contains(out, files[3]+": foreign header (Copyright (c) 2018 Upstream Authors), not overwritten") {
+		t.Errorf("foreign headers not reported (exit %d):\n%s", code, out)
+	}
+	if !strings.Contains(out, "0 already up to date, 3 foreign") {
+		t.Errorf("foreign files not counted in the summary:\n%s", out)
+	}
+	if readFile(t, files[0]) != upstream || readFile(t, files[1]) != apache || readFile(t, files[3]) != trailing {
+		t.Errorf("foreign headers overwritten: %q, %q, %q", readFile(t, files[0]), readFile(t, files[1]), readFile(t, files[3]))
+	}
+	if content := readFile(t, files[2]); !strings.HasPrefix(content, "// "+copyright+"\n") {
+		t.Errorf("our outdated header not updated: %q", content)
+	}
+
+	runCmd(t, "--copyright="+copyright, "--overwrite-foreign", files[0])
+	if content := readFile(t, files[0]); !strings.HasPrefix(content, "// "+copyright+"\n") {
+		t.Errorf("--overwrite-foreign did not replace the header: %q", content)
+	}
+}
+
+func TestLicenseCheckFlagsIncompatibleHeaders(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"LICENSE":    "MIT License\n\nCopyright (c) 2025 Example Corp.\n\nPermission is hereby granted, free of charge, to any person obtaining a copy\nof this software.\n",
+		"mit.go":     "// Copyright (c) 2025 Example Corp.\n// SPDX-License-Identifier: MIT\n\npackage a\n",
+		"dual.go":    "// SPDX-License-Identifier: (Apache-2.0 OR MIT)\n\npackage a\n",
+		"none.go":    "package a\n",
+		"src/gpl.go": "// Copyright (c) 2025 Someone\n//\n// This program is free software: you can redistribute it and/or modify\n// it under the terms of the GNU General Public License as published by\n// the Free Software Foundation, either version 3 of the License, or\n// (at your option) any later version.\n\npackage src\n",
+		"apache.py":  "# SPDX-License-Identifier: Apache-2.0\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, code := runCmd(t, "license-check", dir)
+	license := filepath.Join(dir, "LICENSE")
+	want := "apache.py: Apache-2.0, not compatible with the MIT license of " + license + "\n" +
+		"src/gpl.go: GPL-3.0-or-later, not compatible with the MIT license of " + license + "\n" +
+		"Not compatible with the MIT license of " + license + ": 2 of 4 files with a license in the header\n"
+	if code != exitChanged || out != want {
+		t.Errorf("license-check exit %d:\n%s\nwant:\n%s", code, out, want)
+	}
+
+	if err := os.WriteFile(license, []byte("Apache License\nVersion 2.0, January 2004\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runCmd(t, "license-check", dir); code != exitChanged || !strings.Contains(out, "src/gpl.go: GPL-3.0-or-later") || strings.Contains(out, "apache.py") {
+		t.Errorf("license-check in an Apache-2.0 project (exit %d):\n%s", code, out)
+	}
+}
+
+// FuzzStampContent feeds arbitrary content, such as invalid UTF-8, stray
+// comment markers and carriage returns, to stampContent and removeContent
+// in several comment syntaxes. Stamping must never fail or panic, a second
+// pass must be a no-op, and removing must leave no notice behind.
+func FuzzStampContent(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"\n\n\n",
+		"package main\n",
+		"#!/bin/sh\necho hi",
+		"// " + copyright + "\n\npackage main\n\n// " + copyright + "\n",
+		"/* unterminated\npackage main\n",
+		"<!-- a --><html>\n",
+		"\xff\xfe\x00invalid\xc3\n",
+		"line\r\nline\r\n",
+		"\ufeffpackage main\n",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, content string) {
+		for _, ext := range []string{".go", ".py", ".c", ".html", ".yaml"} {
+			style, _ := copyrighter.StyleFor(ext)
+			opts := copyrighter.Options{Copyright: copyright, Style: style}
+			stamped, _, err := copyrighter.Stamp(content, opts)
+			if err != nil {
+				t.Fatalf("%s: copyrighter.Stamp(%q) failed: %v", ext, content, err)
+			}
+			if err := verifyIdempotent(stamped, opts); err != nil {
+				t.Fatalf("%s: stamping %q is not idempotent: %v", ext, content, err)
+			}
+			removed, _, err := copyrighter.Remove(stamped, opts)
+			if err != nil {
+				t.Fatalf("%s: copyrighter.Remove(%q) failed: %v", ext, stamped, err)
+			}
+			if _, result, _ := copyrighter.Stamp(removed, opts); !result.Changed() {
+				t.Fatalf("%s: notice left behind after removing it from %q: %q", ext, stamped, removed)
+			}
+		}
+	})
+}
+
+func TestPathologicalInputs(t *testing.T) {
+	// A minified file whose single line is longer than bufio's limit
+	long := writeTempFile(t, "package main\n\nvar x = \""+strings.Repeat("x", 1<<20)+"\"\n")
+	runCLI(t, long)
+	if !strings.HasPrefix(readFile(t, long), "// "+copyright+"\n") {
+		t.Error("header not added to a file with a long line")
+	}
+
+	blank := writeTempFile(t, "package main"+strings.Repeat("\n", 1_000_000))
+	start := time.Now()
+	runCLI(t, blank)
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("stamping a million blank lines took %v", elapsed)
+	}
+
+	big := writeTempFile(t, "package main\n"+strings.Repeat("// padding\n", 200_000))
+	out, code := runCmd(t, "--copyright="+copyright, "--max-file-size=1", big)
+	if code != exitError || !strings.Contains(out, "file too large") {
+		t.Errorf("file over --max-file-size not refused (exit %d):\n%s", code, out)
+	}
+	if strings.HasPrefix(readFile(t, big), "//") {
+		t.Error("file over --max-file-size was modified")
+	}
+
+	// The CLI has no limit unless one is set
+	huge := writeTempFile(t, "package main\n"+strings.Repeat("// padding\n", 65<<20/11))
+	runCLI(t, huge)
+	if !strings.HasPrefix(readFile(t, huge), "// "+copyright+"\n") {
+		t.Error("file over 64 MiB not stamped without --max-file-size")
+	}
+}
+
+func TestCPUProfileAndTrace(t *testing.T) {
+	dir := t.TempDir()
+	file := writeTempFile(t, "package main\n")
+	cpuProfile, tracePath := filepath.Join(dir, "cpu.out"), filepath.Join(dir, "trace.out")
+	runCLI(t, "--cpu-profile="+cpuProfile, "--trace="+tracePath, file)
+	for _, path := range []string{cpuProfile, tracePath} {
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("%s not written: %v", path, err)
+		}
+	}
+
+	if out, code := runCmd(t, "--copyright="+copyright, "--trace="+filepath.Join(dir, "missing", "trace.out"), file); code != exitError {
+		t.Errorf("unwritable trace path not refused (exit %d):\n%s", code, out)
+	}
+}
+
+func TestWritesAreAtomic(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "run.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho hi\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	target := filepath.Join(dir, "target.go")
+	if err := os.WriteFile(target, []byte("package main\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(dir, "link.go")
+	if err := os.Symlink(target, link); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+
+	runCLI(t, dir)
+	if info, err := os.Stat(script); err != nil || info.Mode().Perm() != 0755 {
+		t.Errorf("mode of a rewritten file not kept: %v, %v", info.Mode(), err)
+	}
+	if info, err := os.Lstat(link); err != nil || info.Mode()&os.ModeSymlink == 0 {
+		t.Errorf("symlink replaced by a regular file: %v", err)
+	}
+	if !strings.HasPrefix(readFile(t, target), "// "+copyright) {
+		t.Error("symlink target not stamped")
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 3 {
+		t.Errorf("temporary files left behind: %v", entries)
+	}
+}
+
+func TestExceptionsExpire(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "legacy"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	legacy := filepath.Join(dir, "legacy", "old.go")
+	if err := os.WriteFile(legacy, []byte("package legacy\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(config, []byte("exceptions:\n  - path: \"legacy/**\"\n    reason: \"awaiting relicensing\"\n    owner: team-legal\n    expires: 2026-06\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check := func(now string) (string, int) {
+		return runCmdIn(t, dir, "check", "--copyright="+copyright, "--config="+config, "--frozen-time="+now, "legacy")
+	}
+
+	if out, code := check("2026-06-30"); code != exitOK {
+		t.Errorf("excepted file failed the check (exit %d):\n%s", code, out)
+	}
+	out, code := check("2026-07-01")
+	if code != exitChanged || !strings.Contains(out, "exception for legacy/** owned by team-legal expired 2026-06") || !strings.Contains(out, "legacy/old.go: missing header") {
+		t.Errorf("expired exception did not fail the check (exit %d):\n%s", code, out)
+	}
+
+	if err := os.WriteFile(config, []byte("exceptions:\n  - path: \"legacy/**\"\n    owner: team-legal\n    expires: 2026-06\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := check("2026-01-01"); code != exitError || !strings.Contains(out, "reason is required") {
+		t.Errorf("exception without a reason not refused (exit %d):\n%s", code, out)
+	}
+}
+
+func TestImportFromLicenseEye(t *testing.T) {
+	dir := t.TempDir()
+	rc := "header:\n  license:\n    spdx-id: Apache-2.0\n    copyright-owner: Acme Inc.\n  paths-ignore:\n    - dist\n"
+	if err := os.WriteFile(filepath.Join(dir, ".licenserc.yaml"), []byte(rc), 0644); err != nil {
+		t.Fatal(err)
+	}
+	old := filepath.Join(dir, "old.go")
+	if err := os.WriteFile(old, []byte("// Copyright 2021-2023 Acme Inc.\n// SPDX-License-Identifier: Apache-2.0\n\npackage a\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, code := runCmdIn(t, dir, "import", "--from=license-eye", "--until=2027-01", ".licenserc.yaml"); code != exitOK {
+		t.Fatalf("import failed (exit %d):\n%s", code, out)
+	}
+	config := readFile(t, filepath.Join(dir, defaultConfigFile))
+	for _, want := range []string{"spdx: Apache-2.0", "- dist", "from: license-eye", "until: 2027-01"} {
+		if !strings.Contains(config, want) {
+			t.Errorf("config missing %s:\n%s", want, config)
+		}
+	}
+	if out, code := runCmdIn(t, dir, "import", "--from=license-eye", ".licenserc.yaml"); code != exitError || !strings.Contains(out, "already exists") {
+		t.Errorf("import overwrote the config (exit %d):\n%s", code, out)
+	}
+
+	out, code := runCmdIn(t, dir, "check", "--frozen-time=2027-01-31", "old.go")
+	if code != exitOK || !strings.Contains(out, "1 file(s) accepted with a legacy header") {
+		t.Errorf("legacy header not accepted during the migration (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmdIn(t, dir, "check", "--frozen-time=2027-02-01", "old.go"); code != exitChanged {
+		t.Errorf("legacy header accepted after the migration (exit %d):\n%s", code, out)
+	}
+}
+
+func TestHoldersMapPathsToNotices(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":              "package main\n",
+		"contrib/plugin.go":    "package contrib\n",
+		"third_party/lib/x.go": "package lib\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	config := "copyright: \"" + copyright + "\"\nholders:\n  - path: \"third_party/**\"\n    skip: true\n  - path: \"contrib/**\"\n    copyright: \"Copyright (c) 2025 Contributors to X\"\n    spdx: MIT\n"
+	if err := os.WriteFile(filepath.Join(dir, defaultConfigFile), []byte(config), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, code := runCmdIn(t, dir, "--ext=.go", "."); code != exitChanged {
+		t.Fatalf("fix run failed (exit %d):\n%s", code, out)
+	}
+	if content := readFile(t, filepath.Join(dir, "main.go")); !strings.HasPrefix(content, "// "+copyright+"\n") {
+		t.Errorf("main.go did not get the top-level notice: %q", content)
+	}
+	if content := readFile(t, filepath.Join(dir, "contrib/plugin.go")); !strings.HasPrefix(content, "// Copyright (c) 2025 Contributors to X\n// SPDX-License-Identifier: MIT\n") {
+		t.Errorf("contrib/plugin.go did not get its holder's notice: %q", content)
+	}
+	if content := readFile(t, filepath.Join(dir, "third_party/lib/x.go")); content != files["third_party/lib/x.go"] {
+		t.Errorf("skipped file modified: %q", content)
+	}
+	if out, code := runCmdIn(t, dir, "check", "--ext=.go", "."); code != exitOK {
+		t.Errorf("check after the fix run failed (exit %d):\n%s", code, out)
+	}
+}
+
+func TestPackageNoticeInOneFile(t *testing.T) {
+	dir := t.TempDir()
+	for _, pkg := range []string{"a", "b"} {
+		if err := os.Mkdir(filepath.Join(dir, pkg), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files := map[string]string{
+		"a/doc.go": "// Package a does things.\npackage a\n",
+		"a/x.go":   "package a\n",
+		"a/y.go":   "// " + copyright + "\n\npackage a\n\n// " + copyright + "\n",
+		"b/b.go":   "package b\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, code := runCmdIn(t, dir, "check", "--copyright="+copyright, "--package-notice=doc.go", ".")
+	for _, want := range []string{"a/doc.go: missing header", "a/y.go: duplicate notice", "b: package has no doc.go"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("check output lacks %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "a/x.go") || code != exitChanged {
+		t.Errorf("check flagged a file without a notice or exited %d:\n%s", code, out)
+	}
+
+	runCmdIn(t, dir, "--copyright="+copyright, "--package-notice=doc.go", "a")
+	if got := readFile(t, filepath.Join(dir, "a/doc.go")); !strings.HasPrefix(got, "// "+copyright+"\n") {
+		t.Errorf("doc.go not stamped:\n%s", got)
+	}
+	for _, name := range []string{"a/x.go", "a/y.go"} {
+		if got := readFile(t, filepath.Join(dir, name)); got != "package a\n" {
+			t.Errorf("%s carries a notice:\n%s", name, got)
+		}
+	}
+	if out, code := runCmdIn(t, dir, "check", "--copyright="+copyright, "--package-notice=doc.go", "a"); code != exitOK {
+		t.Errorf("package not consistent after the fix (exit %d):\n%s", code, out)
+	}
+}
+
+func TestUndoRestoresLastRun(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal")
+	a, b := filepath.Join(dir, "a.go"), filepath.Join(dir, "b.sh")
+	for file, content := range map[string]string{a: "package a\n", b: "echo hi\n"} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := runCLI(t, "--journal="+journal, a, b)
+	if !strings.Contains(out, "Undo this run with: copy-righter undo --run=") {
+		t.Errorf("fix run does not name its journal:\n%s", out)
+	}
+	if err := os.WriteFile(b, []byte("echo edited\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, code := runCmd(t, "undo", "--journal="+journal)
+	if code != exitError || !strings.Contains(out, "b.sh: changed since run") {
+		t.Errorf("undo restored an edited file (exit %d):\n%s", code, out)
+	}
+	if got := readFile(t, a); got != "package a\n" {
+		t.Errorf("a.go not restored:\n%s", got)
+	}
+	if got := readFile(t, b); got != "echo edited\n" {
+		t.Errorf("edited b.sh overwritten:\n%s", got)
+	}
+
+	if err := os.WriteFile(b, []byte("echo hi\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if out, code := runCmd(t, "undo", "--journal="+journal); code != exitOK {
+		t.Errorf("undo after restoring by hand failed (exit %d):\n%s", code, out)
+	}
+	if out, code := runCmd(t, "undo", "--journal="+journal); code != exitError || !strings.Contains(out, "no run to undo") {
+		t.Errorf("run undone twice (exit %d):\n%s", code, out)
+	}
+}
+
+func TestLargeFileMiddleCopied(t *testing.T) {
+	dir := t.TempDir()
+	journal := filepath.Join(dir, "journal")
+	// A line longer than a window in the middle, as in minified code
+	code := strings.Repeat("x = 1\n", copyrighter.WindowSize/3) + "data = '" + strings.Repeat("é", copyrighter.WindowSize) + "'\n" + strings.Repeat("y = 2\n", copyrighter.WindowSize/3)
+	data := filepath.Join(dir, "data.py")
+	// A leading comment that runs into the middle is processed whole
+	block := "/*\n" + strings.Repeat(" * x\n", copyrighter.WindowSize/5) + code + " */\nz = 3;\n"
+	long := filepath.Join(dir, "long.c")
+	for file, content := range map[string]string{data: code, long: block} {
+		if err := os.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	runCLI(t, "--journal="+journal, data, long)
+	notice := "# " + copyright
+	if got := readFile(t, data); got != notice+"\n\n"+code+"\n"+notice+"\n" {
+		t.Errorf("data.py stamped as %.80q...", got)
+	}
+	if got := readFile(t, long); strings.Contains(got, copyrighter.MiddleMarker) || !strings.HasSuffix(got, "z = 3;\n\n/* "+copyright+" */\n") {
+		t.Errorf("long.c stamped as %.80q...", got)
+	}
+	if out, code := runCmd(t, "check", "--copyright="+copyright, dir); code != exitOK {
+		t.Errorf("check failed after stamping (exit %d):\n%s", code, out)
+	}
+
+	if out, code := runCmd(t, "undo", "--journal="+journal); code != exitOK {
+		t.Fatalf("undo failed (exit %d):\n%s", code, out)
+	}
+	if readFile(t, data) != code || readFile(t, long) != block {
+		t.Error("undo did not restore the large files")
+	}
+}
+
+func TestSidecarStamps(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "app.json"), []byte("{\"a\": 1}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check := func(notice string) (string, int) {
+		return runCmdIn(t, dir, "check", "--copyright="+notice, "--sidecar-ext=.json", ".")
+	}
+
+	if out, code := check(copyright); code != exitChanged || !strings.Contains(out, "app.json: missing sidecar stamp") {
+		t.Errorf("unstamped file passed the check (exit %d):\n%s", code, out)
+	}
+	runCmdIn(t, dir, "--copyright="+copyright, "--sidecar-ext=.json", ".")
+	if got := readFile(t, filepath.Join(dir, "app.json")); got != "{\"a\": 1}\n" {
+		t.Errorf("sidecar file modified:\n%s", got)
+	}
+	if got := readFile(t, filepath.Join(dir, ".copyrighter-stamps.json")); !strings.Contains(got, `"app.json": "`) {
+		t.Errorf("stamp not recorded:\n%s", got)
+	}
+	if out, code := check(copyright); code != exitOK {
+		t.Errorf("stamped file failed the check (exit %d):\n%s", code, out)
+	}
+	if out, code := check("Copyright (c) 2026 Example Corp."); code != exitChanged || !strings.Contains(out, "app.json: outdated sidecar stamp") {
+		t.Errorf("stamp of an older notice passed the check (exit %d):\n%s", code, out)
+	}
+}
+
+func TestNonUTF8Encodings(t *testing.T) {
+	want := "// " + copyright + "\n\npackage main\n\n// Grüße\n\n// " + copyright + "\n"
+	utf16 := func(s string) string {
+		b := []byte{0xff, 0xfe}
+		for _, r := range s {
+			b = append(b, byte(r), byte(r>>8))
+		}
+		return string(b)
+	}
+	latin1 := func(s string) string {
+		var b []byte
+		for _, r := range s {
+			b = append(b, byte(r))
+		}
+		return string(b)
+	}
+	for name, encode := range map[string]func(string) string{"UTF-16": utf16, "Latin-1": latin1} {
+		file := writeTempFile(t, encode("package main\n\n// Grüße\n"))
+		runCLI(t, file)
+		if got := readFile(t, file); got != encode(want) {
+			t.Errorf("%s: stamped as %q, want %q", name, got, encode(want))
+		}
+	}
+
+	// A notice the file's encoding cannot hold fails the file.
+	file := writeTempFile(t, latin1("package main\n\n// Grüße\n"))
+	out, code := runCmd(t, "--copyright=Copyright (c) 2025 Example Corp. — All rights reserved.", file)
+	if code != exitError || !strings.Contains(out, "cannot be written in Latin-1") {
+		t.Errorf("exit code %d, output:\n%s", code, out)
+	}
+	if got := readFile(t, file); got != latin1("package main\n\n// Grüße\n") {
+		t.Errorf("file written: %q", got)
+	}
+}
+
+func TestFollowSymlinks(t *testing.T) {
+	dir := t.TempDir()
+	pkg := filepath.Join(dir, "pkg")
+	if err := os.Mkdir(pkg, 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(pkg, "a.go")
+	if err := os.WriteFile(file, []byte("package pkg\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// A link to the file, one to its directory and one back to the root.
+	for link, target := range map[string]string{"b.go": file, "alias": pkg, "pkg/loop": dir} {
+		if err := os.Symlink(target, filepath.Join(dir, link)); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	out, code := runCmd(t, "check", "--copyright="+copyright, dir)
+	if code != exitChanged || !strings.Contains(out, "1 scanned") || !strings.Contains(out, "3 skipped") {
+		t.Errorf("symlinks not skipped by default (exit %d):\n%s", code, out)
+	}
+
+	out, code = runCmd(t, "check", "--copyright="+copyright, "--follow-symlinks", "-v", dir)
+	if code != exitChanged || !strings.Contains(out, "1 scanned") || !strings.Contains(out, "already visited") {
+		t.Errorf("file reached through symlinks not visited once (exit %d):\n%s", code, out)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	dir := t.TempDir()
+	for _, rel := range []string{"top.go", "a/mid.go", "a/b/deep.go"} {
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("package x\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for depth, want := range map[string]int{"1": 1, "2": 2, "0": 3} {
+		out, _ := runCmd(t, "check", "--copyright="+copyright, "--max-depth="+depth, dir)
+		if !strings.Contains(out, fmt.Sprintf("Summary: %d scanned", want)) {
+			t.Errorf("--max-depth=%s: want %d files scanned:\n%s", depth, want, out)
+		}
+	}
+}
+
+func TestGitAttributesLinguist(t *testing.T) {
+	dir := initGitRepo(t, map[string]string{
+		".gitattributes":        "*.pb.go linguist-generated\nthird_party/** linguist-vendored=true\n",
+		"api/.gitattributes":    "keep.pb.go -linguist-generated\n",
+		"main.go":               "package main\n",
+		"api/api.pb.go":         "package api\n",
+		"api/keep.pb.go":        "package api\n",
+		"third_party/lib/x.go":  "package lib\n",
+		"third_party/README.go": "package third_party\n",
+	})
+
+	out, _ := runCmdIn(t, dir, "check", "--copyright="+copyright, ".")
+	if !strings.Contains(out, "2 scanned") || strings.Contains(out, "api.pb.go") || strings.Contains(out, "third_party") {
+		t.Errorf("linguist attributes not honoured:\n%s", out)
+	}
+	if !strings.Contains(out, "keep.pb.go") {
+		t.Errorf("attribute unset in a nested .gitattributes not honoured:\n%s", out)
+	}
+
+	// --debug-match agrees with the run on a vendored file
+	out, _ = runCmdIn(t, dir, "--copyright="+copyright, "--debug-match=third_party/lib/x.go")
+	if !strings.Contains(out, "skipped: excluded by linguist-vendored in .gitattributes") {
+		t.Errorf("--debug-match does not report the vendored file excluded:\n%s", out)
+	}
+	out, _ = runCmdIn(t, dir, "--copyright="+copyright, "--verbose", "--dry-run", ".")
+	if !strings.Contains(out, "Skipping excluded path: third_party/lib/x.go") {
+		t.Errorf("run does not skip the vendored file:\n%s", out)
+	}
+
+	// Walking the default excludes keeps the .gitattributes opt-out
+	out, _ = runCmdIn(t, dir, "check", "--copyright="+copyright, "--no-default-excludes", ".")
+	if !strings.Contains(out, "2 scanned") || strings.Contains(out, "third_party") {
+		t.Errorf("--no-default-excludes walks vendored files:\n%s", out)
+	}
+
+	out, _ = runCmdIn(t, dir, "check", "--copyright="+copyright, "--include-vendored", "--include-generated", ".")
+	if !strings.Contains(out, "5 scanned") {
+		t.Errorf("linguist attributes not overridden:\n%s", out)
+	}
+	out, _ = runCmdIn(t, dir, "--copyright="+copyright, "--include-vendored", "--debug-match=third_party/lib/x.go")
+	if !strings.Contains(out, "selected: the file is processed") {
+		t.Errorf("--debug-match ignores --include-vendored:\n%s", out)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// maintainedByPattern matches an ownership annotation such as
+// "Maintained-by: team-payments (review 2026-01)", capturing the owner and
+// the optional review date.
+var maintainedByPattern = regexp.MustCompile(`^Maintained-by:\s*(.*?)\s*(?:\(review\s+([0-9-]+)\))?$`)
+
+// maintainer is the ownership annotation found in a file header.
+type maintainer struct {
+	Line   int    `json:"line"` // 1-based line number in the file
+	Owner  string `json:"owner"`
+	Review string `json:"review,omitempty"`
+}
+
+// parseMaintainedBy parses the text of an annotation, without the tag, and
+// checks that its review date is a valid month or day.
+func parseMaintainedBy(value string) (maintainer, error) {
+	m := maintainedByPattern.FindStringSubmatch(copyrighter.MaintainedTag + " " + strings.TrimSpace(value))
+	if m == nil || m[1] == "" {
+		return maintainer{}, fmt.Errorf("invalid maintained-by annotation %q, want OWNER or OWNER (review YYYY-MM)", value)
+	}
+	if m[2] != "" {
+		if _, err := reviewDue(m[2]); err != nil {
+			return maintainer{}, err
+		}
+	}
+	return maintainer{Owner: m[1], Review: m[2]}, nil
+}
+
+// reviewDue returns when a review date expires: the end of the month for
+// "2026-01", the end of the day for "2026-01-15".
+func reviewDue(review string) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, review); err == nil {
+		return t.AddDate(0, 0, 1), nil
+	}
+	if t, err := time.Parse("2006-01", review); err == nil {
+		return t.AddDate(0, 1, 0), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid review date %q, want YYYY-MM or YYYY-MM-DD", review)
+}
+
+// expired reports whether the review date of m has passed at now. An
+// annotation without a review date, or with an unreadable one, never
+// expires.
+func (m maintainer) expired(now time.Time) bool {
+	if m.Review == "" {
+		return false
+	}
+	due, err := reviewDue(m.Review)
+	return err == nil && !now.Before(due)
+}
+
+// findMaintainer returns the ownership annotation in the header of content,
+// if there is one.
+func findMaintainer(content string, opts copyrighter.Options) (maintainer, bool) {
+	line, m := findAnnotation(content, opts, maintainedByPattern)
+	if m == nil {
+		return maintainer{}, false
+	}
+	return maintainer{Line: line, Owner: m[1], Review: m[2]}, true
+}
+
+// findAnnotation returns the 1-based line number and the submatches of the
+// first header line of content that matches pattern once its comment
+// markers are trimmed, or nil. Annotations below the first line of code
+// are not considered.
+func findAnnotation(content string, opts copyrighter.Options, pattern *regexp.Regexp) (int, []string) {
+	_, body := copyrighter.SplitContent(content, opts)
+	offset := countLines(content) - len(body)
+	// The header is the expected notice, extended over the rest of the
+	// leading comment so annotations are found whether or not the run is
+	// configured to add one.
+	end, _ := noticeBounds(body, opts)
+	for end < len(body) {
+		trimmed := strings.TrimSpace(body[end])
+		if trimmed != "" && !opts.Style.IsCommentLike(trimmed) {
+			break
+		}
+		end++
+	}
+	for i, line := range body[:end] {
+		if m := pattern.FindStringSubmatch(strings.Trim(line, commentMarkers)); m != nil {
+			return offset + i + 1, m
+		}
+	}
+	return 0, nil
+}
+
+// reportExpiredReview flags a checked file whose header review date has
+// passed. It returns the annotation when it has expired.
+func (r *runner) reportExpiredReview(filePath, content string, opts copyrighter.Options) *maintainer {
+	m, ok := findMaintainer(content, opts)
+	if !ok || !m.expired(r.now) {
+		return nil
+	}
+	r.logf(logQuiet, "%s:%d: review of the copyright header by %s was due %s\n", filePath, m.Line, m.Owner, m.Review)
+	r.summary.ExpiredReviews++
+	return &m
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// matchVerdict is the outcome of the file selection rules for one path.
+type matchVerdict int
+
+const (
+	matchSelected matchVerdict = iota
+	matchExcluded
+	matchNotIncluded
+	matchUnsupported
+)
+
+// matchStep records one rule evaluated while selecting a file, for
+// --debug-match.
+type matchStep struct {
+	rule    string
+	matched bool
+}
+
+// selectFile decides whether a file found while walking root is processed.
+// The rules apply in a fixed order of precedence: an exclude pattern,
+// configured or built in, or a linguist-vendored attribute unless vendored
+// files are included, always wins, then the file must match an include
+// pattern if any are configured, then its extension must be enabled. The
+// first rule that rejects the file decides; steps lists every rule
+// evaluated up to that point.
+func (r *runner) selectFile(root, path string) (verdict matchVerdict, steps []matchStep) {
+	for _, p := range r.exclude {
+		matched := path != root && matchesPath(p, root, path)
+		steps = append(steps, matchStep{fmt.Sprintf("exclude %q", p), matched})
+		if matched {
+			return matchExcluded, steps
+		}
+	}
+	for _, p := range r.defaultExcludes {
+		matched := path != root && matchesPath(p, root, path)
+		steps = append(steps, matchStep{fmt.Sprintf("default exclude %q", p), matched})
+		if matched {
+			return matchExcluded, steps
+		}
+	}
+	if !r.includeVendored {
+		matched := r.attributes.set(path, linguistVendored)
+		steps = append(steps, matchStep{linguistVendored + " in .gitattributes", matched})
+		if matched {
+			return matchExcluded, steps
+		}
+	}
+
+	if len(r.include) > 0 {
+		included := false
+		for _, p := range r.include {
+			matched := matchesPath(p, root, path)
+			steps = append(steps, matchStep{fmt.Sprintf("include %q", p), matched})
+			if matched {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return matchNotIncluded, steps
+		}
+	}
+
+	supported := isSupportedFile(path, r.extensions) || r.usesSidecar(path)
+	steps = append(steps, matchStep{fmt.Sprintf("extension %q", strings.ToLower(filepath.Ext(path))), supported})
+	if !supported {
+		return matchUnsupported, steps
+	}
+	return matchSelected, steps
+}
+
+// matchesPath reports whether pattern matches a path found while walking
+// root, relative to root: the directories root itself is in, such as a
+// dist or vendor directory the whole checkout lives under, never match.
+func matchesPath(pattern, root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	if err != nil {
+		// One of them is absolute and the other relative
+		absRoot, errRoot := filepath.Abs(root)
+		absPath, errPath := filepath.Abs(path)
+		if errRoot != nil || errPath != nil {
+			return false
+		}
+		if rel, err = filepath.Rel(absRoot, absPath); err != nil {
+			return false
+		}
+	}
+	return matchGlob(pattern, rel)
+}
+
+// explainMatch implements --debug-match: it prints every rule evaluated for
+// path and the resulting decision.
+func (r *runner) explainMatch(w io.Writer, path string) {
+	verdict, steps := r.selectFile(".", path)
+	fmt.Fprintf(w, "%s:\n", path)
+	width := 0
+	for _, s := range steps {
+		width = max(width, len(s.rule))
+	}
+	for _, s := range steps {
+		result := "no match"
+		if s.matched {
+			result = "match"
+		}
+		fmt.Fprintf(w, "  %-*s %s\n", width, s.rule, result)
+	}
+	switch verdict {
+	case matchSelected:
+		fmt.Fprintln(w, "selected: the file is processed")
+	case matchExcluded:
+		fmt.Fprintf(w, "skipped: excluded by %s (exclude rules take precedence over include rules)\n", steps[len(steps)-1].rule)
+	case matchNotIncluded:
+		fmt.Fprintln(w, "skipped: no include pattern matches")
+	case matchUnsupported:
+		fmt.Fprintln(w, "skipped: extension not enabled")
+	}
+}
+
+// shadowedRules returns warnings about include patterns that can never
+// select a file because an exclude pattern takes precedence over them,
+// and about patterns listed twice.
+func shadowedRules(include, exclude []string) []string {
+	var warnings []string
+	seen := make(map[string]bool)
+	for _, p := range exclude {
+		if seen[p] {
+			warnings = append(warnings, fmt.Sprintf("exclude pattern %q is listed more than once", p))
+		}
+		seen[p] = true
+	}
+
+	for _, inc := range include {
+		prefix := literalPrefix(inc)
+		for _, exc := range exclude {
+			if inc == exc || (prefix != "" && excludesPrefix(exc, prefix)) {
+				warnings = append(warnings, fmt.Sprintf("include pattern %q is shadowed by exclude pattern %q", inc, exc))
+				break
+			}
+		}
+	}
+	return warnings
+}
+
+// literalPrefix returns the leading directories of a glob pattern that
+// contain no wildcards, such as "vendor/keep" for "vendor/keep/*.go".
+func literalPrefix(pattern string) string {
+	segments := strings.Split(strings.TrimPrefix(pattern, "./"), "/")
+	n := 0
+	for n < len(segments)-1 && !strings.ContainsAny(segments[n], "*?[") {
+		n++
+	}
+	return strings.Join(segments[:n], "/")
+}
+
+// excludesPrefix reports whether an exclude pattern prunes the directory
+// prefix or one of its parents, so nothing beneath it is ever visited.
+func excludesPrefix(exclude, prefix string) bool {
+	segments := strings.Split(prefix, "/")
+	for i := 1; i <= len(segments); i++ {
+		if matchGlob(exclude, strings.Join(segments[:i], "/")) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,623 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// The tools import converts the configuration of.
+const (
+	toolAddlicense = "addlicense"
+	toolLicenseEye = "license-eye"
+	toolHawkeye    = "hawkeye"
+)
+
+// migrationMonths is how long check accepts the headers of the tool a
+// config was imported from, unless import is given --until.
+const migrationMonths = 3
+
+// legacyHeader is the header format of the tool a config was imported
+// from. Until it expires, check accepts a file whose leading comment
+// matches it as compliant, so a team can switch tools first and rewrite
+// the headers at its own pace.
+type legacyHeader struct {
+	From string `yaml:"from"`
+	// Pattern is a regular expression matched against the text of the
+	// leading comment block of a file.
+	Pattern string `yaml:"pattern"`
+	// Until is a month (YYYY-MM, which ends with that month) or a day
+	// (YYYY-MM-DD), like the expiry of an exception.
+	Until string `yaml:"until"`
+
+	re *regexp.Regexp
+}
+
+// compileLegacyHeaders checks and compiles the legacy_headers setting.
+func compileLegacyHeaders(headers []legacyHeader) ([]legacyHeader, error) {
+	compiled := make([]legacyHeader, len(headers))
+	for i, h := range headers {
+		switch {
+		case h.Pattern == "":
+			return nil, fmt.Errorf("legacy_headers[%d]: pattern is required", i)
+		case h.Until == "":
+			return nil, fmt.Errorf("legacy_headers[%d]: until is required", i)
+		}
+		if _, err := reviewDue(h.Until); err != nil {
+			return nil, fmt.Errorf("legacy_headers[%d]: %w", i, err)
+		}
+		re, err := regexp.Compile(h.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("legacy_headers[%d]: invalid pattern: %w", i, err)
+		}
+		h.re = re
+		compiled[i] = h
+	}
+	return compiled, nil
+}
+
+// expired reports whether the migration window of h has closed at now.
+func (h legacyHeader) expired(now time.Time) bool {
+	due, err := reviewDue(h.Until)
+	return err != nil || !now.Before(due)
+}
+
+// acceptLegacyHeader implements legacy_headers: a file checked, when
+// check is set, that is not up to date but starts with a header in the
+// format of the tool the config was imported from passes while the
+// migration window is open.
+// It returns the options that leave its header and footer in place, and
+// the legacy format that matched, nil if none did.
+func (r *runner) acceptLegacyHeader(content string, opts copyrighter.Options, result copyrighter.Result, check bool) (copyrighter.Options, *legacyHeader) {
+	if !check || !result.Changed() || len(r.legacyHeaders) == 0 {
+		return opts, nil
+	}
+	_, lines := copyrighter.SplitContent(content, opts)
+	block := copyrighter.NormalizeNotice(strings.Join(lines[:opts.Style.LeadingComment(lines, 0)], "\n"))
+	if block == "" {
+		return opts, nil
+	}
+	for i, h := range r.legacyHeaders {
+		if h.expired(r.now) || !h.re.MatchString(block) {
+			continue
+		}
+		opts.LeaveHeader = true
+		opts.LeaveFooter = opts.LeaveFooter || result.Footer != copyrighter.UpToDate
+		return opts, &r.legacyHeaders[i]
+	}
+	return opts, nil
+}
+
+// yearPlaceholder stands for the year in an imported header template until
+// it is replaced by the year to stamp, or by a pattern accepting any year.
+const yearPlaceholder = "\x00year\x00"
+
+// importedHeader is what import reads from another tool's configuration.
+type importedHeader struct {
+	// text is the header, with yearPlaceholder where the tool puts the
+	// year, and year the year to stamp in its place.
+	text string
+	year string
+	// pattern, when the tool declares one, matches its existing headers
+	// instead of a pattern derived from text.
+	pattern string
+	spdx    string
+	include []string
+	exclude []string
+}
+
+// importedConfig is the .copyrighter.yaml written by import.
+type importedConfig struct {
+	Copyright     string         `yaml:"copyright"`
+	SPDX          string         `yaml:"spdx,omitempty"`
+	Include       []string       `yaml:"include,omitempty"`
+	Exclude       []string       `yaml:"exclude,omitempty"`
+	LegacyHeaders []legacyHeader `yaml:"legacy_headers"`
+}
+
+// runImport implements the import subcommand: it converts the
+// configuration of another header tool into a config of ours that stamps
+// the same notice, and accepts the headers that tool wrote until the
+// migration window closes.
+func runImport(cmd *cobra.Command, args []string) {
+	from, _ := cmd.Flags().GetString("from")
+	until, _ := cmd.Flags().GetString("until")
+	output, _ := cmd.Flags().GetString("output")
+	now := time.Now()
+	if until == "" {
+		until = now.AddDate(0, migrationMonths, 0).Format("2006-01")
+	}
+	if err := importConfig(from, args[0], output, until, strconv.Itoa(now.Year())); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	fmt.Printf("Wrote %s from the %s config %s\n", output, from, args[0])
+	fmt.Printf("check accepts headers written by %s until %s; run copy-righter to replace them before then\n", from, until)
+}
+
+// importConfig converts the config of tool at source into a config of ours
+// written to output, stamping year where the tool puts the current year.
+func importConfig(tool, source, output, until, year string) error {
+	if _, err := reviewDue(until); err != nil {
+		return err
+	}
+	if _, err := os.Stat(output); err == nil {
+		return fmt.Errorf("%s already exists; import writes a new config", output)
+	}
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return err
+	}
+	var h importedHeader
+	switch tool {
+	case toolAddlicense:
+		h, err = importAddlicense(string(data), filepath.Dir(source), year)
+	case toolLicenseEye:
+		h, err = importLicenseEye(data, year)
+	case toolHawkeye:
+		h, err = importHawkeye(string(data), filepath.Dir(source), year)
+	default:
+		return fmt.Errorf("invalid --from %q, want %s, %s or %s", tool, toolAddlicense, toolLicenseEye, toolHawkeye)
+	}
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", source, err)
+	}
+
+	pattern := h.pattern
+	if pattern == "" {
+		pattern = legacyPattern(h.text)
+	}
+	legacy := []legacyHeader{{From: tool, Pattern: pattern, Until: until}}
+	if _, err := compileLegacyHeaders(legacy); err != nil {
+		return err
+	}
+	cfg := importedConfig{
+		Copyright:     strings.TrimSpace(strings.ReplaceAll(h.text, yearPlaceholder, h.year)),
+		SPDX:          h.spdx,
+		Include:       h.include,
+		Exclude:       h.exclude,
+		LegacyHeaders: legacy,
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# Imported from the %s config %s by copy-righter import.\n", tool, source)
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+	if err := enc.Encode(cfg); err != nil {
+		return err
+	}
+	return os.WriteFile(output, b.Bytes(), 0644)
+}
+
+// legacyPattern returns a regular expression matching text as a comment:
+// its words in order, separated by any run of whitespace and comment
+// markers, with any year or range of years where text has
+// yearPlaceholder.
+func legacyPattern(text string) string {
+	words := strings.Fields(copyrighter.NormalizeNotice(text))
+	for i, w := range words {
+		parts := strings.Split(w, yearPlaceholder)
+		for j, part := range parts {
+			parts[j] = regexp.QuoteMeta(part)
+		}
+		words[i] = strings.Join(parts, "(?:"+copyrighter.YearRangeSource+")")
+	}
+	return strings.Join(words, `\W+`)
+}
+
+// addlicenseTemplates are the header templates addlicense ships, by SPDX
+// identifier, and addlicenseTypes the legacy names its -l flag accepts.
+var (
+	addlicenseTemplates = map[string]string{
+		"Apache-2.0": `Copyright{{ if .Year }} {{.Year}}{{ end }} {{.Holder}}
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.`,
+		"BSD-3-Clause": `Copyright (c){{ if .Year }} {{.Year}}{{ end }} {{.Holder}} All rights reserved.
+Use of this source code is governed by a BSD-style
+license that can be found in the LICENSE file.`,
+		"MIT": `Copyright (c){{ if .Year }} {{.Year}}{{ end }} {{.Holder}}
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of
+this software and associated documentation files (the "Software"), to deal in
+the Software without restriction, including without limitation the rights to
+use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of
+the Software, and to permit persons to whom the Software is furnished to do so,
+subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all
+copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS
+FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR
+COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER
+IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN
+CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.`,
+		"MPL-2.0": `This Source Code Form is subject to the terms of the Mozilla Public
+License, v. 2.0. If a copy of the MPL was not distributed with this
+file, You can obtain one at https://mozilla.org/MPL/2.0/.`,
+	}
+	addlicenseTypes = map[string]string{"apache": "Apache-2.0", "bsd": "BSD-3-Clause", "mit": "MIT", "mpl": "MPL-2.0"}
+)
+
+// flagList collects the values of a repeated flag.
+type flagList []string
+
+func (l *flagList) String() string     { return strings.Join(*l, ",") }
+func (l *flagList) Set(v string) error { *l = append(*l, v); return nil }
+
+// spdxMode is the value of addlicense's -s flag, which may be given
+// without a value or as -s=only.
+type spdxMode string
+
+func (m *spdxMode) String() string     { return string(*m) }
+func (m *spdxMode) Set(v string) error { *m = spdxMode(v); return nil }
+func (m *spdxMode) IsBoolFlag() bool   { return true }
+
+// importAddlicense reads the addlicense invocation in script, such as a
+// Makefile, a CI step or a file holding just its arguments. addlicense
+// has no config file; its flags are its configuration.
+func importAddlicense(script, dir, year string) (importedHeader, error) {
+	args, err := addlicenseArgs(script)
+	if err != nil {
+		return importedHeader{}, err
+	}
+	fs := flag.NewFlagSet(toolAddlicense, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	holder := fs.String("c", "Google LLC", "")
+	license := fs.String("l", "apache", "")
+	licenseFile := fs.String("f", "", "")
+	stampYear := fs.String("y", year, "")
+	var spdx spdxMode
+	fs.Var(&spdx, "s", "")
+	var ignore flagList
+	fs.Var(&ignore, "ignore", "")
+	fs.Bool("check", false, "")
+	fs.Bool("v", false, "")
+	fs.String("skip", "", "")
+	if err := fs.Parse(args); err != nil {
+		return importedHeader{}, fmt.Errorf("addlicense flags: %w", err)
+	}
+
+	h := importedHeader{year: *stampYear, exclude: ignore}
+	for _, arg := range fs.Args() {
+		if arg == "." || arg == "./..." {
+			continue
+		}
+		if info, err := os.Stat(arg); err == nil && info.IsDir() {
+			arg = strings.TrimSuffix(arg, "/") + "/**"
+		}
+		h.include = append(h.include, arg)
+	}
+
+	spdxID := *license
+	if id, ok := addlicenseTypes[spdxID]; ok {
+		spdxID = id
+	}
+	var tmpl string
+	switch {
+	case *licenseFile != "":
+		path := *licenseFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return importedHeader{}, err
+		}
+		tmpl = string(data)
+	case spdx == "only":
+		tmpl = "SPDX-License-Identifier: {{.SPDXID}}"
+	case spdx != "":
+		// The SPDX line is ours to add
+		tmpl = "Copyright {{.Year}} {{.Holder}}"
+		h.spdx = spdxID
+	default:
+		var ok bool
+		if tmpl, ok = addlicenseTemplates[spdxID]; !ok {
+			return importedHeader{}, fmt.Errorf("addlicense has no template for license %q; give it with -f or use -s", *license)
+		}
+	}
+	t, err := template.New(toolAddlicense).Parse(tmpl)
+	if err != nil {
+		return importedHeader{}, err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, struct{ Year, Holder, SPDXID string }{yearPlaceholder, *holder, spdxID}); err != nil {
+		return importedHeader{}, err
+	}
+	h.text = b.String()
+	return h, nil
+}
+
+// addlicenseArgs returns the arguments of the first addlicense command in
+// script, or every word of script if it is nothing but flags.
+func addlicenseArgs(script string) ([]string, error) {
+	commands := shellCommands(script)
+	for _, words := range commands {
+		for i, w := range words {
+			name, _, _ := strings.Cut(w, "@")
+			if path.Base(name) == toolAddlicense {
+				return words[i+1:], nil
+			}
+		}
+	}
+	if len(commands) == 1 && len(commands[0]) > 0 && strings.HasPrefix(commands[0][0], "-") {
+		return commands[0], nil
+	}
+	return nil, errors.New("no addlicense command found")
+}
+
+// shellCommands splits script into the words of its commands the way a
+// shell would, closely enough to find a command line in a Makefile or a
+// CI step: quotes group words, a backslash continues a line, "#" starts a
+// comment, and line breaks, ";", "&&", "||" and "|" end a command.
+func shellCommands(script string) [][]string {
+	var commands [][]string
+	var words []string
+	var word strings.Builder
+	inWord := false
+	endWord := func() {
+		if inWord {
+			words = append(words, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+	endCommand := func() {
+		endWord()
+		if len(words) > 0 {
+			commands = append(commands, words)
+			words = nil
+		}
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case c == '\\' && i+1 < len(script):
+			i++
+			if script[i] != '\n' {
+				word.WriteByte(script[i])
+				inWord = true
+			}
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(script[i+1:], c)
+			if end < 0 {
+				end = len(script) - i - 1
+			}
+			word.WriteString(script[i+1 : i+1+end])
+			inWord = true
+			i += end + 1
+		case c == '#' && !inWord:
+			for i+1 < len(script) && script[i+1] != '\n' {
+				i++
+			}
+		case c == '\n' || c == ';' || c == '|' || c == '&':
+			endCommand()
+		case c == ' ' || c == '\t' || c == '\r':
+			endWord()
+		default:
+			word.WriteByte(c)
+			inWord = true
+		}
+	}
+	endCommand()
+	return commands
+}
+
+// licenseEyeHeader is a header section of a license-eye .licenserc.yaml.
+type licenseEyeHeader struct {
+	License struct {
+		SPDXID   string `yaml:"spdx-id"`
+		Owner    string `yaml:"copyright-owner"`
+		Year     string `yaml:"copyright-year"`
+		Software string `yaml:"software-name"`
+		Content  string `yaml:"content"`
+		Pattern  string `yaml:"pattern"`
+	} `yaml:"license"`
+	Paths       []string `yaml:"paths"`
+	PathsIgnore []string `yaml:"paths-ignore"`
+}
+
+// importLicenseEye reads a license-eye .licenserc.yaml. Its header
+// section may be a list; only the first entry is imported.
+func importLicenseEye(data []byte, year string) (importedHeader, error) {
+	var doc struct {
+		Header yaml.Node `yaml:"header"`
+	}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return importedHeader{}, err
+	}
+	var headers []licenseEyeHeader
+	switch doc.Header.Kind {
+	case yaml.SequenceNode:
+		if err := doc.Header.Decode(&headers); err != nil {
+			return importedHeader{}, err
+		}
+	case yaml.MappingNode:
+		var h licenseEyeHeader
+		if err := doc.Header.Decode(&h); err != nil {
+			return importedHeader{}, err
+		}
+		headers = append(headers, h)
+	}
+	if len(headers) == 0 {
+		return importedHeader{}, errors.New("no header section")
+	}
+	if len(headers) > 1 {
+		fmt.Fprintf(os.Stderr, "Warning: importing the first of %d header sections; add the others as profiles\n", len(headers))
+	}
+	eye := headers[0]
+	lic := eye.License
+	h := importedHeader{year: year, pattern: lic.Pattern, exclude: eye.PathsIgnore}
+	if lic.Year != "" {
+		h.year = lic.Year
+	}
+	for _, p := range eye.Paths {
+		if p != "**" && p != "**/*" {
+			h.include = append(h.include, p)
+		}
+	}
+	switch {
+	case lic.Content != "":
+		h.text = strings.NewReplacer("[year]", yearPlaceholder, "[owner]", lic.Owner, "[software-name]", lic.Software).Replace(lic.Content)
+	case lic.Owner != "":
+		h.text = "Copyright " + yearPlaceholder + " " + lic.Owner
+		h.spdx = lic.SPDXID
+	default:
+		return importedHeader{}, errors.New("the license has neither content nor a copyright-owner")
+	}
+	return h, nil
+}
+
+var (
+	// hawkeyeProperty is a property in a hawkeye header template, in the
+	// current {{ props["name"] }} syntax or the earlier ${name}.
+	hawkeyeProperty = regexp.MustCompile(`\{\{-?\s*props\[\s*["'](\w+)["']\s*\]\s*-?\}\}|\$\{(\w+)\}`)
+	// hawkeyeYearAttr is one of the file attributes hawkeye offers for the
+	// year, such as {{ attrs.git_file_created_year }}.
+	hawkeyeYearAttr = regexp.MustCompile(`\{\{-?\s*attrs\.\w*year\w*[^}]*\}\}`)
+	hawkeyeTag      = regexp.MustCompile(`\{%.*?%\}`)
+)
+
+// importHawkeye reads a hawkeye licenserc.toml. A headerPath naming one of
+// hawkeye's bundled licenses, such as Apache-2.0.txt, becomes a copyright
+// line with the license as SPDX identifier.
+func importHawkeye(data, dir, year string) (importedHeader, error) {
+	cfg, err := parseTOML(data)
+	if err != nil {
+		return importedHeader{}, err
+	}
+	props := make(map[string]string)
+	if table, ok := cfg["properties"].(map[string]any); ok {
+		for k, v := range table {
+			props[k] = fmt.Sprint(v)
+		}
+	}
+	h := importedHeader{year: year, include: tomlStrings(cfg["includes"]), exclude: tomlStrings(cfg["excludes"])}
+	if inception := props["inceptionYear"]; inception != "" {
+		h.year = inception
+	}
+	for i, p := range h.include {
+		if p == "**" || p == "**/*" {
+			h.include = append(h.include[:i:i], h.include[i+1:]...)
+			break
+		}
+	}
+
+	text, _ := cfg["inlineHeader"].(string)
+	if headerPath, _ := cfg["headerPath"].(string); text == "" && headerPath != "" {
+		p := headerPath
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(dir, p)
+		}
+		content, err := os.ReadFile(p)
+		switch {
+		case err == nil:
+			text = string(content)
+		case errors.Is(err, os.ErrNotExist) && filepath.Base(headerPath) == headerPath:
+			// One of the licenses bundled with hawkeye
+			owner := props["copyrightOwner"]
+			if owner == "" {
+				return importedHeader{}, fmt.Errorf("headerPath %s needs the copyrightOwner property", headerPath)
+			}
+			h.text = "Copyright " + yearPlaceholder + " " + owner
+			h.spdx = strings.TrimSuffix(strings.TrimSuffix(headerPath, ".txt"), "-ASF")
+			return h, nil
+		default:
+			return importedHeader{}, err
+		}
+	}
+	if text == "" {
+		return importedHeader{}, errors.New("neither inlineHeader nor headerPath is set")
+	}
+
+	text = hawkeyeTag.ReplaceAllString(hawkeyeYearAttr.ReplaceAllString(text, yearPlaceholder), "")
+	var missing error
+	text = hawkeyeProperty.ReplaceAllStringFunc(text, func(m string) string {
+		sub := hawkeyeProperty.FindStringSubmatch(m)
+		name := sub[1] + sub[2]
+		if strings.Contains(strings.ToLower(name), "year") {
+			return yearPlaceholder
+		}
+		v, ok := props[name]
+		if !ok {
+			missing = fmt.Errorf("the header uses the property %s, which is not set", name)
+		}
+		return v
+	})
+	if missing != nil {
+		return importedHeader{}, missing
+	}
+	if strings.Contains(text, "{{") {
+		return importedHeader{}, errors.New("the header template uses attributes import cannot convert")
+	}
+	h.text = text
+	return h, nil
+}
+
+// tomlStrings returns the strings of a TOML array.
+func tomlStrings(v any) []string {
+	items, _ := v.([]any)
+	var s []string
+	for _, item := range items {
+		if str, ok := item.(string); ok {
+			s = append(s, str)
+		}
+	}
+	return s
+}
+
+// acceptedNotices returns the accepted_copyright notices as text.
+func acceptedNotices(notices []copyrightNotice) []string {
+	texts := make([]string, 0, len(notices))
+	for _, n := range notices {
+		if text := strings.TrimSpace(string(n)); text != "" {
+			texts = append(texts, text)
+		}
+	}
+	return texts
+}
+
+// acceptAlternateNotice implements accepted_copyright: a file whose header
+// and footer are exactly those of one of the other approved notices is up
+// to date, in check and fix runs alike, so neither fails nor rewrites it.
+// It returns the options of the notice the file carries, and false when
+// it carries none of them.
+func (r *runner) acceptAlternateNotice(content string, opts copyrighter.Options, result copyrighter.Result) (copyrighter.Options, bool) {
+	if r.remove || r.audit != nil || !result.Changed() {
+		return opts, false
+	}
+	for _, text := range r.acceptedCopyright {
+		alt := opts
+		alt.Copyright = text
+		if _, altResult, err := copyrighter.Stamp(content, alt); err == nil && !altResult.Changed() {
+			return alt, true
+		}
+	}
+	return opts, false
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestParseTOML(t *testing.T) {
+	cfg, err := parseTOML(`# hawkeye
+headerPath = 'Apache-2.0.txt'
+inlineHeader = """
+Copyright \
+  ${owner}"""
+includes = [
+  "src/**", # sources
+  "*.rs",
+]
+useDefaultExcludes = true
+
+[properties]
+inceptionYear = 2_022
+"copyright owner" = "Acme"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"headerPath":         "Apache-2.0.txt",
+		"inlineHeader":       "Copyright ${owner}",
+		"includes":           []any{"src/**", "*.rs"},
+		"useDefaultExcludes": true,
+		"properties":         map[string]any{"inceptionYear": int64(2022), "copyright owner": "Acme"},
+	}
+	if !reflect.DeepEqual(cfg, want) {
+		t.Errorf("parseTOML = %#v, want %#v", cfg, want)
+	}
+	if _, err := parseTOML("key = \"open\n"); err == nil || err.Error() != "line 1: unterminated string" {
+		t.Errorf("unterminated string: err = %v", err)
+	}
+}
+
+func TestImportAddlicense(t *testing.T) {
+	script := "license:\n\tgo run github.com/google/addlicense@v1.1.1 -s -c \"Acme Inc.\" \\\n\t  -l mit -ignore 'vendor/**' src && echo done\n"
+	h, err := importAddlicense(script, ".", "2026")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.text != "Copyright "+yearPlaceholder+" Acme Inc." || h.spdx != "MIT" || !reflect.DeepEqual(h.exclude, []string{"vendor/**"}) || !reflect.DeepEqual(h.include, []string{"src"}) {
+		t.Errorf("importAddlicense = %+v", h)
+	}
+	re := regexp.MustCompile(legacyPattern(h.text))
+	for header, want := range map[string]bool{
+		"Copyright 2019-2024 Acme Inc.": true,
+		"Copyright 2024 Acme Inc":       false,
+		"Copyright Acme Inc.":           false,
+	} {
+		if re.MatchString(header) != want {
+			t.Errorf("legacy pattern matching %q = %v, want %v", header, !want, want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// The lines that delimit the section notice maintains in a NOTICE file.
+// Text outside them, such as the product name and its attribution, is
+// kept as written.
+const (
+	noticeBegin = "=== Copyright notices collected by copy-righter notice; do not edit by hand ==="
+	noticeEnd   = "=== End of copyright notices ==="
+)
+
+// defaultNoticeFile is the file notice writes when --output is not given.
+const defaultNoticeFile = "NOTICE"
+
+// noticeHeaderLines is how far into a file notice looks for the copyright
+// statements of its header.
+const noticeHeaderLines = 20
+
+// runNotice implements the notice subcommand: it collects the distinct
+// copyright statements of the headers under the given directories into the
+// NOTICE file Apache-2.0 distributions ship.
+func runNotice(cmd *cobra.Command, args []string) {
+	output, _ := cmd.Flags().GetString("output")
+	check, _ := cmd.Flags().GetBool("check")
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+
+	statements, err := headerStatements(args)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	existing, err := os.ReadFile(output)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	content, err := updateNotice(string(existing), statements)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s: %v\n", output, err)
+		os.Exit(exitError)
+	}
+
+	switch {
+	case content == string(existing):
+		fmt.Printf("%s is up to date (%d copyright %s)\n", output, len(statements), plural(len(statements), "statement", "statements"))
+	case check:
+		fmt.Printf("%s is out of date; run copy-righter notice to update it\n", output)
+		os.Exit(exitChanged)
+	default:
+		if err := writeFileAtomic(output, []byte(content)); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		fmt.Printf("Wrote %s with %d copyright %s\n", output, len(statements), plural(len(statements), "statement", "statements"))
+	}
+}
+
+// headerStatements returns the distinct copyright statements in the
+// headers of the supported source files under roots, sorted. Generated
+// files and the default excludes are skipped.
+func headerStatements(roots []string) ([]string, error) {
+	seen := make(map[string]bool)
+	for _, root := range roots {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() {
+				if path != root && slices.Contains(defaultExcludes, d.Name()) {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if _, ok := copyrighter.StyleFor(path); !ok || !d.Type().IsRegular() {
+				return nil
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			content := string(data)
+			if copyrighter.IsGenerated(content, nil) {
+				return nil
+			}
+			for _, statement := range copyrightStatements([]byte(headLines(content, noticeHeaderLines))) {
+				seen[statement] = true
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	statements := make([]string, 0, len(seen))
+	for statement := range seen {
+		statements = append(statements, statement)
+	}
+	sort.Strings(statements)
+	return statements, nil
+}
+
+// updateNotice returns the NOTICE file content with its section replaced
+// by statements. A file without a section gets it appended.
+func updateNotice(content string, statements []string) (string, error) {
+	var b strings.Builder
+	b.WriteString(noticeBegin + "\n")
+	for _, statement := range statements {
+		b.WriteString(statement + "\n")
+	}
+	b.WriteString(noticeEnd + "\n")
+	section := b.String()
+
+	if begin := strings.Index(content, noticeBegin+"\n"); begin >= 0 {
+		end := strings.Index(content[begin:], noticeEnd+"\n")
+		if end < 0 {
+			return "", fmt.Errorf("copyright notices section without its end line %q", noticeEnd)
+		}
+		return content[:begin] + section + content[begin+end+len(noticeEnd)+1:], nil
+	}
+	switch {
+	case content == "":
+		return section, nil
+	case strings.HasSuffix(content, "\n\n"):
+		return content + section, nil
+	case strings.HasSuffix(content, "\n"):
+		return content + "\n" + section, nil
+	default:
+		return content + "\n\n" + section, nil
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"time"
+)
+
+// notifySettings configures the hooks run when a run finishes.
+type notifySettings struct {
+	// Command is run with "sh -c" and receives the JSON summary on stdin.
+	Command string `yaml:"command"`
+	// Webhook receives the JSON summary as a POST request body.
+	Webhook string `yaml:"webhook"`
+}
+
+// notifyTimeout bounds how long a webhook may delay the end of a run.
+const notifyTimeout = 10 * time.Second
+
+// finish lists the failures of the run, completes the run summary, writes the --output sinks, records it
+// in the history file and delivers it to the configured notification
+// hooks. Output, history and hook failures are reported but never change the
+// outcome of the run.
+func (r *runner) finish() {
+	r.stopProfiling()
+	if err := r.err(); err != nil {
+		printFailures(os.Stderr, err)
+	}
+	r.summary.DryRun = r.dryRun
+	r.summary.Repositories = r.repos.grouped()
+	r.summary.complete()
+	r.logf(logNormal, "%s\n", r.summary.console())
+	if err := r.outputs.write(r.summary); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
+	}
+	if err := r.saveSidecar(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing sidecar: %v\n", err)
+	}
+	if err := r.journal.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing run journal: %v\n", err)
+	}
+	if r.journal.written() && !r.dryRun {
+		r.logf(logNormal, "Undo this run with: copy-righter undo --run=%s\n", r.journal.id)
+	}
+	if err := r.cache.save(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing cache: %v\n", err)
+	}
+	if r.history != "" {
+		if err := r.appendHistory(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error recording run history: %v\n", err)
+		}
+	}
+	if r.notify.Command == "" && r.notify.Webhook == "" {
+		return
+	}
+
+	payload, err := json.Marshal(r.summary)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error encoding run summary: %v\n", err)
+		return
+	}
+	if r.notify.Command != "" {
+		if err := notifyCommand(r.notify.Command, payload, r.out); err != nil {
+			fmt.Fprintf(os.Stderr, "Error running notify command: %v\n", err)
+		}
+	}
+	if r.notify.Webhook != "" {
+		if err := notifyWebhook(r.notify.Webhook, payload); err != nil {
+			fmt.Fprintf(os.Stderr, "Error posting to notify webhook: %v\n", err)
+		}
+	}
+}
+
+func notifyCommand(command string, payload []byte, stdout io.Writer) error {
+	cmd := exec.Command("sh", "-c", command)
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	return cmd.Run()
+}
+
+func notifyWebhook(url string, payload []byte) error {
+	client := &http.Client{Timeout: notifyTimeout}
+	resp, err := client.Post(url, "application/json", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
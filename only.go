@@ -0,0 +1,21 @@
+package main
+
+import "fmt"
+
+// The values of the only setting: which part of the notice a run adds,
+// updates and checks. The other part is left as it is, so footers can be
+// retrofitted into a codebase whose headers are managed elsewhere.
+const (
+	onlyBoth   = "both"
+	onlyHeader = "header"
+	onlyFooter = "footer"
+)
+
+// checkOnly refuses an unknown only setting.
+func checkOnly(only string) error {
+	switch only {
+	case "", onlyBoth, onlyHeader, onlyFooter:
+		return nil
+	}
+	return fmt.Errorf("invalid --only %q, want %s, %s or %s", only, onlyHeader, onlyFooter, onlyBoth)
+}
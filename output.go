@@ -0,0 +1,290 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// outputFormats are the machine-readable sinks --output can write. The
+// console output is always printed in addition to them.
+var outputFormats = map[string]func(*outputSet, runSummary) ([]byte, error){
+	"json":  (*outputSet).renderJSON,
+	"sarif": (*outputSet).renderSARIF,
+}
+
+// outputSink is one --output destination.
+type outputSink struct {
+	format string
+	path   string
+}
+
+// parseOutputs parses --output values of the form FORMAT=PATH. "console"
+// names the human-readable output, which is always printed, and is
+// accepted so CI configs can list every sink explicitly. A bare "json"
+// streams JSON Lines to stdout instead, reported by stream.
+func parseOutputs(values []string) (sinks []outputSink, stream bool, err error) {
+	for _, v := range values {
+		format, path, _ := strings.Cut(v, "=")
+		if format == "console" && path == "" {
+			continue
+		}
+		if format == "json" && v == "json" {
+			stream = true
+			continue
+		}
+		if _, ok := outputFormats[format]; !ok {
+			return nil, false, fmt.Errorf("unsupported output format %q (supported: console, json, sarif)", format)
+		}
+		if path == "" {
+			return nil, false, fmt.Errorf("output %q needs a destination, as %s=PATH", format, format)
+		}
+		sinks = append(sinks, outputSink{format, path})
+	}
+	return sinks, stream, nil
+}
+
+// fileOutcome is what a run found or did for one file.
+type fileOutcome struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+	// Repository is the root of the git repository holding the file.
+	Repository string   `json:"repository,omitempty"`
+	Problems   []string `json:"problems,omitempty"`
+	// Warnings are findings below the severity that fails check or that
+	// fix mode acts on; they are reported and left in the file.
+	Warnings []string `json:"warnings,omitempty"`
+	// Actions lists what was done to the file, such as "header added".
+	Actions []string `json:"actions,omitempty"`
+	// PreviousHeader is the header comment that was replaced or removed.
+	PreviousHeader string    `json:"previous_header,omitempty"`
+	Anomalies      []anomaly `json:"anomalies,omitempty"`
+	// Maintainer is the header's ownership annotation when its review
+	// date has passed.
+	Maintainer *maintainer `json:"maintainer,omitempty"`
+	// ForeignHeader identifies the other party's header a run refused to
+	// overwrite.
+	ForeignHeader string `json:"foreign_header,omitempty"`
+	// Exception is the exception that suppressed the file's findings.
+	Exception *exception `json:"exception,omitempty"`
+	// TemplateVersion is the template version of the header, reported by
+	// check when template versions are tracked.
+	TemplateVersion string `json:"template_version,omitempty"`
+	Error           string `json:"error,omitempty"`
+	// lines is the length of the file, so the footer can be located.
+	lines int
+}
+
+// actionList names what copyrighter.Stamp or copyrighter.Remove did to a file, for
+// the machine-readable output.
+func actionList(result copyrighter.Result) []string {
+	var actions []string
+	for _, part := range []struct {
+		name   string
+		action copyrighter.Action
+	}{{"header", result.Header}, {"footer", result.Footer}} {
+		switch part.action {
+		case copyrighter.Added:
+			actions = append(actions, part.name+" added")
+		case copyrighter.Updated:
+			actions = append(actions, part.name+" updated")
+		case copyrighter.Removed:
+			actions = append(actions, part.name+" removed")
+		}
+	}
+	return actions
+}
+
+// replacedHeader returns the leading comment of content that a stamp
+// replaces or a removal strips, as it appears in the file.
+func replacedHeader(content string, opts copyrighter.Options) string {
+	_, body := copyrighter.SplitContent(content, opts)
+	header, _ := copyrighter.ExpectedNotice(body, opts)
+	return strings.Join(body[:opts.Style.LeadingComment(body, len(header))], "\n")
+}
+
+// outputSet collects per-file outcomes during a run and writes them to
+// every configured sink when it finishes. Recording is safe for
+// concurrent use.
+type outputSet struct {
+	sinks []outputSink
+	// stream, when set, receives every outcome as a line of JSON as soon
+	// as it is recorded, and the summary as the last line.
+	stream *json.Encoder
+
+	mu    sync.Mutex
+	files []fileOutcome
+}
+
+// record adds the outcome for one file. It is a no-op without sinks.
+func (o *outputSet) record(f fileOutcome) {
+	if o == nil {
+		return
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.files = append(o.files, f)
+	if o.stream != nil {
+		o.stream.Encode(f)
+	}
+}
+
+// write renders the collected outcomes to every sink.
+func (o *outputSet) write(summary runSummary) error {
+	if o == nil {
+		return nil
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	sort.SliceStable(o.files, func(i, j int) bool { return o.files[i].Path < o.files[j].Path })
+
+	var errs []string
+	if o.stream != nil {
+		if err := o.stream.Encode(struct {
+			Summary runSummary `json:"summary"`
+		}{summary}); err != nil {
+			errs = append(errs, fmt.Sprintf("json output: %v", err))
+		}
+	}
+	for _, sink := range o.sinks {
+		data, err := outputFormats[sink.format](o, summary)
+		if err == nil {
+			err = os.WriteFile(sink.path, data, 0644)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("%s output %s: %v", sink.format, sink.path, err))
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+func (o *outputSet) renderJSON(summary runSummary) ([]byte, error) {
+	files := o.files
+	if files == nil {
+		files = []fileOutcome{}
+	}
+	data, err := json.MarshalIndent(struct {
+		Summary runSummary    `json:"summary"`
+		Files   []fileOutcome `json:"files"`
+	}{summary, files}, "", "  ")
+	return append(data, '\n'), err
+}
+
+// SARIF 2.1.0, the subset code scanning services read.
+type (
+	sarifLog struct {
+		Schema  string     `json:"$schema"`
+		Version string     `json:"version"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifLocation struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine int `json:"startLine"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	}
+)
+
+// sarifRules describes every rule a result can refer to.
+var sarifRules = []sarifRule{
+	{"missing-header", sarifMessage{"The file has no copyright header."}},
+	{"outdated-header", sarifMessage{"The copyright header differs from the required notice."}},
+	{"missing-footer", sarifMessage{"The file has no copyright footer."}},
+	{"outdated-footer", sarifMessage{"The copyright footer differs from the required notice."}},
+	{"stale-year", sarifMessage{"The copyright notice differs from the required one only in its years."}},
+	{"foreign-header", sarifMessage{"The header belongs to another party and was not overwritten."}},
+	{"expired-review", sarifMessage{"The review date in the header's Maintained-by annotation has passed."}},
+	{"license-text-outside-notice", sarifMessage{"License or copyright text appears outside the header and footer."}},
+	{"processing-error", sarifMessage{"The file could not be processed."}},
+}
+
+func sarifResultAt(ruleID, level, text, path string, line int) sarifResult {
+	var loc sarifLocation
+	loc.PhysicalLocation.ArtifactLocation.URI = strings.TrimPrefix(filepath.ToSlash(path), "./")
+	loc.PhysicalLocation.Region.StartLine = max(line, 1)
+	return sarifResult{RuleID: ruleID, Level: level, Message: sarifMessage{text}, Locations: []sarifLocation{loc}}
+}
+
+func (o *outputSet) renderSARIF(summary runSummary) ([]byte, error) {
+	results := []sarifResult{}
+	for _, f := range o.files {
+		if f.Status == "modified" || f.Status == "would_modify" {
+			// Fixed by this run, nothing left to report
+			continue
+		}
+		// Problems the baseline tolerates are kept visible as notes
+		level := "error"
+		if f.Status == "baselined" {
+			level = "note"
+		}
+		for _, problem := range f.Problems {
+			line := 1
+			if strings.HasSuffix(problem, "footer") {
+				line = f.lines
+			}
+			results = append(results, sarifResultAt(strings.ReplaceAll(problem, " ", "-"), level, f.Path+": "+problem, f.Path, line))
+		}
+		for _, w := range f.Warnings {
+			line := 1
+			if strings.HasSuffix(w, "footer") {
+				line = f.lines
+			}
+			results = append(results, sarifResultAt(strings.ReplaceAll(w, " ", "-"), "warning", f.Path+": "+w, f.Path, line))
+		}
+		for _, a := range f.Anomalies {
+			results = append(results, sarifResultAt("license-text-outside-notice", "warning", "License text outside the header and footer: "+a.Text, f.Path, a.Line))
+		}
+		if f.Error != "" {
+			results = append(results, sarifResultAt("processing-error", "error", f.Error, f.Path, 1))
+		}
+	}
+
+	log := sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs: []sarifRun{{
+			Tool:    sarifTool{Driver: sarifDriver{Name: "copy-righter", Rules: sarifRules}},
+			Results: results,
+		}},
+	}
+	data, err := json.MarshalIndent(log, "", "  ")
+	return append(data, '\n'), err
+}
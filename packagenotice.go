@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// packageNoticeEvery is the default package_notice: every Go file carries
+// the notice. Naming a file instead, such as doc.go, makes it the only
+// file of each package that does.
+const packageNoticeEvery = "every"
+
+// checkPackageNotice refuses a package_notice that is neither every nor
+// the name of a non-test Go file.
+func checkPackageNotice(v string) error {
+	if v == "" || v == packageNoticeEvery {
+		return nil
+	}
+	if filepath.Base(v) != v || !strings.HasSuffix(v, ".go") || strings.HasSuffix(v, "_test.go") {
+		return fmt.Errorf("invalid package notice %q, want %s or the name of a Go file such as doc.go", v, packageNoticeEvery)
+	}
+	return nil
+}
+
+// packageNoticeFile returns the file named by a package_notice, empty
+// when every file carries the notice.
+func packageNoticeFile(v string) string {
+	if v == packageNoticeEvery {
+		return ""
+	}
+	return v
+}
+
+// sharesPackageNotice reports whether filePath is a Go file whose package
+// carries the notice in another file, so it must not carry one itself.
+func (r *runner) sharesPackageNotice(filePath string) bool {
+	return r.packageNotice != "" && strings.ToLower(filepath.Ext(filePath)) == ".go" && filepath.Base(filePath) != r.packageNotice
+}
+
+// checkPackageNoticeFile reports, once per directory, a Go package that
+// lacks the file meant to carry its notice. The file is looked for on disk
+// so a run given only some files of a package still finds it.
+func (r *runner) checkPackageNoticeFile(filePath string) {
+	if r.packageNotice == "" || strings.ToLower(filepath.Ext(filePath)) != ".go" {
+		return
+	}
+	dir := filepath.Dir(filePath)
+	if r.packageDirs[dir] {
+		return
+	}
+	if r.packageDirs == nil {
+		r.packageDirs = make(map[string]bool)
+	}
+	r.packageDirs[dir] = true
+	if _, err := os.Stat(filepath.Join(dir, r.packageNotice)); err == nil {
+		return
+	}
+	r.logf(logQuiet, "%s: package has no %s to carry the copyright notice\n", dir, r.packageNotice)
+	r.summary.PackagesWithoutNotice++
+}
+
+// processSharedNotice handles a Go file whose package notice is in another
+// file. Check flags a notice of ours in it as a duplicate; fix mode removes
+// a duplicate that matches the notice exactly and warns about an outdated
+// one, which is left for a human to remove.
+func (r *runner) processSharedNotice(src *source, opts copyrighter.Options) (bool, error) {
+	filePath, content := src.path, src.content
+	_, result, err := copyrighter.Stamp(content, opts)
+	if err != nil {
+		return false, err
+	}
+	removed, removal, err := copyrighter.Remove(content, opts)
+	if err != nil {
+		return false, err
+	}
+	status, _ := classifyHeader(content, opts, result)
+	duplicate := status == auditOK || status == auditOutdated || removal.Changed()
+	if !r.check && removal.Changed() && !src.fits(removed) {
+		return false, errWindowLost
+	}
+
+	r.summary.Scanned++
+	outcome := fileOutcome{Path: filePath, Status: "up_to_date", lines: src.lines}
+	switch {
+	case !duplicate:
+		r.logf(logVerbose, "Package notice is in %s, leaving: %s\n", r.packageNotice, filePath)
+		r.summary.UpToDate++
+	case r.check:
+		r.logf(logQuiet, "%s: duplicate notice, the package notice is in %s\n", filePath, r.packageNotice)
+		r.summary.Outdated++
+		outcome.Status = "outdated"
+		outcome.Problems = []string{"duplicate package notice"}
+	case !removal.Changed():
+		warning := fmt.Sprintf("outdated duplicate notice, the package notice is in %s; remove it by hand", r.packageNotice)
+		r.logf(logNormal, "%s: warning: %s\n", filePath, warning)
+		r.summary.Warnings++
+		r.summary.UpToDate++
+		outcome.Warnings = []string{warning}
+	default:
+		r.logf(logNormal, "Removing duplicate notice from: %s (the package notice is in %s)\n", filePath, r.packageNotice)
+		if r.stat != nil {
+			r.stat.add(filePath, content, removed)
+		}
+		if r.patch != nil {
+			r.patch.add(filePath, content, removed)
+		}
+		outcome.Actions = actionList(removal)
+		r.summary.Modified++
+		r.summary.Updated++
+		if r.dryRun {
+			r.logf(logNormal, "Dry run, not writing: %s\n", filePath)
+			outcome.Status = "would_modify"
+			r.record(outcome)
+			return true, nil
+		}
+		if _, err := r.write(src, removed); err != nil {
+			return true, err
+		}
+		outcome.Status = "modified"
+	}
+	r.record(outcome)
+	return outcome.Status == "modified", nil
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// This file writes the small, flat tables produced by "report export" in
+// the Parquet format: one row group, one uncompressed PLAIN-encoded data
+// page per column, all columns required. That is enough for every
+// analytics engine to read, without pulling a full Parquet implementation
+// into the tool.
+
+// Parquet physical types and converted types used by the writer.
+const (
+	parquetInt32     int32 = 1
+	parquetInt64     int32 = 2
+	parquetDouble    int32 = 5
+	parquetByteArray int32 = 6
+
+	parquetNoConversion int32 = -1
+	parquetUTF8         int32 = 0
+	parquetDate         int32 = 6
+)
+
+const parquetMagic = "PAR1"
+
+// parquetColumn is one column of a table, with its values already PLAIN
+// encoded.
+type parquetColumn struct {
+	name      string
+	kind      int32
+	converted int32
+	values    bytes.Buffer
+}
+
+func newParquetColumn(name string, kind, converted int32) *parquetColumn {
+	return &parquetColumn{name: name, kind: kind, converted: converted}
+}
+
+func (c *parquetColumn) appendInt32(v int32) {
+	binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+func (c *parquetColumn) appendInt64(v int64) {
+	binary.Write(&c.values, binary.LittleEndian, v)
+}
+
+func (c *parquetColumn) appendDouble(v float64) {
+	binary.Write(&c.values, binary.LittleEndian, math.Float64bits(v))
+}
+
+func (c *parquetColumn) appendString(v string) {
+	binary.Write(&c.values, binary.LittleEndian, uint32(len(v)))
+	c.values.WriteString(v)
+}
+
+// writeParquet writes columns holding numRows values each as a Parquet file.
+func writeParquet(w io.Writer, columns []*parquetColumn, numRows int) error {
+	var file bytes.Buffer
+	file.WriteString(parquetMagic)
+
+	type chunk struct {
+		offset, size int64
+	}
+	chunks := make([]chunk, len(columns))
+	for i, col := range columns {
+		var header compactWriter
+		header.beginStruct()
+		header.i32(1, 0) // DATA_PAGE
+		header.i32(2, int32(col.values.Len()))
+		header.i32(3, int32(col.values.Len()))
+		header.structField(5)
+		header.i32(1, int32(numRows))
+		header.i32(2, 0) // PLAIN
+		header.i32(3, 3) // RLE definition levels, none for required columns
+		header.i32(4, 3) // RLE repetition levels, likewise
+		header.endStruct()
+		header.endStruct()
+
+		chunks[i] = chunk{offset: int64(file.Len()), size: int64(header.Len() + col.values.Len())}
+		file.Write(header.Bytes())
+		file.Write(col.values.Bytes())
+	}
+
+	var meta compactWriter
+	meta.beginStruct()
+	meta.i32(1, 1) // version
+	meta.listHeader(2, compactStruct, len(columns)+1)
+	meta.beginStruct()
+	meta.binary(4, "schema")
+	meta.i32(5, int32(len(columns)))
+	meta.endStruct()
+	for _, col := range columns {
+		meta.beginStruct()
+		meta.i32(1, col.kind)
+		meta.i32(3, 0) // REQUIRED
+		meta.binary(4, col.name)
+		if col.converted != parquetNoConversion {
+			meta.i32(6, col.converted)
+		}
+		meta.endStruct()
+	}
+	meta.i64(3, int64(numRows))
+
+	var total int64
+	for _, c := range chunks {
+		total += c.size
+	}
+	meta.listHeader(4, compactStruct, 1)
+	meta.beginStruct()
+	meta.listHeader(1, compactStruct, len(columns))
+	for i, col := range columns {
+		meta.beginStruct()
+		meta.i64(2, chunks[i].offset)
+		meta.structField(3)
+		meta.i32(1, col.kind)
+		meta.listHeader(2, compactI32, 1)
+		meta.varint(0) // PLAIN
+		meta.listHeader(3, compactBinary, 1)
+		meta.uvarint(uint64(len(col.name)))
+		meta.WriteString(col.name)
+		meta.i32(4, 0) // UNCOMPRESSED
+		meta.i64(5, int64(numRows))
+		meta.i64(6, chunks[i].size)
+		meta.i64(7, chunks[i].size)
+		meta.i64(9, chunks[i].offset)
+		meta.endStruct()
+		meta.endStruct()
+	}
+	meta.i64(2, total)
+	meta.i64(3, int64(numRows))
+	meta.endStruct()
+	meta.binary(6, "copy-righter")
+	meta.endStruct()
+
+	file.Write(meta.Bytes())
+	binary.Write(&file, binary.LittleEndian, uint32(meta.Len()))
+	file.WriteString(parquetMagic)
+	_, err := w.Write(file.Bytes())
+	return err
+}
+
+// Thrift compact protocol type codes.
+const (
+	compactI32    byte = 5
+	compactI64    byte = 6
+	compactBinary byte = 8
+	compactList   byte = 9
+	compactStruct byte = 12
+)
+
+// compactWriter encodes Thrift structs with the compact protocol, which
+// Parquet uses for page headers and file metadata.
+type compactWriter struct {
+	bytes.Buffer
+	// lastID is the previous field id of each open struct, since field
+	// headers are delta encoded.
+	lastID []int16
+}
+
+func (w *compactWriter) beginStruct() {
+	w.lastID = append(w.lastID, 0)
+}
+
+func (w *compactWriter) endStruct() {
+	w.WriteByte(0)
+	w.lastID = w.lastID[:len(w.lastID)-1]
+}
+
+func (w *compactWriter) field(id int16, typ byte) {
+	last := &w.lastID[len(w.lastID)-1]
+	if delta := id - *last; delta > 0 && delta <= 15 {
+		w.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		w.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	*last = id
+}
+
+// structField starts a nested struct field; close it with endStruct.
+func (w *compactWriter) structField(id int16) {
+	w.field(id, compactStruct)
+	w.beginStruct()
+}
+
+func (w *compactWriter) i32(id int16, v int32) {
+	w.field(id, compactI32)
+	w.varint(int64(v))
+}
+
+func (w *compactWriter) i64(id int16, v int64) {
+	w.field(id, compactI64)
+	w.varint(v)
+}
+
+func (w *compactWriter) binary(id int16, s string) {
+	w.field(id, compactBinary)
+	w.uvarint(uint64(len(s)))
+	w.WriteString(s)
+}
+
+func (w *compactWriter) listHeader(id int16, elem byte, n int) {
+	w.field(id, compactList)
+	if n < 15 {
+		w.WriteByte(byte(n)<<4 | elem)
+	} else {
+		w.WriteByte(0xf0 | elem)
+		w.uvarint(uint64(n))
+	}
+}
+
+// varint writes a zigzag-encoded signed integer.
+func (w *compactWriter) varint(v int64) {
+	w.uvarint(uint64(v<<1) ^ uint64(v>>63))
+}
+
+func (w *compactWriter) uvarint(v uint64) {
+	w.Write(binary.AppendUvarint(nil, v))
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// compactReader decodes Thrift compact structs into maps from field id to
+// value, enough to read back what compactWriter writes.
+type compactReader struct {
+	data []byte
+	pos  int
+}
+
+func (r *compactReader) byte() byte {
+	b := r.data[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *compactReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		panic(fmt.Sprintf("bad varint at %d", r.pos))
+	}
+	r.pos += n
+	return v
+}
+
+func (r *compactReader) varint() int64 {
+	u := r.uvarint()
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *compactReader) readStruct() map[int16]any {
+	fields := make(map[int16]any)
+	var last int16
+	for {
+		b := r.byte()
+		if b == 0 {
+			return fields
+		}
+		id := last + int16(b>>4)
+		if b>>4 == 0 {
+			id = int16(r.varint())
+		}
+		last = id
+		fields[id] = r.value(b & 0x0f)
+	}
+}
+
+func (r *compactReader) value(typ byte) any {
+	switch typ {
+	case compactI32, compactI64:
+		return r.varint()
+	case compactBinary:
+		n := int(r.uvarint())
+		s := string(r.data[r.pos : r.pos+n])
+		r.pos += n
+		return s
+	case compactList:
+		h := r.byte()
+		n := int(h >> 4)
+		if n == 15 {
+			n = int(r.uvarint())
+		}
+		list := make([]any, n)
+		for i := range list {
+			list[i] = r.value(h & 0x0f)
+		}
+		return list
+	case compactStruct:
+		return r.readStruct()
+	}
+	panic(fmt.Sprintf("unexpected compact type %d at %d", typ, r.pos))
+}
+
+// TestWriteParquetDecodes reads a written file back as a Parquet reader
+// would: the footer metadata, then each column chunk from its offset, its
+// page header and its PLAIN values.
+func TestWriteParquetDecodes(t *testing.T) {
+	day := newParquetColumn("day", parquetInt32, parquetDate)
+	files := newParquetColumn("files", parquetInt64, parquetNoConversion)
+	ratio := newParquetColumn("ratio", parquetDouble, parquetNoConversion)
+	repo := newParquetColumn("repo", parquetByteArray, parquetUTF8)
+	rows := []struct {
+		day   int32
+		files int64
+		ratio float64
+		repo  string
+	}{
+		{20000, 1500, 0.75, "copy-righter"},
+		{20001, -3, math.Inf(1), ""},
+		{20002, 1 << 40, 1.0 / 3, "ünïcode"},
+	}
+	for _, row := range rows {
+		day.appendInt32(row.day)
+		files.appendInt64(row.files)
+		ratio.appendDouble(row.ratio)
+		repo.appendString(row.repo)
+	}
+	columns := []*parquetColumn{day, files, ratio, repo}
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, columns, len(rows)); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	if string(data[:4]) != parquetMagic || string(data[len(data)-4:]) != parquetMagic {
+		t.Fatalf("file is not framed by %s", parquetMagic)
+	}
+	metaLen := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	metaStart := len(data) - 8 - metaLen
+	r := &compactReader{data: data[:len(data)-8], pos: metaStart}
+	meta := r.readStruct()
+	if r.pos != len(data)-8 {
+		t.Fatalf("metadata decoded to %d, footer length says %d", r.pos, len(data)-8)
+	}
+	if meta[1] != int64(1) || meta[3] != int64(len(rows)) || meta[6] != "copy-righter" {
+		t.Errorf("version %v, num_rows %v, created_by %v", meta[1], meta[3], meta[6])
+	}
+
+	schema := meta[2].([]any)
+	if root := schema[0].(map[int16]any); root[4] != "schema" || root[5] != int64(len(columns)) {
+		t.Errorf("schema root %v", root)
+	}
+	for i, col := range columns {
+		element := schema[i+1].(map[int16]any)
+		want := map[int16]any{1: int64(col.kind), 3: int64(0), 4: col.name}
+		if col.converted != parquetNoConversion {
+			want[6] = int64(col.converted)
+		}
+		if !reflect.DeepEqual(element, want) {
+			t.Errorf("schema element %d = %v, want %v", i, element, want)
+		}
+	}
+
+	rowGroups := meta[4].([]any)
+	if len(rowGroups) != 1 {
+		t.Fatalf("%d row groups", len(rowGroups))
+	}
+	group := rowGroups[0].(map[int16]any)
+	if group[3] != int64(len(rows)) {
+		t.Errorf("row group num_rows %v", group[3])
+	}
+	var total int64
+	for i, c := range group[1].([]any) {
+		col := columns[i]
+		chunk := c.(map[int16]any)
+		md := chunk[3].(map[int16]any)
+		offset := md[9].(int64)
+		size := md[7].(int64)
+		total += size
+		if chunk[2] != offset || md[1] != int64(col.kind) || md[4] != int64(0) || md[5] != int64(len(rows)) || md[6] != size {
+			t.Errorf("column %s chunk metadata %v", col.name, md)
+		}
+		if path := md[3].([]any); len(path) != 1 || path[0] != col.name {
+			t.Errorf("column %s path_in_schema %v", col.name, path)
+		}
+
+		page := &compactReader{data: data[:offset+size], pos: int(offset)}
+		header := page.readStruct()
+		dataHeader := header[5].(map[int16]any)
+		values := data[page.pos : offset+size]
+		if header[1] != int64(0) || header[2] != int64(len(values)) || header[3] != int64(len(values)) ||
+			dataHeader[1] != int64(len(rows)) || dataHeader[2] != int64(0) {
+			t.Errorf("column %s page header %v", col.name, header)
+		}
+
+		plain := bytes.NewReader(values)
+		for j, row := range rows {
+			var got, want any
+			switch col.kind {
+			case parquetInt32:
+				var v int32
+				binary.Read(plain, binary.LittleEndian, &v)
+				got, want = v, row.day
+			case parquetInt64:
+				var v int64
+				binary.Read(plain, binary.LittleEndian, &v)
+				got, want = v, row.files
+			case parquetDouble:
+				var v uint64
+				binary.Read(plain, binary.LittleEndian, &v)
+				got, want = math.Float64frombits(v), row.ratio
+			case parquetByteArray:
+				var n uint32
+				binary.Read(plain, binary.LittleEndian, &n)
+				s := make([]byte, n)
+				plain.Read(s)
+				got, want = string(s), row.repo
+			}
+			if got != want {
+				t.Errorf("column %s row %d = %v, want %v", col.name, j, got, want)
+			}
+		}
+		if plain.Len() != 0 {
+			t.Errorf("column %s has %d bytes past its values", col.name, plain.Len())
+		}
+	}
+	if group[2] != total {
+		t.Errorf("row group total_byte_size %v, chunks add up to %d", group[2], total)
+	}
+}
+
+// TestWriteParquetBytes pins the exact bytes of a one-column, one-row file,
+// worked out by hand from the Parquet and Thrift compact specifications,
+// so the writer is not only checked against the reader above.
+func TestWriteParquetBytes(t *testing.T) {
+	col := newParquetColumn("a", parquetInt32, parquetNoConversion)
+	col.appendInt32(7)
+	var buf bytes.Buffer
+	if err := writeParquet(&buf, []*parquetColumn{col}, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	want := slices.Concat(
+		[]byte("PAR1"),
+		// Page header at offset 4: DATA_PAGE of 4 bytes, then its
+		// DataPageHeader of 1 value, PLAIN, RLE levels.
+		[]byte{0x15, 0x00, 0x15, 0x08, 0x15, 0x08, 0x2c, 0x15, 0x02, 0x15, 0x00, 0x15, 0x06, 0x15, 0x06, 0x00, 0x00},
+		// The value 7, ending the 21-byte column chunk at offset 25.
+		[]byte{0x07, 0x00, 0x00, 0x00},
+		// FileMetaData: version 1 and the schema root with one child.
+		[]byte{0x15, 0x02, 0x19, 0x2c, 0x48, 0x06}, []byte("schema"), []byte{0x15, 0x02, 0x00},
+		// The REQUIRED INT32 column "a", and num_rows 1.
+		[]byte{0x15, 0x02, 0x25, 0x00, 0x18, 0x01, 'a', 0x00, 0x16, 0x02},
+		// One row group of one column chunk at file_offset 4, whose
+		// metadata has INT32, PLAIN, path "a", UNCOMPRESSED, 1 value,
+		// sizes 21 and data_page_offset 4.
+		[]byte{0x19, 0x1c, 0x19, 0x1c, 0x26, 0x08, 0x1c, 0x15, 0x02, 0x19, 0x15, 0x00, 0x19, 0x18, 0x01, 'a',
+			0x15, 0x00, 0x16, 0x02, 0x16, 0x2a, 0x16, 0x2a, 0x26, 0x08, 0x00, 0x00},
+		// total_byte_size 21 and num_rows 1 of the row group.
+		[]byte{0x16, 0x2a, 0x16, 0x02, 0x00},
+		// created_by.
+		[]byte{0x28, 0x0c}, []byte("copy-righter"), []byte{0x00},
+		// The 73-byte footer length and the closing magic.
+		[]byte{0x49, 0x00, 0x00, 0x00}, []byte("PAR1"),
+	)
+	if got := buf.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("writeParquet wrote\n% x\nwant\n% x", got, want)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// patchContext is the number of unchanged lines around each change in a
+// patch, as git diff writes them.
+const patchContext = 3
+
+// patchWriter collects the changes of a run as a git-style unified patch
+// instead of writing them to the tree, so they can be reviewed, attached
+// to a ticket and applied later with git apply.
+type patchWriter struct {
+	path  string
+	b     strings.Builder
+	files int
+}
+
+// add appends the change of the file at path from before to after.
+func (p *patchWriter) add(path, before, after string) {
+	if before == after {
+		return
+	}
+	name := patchPath(path)
+	fmt.Fprintf(&p.b, "diff --git a/%s b/%s\n--- a/%s\n+++ b/%s\n", name, name, name, name)
+	for _, hunk := range patchHunks(diffLines(splitForDiff(before), splitForDiff(after))) {
+		p.b.WriteString(hunk)
+	}
+	p.files++
+}
+
+// write saves the patch, empty when the run changed nothing.
+func (p *patchWriter) write() error {
+	return writeFileAtomic(p.path, []byte(p.b.String()))
+}
+
+// patchPath returns the name of path in a patch: relative to the working
+// directory, which git apply is run from, with forward slashes.
+func patchPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, abs); err == nil {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// patchHunks renders the edit script ops as unified diff hunks with
+// patchContext lines of context, merging changes whose context overlaps.
+func patchHunks(ops []diffOp) []string {
+	var hunks []string
+	// aLine and bLine are the 0-based line numbers in the old and new
+	// file of the op at each index.
+	aLine, bLine := make([]int, len(ops)+1), make([]int, len(ops)+1)
+	for i, op := range ops {
+		aLine[i+1], bLine[i+1] = aLine[i], bLine[i]
+		if op.kind != '+' {
+			aLine[i+1]++
+		}
+		if op.kind != '-' {
+			bLine[i+1]++
+		}
+	}
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		start := max(i-patchContext, 0)
+		end := i
+		for j := i; j < len(ops) && j <= end+2*patchContext+1; j++ {
+			if ops[j].kind != ' ' {
+				end = j
+			}
+		}
+		end = min(end+patchContext+1, len(ops))
+
+		var b strings.Builder
+		aStart, aCount := aLine[start], aLine[end]-aLine[start]
+		bStart, bCount := bLine[start], bLine[end]-bLine[start]
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+		for _, op := range ops[start:end] {
+			b.WriteString(string(op.kind) + op.line)
+			if !strings.HasSuffix(op.line, "\n") {
+				b.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		hunks = append(hunks, b.String())
+		i = end
+	}
+	return hunks
+}
+
+// hunkRange formats the range of a hunk in one file: its 1-based first
+// line and length, or the line before it when it is empty.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+// setPatch makes the run collect its changes in the file named by
+// --patch instead of writing them, as a dry run does.
+func (r *runner) setPatch(cmd *cobra.Command) {
+	if path, _ := cmd.Flags().GetString("patch"); path != "" {
+		r.patch = &patchWriter{path: path}
+		r.dryRun = true
+	}
+}
+
+// writePatch saves the patch of the run, if any.
+func (r *runner) writePatch() {
+	if r.patch == nil {
+		return
+	}
+	if err := r.patch.write(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: writing patch: %v\n", err)
+		os.Exit(exitError)
+	}
+	r.logf(logNormal, "Wrote the changes to %d %s to %s\n", r.patch.files, plural(r.patch.files, "file", "files"), r.patch.path)
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// maxPathLength bounds a single path read from a list, well above PATH_MAX
+// on common platforms.
+const maxPathLength = 64 * 1024
+
+// readNulSeparated calls yield for each NUL-separated path in rd, stopping
+// early if yield returns false. Empty entries are ignored.
+func readNulSeparated(rd io.Reader, yield func(string) bool) error {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 0, 4096), maxPathLength)
+	scanner.Split(scanNul)
+	for scanner.Scan() {
+		if path := scanner.Text(); path != "" && !yield(path) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// readPathList calls yield for each path in a list read from rd, stopping
+// early if yield returns false. The paths are separated by NULs or by
+// newlines, whichever the list uses first, so it takes the output of both
+// git ls-files and git ls-files -z. Empty entries are ignored.
+func readPathList(rd io.Reader, yield func(string) bool) error {
+	scanner := bufio.NewScanner(rd)
+	scanner.Buffer(make([]byte, 0, 4096), maxPathLength)
+	detected, lines := false, false
+	scanner.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		if !detected {
+			if i := bytes.IndexAny(data, "\x00\n"); i >= 0 {
+				detected, lines = true, data[i] == '\n'
+			}
+		}
+		if lines {
+			return bufio.ScanLines(data, atEOF)
+		}
+		return scanNul(data, atEOF)
+	})
+	for scanner.Scan() {
+		if path := scanner.Text(); path != "" && !yield(path) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// scanNul is a bufio.SplitFunc for NUL-terminated records.
+func scanNul(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexByte(data, 0); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
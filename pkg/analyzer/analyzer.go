@@ -0,0 +1,152 @@
+// Package analyzer exposes the copyright header check of copy-righter as a
+// go/analysis Analyzer, for go vet -vettool, golangci-lint and other
+// analysis drivers. A Go file with a missing or outdated header or footer
+// is reported with a suggested fix that stamps it.
+package analyzer
+
+import (
+	"errors"
+	"go/ast"
+	"go/token"
+	"regexp"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// Settings configures the Analyzer. The JSON names are those of the
+// golangci-lint plugin settings.
+type Settings struct {
+	// Copyright is the notice every file must carry.
+	Copyright string `json:"copyright"`
+	// SPDX is the SPDX license expression the header must carry, if any.
+	SPDX string `json:"spdx"`
+	// NoFooter checks the header only.
+	NoFooter bool `json:"no-footer"`
+	// MaxLineLength wraps a longer notice; 0 does not wrap. Nil wraps at
+	// copyrighter.DefaultMaxLineLength, as the CLI does.
+	MaxLineLength *int `json:"max-line-length"`
+}
+
+// Analyzer checks the copyright header and footer of Go files; the
+// -copyright flag sets the notice.
+var Analyzer = newAnalyzer(&Settings{})
+
+// New returns an Analyzer checking for the notice of s, for drivers that
+// configure analyzers in code instead of with flags.
+func New(s Settings) *analysis.Analyzer {
+	if s.MaxLineLength != nil {
+		// The flag of the Analyzer must not set the caller's int
+		n := *s.MaxLineLength
+		s.MaxLineLength = &n
+	}
+	return newAnalyzer(&s)
+}
+
+func newAnalyzer(s *Settings) *analysis.Analyzer {
+	if s.MaxLineLength == nil {
+		n := copyrighter.DefaultMaxLineLength
+		s.MaxLineLength = &n
+	}
+	a := &analysis.Analyzer{
+		Name: "copyrighter",
+		Doc:  "check that Go files carry the copyright header and footer\n\nFiles with a missing or outdated notice are reported with a suggested fix that stamps it, as copy-righter does. Generated files are skipped.",
+		URL:  "https://github.com/earik87/copy-righter",
+		Run:  s.run,
+	}
+	a.Flags.StringVar(&s.Copyright, "copyright", s.Copyright, "copyright notice every file must carry")
+	a.Flags.StringVar(&s.SPDX, "spdx", s.SPDX, "SPDX license expression the header must carry")
+	a.Flags.BoolVar(&s.NoFooter, "no-footer", s.NoFooter, "check the header only")
+	a.Flags.IntVar(s.MaxLineLength, "max-line-length", *s.MaxLineLength, "wrap a notice whose lines would be longer, 0 to never wrap")
+	return a
+}
+
+func (s *Settings) run(pass *analysis.Pass) (any, error) {
+	if s.Copyright == "" {
+		return nil, errors.New("no copyright notice set, see -copyright")
+	}
+	p := copyrighter.Processor{
+		Options: copyrighter.Options{Copyright: s.Copyright, SPDX: s.SPDX, MaxLineLength: *s.MaxLineLength, NoticePatterns: []*regexp.Regexp{copyrighter.CopyrightPattern}},
+		Footers: map[string]bool{".go": !s.NoFooter},
+	}
+	for _, f := range pass.Files {
+		if ast.IsGenerated(f) {
+			continue
+		}
+		file := pass.Fset.File(f.FileStart)
+		opts, err := p.OptionsFor(file.Name())
+		if err != nil {
+			// Not a Go source file, such as a file generated by cgo
+			continue
+		}
+		data, err := pass.ReadFile(file.Name())
+		if err != nil {
+			return nil, err
+		}
+		content := string(data)
+		if copyrighter.IsGenerated(content, nil) || copyrighter.IsIgnored(content) {
+			continue
+		}
+		_, result, err := copyrighter.Stamp(content, opts)
+		if err != nil {
+			return nil, err
+		}
+		// The header and footer are reported apart, each with its own
+		// fix, so the edits stay small and the footer is reported where
+		// it belongs.
+		if result.Header != copyrighter.UpToDate {
+			headerOnly := opts
+			headerOnly.LeaveFooter = true
+			if err := report(pass, file, content, headerOnly, "header", result.Header); err != nil {
+				return nil, err
+			}
+		}
+		if result.Footer != copyrighter.UpToDate {
+			footerOnly := opts
+			footerOnly.LeaveHeader = true
+			if err := report(pass, file, content, footerOnly, "footer", result.Footer); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
+
+// report reports the header or footer, what, of file as missing or
+// outdated, with a fix turning content into what Stamp makes of it with
+// opts.
+func report(pass *analysis.Pass, file *token.File, content string, opts copyrighter.Options, what string, action copyrighter.Action) error {
+	stamped, _, err := copyrighter.Stamp(content, opts)
+	if err != nil {
+		return err
+	}
+	start, end, text := minimalEdit(content, stamped)
+	problem := "missing"
+	if action == copyrighter.Updated {
+		problem = "outdated"
+	}
+	pos := file.Pos(start)
+	pass.Report(analysis.Diagnostic{
+		Pos:     pos,
+		Message: problem + " copyright " + what,
+		SuggestedFixes: []analysis.SuggestedFix{{
+			Message:   "Stamp the copyright " + what,
+			TextEdits: []analysis.TextEdit{{Pos: pos, End: file.Pos(end), NewText: []byte(text)}},
+		}},
+	})
+	return nil
+}
+
+// minimalEdit returns the single edit turning old into new: the bytes
+// from start to end of old are replaced by text.
+func minimalEdit(old, new string) (start, end int, text string) {
+	for start < len(old) && start < len(new) && old[start] == new[start] {
+		start++
+	}
+	suffix := 0
+	for suffix < len(old)-start && suffix < len(new)-start && old[len(old)-1-suffix] == new[len(new)-1-suffix] {
+		suffix++
+	}
+	return start, len(old) - suffix, new[start : len(new)-suffix]
+}
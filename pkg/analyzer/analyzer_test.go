@@ -0,0 +1,20 @@
+package analyzer
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// longNotice is longer than the 100 columns notices are wrapped at.
+const longNotice = "Copyright (c) 2025 Example Corp. Licensed to the members of the Example Consortium under the terms of their membership agreement."
+
+func TestAnalyzer(t *testing.T) {
+	a := New(Settings{Copyright: "Copyright (c) 2025 Example Corp."})
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), a, "a")
+}
+
+func TestAnalyzerWrapsLongNotice(t *testing.T) {
+	a := New(Settings{Copyright: longNotice})
+	analysistest.RunWithSuggestedFixes(t, analysistest.TestData(), a, "b")
+}
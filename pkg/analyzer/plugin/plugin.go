@@ -0,0 +1,38 @@
+// Package plugin registers the Analyzer of copy-righter as a golangci-lint
+// module plugin named copyrighter. Importing it, as the golangci-lint
+// custom build does, registers the plugin; programs that only need the
+// Analyzer import the analyzer package and leave the plugin out.
+package plugin
+
+import (
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/earik87/copy-righter/pkg/analyzer"
+)
+
+func init() {
+	register.Plugin("copyrighter", newPlugin)
+}
+
+// plugin is the golangci-lint module plugin of the Analyzer, configured by
+// the settings of the linter in .golangci.yml.
+type plugin struct {
+	settings analyzer.Settings
+}
+
+func newPlugin(conf any) (register.LinterPlugin, error) {
+	s, err := register.DecodeSettings[analyzer.Settings](conf)
+	if err != nil {
+		return nil, err
+	}
+	return &plugin{settings: s}, nil
+}
+
+func (p *plugin) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	return []*analysis.Analyzer{analyzer.New(p.settings)}, nil
+}
+
+func (p *plugin) GetLoadMode() string {
+	return register.LoadModeSyntax
+}
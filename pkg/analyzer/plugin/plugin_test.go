@@ -0,0 +1,26 @@
+package plugin
+
+import (
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+// TestPluginWrapsLongNotice checks that the plugin wraps notices as the
+// CLI does when its settings leave max-line-length out.
+func TestPluginWrapsLongNotice(t *testing.T) {
+	p, err := newPlugin(map[string]any{"copyright": "Copyright (c) 2025 Example Corp. Licensed to the members of the Example Consortium under the terms of their membership agreement."})
+	if err != nil {
+		t.Fatal(err)
+	}
+	analyzers, err := p.BuildAnalyzers()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir, err := filepath.Abs(filepath.Join("..", "testdata"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysistest.RunWithSuggestedFixes(t, dir, analyzers[0], "b")
+}
@@ -0,0 +1,3 @@
+package a // want "missing copyright header"
+
+var X = 1 // want "missing copyright footer"
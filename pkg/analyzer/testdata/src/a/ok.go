@@ -0,0 +1,5 @@
+// Copyright (c) 2025 Example Corp.
+
+package a
+
+// Copyright (c) 2025 Example Corp.
@@ -0,0 +1,5 @@
+// Copyright (c) 2019 Example Corp. // want "outdated copyright header"
+
+package a
+
+// Copyright (c) 2025 Example Corp.
@@ -0,0 +1,3 @@
+package b // want "missing copyright header"
+
+var X = 1 // want "missing copyright footer"
@@ -0,0 +1,117 @@
+// Package client calls copy-righter serve, so services stamp and check
+// files with the rules of a repository without writing the requests by
+// hand. The types are those the server decodes and encodes, and OpenAPI
+// is the document describing them for clients in other languages.
+package client
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// OpenAPI is the OpenAPI 3 document of serve, which the server also
+// answers at /v1/openapi.yaml.
+//
+//go:embed openapi.yaml
+var OpenAPI []byte
+
+// Request is the body of a request to the stamp and check endpoints.
+// The language is taken from path, which the include, exclude and holders
+// settings are also matched against, or from language: a name such as
+// "Go" or an extension such as "go" or ".go".
+type Request struct {
+	Path     string `json:"path,omitempty"`
+	Language string `json:"language,omitempty"`
+	Content  string `json:"content"`
+}
+
+// Response is the verdict on a request. Status takes the values of the
+// JSON report of a run; content is the stamped content and is only
+// returned by the stamp endpoint.
+type Response struct {
+	Status   string   `json:"status"`
+	Problems []string `json:"problems,omitempty"`
+	Warnings []string `json:"warnings,omitempty"`
+	Actions  []string `json:"actions,omitempty"`
+	Content  *string  `json:"content,omitempty"`
+	// ForeignHeader identifies the other party's header stamp refused to
+	// overwrite.
+	ForeignHeader string `json:"foreign_header,omitempty"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Error is a request the server did not judge: malformed, too large or in
+// an unsupported language.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("copy-righter serve: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Client calls the serve at BaseURL, such as "http://localhost:8080",
+// with HTTPClient, or http.DefaultClient when it is nil.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// New returns a Client of the serve at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Stamp returns the verdict on the content of req with its stamped
+// content.
+func (c *Client) Stamp(ctx context.Context, req Request) (*Response, error) {
+	return c.post(ctx, "/v1/stamp", req)
+}
+
+// Check returns the verdict on the content of req.
+func (c *Client) Check(ctx context.Context, req Request) (*Response, error) {
+	return c.post(ctx, "/v1/check", req)
+}
+
+func (c *Client) post(ctx context.Context, endpoint string, req Request) (*Response, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.BaseURL+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	httpResp, err := httpClient.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(httpResp.Body)
+	if err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		if httpResp.StatusCode != http.StatusOK {
+			return nil, &Error{StatusCode: httpResp.StatusCode, Message: strings.TrimSpace(string(data))}
+		}
+		return nil, fmt.Errorf("decoding the response of %s: %w", endpoint, err)
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return nil, &Error{StatusCode: httpResp.StatusCode, Message: resp.Error}
+	}
+	return &resp, nil
+}
@@ -0,0 +1,84 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TestOpenAPIMatchesTypes checks that the schemas of the OpenAPI document
+// have the fields of Request and Response, with the fields that are never
+// omitted required.
+func TestOpenAPIMatchesTypes(t *testing.T) {
+	var spec struct {
+		Components struct {
+			Schemas map[string]struct {
+				Required   []string       `yaml:"required"`
+				Properties map[string]any `yaml:"properties"`
+			} `yaml:"schemas"`
+		} `yaml:"components"`
+	}
+	if err := yaml.Unmarshal(OpenAPI, &spec); err != nil {
+		t.Fatal(err)
+	}
+	for name, typ := range map[string]reflect.Type{"Request": reflect.TypeFor[Request](), "Response": reflect.TypeFor[Response]()} {
+		schema, ok := spec.Components.Schemas[name]
+		if !ok {
+			t.Errorf("no schema for %s", name)
+			continue
+		}
+		var fields, required []string
+		for i := range typ.NumField() {
+			tag, opts, _ := strings.Cut(typ.Field(i).Tag.Get("json"), ",")
+			fields = append(fields, tag)
+			if opts != "omitempty" {
+				required = append(required, tag)
+			}
+		}
+		var properties []string
+		for p := range schema.Properties {
+			properties = append(properties, p)
+		}
+		slices.Sort(fields)
+		slices.Sort(properties)
+		slices.Sort(required)
+		slices.Sort(schema.Required)
+		if !slices.Equal(fields, properties) {
+			t.Errorf("%s: fields %v, schema properties %v", name, fields, properties)
+		}
+		if !slices.Equal(required, schema.Required) {
+			t.Errorf("%s: required fields %v, schema requires %v", name, required, schema.Required)
+		}
+	}
+}
+
+func TestClientReturnsRefusals(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/check":
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+			w.Write([]byte(`{"status":"failed","error":"invalid request: http: request body too large"}`))
+		default:
+			http.Error(w, "bad gateway", http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+	c := New(server.URL + "/")
+
+	_, err := c.Check(context.Background(), Request{Path: "a.go", Content: "package a\n"})
+	if serr := (*Error)(nil); !errors.As(err, &serr) || serr.StatusCode != http.StatusRequestEntityTooLarge || !strings.Contains(serr.Message, "too large") {
+		t.Errorf("413: %v", err)
+	}
+	_, err = c.Stamp(context.Background(), Request{Path: "a.go", Content: "package a\n"})
+	if serr := (*Error)(nil); !errors.As(err, &serr) || serr.StatusCode != http.StatusBadGateway || serr.Message != "bad gateway" {
+		t.Errorf("502 without a verdict: %v", err)
+	}
+}
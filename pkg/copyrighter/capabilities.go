@@ -0,0 +1,56 @@
+package copyrighter
+
+// FooterSupport says whether the files of a language get a footer.
+type FooterSupport int
+
+const (
+	// FooterDefault stamps a footer unless the config turns it off.
+	FooterDefault FooterSupport = iota
+	// FooterOptIn stamps a footer only when the config turns it on, for
+	// formats where trailing comments are unusual.
+	FooterOptIn
+	// FooterUnsupported never stamps a footer; turning it on is an error.
+	FooterUnsupported
+)
+
+// String is how the languages command shows f.
+func (f FooterSupport) String() string {
+	switch f {
+	case FooterDefault:
+		return "yes"
+	case FooterOptIn:
+		return "opt-in"
+	default:
+		return "no"
+	}
+}
+
+// Capabilities is what copy-righter can do with the files of a language.
+// The matrix is checked when the config is loaded, so a setting a format
+// cannot honour is an error instead of undefined behaviour.
+type Capabilities struct {
+	Header bool
+	Footer FooterSupport
+}
+
+// headerAndFooter is the capability of every language whose comments can
+// appear anywhere in a file.
+var headerAndFooter = Capabilities{Header: true, Footer: FooterDefault}
+
+// headerFooterOptIn is the capability of templates, where the line break
+// before a footer ends up in every rendered page, and of YAML, where a
+// footer after the last document of a stream is read as part of it.
+var headerFooterOptIn = Capabilities{Header: true, Footer: FooterOptIn}
+
+// FooterEnabled reports whether files with extension ext get a footer,
+// given the footers setting.
+func FooterEnabled(ext string, footers map[string]bool) bool {
+	lang, ok := languagesByExtension[ext]
+	if !ok || lang.Caps.Footer == FooterUnsupported {
+		return false
+	}
+	if enabled, set := footers[ext]; set {
+		return enabled
+	}
+	return lang.Caps.Footer == FooterDefault
+}
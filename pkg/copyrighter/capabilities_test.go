@@ -0,0 +1,31 @@
+package copyrighter
+
+import "testing"
+
+func TestFooterEnabled(t *testing.T) {
+	languagesByExtension[".test"] = Language{Name: "Test", Extensions: []string{".test"}, Caps: Capabilities{Header: true, Footer: FooterUnsupported}}
+	languagesByExtension[".opt"] = Language{Name: "Opt", Extensions: []string{".opt"}, Caps: Capabilities{Header: true, Footer: FooterOptIn}}
+	t.Cleanup(func() {
+		delete(languagesByExtension, ".test")
+		delete(languagesByExtension, ".opt")
+	})
+
+	for _, tc := range []struct {
+		ext     string
+		footers map[string]bool
+		want    bool
+	}{
+		{".go", nil, true},
+		{".go", map[string]bool{".go": false}, false},
+		{".opt", nil, false},
+		{".opt", map[string]bool{".opt": true}, true},
+		{".test", nil, false},
+		{".yaml", nil, false},
+		{".yaml", map[string]bool{".yaml": true}, true},
+		{".jinja", nil, false},
+	} {
+		if got := FooterEnabled(tc.ext, tc.footers); got != tc.want {
+			t.Errorf("FooterEnabled(%q, %v) = %v, want %v", tc.ext, tc.footers, got, tc.want)
+		}
+	}
+}
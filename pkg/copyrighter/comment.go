@@ -0,0 +1,506 @@
+package copyrighter
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// CommentStyle describes how a language writes the copyright comment and
+// which leading lines the language requires to stay above it.
+type CommentStyle struct {
+	// linePrefix starts a line comment, such as "//".
+	linePrefix string
+	// blockStart and blockEnd wrap a block comment, such as "/*" and "*/".
+	// They are used for languages without line comments.
+	blockStart, blockEnd string
+	// blockMiddle prefixes the inner lines of a multi-line block comment and
+	// blockClose is the line that ends it, such as " * " and " */".
+	blockMiddle, blockClose string
+	// continuation, when set, replaces the prefix of every text line of a
+	// multi-line notice: linePrefix and a space, or blockMiddle. It lets
+	// the rendered notice match an existing template byte for byte.
+	continuation string
+	// lineAlt is a line comment prefix a block-style language also accepts,
+	// such as "//" in C, so an existing notice written that way is replaced
+	// instead of having the new block stacked on top of it.
+	lineAlt string
+	// directive matches comment lines that instruct the toolchain rather
+	// than document the file, such as Go build constraints. They are never
+	// taken for an existing notice; the header goes above them.
+	directive *regexp.Regexp
+	// codeComment returns the index of the first leading line that
+	// documents the code rather than the file, such as a Go package doc
+	// comment, or len(lines) if there is none. An existing header is never
+	// looked for past it.
+	codeComment func(lines []string) int
+	// preamble matches leading lines that must come first in the file, such
+	// as an XML declaration.
+	preamble []*regexp.Regexp
+}
+
+// format renders copyright text as a single comment line in this style.
+// Text that is already a comment in this style is used as is.
+func (s CommentStyle) format(text string) string {
+	trimmed := strings.TrimSpace(text)
+	if s.IsComment(trimmed) {
+		return trimmed
+	}
+	if s.linePrefix != "" {
+		return s.linePrefix + " " + trimmed
+	}
+	return s.blockStart + " " + trimmed + " " + s.blockEnd
+}
+
+// Render formats copyright text as the lines of a header or footer. Single
+// line text becomes one comment line; multi-line text becomes a run of line
+// comments or one block comment, depending on the style.
+func (s CommentStyle) Render(text string) []string {
+	textLines := strings.Split(strings.Trim(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	if len(textLines) == 1 {
+		return []string{s.format(textLines[0])}
+	}
+	for i, line := range textLines {
+		textLines[i] = strings.TrimRight(line, " \t")
+	}
+
+	if s.linePrefix != "" {
+		prefix := s.linePrefix + " "
+		if s.continuation != "" {
+			prefix = s.continuation
+		}
+		rendered := make([]string, len(textLines))
+		for i, line := range textLines {
+			switch {
+			case strings.HasPrefix(line, s.linePrefix):
+				rendered[i] = line
+			case line == "":
+				rendered[i] = strings.TrimRight(prefix, " ")
+			default:
+				rendered[i] = prefix + line
+			}
+		}
+		return rendered
+	}
+
+	if strings.HasPrefix(textLines[0], s.blockStart) {
+		// Already a complete block comment
+		return textLines
+	}
+	middle := s.blockMiddle
+	if s.continuation != "" {
+		middle = s.continuation
+	}
+	rendered := []string{s.blockStart}
+	for _, line := range textLines {
+		if line == "" {
+			rendered = append(rendered, strings.TrimRight(middle, " "))
+		} else {
+			rendered = append(rendered, middle+line)
+		}
+	}
+	return append(rendered, s.blockClose)
+}
+
+// Wrap breaks the lines of copyright text at spaces so that every line of
+// its rendering fits in width columns, or returns text unchanged when it
+// already does or width is 0. A word longer than a line is not broken.
+func (s CommentStyle) Wrap(text string, width int) string {
+	if width <= 0 {
+		return text
+	}
+	fits := true
+	for _, line := range s.Render(text) {
+		fits = fits && utf8.RuneCountInString(line) <= width
+	}
+	if fits {
+		return text
+	}
+	prefix := s.linePrefix + " "
+	if s.linePrefix == "" {
+		prefix = s.blockMiddle
+	}
+	if s.continuation != "" {
+		prefix = s.continuation
+	}
+	available := width - utf8.RuneCountInString(prefix)
+	var wrapped []string
+	for _, line := range strings.Split(strings.Trim(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 {
+			wrapped = append(wrapped, "")
+			continue
+		}
+		current := words[0]
+		for _, word := range words[1:] {
+			if utf8.RuneCountInString(current)+1+utf8.RuneCountInString(word) > available {
+				wrapped = append(wrapped, current)
+				current = word
+			} else {
+				current += " " + word
+			}
+		}
+		wrapped = append(wrapped, current)
+	}
+	return strings.Join(wrapped, "\n")
+}
+
+// LeadingComment returns how many lines at the start of lines form an
+// existing header that a header of headerLines lines should replace. A
+// single-line header replaces a single comment line; a multi-line header
+// replaces the whole leading comment block.
+func (s CommentStyle) LeadingComment(lines []string, headerLines int) int {
+	if s.codeComment != nil {
+		lines = lines[:s.codeComment(lines)]
+	}
+	if len(lines) == 0 {
+		return 0
+	}
+	if headerLines == 1 {
+		if s.IsComment(lines[0]) && !s.IsDirective(lines[0]) {
+			return 1
+		}
+		return s.leadingNotice(lines)
+	}
+
+	if s.linePrefix != "" {
+		n := 0
+		for n < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[n]), s.linePrefix) && !s.IsDirective(lines[n]) {
+			n++
+		}
+		return n
+	}
+	if !strings.HasPrefix(strings.TrimSpace(lines[0]), s.blockStart) {
+		return s.leadingNotice(lines)
+	}
+	for i, line := range lines {
+		if strings.HasSuffix(strings.TrimSpace(line), s.blockEnd) && (i > 0 || s.IsComment(strings.TrimSpace(line))) {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// TrailingComment is the mirror image of LeadingComment for footers. A
+// headerLines of 0 returns the whole trailing comment block.
+func (s CommentStyle) TrailingComment(lines []string, headerLines int) int {
+	if len(lines) == 0 {
+		return 0
+	}
+	last := len(lines) - 1
+	if headerLines == 1 {
+		if s.IsComment(lines[last]) {
+			return 1
+		}
+		return s.trailingNotice(lines)
+	}
+
+	if s.linePrefix != "" {
+		n := 0
+		for n < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[last-n]), s.linePrefix) {
+			n++
+		}
+		return n
+	}
+	if n := s.trailingBlock(lines); n > 0 || headerLines == 0 {
+		return n
+	}
+	return s.trailingNotice(lines)
+}
+
+// trailingBlock returns the length of the block comment that ends on the
+// last line, or 0 if the last line is not the end of a comment block, such
+// as a comment after code like "#endif /* H */".
+func (s CommentStyle) trailingBlock(lines []string) int {
+	last := len(lines) - 1
+	final := strings.TrimSpace(lines[last])
+	if !strings.HasSuffix(final, s.blockEnd) {
+		return 0
+	}
+	if strings.HasPrefix(final, s.blockStart) {
+		return 1
+	}
+	if strings.Contains(final, s.blockStart) {
+		return 0
+	}
+	for i := last - 1; i >= 0; i-- {
+		line := strings.TrimSpace(lines[i])
+		if strings.HasSuffix(line, s.blockEnd) {
+			// An earlier comment closes here
+			return 0
+		}
+		if strings.HasPrefix(line, s.blockStart) {
+			return len(lines) - i
+		}
+	}
+	return 0
+}
+
+// leadingNotice returns the length of a copyright notice at the start of
+// lines written as a multi-line block comment or a run of lineAlt
+// comments, which a header replaces whole. Comments that do not mention a
+// copyright, such as doc comments, are left alone.
+func (s CommentStyle) leadingNotice(lines []string) int {
+	if s.blockStart == "" {
+		return 0
+	}
+	n := 0
+	first := strings.TrimSpace(lines[0])
+	switch {
+	case s.lineAlt != "" && strings.HasPrefix(first, s.lineAlt):
+		for n < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[n]), s.lineAlt) {
+			n++
+		}
+	case strings.HasPrefix(first, s.blockStart):
+		for i, line := range lines {
+			if i > 0 && strings.HasSuffix(strings.TrimSpace(line), s.blockEnd) {
+				n = i + 1
+				break
+			}
+		}
+	}
+	if n == 0 || !CopyrightPattern.MatchString(strings.Join(lines[:n], "\n")) {
+		return 0
+	}
+	return n
+}
+
+// trailingNotice is the mirror image of leadingNotice for footers.
+func (s CommentStyle) trailingNotice(lines []string) int {
+	if s.blockStart == "" {
+		return 0
+	}
+	last := len(lines) - 1
+	n := 0
+	if s.lineAlt != "" && strings.HasPrefix(strings.TrimSpace(lines[last]), s.lineAlt) {
+		for n < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[last-n]), s.lineAlt) {
+			n++
+		}
+	} else {
+		n = s.trailingBlock(lines)
+	}
+	if n == 0 || !CopyrightPattern.MatchString(strings.Join(lines[len(lines)-n:], "\n")) {
+		return 0
+	}
+	return n
+}
+
+// IsComment reports whether line consists of a single comment in this style.
+func (s CommentStyle) IsComment(line string) bool {
+	if s.linePrefix != "" {
+		return strings.HasPrefix(line, s.linePrefix)
+	}
+	return strings.HasPrefix(line, s.blockStart) && strings.HasSuffix(line, s.blockEnd) &&
+		len(line) >= len(s.blockStart)+len(s.blockEnd)
+}
+
+// IsDirective reports whether line is a toolchain directive in this style
+// or a generated-code marker. Neither is ever replaced by a header.
+func (s CommentStyle) IsDirective(line string) bool {
+	return generatedMarker.MatchString(line) || s.directive != nil && s.directive.MatchString(line)
+}
+
+// IsCommentLike reports whether a trimmed line looks like part of a
+// comment in this style.
+func (s CommentStyle) IsCommentLike(line string) bool {
+	for _, prefix := range []string{s.linePrefix, s.lineAlt, s.blockStart, strings.TrimSpace(s.blockMiddle)} {
+		if prefix != "" && strings.HasPrefix(line, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CodeComment returns the index of the first leading line that documents
+// the code rather than the file, or len(lines) if there is none.
+func (s CommentStyle) CodeComment(lines []string) int {
+	if s.codeComment == nil {
+		return len(lines)
+	}
+	return s.codeComment(lines)
+}
+
+// WithContinuation returns s with prefix replacing the prefix of the text
+// lines of a multi-line notice; an empty prefix keeps the default.
+func (s CommentStyle) WithContinuation(prefix string) CommentStyle {
+	s.continuation = prefix
+	return s
+}
+
+// LinePrefix returns the prefix of a line comment, empty for a language
+// without line comments.
+func (s CommentStyle) LinePrefix() string {
+	return s.linePrefix
+}
+
+// BlockStart and BlockEnd return the delimiters of a block comment, empty
+// for a language without block comments.
+func (s CommentStyle) BlockStart() string {
+	return s.blockStart
+}
+
+func (s CommentStyle) BlockEnd() string {
+	return s.blockEnd
+}
+
+var (
+	xmlDeclaration = regexp.MustCompile(`^\s*<\?xml\b`)
+	doctype        = regexp.MustCompile(`(?i)^\s*<!DOCTYPE\b`)
+	// doctypeLiteral continues a DOCTYPE split across lines, as XHTML
+	// documents do with their system identifier.
+	doctypeLiteral = regexp.MustCompile(`^\s*"[^"]*"\s*>?\s*$`)
+	// Shebang must stay the first line of a script in any language.
+	Shebang = regexp.MustCompile(`^#!`)
+	// pythonEncoding is a PEP 263 source encoding declaration, which must
+	// stay within the first two lines.
+	pythonEncoding = regexp.MustCompile(`^[ \t\f]*#.*?coding[:=][ \t]*[-_.a-zA-Z0-9]+`)
+	// GoBuildConstraint is a //go:build or legacy // +build line. Both must
+	// stay ahead of the package clause, separated from it by a blank line.
+	GoBuildConstraint = regexp.MustCompile(`^\s*//(go:build|\s*\+build)(\s|$)`)
+	// rcCodePage sets the code page a resource script is read in, so it
+	// must come before a notice with non-ASCII characters such as ©.
+	rcCodePage = regexp.MustCompile(`^\s*#\s*pragma\s+code_page\b`)
+)
+
+var (
+	lineGo      = CommentStyle{linePrefix: "//", directive: GoBuildConstraint, codeComment: goPackageDoc}
+	lineRC      = CommentStyle{linePrefix: "//", preamble: []*regexp.Regexp{rcCodePage}}
+	lineIni     = CommentStyle{linePrefix: ";"}
+	lineHash    = CommentStyle{linePrefix: "#"}
+	linePython  = CommentStyle{linePrefix: "#", preamble: []*regexp.Regexp{pythonEncoding}}
+	blockCStyle = CommentStyle{blockStart: "/*", blockEnd: "*/", blockMiddle: " * ", blockClose: " */"}
+	blockCLike  = CommentStyle{blockStart: "/*", blockEnd: "*/", blockMiddle: " * ", blockClose: " */", lineAlt: "//"}
+	blockXML    = CommentStyle{blockStart: "<!--", blockEnd: "-->", blockMiddle: "  ", blockClose: "-->",
+		preamble: []*regexp.Regexp{xmlDeclaration, doctype, doctypeLiteral}}
+
+	// Template engines get their own comment syntax so the notice is never
+	// copied into the rendered output.
+	blockGoTemplate = CommentStyle{blockStart: "{{/*", blockEnd: "*/}}", blockMiddle: "  ", blockClose: "*/}}"}
+	blockHandlebars = CommentStyle{blockStart: "{{!--", blockEnd: "--}}", blockMiddle: "  ", blockClose: "--}}"}
+	blockEJS        = CommentStyle{blockStart: "<%#", blockEnd: "%>", blockMiddle: "  ", blockClose: "%>"}
+	blockJinja      = CommentStyle{blockStart: "{#", blockEnd: "#}", blockMiddle: "  ", blockClose: "#}"}
+)
+
+// goPackageDoc returns the line where the package doc comment of a Go
+// file starts. go/parser attaches any comment directly above the package
+// clause to it, including a header written without a blank line below it,
+// so a doc comment that mentions a copyright counts as a header unless it
+// also has the conventional "Package name" sentence, which then starts the
+// documentation.
+func goPackageDoc(lines []string) int {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", strings.Join(upToPackageClause(lines), "\n"), parser.PackageClauseOnly|parser.ParseComments)
+	if err != nil {
+		// The package clause found was inside a comment
+		fset = token.NewFileSet()
+		f, err = parser.ParseFile(fset, "", strings.Join(lines, "\n"), parser.PackageClauseOnly|parser.ParseComments)
+	}
+	if err != nil || f.Doc == nil {
+		return len(lines)
+	}
+	first := fset.Position(f.Doc.Pos()).Line - 1
+	last := fset.Position(f.Doc.End()).Line - 1
+	for i := first; i <= last && i < len(lines); i++ {
+		text := strings.TrimLeft(strings.TrimSpace(lines[i]), "/* ")
+		if strings.HasPrefix(text, "Package "+f.Name.Name+" ") || text == "Package "+f.Name.Name {
+			return i
+		}
+	}
+	if CopyrightPattern.MatchString(strings.Join(lines[first:last+1], "\n")) {
+		return len(lines)
+	}
+	return first
+}
+
+// upToPackageClause returns the lines of a Go file up to the first one
+// that looks like its package clause, so a huge file is not joined and
+// scanned only to find it.
+func upToPackageClause(lines []string) []string {
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "package ") {
+			return lines[:i+1]
+		}
+	}
+	return lines
+}
+
+// Language declares how the files of one kind are stamped. Supporting a
+// new language means adding an entry to Languages; nothing else in the
+// package assumes a particular comment syntax.
+type Language struct {
+	Name       string
+	Extensions []string
+	Style      CommentStyle
+	Caps       Capabilities
+}
+
+// Languages lists every language copy-righter can stamp.
+var Languages = []Language{
+	{"Go", []string{".go"}, lineGo, headerAndFooter},
+	{"CSS", []string{".css", ".scss", ".less"}, blockCStyle, headerAndFooter},
+	{"HTML", []string{".html", ".htm"}, blockXML, headerAndFooter},
+	{"XML", []string{".xml"}, blockXML, headerAndFooter},
+	{"SVG", []string{".svg"}, blockXML, headerAndFooter},
+	{"Vue", []string{".vue"}, blockXML, headerAndFooter},
+	{"C", []string{".c", ".h"}, blockCLike, headerAndFooter},
+	{"C++", []string{".cc", ".cpp", ".hpp"}, blockCLike, headerAndFooter},
+	{"Java", []string{".java"}, blockCLike, headerAndFooter},
+	{"JavaScript", []string{".js", ".mjs", ".jsx"}, blockCLike, headerAndFooter},
+	{"TypeScript", []string{".ts", ".tsx"}, blockCLike, headerAndFooter},
+	{"Python", []string{".py"}, linePython, headerAndFooter},
+	{"Shell", []string{".sh"}, lineHash, headerAndFooter},
+	{"YAML", []string{".yaml", ".yml"}, lineHash, headerFooterOptIn},
+	{"TOML", []string{".toml"}, lineHash, headerAndFooter},
+	{"Go template", []string{".tmpl", ".gotmpl"}, blockGoTemplate, headerFooterOptIn},
+	{"Handlebars", []string{".hbs"}, blockHandlebars, headerFooterOptIn},
+	{"EJS", []string{".ejs"}, blockEJS, headerFooterOptIn},
+	{"Jinja", []string{".jinja"}, blockJinja, headerFooterOptIn},
+	{"Windows resource", []string{".rc"}, lineRC, headerAndFooter},
+	{"Inno Setup", []string{".iss"}, lineIni, headerAndFooter},
+}
+
+// languagesByExtension maps lower-case file extensions to the language
+// they are stamped as.
+var languagesByExtension = byExtension(Languages)
+
+func byExtension(langs []Language) map[string]Language {
+	byExt := make(map[string]Language)
+	for _, lang := range langs {
+		for _, ext := range lang.Extensions {
+			if _, dup := byExt[ext]; dup {
+				panic("copy-righter: extension " + ext + " declared by more than one language")
+			}
+			byExt[ext] = lang
+		}
+	}
+	return byExt
+}
+
+// Extensions lists the file extensions copy-righter knows how to stamp.
+var Extensions = sortedExtensions(languagesByExtension)
+
+func sortedExtensions(byExt map[string]Language) []string {
+	exts := make([]string, 0, len(byExt))
+	for ext := range byExt {
+		exts = append(exts, ext)
+	}
+	sort.Strings(exts)
+	return exts
+}
+
+// LanguageFor returns the language of a file based on its extension. An
+// extension on its own, such as ".go", names the same language.
+func LanguageFor(path string) (Language, bool) {
+	lang, ok := languagesByExtension[strings.ToLower(filepath.Ext(path))]
+	return lang, ok
+}
+
+// StyleFor returns the comment style for a file based on its extension.
+func StyleFor(path string) (CommentStyle, bool) {
+	lang, ok := LanguageFor(path)
+	return lang.Style, ok
+}
@@ -0,0 +1,90 @@
+package copyrighter
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestLanguagesRecogniseTheirOwnNotices checks every declared language can
+// find the header and footer it renders, so a new entry in Languages is
+// enough to support a file type.
+func TestLanguagesRecogniseTheirOwnNotices(t *testing.T) {
+	texts := []string{
+		"Copyright (c) 2025 Example Corp.",
+		"Copyright (c) 2025 Example Corp.\n\nLicensed under the Apache License, Version 2.0.",
+	}
+	for _, lang := range Languages {
+		for _, text := range texts {
+			notice := lang.Style.Render(text)
+			lines := append(append(append([]string{}, notice...), "", "body", ""), notice...)
+
+			if got := lang.Style.LeadingComment(lines, len(notice)); got != len(notice) {
+				t.Errorf("%s: leadingComment = %d, want %d for %q", lang.Name, got, len(notice), notice)
+			}
+			if got := lang.Style.TrailingComment(lines, len(notice)); got != len(notice) {
+				t.Errorf("%s: trailingComment = %d, want %d for %q", lang.Name, got, len(notice), notice)
+			}
+			if len(notice) == 1 && !lang.Style.IsComment(notice[0]) {
+				t.Errorf("%s: %q is not recognised as a comment", lang.Name, notice[0])
+			}
+		}
+	}
+}
+
+func TestContinuationPrefix(t *testing.T) {
+	text := "Copyright (c) 2025 Example Corp.\n\nAll rights reserved."
+	for _, tc := range []struct {
+		style        CommentStyle
+		continuation string
+		want         []string
+	}{
+		{blockCLike, " ** ", []string{"/*", " ** Copyright (c) 2025 Example Corp.", " **", " ** All rights reserved.", " */"}},
+		{lineHash, "#  ", []string{"#  Copyright (c) 2025 Example Corp.", "#", "#  All rights reserved."}},
+	} {
+		style := tc.style
+		style.continuation = tc.continuation
+		notice := style.Render(text)
+		if strings.Join(notice, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("render with continuation %q = %q, want %q", tc.continuation, notice, tc.want)
+		}
+		lines := append(append(append([]string{}, notice...), "", "body", ""), notice...)
+		if got := style.LeadingComment(lines, len(notice)); got != len(notice) {
+			t.Errorf("continuation %q: leadingComment = %d, want %d", tc.continuation, got, len(notice))
+		}
+	}
+}
+
+func TestMergeHeader(t *testing.T) {
+	const text = "Copyright (c) 2025 Example Corp."
+	for _, tc := range []struct {
+		name, ext, content, want string
+	}{
+		{"line comments", ".sh", "# Deploy helper.\necho hi\n",
+			"# Copyright (c) 2025 Example Corp.\n#\n# Deploy helper.\necho hi\n\n# Copyright (c) 2025 Example Corp.\n"},
+		{"block comment", ".c", "/**\n * Widget rendering.\n */\nint x;\n",
+			"/**\n * Copyright (c) 2025 Example Corp.\n *\n * Widget rendering.\n */\nint x;\n\n/* Copyright (c) 2025 Example Corp. */\n"},
+		{"outdated notice", ".sh", "# Copyright (c) 2019 Old Corp.\n#\n# Deploy helper.\necho hi\n",
+			"# Copyright (c) 2025 Example Corp.\n#\n# Deploy helper.\necho hi\n\n# Copyright (c) 2025 Example Corp.\n"},
+		{"go package doc", ".go", "// Package billing handles invoices.\npackage billing\n",
+			"// Copyright (c) 2025 Example Corp.\n\n// Package billing handles invoices.\npackage billing\n\n// Copyright (c) 2025 Example Corp.\n"},
+	} {
+		style, _ := StyleFor("file" + tc.ext)
+		opts := Options{Copyright: text, Style: style, MergeHeader: true}
+		got, _, err := Stamp(tc.content, opts)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: stamped\n%s\nwant\n%s", tc.name, got, tc.want)
+		}
+		if again, result, _ := Stamp(got, opts); again != got || result.Changed() {
+			t.Errorf("%s: second stamp changed the file:\n%s", tc.name, again)
+		}
+		if tc.name == "outdated notice" {
+			continue
+		}
+		if removed, _, _ := Remove(got, opts); removed != tc.content {
+			t.Errorf("%s: removing the notice left\n%s\nwant\n%s", tc.name, removed, tc.content)
+		}
+	}
+}
@@ -0,0 +1,508 @@
+// Package copyrighter stamps and checks copyright headers and footers. It
+// is the core of the copy-righter command, usable by other Go tools such as
+// linters and bots without running the command: Stamp and Remove rewrite
+// the content of one file for a comment style, and a Processor picks the
+// style and footer settings from a file's path.
+//
+// Nothing here reads or writes files; callers pass content in and write
+// the result back themselves.
+package copyrighter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"regexp"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Action describes what Stamp or Remove did to a header or footer.
+type Action int
+
+const (
+	UpToDate Action = iota
+	Added
+	Updated
+	Removed
+)
+
+// Result records the header and footer actions taken by Stamp or Remove.
+type Result struct {
+	Header Action
+	Footer Action
+	// KeptTrailingComment is set when the last line was a comment that
+	// looked meaningful, so the footer was added below it instead.
+	KeptTrailingComment bool
+	// NoFooter is set when the footer is turned off for the file.
+	NoFooter bool
+	// LeftHeader and LeftFooter are set when an outdated header or footer
+	// was left as it is, see Options.
+	LeftHeader, LeftFooter bool
+}
+
+// Changed reports whether the header or footer needs a change.
+func (r Result) Changed() bool {
+	return r.Header != UpToDate || r.Footer != UpToDate
+}
+
+// Options controls how Stamp places the header and footer.
+type Options struct {
+	// Copyright is the text of the notice, rendered as a comment in Style.
+	Copyright string
+	Style     CommentStyle
+	// Preamble matches leading lines that must stay above the header, such
+	// as export-control notices mandated by regulation.
+	Preamble []*regexp.Regexp
+	// MergeHeader puts the header at the top of a leading comment block
+	// that describes the file instead of in a block of its own.
+	MergeHeader bool
+	// SPDX is the SPDX license expression added to the header, if any.
+	SPDX string
+	// MaintainedBy is the ownership annotation added to the header, if any,
+	// such as "team-payments (review 2026-01)".
+	MaintainedBy string
+	// TemplateVersion is the version of the notice template added to the
+	// header, if any, so the adoption of a new legal text can be tracked.
+	TemplateVersion string
+	// UpdateYearRange extends the template's year into a range starting at
+	// the year of an existing notice instead of overwriting that year.
+	UpdateYearRange bool
+	// NoFooter stamps the header only, for languages whose footer is
+	// turned off.
+	NoFooter bool
+	// MaxLineLength wraps copyright text whose comment lines would be
+	// longer onto several lines, see CommentStyle.Wrap; 0 does not wrap.
+	MaxLineLength int
+	// BlankLines is how many blank lines separate the header from the code
+	// and the code from the footer; an existing notice is respaced to
+	// match. Nil puts one blank line next to a new notice and keeps the
+	// spacing of an existing one.
+	BlankLines *BlankLines
+	// LeaveHeader and LeaveFooter keep an existing header or footer as it
+	// is, for findings below the fix severity or runs that manage only the
+	// other part. Remove leaves it in place too.
+	LeaveHeader, LeaveFooter bool
+	// NoticePatterns recognise the comment at the top or bottom of a file
+	// as a notice to update when one of its first NoticeLines lines
+	// matches one of them, so a header differing only in its year or
+	// spacing is replaced. Other comments, such as a file description,
+	// are kept and the notice is added next to them. Nil treats every
+	// such comment as a notice.
+	NoticePatterns []*regexp.Regexp
+	// NoticeLines defaults to DefaultNoticeLines.
+	NoticeLines int
+	// MatchPatterns recognise legacy header formats: a leading comment
+	// block one of whose first NoticeLines lines matches one of them is
+	// replaced as a whole, however many lines the new header has.
+	MatchPatterns []*regexp.Regexp
+}
+
+// DefaultMaxLineLength is the column the CLI wraps notices at, short
+// enough for line length linters such as lll.
+const DefaultMaxLineLength = 100
+
+// BlankLines is a blank-line policy, see Options.BlankLines.
+type BlankLines struct {
+	Header, Footer int
+}
+
+// MaxBlankLines is the most blank lines BlankLines can ask for.
+const MaxBlankLines = 2
+
+// gaps returns the blank lines after the header and before the footer.
+func (o Options) gaps() (header, footer int) {
+	if o.BlankLines == nil {
+		return 1, 1
+	}
+	return o.BlankLines.Header, o.BlankLines.Footer
+}
+
+// DefaultNoticeLines is how many lines of an existing comment
+// NoticePatterns are matched against by default.
+const DefaultNoticeLines = 10
+
+// The tags of the lines HeaderText adds below the copyright text.
+const (
+	// SPDXTag introduces the license identifier line.
+	SPDXTag = "SPDX-License-Identifier:"
+	// MaintainedTag introduces the ownership annotation.
+	MaintainedTag = "Maintained-by:"
+	// TemplateVersionTag introduces the template version line.
+	TemplateVersionTag = "Template-Version:"
+)
+
+// SPDXExpression loosely validates an SPDX license expression such as
+// "Apache-2.0" or "(MIT OR GPL-2.0-or-later)".
+var SPDXExpression = regexp.MustCompile(`^[A-Za-z0-9.+\-() ]+$`)
+
+// Stamp returns content with opts.Copyright as its header and, unless
+// opts.NoFooter is set, its footer, replacing an outdated notice, and
+// what it changed. The header also carries the SPDX identifier,
+// ownership annotation and template version of opts when they are set.
+// Only the head and tail of a large file are looked at, see WindowSize.
+func Stamp(content string, opts Options) (string, Result, error) {
+	if rest, ok := strings.CutPrefix(content, utf8BOM); ok {
+		stamped, result, err := Stamp(rest, opts)
+		return utf8BOM + stamped, result, err
+	}
+	if stamped, result, ok, err := windowed(content, opts, Stamp); ok {
+		return stamped, result, err
+	}
+	var result Result
+	style := opts.Style
+	hadTrailingNewline := strings.HasSuffix(content, "\n")
+
+	preamble, lines := SplitContent(content, opts)
+	header, footer := ExpectedNotice(lines, opts)
+	headerHash := NoticeHash(header)
+	footerHash := NoticeHash(footer)
+	headerGap, footerGap := opts.gaps()
+	strict := opts.BlankLines != nil
+
+	if len(lines) == 0 {
+		// Empty file (or nothing after the preamble), just add copyright header and footer
+		result.NoFooter = opts.NoFooter
+		if !opts.LeaveHeader {
+			lines = header
+			result.Header = Added
+		}
+		if !opts.NoFooter && !opts.LeaveFooter {
+			if len(lines) > 0 {
+				lines = joinBlocks(lines, blank(footerGap))
+			}
+			lines = joinBlocks(lines, footer)
+			result.Footer = Added
+		}
+		if !result.Changed() {
+			return content, result, nil
+		}
+		return strings.Join(withPreamble(preamble, lines), "\n") + "\n", result, nil
+	}
+
+	// Check and update header
+	if opts.LeaveHeader {
+		result.LeftHeader = true
+	} else if len(lines) >= len(header) && NoticeHash(lines[:len(header)]) == headerHash {
+		result.Header = UpToDate
+		if strict {
+			if spaced, changed := spaceHeader(lines, len(header), headerGap, opts); changed {
+				lines = spaced
+				result.Header = Updated
+			}
+		}
+	} else if merged, action := mergeHeader(lines, header, opts); merged != nil {
+		lines = merged
+		result.Header = action
+	} else if existing := existingHeader(lines, len(header), opts); existing > 0 {
+		rest := lines[existing:]
+		if strict {
+			lines = joinBlocks(header, blank(headerGap), rest[leadingBlanks(rest):])
+		} else if len(rest) > 0 && rest[0] == "" {
+			// Keep blank line after header
+			lines = joinBlocks(header, rest)
+		} else {
+			lines = joinBlocks(header, []string{""}, rest)
+		}
+		result.Header = Updated
+	} else {
+		// No copyright found, add at top
+		if strict {
+			lines = lines[leadingBlanks(lines):]
+		}
+		lines = joinBlocks(header, blank(headerGap), lines)
+		result.Header = Added
+	}
+
+	// Check and update footer
+	if opts.NoFooter {
+		result.NoFooter = true
+	} else if opts.LeaveFooter {
+		result.LeftFooter = true
+	} else if len(lines) >= len(footer) && NoticeHash(lines[len(lines)-len(footer):]) == footerHash {
+		result.Footer = UpToDate
+		if strict {
+			if spaced, changed := spaceFooter(lines, len(footer), footerGap); changed {
+				lines = spaced
+				result.Footer = Updated
+			}
+		}
+	} else if existing := style.TrailingComment(lines, len(footer)); existing > 0 && (isMeaningfulTrailingComment(lines, style) || !IsNotice(lines[len(lines)-existing:], opts)) {
+		// The trailing comment belongs to the code, keep it and add the footer below
+		if strict {
+			lines = lines[:len(lines)-trailingBlanks(lines)]
+		}
+		lines = joinBlocks(lines, blank(footerGap), footer)
+		result.Footer = Added
+		result.KeptTrailingComment = true
+	} else if existing > 0 {
+		before := lines[:len(lines)-existing]
+		// Check if there's a blank line before the footer comment
+		if strict {
+			lines = joinBlocks(before[:len(before)-trailingBlanks(before)], blank(footerGap), footer)
+		} else if len(before) > 0 && before[len(before)-1] == "" {
+			lines = joinBlocks(before, footer)
+		} else {
+			lines = joinBlocks(before, []string{""}, footer)
+		}
+		result.Footer = Updated
+	} else {
+		// No copyright footer found, add at bottom
+		if strict {
+			lines = lines[:len(lines)-trailingBlanks(lines)]
+		}
+		lines = joinBlocks(lines, blank(footerGap), footer)
+		result.Footer = Added
+	}
+
+	// Determine if we should add trailing newline:
+	// - If adding a new footer: always add trailing newline (Go idiomatic)
+	// - If updating existing footer: preserve original format (developer's responsibility)
+	out := strings.Join(withPreamble(preamble, lines), "\n")
+	if result.Footer == Added {
+		// New footer - add trailing newline
+		out += "\n"
+	} else if hadTrailingNewline {
+		// Updating footer and original had trailing newline - preserve it
+		out += "\n"
+	}
+	// Otherwise: updating footer without original trailing newline - don't add one
+
+	return out, result, nil
+}
+
+// SplitContent splits content into lines and separates the leading lines
+// that must stay above the header. A byte order mark is left out; the
+// functions that rewrite content put it back.
+func SplitContent(content string, opts Options) (preamble, lines []string) {
+	return SplitPreamble(splitLines(strings.TrimPrefix(content, utf8BOM)), PreamblePatterns(opts.Style, opts.Preamble))
+}
+
+// utf8BOM is the byte order mark some editors on Windows write at the start
+// of UTF-8 files. It stays first in a stamped file, above the header, and
+// is not part of the first line when comparing it to a notice.
+const utf8BOM = "\ufeff"
+
+// splitLines splits content into lines like bufio.ScanLines, without its
+// limit on the length of a line and with a single allocation however many
+// lines there are, so minified files and pathological inputs cost time
+// linear in their size. Unlike ScanLines it drops every trailing carriage
+// return, not just one, or a stray one would be left to the next run.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, "\r")
+	}
+	return lines
+}
+
+// PreamblePatterns returns the patterns matching leading lines that stay
+// above the header: a shebang in any language, then the style's own
+// preamble and the configured extra patterns.
+func PreamblePatterns(style CommentStyle, extra []*regexp.Regexp) []*regexp.Regexp {
+	patterns := []*regexp.Regexp{Shebang}
+	patterns = append(patterns, style.preamble...)
+	return append(patterns, extra...)
+}
+
+// ExpectedNotice returns the header and footer lines the options require
+// for a file whose lines after the preamble are lines.
+func ExpectedNotice(lines []string, opts Options) (header, footer []string) {
+	text := opts.Style.Wrap(norm.NFC.String(opts.Copyright), opts.MaxLineLength)
+	footer = opts.Style.Render(text)
+	header = footer
+	if full := HeaderText(text, opts.SPDX, opts.MaintainedBy, opts.TemplateVersion); full != text {
+		header = opts.Style.Render(full)
+	}
+	if opts.UpdateYearRange && len(lines) > 0 {
+		existingHeader := lines[:min(len(header), len(lines))]
+		existingFooter := lines[len(lines)-min(len(footer), len(lines)):]
+		header = extendYearRange(header, existingHeader, existingFooter)
+		footer = extendYearRange(footer, existingHeader, existingFooter)
+	}
+	return header, footer
+}
+
+// HeaderText is the copyright text followed by the SPDX identifier, the
+// ownership annotation and the template version lines, when they are set.
+func HeaderText(text, spdx, maintainedBy, templateVersion string) string {
+	if spdx != "" {
+		text += "\n" + SPDXTag + " " + spdx
+	}
+	if maintainedBy != "" {
+		text += "\n" + MaintainedTag + " " + maintainedBy
+	}
+	if templateVersion != "" {
+		text += "\n" + TemplateVersionTag + " " + templateVersion
+	}
+	return text
+}
+
+// existingHeader returns the number of lines at the start of lines that
+// form the notice a header of headerLines lines replaces, 0 if none.
+func existingHeader(lines []string, headerLines int, opts Options) int {
+	if len(opts.MatchPatterns) > 0 {
+		block := lines[:opts.Style.LeadingComment(lines, 0)]
+		for _, line := range block[:min(opts.noticeLines(), len(block))] {
+			if matchesAny(line, opts.MatchPatterns) {
+				return len(block)
+			}
+		}
+	}
+	if existing := opts.Style.LeadingComment(lines, headerLines); existing > 0 && IsNotice(lines[:existing], opts) {
+		return existing
+	}
+	return 0
+}
+
+func (o Options) noticeLines() int {
+	if o.NoticeLines <= 0 {
+		return DefaultNoticeLines
+	}
+	return o.NoticeLines
+}
+
+// IsNotice reports whether block, an existing comment at the top or bottom
+// of a file, is recognised as a notice by the NoticePatterns of opts.
+func IsNotice(block []string, opts Options) bool {
+	if opts.NoticePatterns == nil {
+		return true
+	}
+	for _, line := range block[:min(opts.noticeLines(), len(block))] {
+		if matchesAny(line, opts.NoticePatterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// blank returns n blank lines to separate a notice from the code.
+func blank(n int) []string {
+	return make([]string, n)
+}
+
+// leadingBlanks and trailingBlanks count the blank lines at the start and
+// end of lines.
+func leadingBlanks(lines []string) int {
+	n := 0
+	for n < len(lines) && lines[n] == "" {
+		n++
+	}
+	return n
+}
+
+func trailingBlanks(lines []string) int {
+	n := 0
+	for n < len(lines) && lines[len(lines)-1-n] == "" {
+		n++
+	}
+	return n
+}
+
+// spaceHeader returns lines with gap blank lines between the header, its
+// first n lines, and the code, and whether that changed them. A file
+// holding only the header, or a header merged into the comment block
+// below it, is left as it is.
+func spaceHeader(lines []string, n, gap int, opts Options) ([]string, bool) {
+	rest := lines[n:]
+	blanks := leadingBlanks(rest)
+	if blanks == gap || blanks == len(rest) || opts.MergeHeader && blanks == 0 && opts.Style.LeadingComment(rest, 0) > 0 {
+		return lines, false
+	}
+	return joinBlocks(lines[:n], blank(gap), rest[blanks:]), true
+}
+
+// spaceFooter is spaceHeader for a footer of n lines.
+func spaceFooter(lines []string, n, gap int) ([]string, bool) {
+	before := lines[:len(lines)-n]
+	blanks := trailingBlanks(before)
+	if blanks == gap || blanks == len(before) {
+		return lines, false
+	}
+	return joinBlocks(before[:len(before)-blanks], blank(gap), lines[len(lines)-n:]), true
+}
+
+// joinBlocks concatenates runs of lines into a new slice.
+func joinBlocks(blocks ...[]string) []string {
+	var n int
+	for _, b := range blocks {
+		n += len(b)
+	}
+	joined := make([]string, 0, n)
+	for _, b := range blocks {
+		joined = append(joined, b...)
+	}
+	return joined
+}
+
+// SplitPreamble separates the leading lines matching any of the preamble
+// patterns from the rest of the file. Blank lines between the preamble and
+// the body are dropped; withPreamble puts exactly one back.
+func SplitPreamble(lines []string, patterns []*regexp.Regexp) (preamble, body []string) {
+	n := 0
+	for n < len(lines) && matchesAny(lines[n], patterns) {
+		n++
+	}
+	if n == 0 {
+		return nil, lines
+	}
+	body = lines[n:]
+	for len(body) > 0 && strings.TrimSpace(body[0]) == "" {
+		body = body[1:]
+	}
+	return lines[:n:n], body
+}
+
+// withPreamble joins a protected preamble back onto the stamped body.
+func withPreamble(preamble, body []string) []string {
+	if len(preamble) == 0 {
+		return body
+	}
+	return append(append(preamble, ""), body...)
+}
+
+func matchesAny(s string, patterns []*regexp.Regexp) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	// annotationPattern matches comments that reference work items or links:
+	// TODO/FIXME markers, ticket keys such as ABC-123, issue numbers and URLs.
+	annotationPattern = regexp.MustCompile(`(?i:\b(?:TODO|FIXME|XXX|HACK)\b)|\b[A-Z][A-Z0-9]+-[0-9]+\b|#[0-9]+\b|https?://`)
+	// CopyrightPattern matches comments that look like a copyright notice.
+	CopyrightPattern = regexp.MustCompile(`(?i)copyright|©|\(c\)`)
+)
+
+// isMeaningfulTrailingComment reports whether the comment on the last line
+// should be kept rather than overwritten by the footer. A comment is kept
+// when it references a ticket or URL, or when its comment block is attached
+// to code with no blank line in between. Comments that already look like a
+// copyright notice are never considered meaningful.
+func isMeaningfulTrailingComment(lines []string, style CommentStyle) bool {
+	start := len(lines) - max(1, style.TrailingComment(lines, 0))
+	block := lines[start:]
+	for _, line := range block {
+		if CopyrightPattern.MatchString(line) {
+			return false
+		}
+	}
+	for _, line := range block {
+		if annotationPattern.MatchString(line) {
+			return true
+		}
+	}
+	return start > 0 && strings.TrimSpace(lines[start-1]) != ""
+}
+
+func hash(s string) string {
+	h := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(h[:])
+}
@@ -0,0 +1,151 @@
+package copyrighter
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestNoticePatterns(t *testing.T) {
+	const notice = "# Copyright (c) 2025 Example Corp."
+	recognise := []*regexp.Regexp{CopyrightPattern}
+	for _, tc := range []struct {
+		name     string
+		content  string
+		patterns []*regexp.Regexp
+		want     string
+	}{
+		{"old notice", "# Copyright 2019 Example Corp\n\nx = 1\n", recognise,
+			notice + "\n\nx = 1\n\n" + notice + "\n"},
+		{"modeline kept", "# vim: set ts=4:\n\nx = 1\n\n# vim: ft=python\n", recognise,
+			notice + "\n\n# vim: set ts=4:\n\nx = 1\n\n# vim: ft=python\n\n" + notice + "\n"},
+		{"notice below the lines looked at", "# vim: set ts=4:\n#\n#\n#\n#\n#\n#\n#\n#\n#\n# Copyright 2019 Example Corp\n\nx = 1\n", recognise,
+			notice + "\n\n# vim: set ts=4:\n#\n#\n#\n#\n#\n#\n#\n#\n#\n# Copyright 2019 Example Corp\n\nx = 1\n\n" + notice + "\n"},
+		{"any comment without patterns", "# vim: set ts=4:\n\nx = 1\n\n# vim: ft=python\n", nil,
+			notice + "\n\nx = 1\n\n" + notice + "\n"},
+	} {
+		opts := Options{Copyright: "Copyright (c) 2025 Example Corp.", Style: lineHash, NoticePatterns: tc.patterns}
+		got, _, err := Stamp(tc.content, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: Stamp = %q, want %q", tc.name, got, tc.want)
+		}
+		if again, result, _ := Stamp(got, opts); again != got || result.Changed() {
+			t.Errorf("%s: second Stamp changed %q to %q", tc.name, got, again)
+		}
+	}
+}
+
+func TestBlankLines(t *testing.T) {
+	const notice = "# Copyright (c) 2025 Example Corp."
+	for _, tc := range []struct {
+		name    string
+		content string
+		blank   BlankLines
+		want    string
+	}{
+		{"new notice, none", "x = 1\n", BlankLines{0, 0},
+			notice + "\nx = 1\n" + notice + "\n"},
+		{"new notice, two", "\nx = 1\n\n", BlankLines{2, 2},
+			notice + "\n\n\nx = 1\n\n\n" + notice + "\n"},
+		{"respaced", notice + "\n\n\n\nx = 1\n" + notice + "\n", BlankLines{1, 2},
+			notice + "\n\nx = 1\n\n\n" + notice + "\n"},
+		{"outdated notice", "# Copyright 2019 Example Corp\nx = 1\n# Copyright 2019 Example Corp\n", BlankLines{1, 1},
+			notice + "\n\nx = 1\n\n" + notice + "\n"},
+	} {
+		opts := Options{Copyright: "Copyright (c) 2025 Example Corp.", Style: lineHash, NoticePatterns: []*regexp.Regexp{CopyrightPattern}, BlankLines: &tc.blank}
+		got, _, err := Stamp(tc.content, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: Stamp = %q, want %q", tc.name, got, tc.want)
+		}
+		if again, result, _ := Stamp(got, opts); again != got || result.Changed() {
+			t.Errorf("%s: second Stamp changed %q to %q", tc.name, got, again)
+		}
+		if removed, _, _ := Remove(got, opts); removed != "x = 1\n" {
+			t.Errorf("%s: Remove = %q", tc.name, removed)
+		}
+	}
+}
+
+func TestMaxLineLength(t *testing.T) {
+	const text = "Copyright (c) 2025 Example Corp. All rights reserved. Licensed under the Example License."
+	for _, tc := range []struct {
+		name  string
+		style CommentStyle
+		width int
+		want  string
+	}{
+		{"fits", lineHash, 100, "# " + text + "\n\nx = 1\n\n# " + text + "\n"},
+		{"line comments", lineHash, 40,
+			"# Copyright (c) 2025 Example Corp. All\n# rights reserved. Licensed under the\n# Example License.\n\nx = 1\n\n# Copyright (c) 2025 Example Corp. All\n# rights reserved. Licensed under the\n# Example License.\n"},
+		{"block comment", blockCStyle, 40,
+			"/*\n * Copyright (c) 2025 Example Corp. All\n * rights reserved. Licensed under the\n * Example License.\n */\n\nx = 1\n\n/*\n * Copyright (c) 2025 Example Corp. All\n * rights reserved. Licensed under the\n * Example License.\n */\n"},
+	} {
+		opts := Options{Copyright: text, Style: tc.style, MaxLineLength: tc.width}
+		got, _, err := Stamp("x = 1\n", opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tc.want {
+			t.Errorf("%s: Stamp = %q, want %q", tc.name, got, tc.want)
+		}
+		if again, result, _ := Stamp(got, opts); again != got || result.Changed() {
+			t.Errorf("%s: second Stamp changed %q to %q", tc.name, got, again)
+		}
+	}
+}
+
+func TestIsIgnored(t *testing.T) {
+	for _, tc := range []struct {
+		content string
+		want    bool
+	}{
+		{"// copy-righter:ignore\npackage main\n", true},
+		{"#!/bin/sh\n# copy-righter:ignore copied from upstream\n", true},
+		{"/* copy-righter:ignore */\nbody {}\n", true},
+		{"<!-- copy-righter:ignore -->\n<html></html>\n", true},
+		{"package main\n\nvar s = \"// copy-righter:ignore\"\n", false},
+		{"// copy-righter:ignored\n", false},
+		{strings.Repeat("\n", IgnoreLines) + "// copy-righter:ignore\n", false},
+	} {
+		if got := IsIgnored(tc.content); got != tc.want {
+			t.Errorf("IsIgnored(%q) = %v, want %v", tc.content, got, tc.want)
+		}
+	}
+}
+
+func TestStampLargeFile(t *testing.T) {
+	const notice = "# Copyright (c) 2025 Example Corp."
+	opts := Options{Copyright: "Copyright (c) 2025 Example Corp.", Style: lineHash}
+	// A line longer than a window in the middle, as in minified code.
+	code := strings.Repeat("x = 1\n", 2*WindowSize/6) + "data = '" + strings.Repeat("a", 2*WindowSize) + "'\n" + strings.Repeat("y = 2\n", 2*WindowSize/6)
+	want := notice + "\n\n" + code + "\n" + notice + "\n"
+
+	got, result, err := Stamp(code, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != want || result.Header != Added || result.Footer != Added {
+		t.Errorf("Stamp: %v, header %v, footer %v", got == want, result.Header, result.Footer)
+	}
+	if again, result, _ := Stamp(got, opts); again != got || result.Changed() {
+		t.Error("second Stamp changed the file")
+	}
+	if removed, _, _ := Remove(got, opts); removed != code {
+		t.Error("Remove did not restore the file")
+	}
+
+	// A header whose comment runs past the head is replaced as a whole.
+	block := Options{Copyright: opts.Copyright, Style: blockCStyle, SPDX: "MIT"}
+	header := "/*\n * Copyright (c) 2025 Example Corp.\n * SPDX-License-Identifier: MIT\n */"
+	open := "/*\n" + strings.Repeat(" * x\n", WindowSize/5) + code + " */\nz = 3\n"
+	got, _, err = Stamp(open, block)
+	if want := header + "\n\nz = 3\n\n/* Copyright (c) 2025 Example Corp. */\n"; err != nil || got != want {
+		t.Errorf("Stamp of a long header = %.80q, want %q", got, want)
+	}
+}
@@ -0,0 +1,52 @@
+package copyrighter
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+// generatedMarker matches the canonical "Code generated ... DO NOT EDIT."
+// line in the comment syntax of any supported language. Stamping generated
+// files only causes churn, since the generator drops the notice again.
+var generatedMarker = regexp.MustCompile(`^\s*(//|#|;|--|/\*+|\*|<!--|\{\{/\*|\{\{!--|<%#|\{#)\s*Code generated .* DO NOT EDIT\.`)
+
+// IsGenerated reports whether any line of content matches the canonical
+// generated-code marker or one of the extra patterns.
+func IsGenerated(content string, patterns []*regexp.Regexp) bool {
+	scanner := bufio.NewScanner(strings.NewReader(strings.TrimPrefix(content, utf8BOM)))
+	scanner.Buffer(nil, len(content)+1)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if generatedMarker.MatchString(line) || matchesAny(line, patterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// IgnoreDirective is the comment that opts a file out of stamping, such as
+// a file copied from upstream that keeps its own license. It may be
+// followed by a reason.
+const IgnoreDirective = "copy-righter:ignore"
+
+// IgnoreLines is how many lines from the top of a file the directive is
+// looked for in.
+const IgnoreLines = 10
+
+// ignoreMarker matches the directive in the comment syntax of any
+// supported language.
+var ignoreMarker = regexp.MustCompile(`^\s*(//|#|;|--|/\*+|\*|<!--|\{\{/\*|\{\{!--|<%#|\{#)\s*` + regexp.QuoteMeta(IgnoreDirective) + `(\s|\*/|-->|$)`)
+
+// IsIgnored reports whether one of the first IgnoreLines lines of content
+// is a comment carrying IgnoreDirective.
+func IsIgnored(content string) bool {
+	scanner := bufio.NewScanner(strings.NewReader(strings.TrimPrefix(content, utf8BOM)))
+	scanner.Buffer(nil, len(content)+1)
+	for n := 0; n < IgnoreLines && scanner.Scan(); n++ {
+		if ignoreMarker.MatchString(scanner.Text()) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package copyrighter
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// quoteFolder maps typographic apostrophes and quotes to their ASCII forms,
+// which editors substitute freely in holder names such as "O’Brien & Co".
+var quoteFolder = strings.NewReplacer(
+	"‘", "'", "’", "'", "‛", "'", "ʼ", "'", "′", "'",
+	"“", `"`, "”", `"`, "‟", `"`, "″", `"`,
+)
+
+// NormalizeNotice returns the canonical form of notice text used when
+// detecting an existing notice: NFC composed, with quotes folded to ASCII.
+// Files written on macOS often carry accented holder names in NFD, which
+// must not make an otherwise identical notice look outdated.
+func NormalizeNotice(s string) string {
+	return quoteFolder.Replace(norm.NFC.String(s))
+}
+
+// NoticeHash hashes notice lines in their normalized form.
+func NoticeHash(lines []string) string {
+	return hash(NormalizeNotice(strings.Join(lines, "\n")))
+}
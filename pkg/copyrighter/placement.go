@@ -0,0 +1,142 @@
+package copyrighter
+
+import "strings"
+
+// topBlock is the comment block at the start of a file, split into the
+// notice at its top, if any, and the rest of its text. For a block comment
+// open and close hold its first and last lines.
+type topBlock struct {
+	open, close  []string
+	notice, rest []string
+	// n is the number of lines of the whole block.
+	n int
+}
+
+// topBlock finds the comment block lines start with. A block comment must
+// open on a line of its own, such as "/*" or "/**", so the notice can go
+// below that line. A notice at the top of the block ends at the first
+// empty comment line. Toolchain directives and code comments, such as a
+// Go package doc comment, never count.
+func (s CommentStyle) topBlock(lines []string) (topBlock, bool) {
+	var b topBlock
+	if s.codeComment != nil {
+		lines = lines[:s.codeComment(lines)]
+	}
+	if len(lines) == 0 {
+		return b, false
+	}
+	var text []string
+	if s.linePrefix != "" {
+		for b.n < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[b.n]), s.linePrefix) && !s.IsDirective(lines[b.n]) {
+			b.n++
+		}
+		text = lines[:b.n]
+	} else {
+		opener, ok := strings.CutPrefix(strings.TrimSpace(lines[0]), s.blockStart)
+		if !ok || strings.Trim(opener, "*") != "" {
+			return b, false
+		}
+		for i := 1; i < len(lines) && b.n == 0; i++ {
+			if strings.HasSuffix(strings.TrimSpace(lines[i]), s.blockEnd) {
+				b.n = i + 1
+			}
+		}
+		if b.n == 0 {
+			return b, false
+		}
+		b.open, b.close = lines[:1], lines[b.n-1:b.n]
+		text = lines[1 : b.n-1]
+	}
+	if len(text) == 0 {
+		return b, false
+	}
+
+	separator := strings.TrimSpace(s.mergeSeparator())
+	end := len(text)
+	for i, line := range text {
+		if strings.TrimSpace(line) == separator {
+			end = i
+			break
+		}
+	}
+	b.rest = text
+	if CopyrightPattern.MatchString(strings.Join(text[:end], "\n")) {
+		b.notice, b.rest = text[:end], text[min(end+1, len(text)):]
+	}
+	return b, true
+}
+
+// mergeSeparator is the empty comment line between a merged notice and the
+// rest of the comment block.
+func (s CommentStyle) mergeSeparator() string {
+	if s.linePrefix != "" {
+		prefix := s.linePrefix + " "
+		if s.continuation != "" {
+			prefix = s.continuation
+		}
+		return strings.TrimRight(prefix, " ")
+	}
+	middle := s.blockMiddle
+	if s.continuation != "" {
+		middle = s.continuation
+	}
+	return strings.TrimRight(middle, " ")
+}
+
+// mergedNotice returns the lines of a rendered header as they appear
+// inside a comment block: unchanged for line comments, without the opening
+// and closing lines for a block comment.
+func (s CommentStyle) mergedNotice(header []string) []string {
+	if s.linePrefix != "" {
+		return header
+	}
+	if len(header) == 1 {
+		middle := s.blockMiddle
+		if s.continuation != "" {
+			middle = s.continuation
+		}
+		text := strings.TrimSuffix(strings.TrimPrefix(header[0], s.blockStart), s.blockEnd)
+		return []string{middle + strings.TrimSpace(text)}
+	}
+	return header[1 : len(header)-1]
+}
+
+// mergeHeader returns lines with header merged into the top of their
+// leading comment block, replacing a notice already there, and what that
+// did to the header. It returns nil lines unless the options merge the
+// header and there is a comment block with other text to merge into; the
+// header then goes on top as usual.
+func mergeHeader(lines, header []string, opts Options) ([]string, Action) {
+	if !opts.MergeHeader {
+		return nil, UpToDate
+	}
+	style := opts.Style
+	b, ok := style.topBlock(lines)
+	if !ok || len(b.rest) == 0 {
+		return nil, UpToDate
+	}
+	merged := joinBlocks(b.open, style.mergedNotice(header), []string{style.mergeSeparator()}, b.rest, b.close)
+	switch {
+	case NoticeHash(merged) == NoticeHash(lines[:b.n]):
+		return lines, UpToDate
+	case b.notice != nil:
+		return joinBlocks(merged, lines[b.n:]), Updated
+	default:
+		return joinBlocks(merged, lines[b.n:]), Added
+	}
+}
+
+// unmergeHeader returns lines without header at the top of their leading
+// comment block, or nil if it is not there or the options do not merge
+// the header.
+func unmergeHeader(lines, header []string, opts Options) []string {
+	if !opts.MergeHeader {
+		return nil
+	}
+	style := opts.Style
+	b, ok := style.topBlock(lines)
+	if !ok || b.notice == nil || len(b.rest) == 0 || NoticeHash(b.notice) != NoticeHash(style.mergedNotice(header)) {
+		return nil
+	}
+	return joinBlocks(b.open, b.rest, b.close, lines[b.n:])
+}
@@ -0,0 +1,78 @@
+package copyrighter
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+// ErrUnsupported is returned for a file no language is declared for.
+var ErrUnsupported = errors.New("unsupported file type")
+
+// Processor stamps, checks and removes the notice of files of any
+// supported language with the same settings, choosing the comment style
+// and footer from each file's extension, as the copy-righter command does.
+type Processor struct {
+	// Options is the notice every file gets; its Style and NoFooter are
+	// set per file.
+	Options Options
+	// Footers overrides per extension, such as ".go", whether a footer is
+	// stamped; languages default to their Capabilities.
+	Footers map[string]bool
+	// Continuation sets per extension the prefix of the lines of a
+	// multi-line notice, see CommentStyle.WithContinuation.
+	Continuation map[string]string
+	// Template, when set, is the notice as a text/template rendered for
+	// each file with its TemplateData in place of Options.Copyright; see
+	// Funcs for what it may call.
+	Template string
+	// Year is the year templates are rendered with, 0 for the current one.
+	Year  int
+	funcs template.FuncMap
+}
+
+// OptionsFor returns the options for the file at path. It fails with
+// ErrUnsupported when no language is declared for its extension.
+func (p *Processor) OptionsFor(path string) (Options, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	style, ok := StyleFor(path)
+	if !ok {
+		return Options{}, fmt.Errorf("%w %q", ErrUnsupported, filepath.Ext(path))
+	}
+	var err error
+	opts := p.Options
+	if opts.Copyright, err = p.Notice(path); err != nil {
+		return Options{}, err
+	}
+	opts.Style = style.WithContinuation(p.Continuation[ext])
+	opts.NoFooter = !FooterEnabled(ext, p.Footers)
+	return opts, nil
+}
+
+// Stamp returns content, the content of the file at path, with its notice
+// added or brought up to date.
+func (p *Processor) Stamp(path, content string) (string, Result, error) {
+	opts, err := p.OptionsFor(path)
+	if err != nil {
+		return "", Result{}, err
+	}
+	return Stamp(content, opts)
+}
+
+// Check reports what Stamp would change in content without changing it.
+func (p *Processor) Check(path, content string) (Result, error) {
+	_, result, err := p.Stamp(path, content)
+	return result, err
+}
+
+// Remove returns content, the content of the file at path, without its
+// notice.
+func (p *Processor) Remove(path, content string) (string, Result, error) {
+	opts, err := p.OptionsFor(path)
+	if err != nil {
+		return "", Result{}, err
+	}
+	return Remove(content, opts)
+}
@@ -0,0 +1,40 @@
+package copyrighter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestProcessor(t *testing.T) {
+	p := &Processor{
+		Options: Options{Copyright: "Copyright (c) 2025 Example Corp."},
+		Footers: map[string]bool{".py": false},
+	}
+
+	stamped, result, err := p.Stamp("main.go", "package main\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "// Copyright (c) 2025 Example Corp.\n\npackage main\n\n// Copyright (c) 2025 Example Corp.\n"
+	if stamped != want || result.Header != Added || result.Footer != Added {
+		t.Errorf("Stamp = %q, %+v, want %q with header and footer added", stamped, result, want)
+	}
+	if result, err := p.Check("main.go", stamped); err != nil || result.Changed() {
+		t.Errorf("Check of stamped content = %+v, %v, want no change", result, err)
+	}
+	if removed, _, err := p.Remove("main.go", stamped); err != nil || removed != "package main\n" {
+		t.Errorf("Remove = %q, %v, want the original content", removed, err)
+	}
+
+	stamped, result, err = p.Stamp("tool.py", "print('hi')\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamped != "# Copyright (c) 2025 Example Corp.\n\nprint('hi')\n" || !result.NoFooter {
+		t.Errorf("footer turned off for .py, got %q, %+v", stamped, result)
+	}
+
+	if _, _, err := p.Stamp("data.bin", ""); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("unsupported file: err = %v, want ErrUnsupported", err)
+	}
+}
@@ -0,0 +1,59 @@
+package copyrighter
+
+import "strings"
+
+// Remove is the inverse of Stamp: it deletes a header and
+// footer matching the options together with the blank line that separates
+// each from the code, leaving the rest of the file untouched.
+func Remove(content string, opts Options) (string, Result, error) {
+	if rest, ok := strings.CutPrefix(content, utf8BOM); ok {
+		removed, result, err := Remove(rest, opts)
+		return utf8BOM + removed, result, err
+	}
+	if removed, result, ok, err := windowed(content, opts, Remove); ok {
+		return removed, result, err
+	}
+	var result Result
+	hadTrailingNewline := strings.HasSuffix(content, "\n")
+
+	preamble, lines := SplitContent(content, opts)
+	header, footer := ExpectedNotice(lines, opts)
+	headerGap, footerGap := opts.gaps()
+
+	// A header stamped before --spdx was enabled looks like the footer
+	for _, candidate := range [][]string{header, footer} {
+		if opts.LeaveHeader {
+			break
+		}
+		if unmerged := unmergeHeader(lines, candidate, opts); unmerged != nil {
+			lines = unmerged
+			result.Header = Removed
+			break
+		}
+		if len(lines) >= len(candidate) && NoticeHash(lines[:len(candidate)]) == NoticeHash(candidate) {
+			lines = lines[len(candidate):]
+			lines = lines[min(headerGap, leadingBlanks(lines)):]
+			result.Header = Removed
+			break
+		}
+	}
+
+	if !opts.LeaveFooter && len(lines) >= len(footer) && NoticeHash(lines[len(lines)-len(footer):]) == NoticeHash(footer) {
+		lines = lines[:len(lines)-len(footer)]
+		lines = lines[:len(lines)-min(footerGap, trailingBlanks(lines))]
+		result.Footer = Removed
+	}
+
+	if !result.Changed() {
+		return content, result, nil
+	}
+	lines = withPreamble(preamble, lines)
+	if len(lines) == 0 {
+		return "", result, nil
+	}
+	out := strings.Join(lines, "\n")
+	if hadTrailingNewline {
+		out += "\n"
+	}
+	return out, result, nil
+}
@@ -0,0 +1,172 @@
+package copyrighter
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// TemplateData is what Processor.Template is rendered with for a file.
+type TemplateData struct {
+	// Path is the path of the file as given, with forward slashes, Base
+	// its name and Ext its extension, such as ".go".
+	Path, Base, Ext string
+	// Language is the name of its language, such as "Go".
+	Language string
+	// Year is Processor.Year, or the current year when that is 0.
+	Year int
+}
+
+// MaxTemplateOutput is the most a notice template may write for a file.
+const MaxTemplateOutput = 64 << 10
+
+// ErrTemplate is returned for a notice template the sandbox refuses, or
+// whose rendering fails.
+var ErrTemplate = errors.New("notice template")
+
+// templateFuncs are the functions every notice template may call, besides
+// the comparisons, logic and formatting built into text/template.
+var templateFuncs = template.FuncMap{
+	"upper":   strings.ToUpper,
+	"lower":   strings.ToLower,
+	"trim":    strings.TrimSpace,
+	"replace": strings.ReplaceAll,
+}
+
+// Funcs adds functions for Template to call, such as one looking the
+// holder of a path up in another service, and returns p. A function
+// given the name of a default one replaces it.
+//
+// Templates run in a sandbox, since they often come from the repository
+// being stamped: besides the functions given here and upper, lower, trim
+// and replace from the strings package, they may only use the builtins of
+// text/template other than call. They may not range or define and call
+// templates, and may write at most MaxTemplateOutput bytes. Funcs is the
+// only way to give them more.
+func (p *Processor) Funcs(funcs template.FuncMap) *Processor {
+	if p.funcs == nil {
+		p.funcs = make(template.FuncMap, len(funcs))
+	}
+	for name, fn := range funcs {
+		p.funcs[name] = fn
+	}
+	return p
+}
+
+// Notice returns the text of the notice of the file at path: Template
+// rendered for it, or Options.Copyright when there is no template.
+func (p *Processor) Notice(path string) (string, error) {
+	if p.Template == "" {
+		return p.Options.Copyright, nil
+	}
+	t, err := p.parseTemplate()
+	if err != nil {
+		return "", err
+	}
+	data := TemplateData{Path: filepath.ToSlash(path), Base: filepath.Base(path), Ext: filepath.Ext(path), Year: p.Year}
+	if lang, ok := LanguageFor(path); ok {
+		data.Language = lang.Name
+	}
+	if data.Year == 0 {
+		data.Year = time.Now().Year()
+	}
+	var out limitedBuilder
+	if err := t.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+	return out.String(), nil
+}
+
+// parseTemplate parses Template with the functions it may call and
+// checks it keeps to the sandbox.
+func (p *Processor) parseTemplate() (*template.Template, error) {
+	funcs := make(template.FuncMap, len(templateFuncs)+len(p.funcs))
+	for name, fn := range templateFuncs {
+		funcs[name] = fn
+	}
+	for name, fn := range p.funcs {
+		funcs[name] = fn
+	}
+	t, err := template.New("notice").Option("missingkey=error").Funcs(funcs).Parse(p.Template)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+	if len(t.Templates()) > 1 {
+		return nil, fmt.Errorf("%w: templates may not define other templates", ErrTemplate)
+	}
+	if err := checkSandbox(t.Tree.Root); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrTemplate, err)
+	}
+	return t, nil
+}
+
+// checkSandbox refuses the actions of a template that could run for a
+// long time or call functions it was not given: range, calls of other
+// templates and the call builtin.
+func checkSandbox(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, c := range n.Nodes {
+			if err := checkSandbox(c); err != nil {
+				return err
+			}
+		}
+	case *parse.RangeNode:
+		return errors.New("range is not allowed")
+	case *parse.TemplateNode:
+		return errors.New("template calls are not allowed")
+	case *parse.IfNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return checkBranch(&n.BranchNode)
+	case *parse.ActionNode:
+		return checkSandbox(n.Pipe)
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			for _, arg := range cmd.Args {
+				if err := checkSandbox(arg); err != nil {
+					return err
+				}
+			}
+		}
+	case *parse.ChainNode:
+		return checkSandbox(n.Node)
+	case *parse.IdentifierNode:
+		if n.Ident == "call" {
+			return errors.New("call is not allowed")
+		}
+	}
+	return nil
+}
+
+func checkBranch(b *parse.BranchNode) error {
+	for _, n := range []parse.Node{b.Pipe, b.List, b.ElseList} {
+		if err := checkSandbox(n); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitedBuilder collects the output of a template, failing once it
+// exceeds MaxTemplateOutput.
+type limitedBuilder struct {
+	strings.Builder
+}
+
+func (b *limitedBuilder) Write(p []byte) (int, error) {
+	if b.Len()+len(p) > MaxTemplateOutput {
+		return 0, fmt.Errorf("output over %d bytes", MaxTemplateOutput)
+	}
+	return b.Builder.Write(p)
+}
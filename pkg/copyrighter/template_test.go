@@ -0,0 +1,48 @@
+package copyrighter
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestTemplate(t *testing.T) {
+	p := &Processor{
+		Template: "Copyright (c) {{.Year}} {{holder .Path}} ({{.Language}}, {{.Base}}, {{upper .Ext}})",
+		Year:     2025,
+	}
+	p.Funcs(template.FuncMap{"holder": func(path string) string {
+		if strings.HasPrefix(path, "vendor/") {
+			return "Vendor Inc."
+		}
+		return "Example Corp."
+	}})
+
+	stamped, _, err := p.Stamp("vendor/lib/main.go", "package main\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "// Copyright (c) 2025 Vendor Inc. (Go, main.go, .GO)\n"
+	if !strings.HasPrefix(stamped, want) {
+		t.Errorf("Stamp = %q, want it to start with %q", stamped, want)
+	}
+	if notice, err := p.Notice("tool.py"); err != nil || notice != "Copyright (c) 2025 Example Corp. (Python, tool.py, .PY)" {
+		t.Errorf("Notice(tool.py) = %q, %v", notice, err)
+	}
+
+	for _, tc := range []struct{ name, template string }{
+		{"range", "{{range .Path}}x{{end}}"},
+		{"define", `{{define "x"}}x{{end}}{{template "x"}}`},
+		{"call", "{{call .Path}}"},
+		{"call in if", "{{if true}}{{call .Path}}{{end}}"},
+		{"unknown function", "{{exec .Path}}"},
+		{"unknown field", "{{.Holder}}"},
+		{"too long", "{{printf \"%99999d\" 1}}"},
+	} {
+		refused := &Processor{Template: tc.template}
+		if _, err := refused.Notice("main.go"); !errors.Is(err, ErrTemplate) {
+			t.Errorf("%s: Notice err = %v, want ErrTemplate", tc.name, err)
+		}
+	}
+}
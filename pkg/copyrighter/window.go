@@ -0,0 +1,100 @@
+package copyrighter
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// WindowSize is how much of the start and of the end of a large file
+// Stamp and Remove look at. The notice lives in the first and last lines,
+// so the middle of a file over four windows, such as a data file or a
+// minified bundle, is copied through as it is instead of being split into
+// lines and joined again. A comment at the top of such a file that ends
+// in the middle is taken for code, so the notice goes above it.
+const WindowSize = 64 << 10
+
+// MiddleMarker is the line that stands for the middle of a large file in
+// the view of its head and tail, see Windows. It is code in every comment
+// style, so Stamp and Remove leave it in place and look for no notice
+// past it.
+const MiddleMarker = "\x00copy-righter:middle\x00"
+
+// Windows are the head and tail of a large file, cut at line breaks, and
+// the offsets of the middle between them.
+type Windows struct {
+	Head, Tail             string
+	MiddleStart, MiddleEnd int64
+}
+
+// ReadWindows reads the head and tail of the size bytes of r, at most
+// WindowSize bytes each. It returns nil for content of at most four
+// windows, which is handled whole, and for content whose first or last
+// window holds no line break.
+func ReadWindows(r io.ReaderAt, size int64) (*Windows, error) {
+	if size <= 4*WindowSize {
+		return nil, nil
+	}
+	buf := make([]byte, WindowSize)
+	if _, err := r.ReadAt(buf, 0); err != nil {
+		return nil, err
+	}
+	h := bytes.LastIndexByte(buf, '\n')
+	if h < 0 {
+		return nil, nil
+	}
+	head := string(buf[:h+1])
+	if _, err := r.ReadAt(buf, size-WindowSize); err != nil && err != io.EOF {
+		return nil, err
+	}
+	t := bytes.IndexByte(buf, '\n')
+	if t < 0 {
+		return nil, nil
+	}
+	return &Windows{
+		Head:        head,
+		Tail:        string(buf[t+1:]),
+		MiddleStart: int64(h + 1),
+		MiddleEnd:   size - WindowSize + int64(t+1),
+	}, nil
+}
+
+// View returns the head and tail joined by MiddleMarker, the content to
+// stamp or remove the notice of in place of the whole file.
+func (w *Windows) View() string {
+	return w.Head + MiddleMarker + "\n" + w.Tail
+}
+
+// SplitView cuts the view of a large file, as rewritten by Stamp or
+// Remove, around MiddleMarker, so the middle can be put back between the
+// two parts. It reports false when the marker is no longer a line of its
+// own, as when the comment of a header runs past the head; the file must
+// then be handled whole.
+func SplitView(view string) (before, after string, ok bool) {
+	before, after, found := strings.Cut(view, MiddleMarker+"\n")
+	if !found || strings.Contains(after, MiddleMarker) || before != "" && !strings.HasSuffix(before, "\n") {
+		return "", "", false
+	}
+	return before, after, true
+}
+
+// windowed applies transform, Stamp or Remove, to the view of content
+// when it is large, see ReadWindows. It reports false when content is
+// handled whole: when it is small, has carriage returns, as rewriting
+// normalises its line endings throughout, or when the transform rewrote
+// the marker.
+func windowed(content string, opts Options, transform func(string, Options) (string, Result, error)) (string, Result, bool, error) {
+	w, err := ReadWindows(strings.NewReader(content), int64(len(content)))
+	if err != nil || w == nil || strings.Contains(content, "\r") {
+		return "", Result{}, false, nil
+	}
+	out, result, err := transform(w.View(), opts)
+	if err != nil {
+		return "", result, true, err
+	}
+	before, after, ok := SplitView(out)
+	if !ok {
+		return "", Result{}, false, nil
+	}
+	return before + content[w.MiddleStart:w.MiddleEnd] + after, result, true, nil
+}
@@ -0,0 +1,61 @@
+package copyrighter
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+)
+
+// YearRangeSource matches a year or a run of years such as "2021",
+// "2021-2025" or "2019, 2021".
+const YearRangeSource = `(?:19|20)[0-9]{2}(?:\s*(?:-|–|,)\s*(?:19|20)[0-9]{2})*`
+
+var (
+	YearRangePattern = regexp.MustCompile(YearRangeSource)
+	yearPattern      = regexp.MustCompile(`(?:19|20)[0-9]{2}`)
+)
+
+// yearBounds returns the earliest and latest year mentioned in s.
+func yearBounds(s string) (first, last int) {
+	for _, y := range yearPattern.FindAllString(s, -1) {
+		year, _ := strconv.Atoi(y)
+		if first == 0 || year < first {
+			first = year
+		}
+		last = max(last, year)
+	}
+	return first, last
+}
+
+// extendYearRange keeps the original year of an existing notice. For each
+// header line containing a year, the corresponding line of every existing
+// block is compared with the template ignoring years; when one matches and
+// starts earlier, the header line gets a "first-last" range instead of the
+// template's year.
+func extendYearRange(header []string, existing ...[]string) []string {
+	extended := slices.Clone(header)
+	for i, line := range header {
+		loc := YearRangePattern.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+		prefix, suffix := line[:loc[0]], line[loc[1]:]
+		templateFirst, templateLast := yearBounds(line[loc[0]:loc[1]])
+		same := regexp.MustCompile("^" + regexp.QuoteMeta(prefix) + "(" + YearRangeSource + ")" + regexp.QuoteMeta(suffix) + "$")
+
+		first := templateFirst
+		for _, block := range existing {
+			if i >= len(block) {
+				continue
+			}
+			if m := same.FindStringSubmatch(block[i]); m != nil {
+				existingFirst, _ := yearBounds(m[1])
+				first = min(first, existingFirst)
+			}
+		}
+		if first < templateFirst {
+			extended[i] = prefix + strconv.Itoa(first) + "-" + strconv.Itoa(templateLast) + suffix
+		}
+	}
+	return extended
+}
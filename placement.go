@@ -0,0 +1,20 @@
+package main
+
+import "fmt"
+
+// The values of the placement setting. A separate header is a comment
+// block of its own above the code; a merged header goes at the top of the
+// comment block that already describes the file, as some projects require.
+const (
+	placementSeparate = "separate"
+	placementMerge    = "merge"
+)
+
+// checkPlacement refuses an unknown placement.
+func checkPlacement(placement string) error {
+	switch placement {
+	case "", placementSeparate, placementMerge:
+		return nil
+	}
+	return fmt.Errorf("invalid placement %q, want %s or %s", placement, placementSeparate, placementMerge)
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// runPolicyShow implements "policy show": it renders the effective policy
+// resolved from the config file, profile and flags as a Markdown document
+// auditors can attach to compliance evidence.
+func runPolicyShow(cmd *cobra.Command, args []string) {
+	s, cfg, err := loadSettings(cmd)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	profile, _ := cmd.Flags().GetString("profile")
+	writePolicy(os.Stdout, s, cfg, profile)
+}
+
+func writePolicy(w io.Writer, s settings, cfg *config, profile string) {
+	fmt.Fprintln(w, "# Copyright policy")
+	fmt.Fprintln(w)
+	if cfg.path != "" {
+		fmt.Fprintf(w, "- Source: `%s` (sha256 `%s`)\n", cfg.path, cfg.sha256)
+	} else {
+		fmt.Fprintln(w, "- Source: command-line flags (no config file)")
+	}
+	if profile != "" {
+		fmt.Fprintf(w, "- Profile: `%s`\n", profile)
+	}
+	if s.CopyrightFile != "" {
+		fmt.Fprintf(w, "- Notice text read from: `%s`\n", s.CopyrightFile)
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "## Required notice")
+	fmt.Fprintln(w)
+	if s.Copyright == "" {
+		fmt.Fprintln(w, "No notice is configured; runs fail until `copyright` or `copyright_file` is set.")
+	} else {
+		fmt.Fprintln(w, "Every file in scope must start and end with the notice below, written in the comment syntax of its file type.")
+		if s.SPDX != "" {
+			fmt.Fprintf(w, "The header must also carry the SPDX license identifier `%s`.\n", s.SPDX)
+		}
+		if s.MaintainedBy != "" {
+			fmt.Fprintf(w, "The header must also name its maintainer, `%s %s`; check fails once the review date has passed.\n", copyrighter.MaintainedTag, s.MaintainedBy)
+		}
+		if s.TemplateVersion != "" {
+			fmt.Fprintf(w, "The header must also carry the template version, `%s %s`.\n", copyrighter.TemplateVersionTag, s.TemplateVersion)
+		}
+		for _, text := range acceptedNotices(s.AcceptedCopyright) {
+			fmt.Fprintf(w, "A file may carry the approved notice `%s` instead.\n", strings.ReplaceAll(text, "\n", " "))
+		}
+		if s.MinTemplateVersion != "" {
+			fmt.Fprintf(w, "While the template is rolled out, check accepts headers of template version %s or later.\n", s.MinTemplateVersion)
+		}
+		if s.Placement == placementMerge {
+			fmt.Fprintln(w, "When a file starts with a comment block describing it, the header goes at the top of that block instead of in a block of its own.")
+		}
+		if file := packageNoticeFile(s.PackageNotice); file != "" {
+			fmt.Fprintf(w, "In each Go package only `%s` carries the notice; check fails for a package without it and for a copy of the notice in its other files.\n", file)
+		}
+		var headerOnly []string
+		for _, ext := range s.Extensions {
+			if !copyrighter.FooterEnabled(ext, s.Footers) {
+				headerOnly = append(headerOnly, ext)
+			}
+		}
+		if len(headerOnly) > 0 {
+			fmt.Fprintf(w, "Files of type %s carry the header only, without a footer.\n", codeList(headerOnly))
+		}
+		switch s.Only {
+		case onlyHeader:
+			fmt.Fprintln(w, "Only the header is enforced; an existing footer is left as it is.")
+		case onlyFooter:
+			fmt.Fprintln(w, "Only the footer is enforced; an existing header is left as it is.")
+		}
+		if len(s.SidecarExtensions) > 0 {
+			sidecar := s.Sidecar
+			if sidecar == "" {
+				sidecar = defaultSidecar
+			}
+			fmt.Fprintf(w, "Files of type %s cannot carry a comment; their stamp is recorded in `%s` instead.\n", codeList(normalizeExtensions(s.SidecarExtensions)), sidecar)
+		}
+		for _, ext := range s.Extensions {
+			style, _ := copyrighter.StyleFor(ext)
+			style = style.WithContinuation(s.Continuation[ext])
+			notice := copyrighter.HeaderText(style.Wrap(string(s.Copyright), s.maxLineLength()), s.SPDX, s.MaintainedBy, s.TemplateVersion)
+			fmt.Fprintln(w)
+			fmt.Fprintf(w, "`%s`:\n\n", ext)
+			for _, line := range style.Render(notice) {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "## Scope")
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "- File types: %s\n", codeList(s.Extensions))
+	if len(s.Include) > 0 {
+		fmt.Fprintf(w, "- Included paths: %s (excluded paths take precedence)\n", codeList(s.Include))
+	}
+	if len(s.Exclude) > 0 {
+		fmt.Fprintf(w, "- Excluded paths: %s\n", codeList(s.Exclude))
+	} else {
+		fmt.Fprintln(w, "- Excluded paths: none")
+	}
+	if defaults := s.defaultExcludes(); len(defaults) > 0 {
+		fmt.Fprintf(w, "- Always skipped: %s directories\n", codeList(defaults))
+	}
+	if !isTrue(s.IncludeVendored) {
+		fmt.Fprintln(w, "- Files marked `linguist-vendored` in a `.gitattributes` are skipped.")
+	}
+	if len(s.Roots) > 0 {
+		fmt.Fprintf(w, "- Allowed roots: %s (files resolving outside them, through symlinks or otherwise, are refused)\n", codeList(s.Roots))
+	}
+	switch {
+	case isTrue(s.IncludeGenerated):
+		fmt.Fprintln(w, "- Generated files are in scope.")
+	case len(s.Generated) > 0:
+		fmt.Fprintf(w, "- Generated files are out of scope: those marked `Code generated ... DO NOT EDIT.` or matching %s.\n", codeList(s.Generated))
+	default:
+		fmt.Fprintln(w, "- Generated files are out of scope: those marked `Code generated ... DO NOT EDIT.`")
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "## Placement")
+	fmt.Fprintln(w)
+	if len(s.Preamble) > 0 {
+		fmt.Fprintf(w, "- Leading lines matching %s are protected and stay above the notice.\n", codeList(s.Preamble))
+	}
+	if isTrue(s.UpdateYearRange) {
+		fmt.Fprintln(w, "- The year of an existing notice is kept and extended into a range ending in the current year.")
+	} else {
+		fmt.Fprintln(w, "- An existing notice that differs from the required text is replaced.")
+	}
+	if b := s.BlankLines.policy(); b != nil {
+		fmt.Fprintf(w, "- The header is followed by %d %s and the footer preceded by %d %s.\n", b.Header, plural(b.Header, "blank line", "blank lines"), b.Footer, plural(b.Footer, "blank line", "blank lines"))
+	}
+	fmt.Fprintln(w, "- A trailing comment that references a ticket or URL, or is attached to code, is kept and the footer is added below it.")
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "## Enforcement")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "- `copy-righter check` fails when any file in scope has a missing or outdated header or footer.")
+	if p, err := newSeverityPolicy(s.Severity, s.FixSeverity); err == nil {
+		for _, kind := range findingKinds {
+			switch level := p.of(kind); level {
+			case severityOff:
+				fmt.Fprintf(w, "- `%s` findings are ignored.\n", kind)
+			case severityWarning:
+				fmt.Fprintf(w, "- `%s` findings are reported as warnings and do not fail the check.\n", kind)
+			}
+		}
+		if p.fix == severityError {
+			fmt.Fprintln(w, "- Fixing only acts on findings of severity `error`; warnings are left for a person to resolve.")
+		}
+	}
+	if len(s.Exceptions) > 0 {
+		fmt.Fprintln(w, "- The findings of the files below are suppressed until their exception expires; check fails for an expired exception until it is renewed or removed:")
+		for _, e := range s.Exceptions {
+			fmt.Fprintf(w, "  - `%s`: %s (owner %s, expires %s)\n", e.Path, e.Reason, e.Owner, e.Expires)
+		}
+	}
+	for _, h := range s.Holders {
+		if h.Skip {
+			fmt.Fprintf(w, "- Files matching `%s` belong to other owners and are left untouched.\n", h.Path)
+		} else {
+			fmt.Fprintf(w, "- Files matching `%s` carry the notice `%s` instead.\n", h.Path, strings.TrimSpace(string(h.Copyright)))
+		}
+	}
+	for _, h := range s.LegacyHeaders {
+		fmt.Fprintf(w, "- Until %s, check accepts headers in the format written by %s while files are migrated.\n", h.Until, h.From)
+	}
+	if !isTrue(s.OverwriteForeign) {
+		fmt.Fprintln(w, "- Fix runs leave a header that credits another party, or carries another license, in place and report it instead of overwriting it.")
+	}
+	fmt.Fprintln(w, "- An emergency release may bypass the check with `COPYRIGHTER_SKIP=1`; the bypass is logged and recorded in the run summary.")
+	if s.Notify.Command != "" || s.Notify.Webhook != "" {
+		fmt.Fprintln(w, "- Run summaries are delivered to the configured notification hooks.")
+	}
+}
+
+// codeList renders values as a comma-separated list of inline code spans.
+func codeList(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = "`" + v + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
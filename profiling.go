@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// startProfiling starts the CPU profile and the execution trace asked for
+// with --cpu-profile and --trace, so a slow run can be diagnosed with go
+// tool pprof and go tool trace. The returned function stops both and
+// closes their files; it must be called before the process exits.
+func startProfiling(cpuProfile, tracePath string) (stop func() error, err error) {
+	var stops []func() error
+	stop = func() error {
+		var errs []error
+		for _, s := range stops {
+			errs = append(errs, s())
+		}
+		return errors.Join(errs...)
+	}
+	if cpuProfile != "" {
+		f, err := os.Create(cpuProfile)
+		if err != nil {
+			return nil, fmt.Errorf("creating CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(f); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("starting CPU profile: %w", err)
+		}
+		stops = append(stops, func() error {
+			pprof.StopCPUProfile()
+			return f.Close()
+		})
+	}
+	if tracePath != "" {
+		f, err := os.Create(tracePath)
+		if err != nil {
+			stop()
+			return nil, fmt.Errorf("creating trace: %w", err)
+		}
+		if err := trace.Start(f); err != nil {
+			f.Close()
+			stop()
+			return nil, fmt.Errorf("starting trace: %w", err)
+		}
+		stops = append(stops, func() error {
+			trace.Stop()
+			return f.Close()
+		})
+	}
+	return stop, nil
+}
+
+// stopProfiling stops the profiles of the run, if any.
+func (r *runner) stopProfiling() {
+	if r.profiling == nil {
+		return
+	}
+	if err := r.profiling(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing profile: %v\n", err)
+	}
+	r.profiling = nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"golang.org/x/term"
+)
+
+// progressRedraw is the shortest time between two redraws of the progress
+// line, so a fast run does not spend its time writing to the terminal.
+const progressRedraw = 100 * time.Millisecond
+
+// progressLookahead is how many files a run on a terminal finds ahead of
+// the one it processes. A tree that size or smaller is walked before the
+// first file is processed, so its progress line shows the total from the
+// start; a larger one shows the count alone until its walk ends, so the
+// files waiting to be processed never take more memory than that.
+const progressLookahead = 10000
+
+// progressLine keeps a "Processed N/M files" line at the bottom of the
+// terminal during a run, so a long run shows how far along it is between
+// the lines it prints, or "Processed N files" while the total is unknown.
+// Console messages clear it first; the next file processed draws it
+// again. Methods on a nil progressLine do nothing.
+type progressLine struct {
+	out io.Writer
+	// total is the number of files the run has found, 0 while unknown.
+	total int
+	done  int
+	last  time.Time
+	shown bool
+}
+
+// newProgressLine returns a progress line on stderr, or nil when stderr
+// is not a terminal, such as when it is piped or in CI, or the run is
+// quiet.
+func newProgressLine(level logLevel) *progressLine {
+	if level < logNormal || !term.IsTerminal(int(os.Stderr.Fd())) {
+		return nil
+	}
+	return &progressLine{out: os.Stderr}
+}
+
+// step counts a processed file and redraws the line if it is due.
+func (p *progressLine) step() {
+	if p == nil {
+		return
+	}
+	p.done++
+	if now := time.Now(); p.done == p.total || now.Sub(p.last) >= progressRedraw {
+		p.last = now
+		p.draw()
+	}
+}
+
+func (p *progressLine) draw() {
+	if p.total > 0 {
+		fmt.Fprintf(p.out, "\r\x1b[KProcessed %d/%d %s\r", p.done, p.total, plural(p.total, "file", "files"))
+	} else {
+		fmt.Fprintf(p.out, "\r\x1b[KProcessed %d %s\r", p.done, plural(p.done, "file", "files"))
+	}
+	p.shown = true
+}
+
+// lookahead holds the files a run has found but not processed yet, see
+// progressLookahead.
+type lookahead struct {
+	progress *progressLine
+	n        int
+	queue    []func()
+}
+
+// schedule queues task, running the oldest queued one once more than n
+// are waiting.
+func (l *lookahead) schedule(task func()) {
+	l.queue = append(l.queue, task)
+	if len(l.queue) > l.n {
+		l.next()
+	}
+}
+
+// finish runs the queued tasks, once the walk has found them all, so the
+// progress line knows the total.
+func (l *lookahead) finish() {
+	if l.progress != nil {
+		l.progress.total = l.progress.done + len(l.queue)
+	}
+	for len(l.queue) > 0 {
+		l.next()
+	}
+}
+
+func (l *lookahead) next() {
+	task := l.queue[0]
+	l.queue[0] = nil
+	l.queue = l.queue[1:]
+	task()
+	l.progress.step()
+}
+
+// clear erases the line, if it is shown.
+func (p *progressLine) clear() {
+	if p == nil || !p.shown {
+		return
+	}
+	fmt.Fprint(p.out, "\r\x1b[K")
+	p.shown = false
+}
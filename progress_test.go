@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestProgressLine checks the line drawn while the total is unknown and
+// once it is, and that clearing erases it only when shown.
+func TestProgressLine(t *testing.T) {
+	var buf bytes.Buffer
+	p := &progressLine{out: &buf}
+	p.clear()
+	if buf.Len() != 0 {
+		t.Errorf("clear wrote %q before anything was drawn", buf.String())
+	}
+
+	p.step()
+	if got, want := buf.String(), "\r\x1b[KProcessed 1 file\r"; got != want {
+		t.Errorf("first step drew %q, want %q", got, want)
+	}
+	buf.Reset()
+	p.step()
+	if buf.Len() != 0 {
+		t.Errorf("step redrew within %v: %q", progressRedraw, buf.String())
+	}
+
+	p.total = 3
+	p.step()
+	if got, want := buf.String(), "\r\x1b[KProcessed 3/3 files\r"; got != want {
+		t.Errorf("last step drew %q, want %q", got, want)
+	}
+	buf.Reset()
+	p.clear()
+	if got, want := buf.String(), "\r\x1b[K"; got != want {
+		t.Errorf("clear wrote %q, want %q", got, want)
+	}
+
+	// Errors printed during a run clear the line first
+	p.draw()
+	buf.Reset()
+	r := &runner{progress: p}
+	r.errorf("")
+	if got, want := buf.String(), "\r\x1b[K"; got != want || p.shown {
+		t.Errorf("errorf left the line shown, wrote %q", got)
+	}
+}
+
+// TestLookaheadStreams checks that files are processed while the walk is
+// still finding more, with the count alone, and against the total once
+// the walk is over.
+func TestLookaheadStreams(t *testing.T) {
+	var buf bytes.Buffer
+	p := &progressLine{out: &buf}
+	ahead := &lookahead{progress: p, n: 2}
+	ran := 0
+	for range 5 {
+		ahead.schedule(func() { ran++ })
+	}
+	if ran != 3 || p.done != 3 {
+		t.Errorf("%d of 5 tasks ran, %d counted, before the walk ended; want 3", ran, p.done)
+	}
+	if strings.Contains(buf.String(), "/") {
+		t.Errorf("total shown before the walk ended: %q", buf.String())
+	}
+
+	ahead.finish()
+	if ran != 5 || len(ahead.queue) != 0 {
+		t.Errorf("%d of 5 tasks ran after finish", ran)
+	}
+	if got := buf.String(); !strings.HasSuffix(got, "\r\x1b[KProcessed 5/5 files\r") {
+		t.Errorf("progress ended with %q, want the total", got)
+	}
+}
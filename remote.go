@@ -0,0 +1,102 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// copyrightURLTimeout bounds the fetch of a remote notice, so an
+// unreachable server does not hang every run.
+const copyrightURLTimeout = 10 * time.Second
+
+// defaultCopyrightURLTTL is how long a fetched notice is used before it
+// is fetched again.
+const defaultCopyrightURLTTL = 24 * time.Hour
+
+// maxCopyrightURLSize is the largest remote notice accepted; anything
+// larger is not a header.
+const maxCopyrightURLSize = 1 << 20
+
+// copyrightURLCacheDir returns the directory fetched notices are kept in,
+// or "" when the user has no cache directory.
+func copyrightURLCacheDir() string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(cache, "copy-righter", "templates")
+}
+
+// fetchCopyright returns the notice published at url. A copy fetched less
+// than ttl ago is used without asking the server; when the server cannot
+// be reached, an older copy is used with a warning, so a central outage
+// does not stop every run.
+func fetchCopyright(url string, ttl time.Duration) (string, error) {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return "", fmt.Errorf("invalid copyright URL %q, want http or https", url)
+	}
+	var cached string
+	if dir := copyrightURLCacheDir(); dir != "" {
+		sum := sha256.Sum256([]byte(url))
+		cached = filepath.Join(dir, hex.EncodeToString(sum[:]))
+	}
+	var stale []byte
+	if cached != "" {
+		if info, err := os.Stat(cached); err == nil {
+			data, err := os.ReadFile(cached)
+			if err == nil && time.Since(info.ModTime()) < ttl {
+				return string(data), nil
+			}
+			stale = data
+		}
+	}
+
+	text, err := getCopyright(url)
+	if err != nil {
+		if stale == nil {
+			return "", err
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %v; using the copy cached in %s\n", err, cached)
+		return string(stale), nil
+	}
+	if cached != "" {
+		if err := os.MkdirAll(filepath.Dir(cached), 0o755); err == nil {
+			if err := writeFileAtomic(cached, []byte(text)); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: caching the notice of %s: %v\n", url, err)
+			}
+		}
+	}
+	return text, nil
+}
+
+func getCopyright(url string) (string, error) {
+	client := &http.Client{Timeout: copyrightURLTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("fetching copyright notice: %w", err)
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("fetching copyright notice: %s returned %s", url, resp.Status)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCopyrightURLSize+1))
+	if err != nil {
+		return "", fmt.Errorf("fetching copyright notice: %w", err)
+	}
+	if len(data) > maxCopyrightURLSize {
+		return "", fmt.Errorf("fetching copyright notice: %s is larger than %d bytes", url, maxCopyrightURLSize)
+	}
+	if strings.TrimSpace(string(data)) == "" {
+		return "", fmt.Errorf("fetching copyright notice: %s is empty", url)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// runRemove implements the remove subcommand: it strips the header and
+// footer copy-righter would stamp with the same settings, for example when
+// relicensing a tree.
+func runRemove(cmd *cobra.Command, args []string) {
+	r := newRunner(cmd, args)
+	r.remove = true
+	r.dryRun, _ = cmd.Flags().GetBool("dry-run")
+	if stat, _ := cmd.Flags().GetBool("stat"); stat {
+		r.stat = &diffStat{}
+	}
+	r.setPatch(cmd)
+
+	r.runArgs(args)
+
+	if r.stat != nil {
+		r.stat.print(r.out)
+	}
+	r.writePatch()
+	r.finish()
+	os.Exit(r.summary.exitCode())
+}
+
+// reportRemoval prints what copyrighter.Remove did to a file.
+func (r *runner) reportRemoval(filePath string, result copyrighter.Result) {
+	if result.Header == copyrighter.Removed {
+		r.logf(logNormal, "Removing copyright header from: %s\n", filePath)
+	}
+	if result.Footer == copyrighter.Removed {
+		r.logf(logNormal, "Removing copyright footer from: %s\n", filePath)
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// isRepoRoot reports whether dir is the root of a git repository. A .git
+// file marks a submodule or worktree.
+func isRepoRoot(dir string) bool {
+	_, err := os.Lstat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// repoSummary is the share of a run that fell in one git repository.
+type repoSummary struct {
+	Root     string `json:"root"`
+	Scanned  int    `json:"scanned"`
+	Modified int    `json:"modified"`
+	UpToDate int    `json:"up_to_date"`
+	Outdated int    `json:"outdated"`
+	Skipped  int    `json:"skipped"`
+	Failed   int    `json:"failed"`
+}
+
+// repoIndex finds the repository of every file of a run and keeps the
+// results of each repository apart. Files outside any repository are
+// grouped under an empty root.
+type repoIndex struct {
+	// roots caches the repository root of every directory looked up.
+	roots     map[string]string
+	summaries map[string]*repoSummary
+}
+
+func newRepoIndex() *repoIndex {
+	return &repoIndex{roots: make(map[string]string), summaries: make(map[string]*repoSummary)}
+}
+
+// rootOf returns the root of the git repository that contains path, or
+// "" when path is not in one.
+func (x *repoIndex) rootOf(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	return x.dirRoot(filepath.Dir(abs))
+}
+
+func (x *repoIndex) dirRoot(dir string) string {
+	if root, ok := x.roots[dir]; ok {
+		return root
+	}
+	root := ""
+	if isRepoRoot(dir) {
+		root = dir
+	} else if parent := filepath.Dir(dir); parent != dir {
+		root = x.dirRoot(parent)
+	}
+	x.roots[dir] = root
+	return root
+}
+
+// summary returns the summary of the repository that contains path.
+func (x *repoIndex) summary(path string) *repoSummary {
+	root := x.rootOf(path)
+	s, ok := x.summaries[root]
+	if !ok {
+		s = &repoSummary{Root: root}
+		x.summaries[root] = s
+	}
+	return s
+}
+
+// add counts the outcome of a file in its repository's summary.
+func (x *repoIndex) add(f fileOutcome) {
+	s := x.summary(f.Path)
+	switch f.Status {
+	case "failed":
+		s.Failed++
+	case "generated", "excepted", "ignored", "skipped":
+	case "modified", "would_modify":
+		s.Scanned++
+		s.Modified++
+	case "baselined":
+		s.Scanned++
+	case "up_to_date", auditOK.String():
+		s.Scanned++
+		s.UpToDate++
+	default:
+		s.Scanned++
+		s.Outdated++
+	}
+}
+
+// grouped returns the per-repository summaries sorted by root, or nil
+// when the run stayed within a single repository.
+func (x *repoIndex) grouped() []repoSummary {
+	if x == nil || len(x.summaries) < 2 {
+		return nil
+	}
+	grouped := make([]repoSummary, 0, len(x.summaries))
+	for _, s := range x.summaries {
+		grouped = append(grouped, *s)
+	}
+	sort.Slice(grouped, func(i, j int) bool { return grouped[i].Root < grouped[j].Root })
+	return grouped
+}
+
+// console returns the line printed for a repository below the summary
+// of a run that spanned several.
+func (s repoSummary) console() string {
+	root := s.Root
+	if root == "" {
+		root = "(no repository)"
+	}
+	return fmt.Sprintf("  %s: %d scanned, %d modified, %d up to date, %d outdated, %d skipped, %d errored",
+		root, s.Scanned, s.Modified, s.UpToDate, s.Outdated, s.Skipped, s.Failed)
+}
+
+// record adds the outcome of a file to the outputs and to its
+// repository's summary.
+func (r *runner) record(f fileOutcome) {
+	if r.repos != nil {
+		f.Repository = r.repos.rootOf(f.Path)
+		r.repos.add(f)
+	}
+	r.outputs.record(f)
+}
+
+// skip counts a file left alone without being scanned.
+func (r *runner) skip(path string) {
+	r.summary.Skipped++
+	if r.repos != nil {
+		r.repos.summary(path).Skipped++
+	}
+}
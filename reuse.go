@@ -0,0 +1,415 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// The REUSE specification (reuse.software) expects every file to carry
+// its copyright and license as tagged lines, in a comment, in a
+// FILE.license sidecar or in the .reuse/dep5 file of the project, and the
+// text of every license used under LICENSES/.
+const (
+	reuseCopyrightTag = "SPDX-FileCopyrightText:"
+	reuseDep5File     = ".reuse/dep5"
+	reuseLicensesDir  = "LICENSES"
+)
+
+// reuseNotice tags the copyright lines of text as REUSE expects. When no
+// line looks like a copyright statement, every line is a holder line.
+func reuseNotice(text copyrightNotice) copyrightNotice {
+	lines := strings.Split(strings.TrimSpace(string(text)), "\n")
+	statements := slices.ContainsFunc(lines, copyrighter.CopyrightPattern.MatchString)
+	for i, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, reuseCopyrightTag) || statements && !copyrighter.CopyrightPattern.MatchString(line) {
+			continue
+		}
+		lines[i] = reuseCopyrightTag + " " + line
+	}
+	return copyrightNotice(strings.Join(lines, "\n"))
+}
+
+// dep5 is the .reuse/dep5 file of a project: paragraphs giving the
+// copyright and license of the files matching their patterns, for files
+// that cannot or should not carry them, such as images.
+type dep5 struct {
+	// root is the absolute directory the patterns are relative to.
+	root       string
+	paragraphs []dep5Paragraph
+}
+
+type dep5Paragraph struct {
+	patterns  []*regexp.Regexp
+	copyright string
+	license   string
+}
+
+// loadDep5 reads the dep5 file of the project at root, or returns nil if
+// it has none.
+func loadDep5(root string) (*dep5, error) {
+	data, err := os.ReadFile(filepath.Join(root, reuseDep5File))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	paragraphs, err := parseDep5(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Join(root, reuseDep5File), err)
+	}
+	return &dep5{root: abs, paragraphs: paragraphs}, nil
+}
+
+// parseDep5 parses the Files paragraphs of a machine-readable Debian
+// copyright file. The header paragraph and other fields are ignored.
+func parseDep5(data []byte) ([]dep5Paragraph, error) {
+	var paragraphs []dep5Paragraph
+	fields := make(map[string]string)
+	var last string
+	flush := func() error {
+		if files, ok := fields["files"]; ok {
+			p := dep5Paragraph{copyright: fields["copyright"], license: fields["license"]}
+			if strings.TrimSpace(files) == "" {
+				return errors.New("Files paragraph without patterns")
+			}
+			for _, glob := range strings.Fields(files) {
+				p.patterns = append(p.patterns, dep5Pattern(glob))
+			}
+			paragraphs = append(paragraphs, p)
+		}
+		clear(fields)
+		last = ""
+		return nil
+	}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), " \t\r")
+		switch {
+		case line == "":
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		case line[0] == ' ' || line[0] == '\t':
+			if last == "" {
+				return nil, fmt.Errorf("continuation line %q without a field", strings.TrimSpace(line))
+			}
+			fields[last] += "\n" + strings.TrimSpace(line)
+		default:
+			name, value, ok := strings.Cut(line, ":")
+			if !ok {
+				return nil, fmt.Errorf("line %q is not a field", line)
+			}
+			last = strings.ToLower(strings.TrimSpace(name))
+			fields[last] = strings.TrimSpace(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return paragraphs, flush()
+}
+
+// dep5Pattern compiles a dep5 file pattern, where * matches any run of
+// characters, slashes included, and ? any one character.
+func dep5Pattern(glob string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(strings.TrimPrefix(glob, "./"))
+	quoted = strings.ReplaceAll(quoted, `\*`, `.*`)
+	quoted = strings.ReplaceAll(quoted, `\?`, `.`)
+	return regexp.MustCompile("^" + quoted + "$")
+}
+
+// covers returns the paragraph giving the copyright and license of path,
+// the last one matching as the format specifies, or nil.
+func (d *dep5) covers(path string) *dep5Paragraph {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil
+	}
+	rel, err := filepath.Rel(d.root, abs)
+	if err != nil {
+		return nil
+	}
+	rel = filepath.ToSlash(rel)
+	for i := len(d.paragraphs) - 1; i >= 0; i-- {
+		for _, re := range d.paragraphs[i].patterns {
+			if re.MatchString(rel) {
+				return &d.paragraphs[i]
+			}
+		}
+	}
+	return nil
+}
+
+// skipDep5 records a file whose copyright and license the dep5 file
+// gives, so it must not get a header of its own.
+func (r *runner) skipDep5(filePath string) {
+	r.logf(logVerbose, "Skipping file covered by %s: %s\n", reuseDep5File, filePath)
+	r.skip(filePath)
+	r.record(fileOutcome{Path: filePath, Status: "skipped"})
+}
+
+// reuseReport is the outcome of reuse-lint for a project.
+type reuseReport struct {
+	files int
+	// noCopyright and noLicense list the files without a copyright or
+	// license, by path relative to the project.
+	noCopyright []string
+	noLicense   []string
+	// missingLicenses maps the licenses used without a text under
+	// LICENSES to a file using them; unusedLicenses are texts no file
+	// uses.
+	missingLicenses map[string]string
+	unusedLicenses  []string
+}
+
+func (rep *reuseReport) compliant() bool {
+	return len(rep.noCopyright) == 0 && len(rep.noLicense) == 0 && len(rep.missingLicenses) == 0 && len(rep.unusedLicenses) == 0
+}
+
+// runReuseLint implements the reuse-lint subcommand.
+func runReuseLint(cmd *cobra.Command, args []string) {
+	root := "."
+	if len(args) > 0 {
+		root = args[0]
+	}
+	rep, err := lintReuse(root)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	rep.print(os.Stdout)
+	if !rep.compliant() {
+		os.Exit(exitChanged)
+	}
+}
+
+// reuseLicenseTag matches the license line of a file, capturing the
+// expression.
+var reuseLicenseTag = regexp.MustCompile(copyrighter.SPDXTag + `\s*(.*)`)
+
+// lintReuse checks the project at root against the REUSE specification:
+// every file must have a copyright and a license, and every license used
+// must have its text under LICENSES, which must hold no others. Files git
+// ignores are not checked.
+func lintReuse(root string) (*reuseReport, error) {
+	d, err := loadDep5(root)
+	if err != nil {
+		return nil, err
+	}
+	files, err := reuseFiles(root)
+	if err != nil {
+		return nil, err
+	}
+	texts, err := licenseTexts(root)
+	if err != nil {
+		return nil, err
+	}
+
+	rep := &reuseReport{missingLicenses: make(map[string]string)}
+	used := make(map[string]bool)
+	for _, rel := range files {
+		path := filepath.Join(root, filepath.FromSlash(rel))
+		var copyright bool
+		var licenses []string
+		for _, source := range []string{path, path + ".license"} {
+			c, l, err := reuseInfo(source)
+			if err != nil {
+				return nil, err
+			}
+			copyright = copyright || c
+			licenses = append(licenses, l...)
+		}
+		if d != nil {
+			if p := d.covers(path); p != nil {
+				copyright = copyright || p.copyright != ""
+				if p.license != "" {
+					licenses = append(licenses, strings.SplitN(p.license, "\n", 2)[0])
+				}
+			}
+		}
+		rep.files++
+		if !copyright {
+			rep.noCopyright = append(rep.noCopyright, rel)
+		}
+		if len(licenses) == 0 {
+			rep.noLicense = append(rep.noLicense, rel)
+		}
+		for _, expr := range licenses {
+			for _, id := range licenseIDs(expr) {
+				used[id] = true
+				if _, ok := rep.missingLicenses[id]; !ok && !texts[id] {
+					rep.missingLicenses[id] = rel
+				}
+			}
+		}
+	}
+	for id := range texts {
+		if !used[id] {
+			rep.unusedLicenses = append(rep.unusedLicenses, id)
+		}
+	}
+	sort.Strings(rep.unusedLicenses)
+	return rep, nil
+}
+
+// reuseFiles lists the files of the project at root REUSE covers, by
+// slash-separated path relative to root and sorted. In a git work tree
+// files git ignores are left out.
+func reuseFiles(root string) ([]string, error) {
+	var files []string
+	if out, err := git("-C", root, "ls-files", "--cached", "--others", "--exclude-standard", "-z"); err == nil {
+		for _, rel := range splitNul(out) {
+			if info, err := os.Lstat(filepath.Join(root, rel)); err == nil && info.Mode().IsRegular() {
+				files = append(files, rel)
+			}
+		}
+	} else {
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.IsDir() && d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			if d.Type().IsRegular() {
+				rel, err := filepath.Rel(root, path)
+				if err != nil {
+					return err
+				}
+				files = append(files, filepath.ToSlash(rel))
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	covered := files[:0]
+	for _, rel := range files {
+		if !reuseExempt(rel) {
+			covered = append(covered, rel)
+		}
+	}
+	sort.Strings(covered)
+	return covered, nil
+}
+
+// reuseExempt reports whether the specification exempts a file: the
+// license texts, the REUSE metadata, .license sidecars and the license
+// files at the top of the project.
+func reuseExempt(rel string) bool {
+	first, _, _ := strings.Cut(rel, "/")
+	upper := strings.ToUpper(rel)
+	return first == reuseLicensesDir || first == ".reuse" ||
+		strings.HasSuffix(rel, ".license") ||
+		!strings.Contains(rel, "/") && (strings.HasPrefix(upper, "LICENSE") || strings.HasPrefix(upper, "LICENCE") || strings.HasPrefix(upper, "COPYING"))
+}
+
+// licenseTexts returns the identifiers of the license texts under
+// LICENSES, named after them with any extension.
+func licenseTexts(root string) (map[string]bool, error) {
+	entries, err := os.ReadDir(filepath.Join(root, reuseLicensesDir))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	texts := make(map[string]bool, len(entries))
+	for _, e := range entries {
+		if !e.IsDir() {
+			texts[strings.TrimSuffix(e.Name(), filepath.Ext(e.Name()))] = true
+		}
+	}
+	return texts, nil
+}
+
+// reuseInfo reports whether the file at path carries a copyright and
+// returns the license expressions it declares. A missing or binary file
+// declares nothing.
+func reuseInfo(path string) (copyright bool, licenses []string, err error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+	defer f.Close()
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return false, nil, err
+	}
+	if bytes.IndexByte(content[:min(len(content), 8000)], 0) >= 0 {
+		return false, nil, nil
+	}
+	for line := range strings.Lines(string(content)) {
+		if strings.Contains(line, reuseCopyrightTag) || copyrighter.CopyrightPattern.MatchString(line) {
+			copyright = true
+		}
+		if m := reuseLicenseTag.FindStringSubmatch(line); m != nil {
+			if expr := strings.TrimRight(m[1], commentMarkers+"\r\n"); expr != "" {
+				licenses = append(licenses, expr)
+			}
+		}
+	}
+	return copyright, licenses, nil
+}
+
+// licenseIDs returns the license and exception identifiers of an SPDX
+// license expression.
+func licenseIDs(expr string) []string {
+	var ids []string
+	for _, token := range strings.FieldsFunc(expr, func(r rune) bool { return r == ' ' || r == '(' || r == ')' }) {
+		switch token {
+		case "AND", "OR", "WITH":
+		default:
+			ids = append(ids, strings.TrimSuffix(token, "+"))
+		}
+	}
+	return ids
+}
+
+func (rep *reuseReport) print(w io.Writer) {
+	for _, rel := range rep.noCopyright {
+		fmt.Fprintf(w, "%s: no copyright\n", rel)
+	}
+	for _, rel := range rep.noLicense {
+		fmt.Fprintf(w, "%s: no license\n", rel)
+	}
+	ids := make([]string, 0, len(rep.missingLicenses))
+	for id := range rep.missingLicenses {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		fmt.Fprintf(w, "%s/%s.txt: missing, %s is used by %s\n", reuseLicensesDir, id, id, rep.missingLicenses[id])
+	}
+	for _, id := range rep.unusedLicenses {
+		fmt.Fprintf(w, "%s/%s: not used by any file\n", reuseLicensesDir, id)
+	}
+	if rep.compliant() {
+		fmt.Fprintf(w, "All %d %s comply with the REUSE specification\n", rep.files, plural(rep.files, "file", "files"))
+		return
+	}
+	fmt.Fprintf(w, "Not REUSE compliant: %d of %d %s without a copyright, %d without a license, %d missing and %d unused license %s\n",
+		len(rep.noCopyright), rep.files, plural(rep.files, "file", "files"), len(rep.noLicense), len(rep.missingLicenses), len(rep.unusedLicenses), plural(len(rep.missingLicenses)+len(rep.unusedLicenses), "text", "texts"))
+}
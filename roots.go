@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// resolvePath returns the absolute path of path with every symlink in it
+// resolved, which is where a write to path actually lands.
+func resolvePath(path string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Abs(resolved)
+}
+
+// resolveRoots resolves the configured roots once per run.
+func resolveRoots(roots []string) ([]string, error) {
+	resolved := make([]string, 0, len(roots))
+	for _, root := range roots {
+		abs, err := resolvePath(root)
+		if err != nil {
+			return nil, fmt.Errorf("root %s: %w", root, err)
+		}
+		resolved = append(resolved, abs)
+	}
+	return resolved, nil
+}
+
+// within reports whether path is root or lies beneath it. Both must be
+// absolute and clean.
+func within(path, root string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// checkContained refuses a file whose resolved location is outside the
+// allowed roots, so a stray symlink cannot make the run rewrite a file
+// elsewhere on the machine. Without configured roots, the argument the
+// file was found under is the only root.
+func (r *runner) checkContained(path, arg string) error {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return err
+	}
+	roots := r.roots
+	if len(roots) == 0 {
+		root, err := resolvePath(arg)
+		if err != nil {
+			return err
+		}
+		roots = []string{root}
+	}
+	for _, root := range roots {
+		if within(resolved, root) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%s resolves to %s, %w (%s); refusing to process it", path, resolved, errOutsideRoots, strings.Join(roots, ", "))
+}
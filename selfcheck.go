@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"encoding/xml"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// corpusFS holds representative source files for every supported language.
+// selfcheck stamps each of them and verifies the result is still valid.
+//
+//go:embed testdata/corpus
+var corpusFS embed.FS
+
+const corpusRoot = "testdata/corpus"
+
+// selfcheckHeaders are stamped onto every corpus file in turn, covering both
+// single-line headers and multi-line comment blocks.
+var selfcheckHeaders = []string{
+	"Copyright (c) 2025 Example Corp. All rights reserved.",
+	"Copyright (c) 2025 Example Corp.\n\nLicensed under the Apache License, Version 2.0.\nSee LICENSE for details.",
+}
+
+// syntaxValidators check that stamped output still parses, for languages
+// where a parser is available without external tooling.
+var syntaxValidators = map[string]func(name string, src []byte) error{
+	".go":     validateGo,
+	".svg":    validateXML,
+	".xml":    validateXML,
+	".yaml":   validateYAML,
+	".yml":    validateYAML,
+	".tmpl":   validateGoTemplate,
+	".gotmpl": validateGoTemplate,
+}
+
+// validateGo checks that src parses and that its build constraints are
+// still honored: go/build only reads them above the last blank line before
+// the package clause.
+func validateGo(name string, src []byte) error {
+	if _, err := parser.ParseFile(token.NewFileSet(), name, src, parser.ParseComments); err != nil {
+		return err
+	}
+	lines := strings.Split(string(src), "\n")
+	end := 0
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			end = i
+		} else if !strings.HasPrefix(trimmed, "//") {
+			break
+		}
+	}
+	for i, line := range lines {
+		if copyrighter.GoBuildConstraint.MatchString(line) && i >= end {
+			return fmt.Errorf("line %d: build constraint is no longer honored", i+1)
+		}
+	}
+	return nil
+}
+
+// validateYAML checks that every document in src parses.
+func validateYAML(name string, src []byte) error {
+	dec := yaml.NewDecoder(bytes.NewReader(src))
+	for {
+		var doc any
+		if err := dec.Decode(&doc); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+// validateGoTemplate checks that src parses as a text/template and that the
+// stamped notice does not leak into the rendered output.
+func validateGoTemplate(name string, src []byte) error {
+	tmpl, err := template.New(name).Parse(string(src))
+	if err != nil {
+		return err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, nil); err != nil {
+		return err
+	}
+	if copyrighter.CopyrightPattern.Match(out.Bytes()) {
+		return fmt.Errorf("copyright notice leaks into rendered output")
+	}
+	return nil
+}
+
+// validateXML checks that src is well-formed XML.
+func validateXML(name string, src []byte) error {
+	dec := xml.NewDecoder(bytes.NewReader(src))
+	for {
+		if _, err := dec.Token(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+	}
+}
+
+func runSelfcheck(cmd *cobra.Command, args []string) {
+	failures := 0
+	covered := make(map[string]bool)
+
+	err := fs.WalkDir(corpusFS, corpusRoot, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		covered[strings.ToLower(path.Ext(p))] = true
+		for i, header := range selfcheckHeaders {
+			if err := selfcheckFile(p, header); err != nil {
+				fmt.Printf("FAIL %s (header %d): %v\n", p, i+1, err)
+				failures++
+				return nil
+			}
+		}
+		fmt.Printf("ok   %s\n", p)
+		return nil
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error reading embedded corpus: %v\n", err)
+		os.Exit(exitError)
+	}
+
+	for _, ext := range copyrighter.Extensions {
+		if !covered[ext] {
+			fmt.Printf("FAIL %s: no corpus files for supported extension\n", ext)
+			failures++
+		}
+	}
+
+	if failures > 0 {
+		fmt.Printf("selfcheck failed: %d problem(s)\n", failures)
+		os.Exit(exitError)
+	}
+	fmt.Println("selfcheck passed")
+}
+
+// selfcheckFile stamps one corpus file in memory with the given header text
+// and verifies placement, syntax and idempotency of the result.
+func selfcheckFile(name, copyrightText string) error {
+	src, err := corpusFS.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return checkStamping(name, src, copyrightText)
+}
+
+// checkStamping stamps src in memory and verifies placement, syntax and
+// idempotency of the result. Syntax is only checked for sources that were
+// valid to begin with, such as fixtures cut from the middle of a file.
+func checkStamping(name string, src []byte, copyrightText string) error {
+	style, ok := copyrighter.StyleFor(name)
+	if !ok {
+		return fmt.Errorf("no comment style for %s", path.Ext(name))
+	}
+	header := strings.Join(style.Render(copyrightText), "\n")
+	opts := copyrighter.Options{Copyright: copyrightText, Style: style}
+	stamped, _, err := copyrighter.Stamp(string(src), opts)
+	if err != nil {
+		return err
+	}
+
+	lines := strings.Split(strings.TrimRight(stamped, "\n"), "\n")
+	_, body := copyrighter.SplitPreamble(lines, copyrighter.PreamblePatterns(style, nil))
+	if !strings.HasPrefix(strings.Join(body, "\n"), header+"\n") {
+		return fmt.Errorf("header not at the start of the file after the preamble")
+	}
+	if !strings.HasSuffix(strings.Join(lines, "\n"), "\n"+header) {
+		return fmt.Errorf("footer not at the end of the file")
+	}
+
+	srcLines := strings.Split(string(src), "\n")
+	for _, line := range srcLines {
+		if style.IsDirective(line) && !strings.Contains(stamped, line+"\n") {
+			return fmt.Errorf("directive %q lost", line)
+		}
+	}
+	if i := style.CodeComment(srcLines); i < len(srcLines) && !strings.Contains(stamped, srcLines[i]+"\n") {
+		return fmt.Errorf("documentation comment %q lost", srcLines[i])
+	}
+
+	if validate, ok := syntaxValidators[strings.ToLower(path.Ext(name))]; ok && validate(name, src) == nil {
+		if err := validate(name, []byte(stamped)); err != nil {
+			return fmt.Errorf("stamped output is not valid: %w", err)
+		}
+	}
+
+	again, result, err := copyrighter.Stamp(stamped, opts)
+	if err != nil {
+		return err
+	}
+	if result.Changed() || again != stamped {
+		return fmt.Errorf("second run changed the output")
+	}
+	return nil
+}
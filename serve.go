@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/earik87/copy-righter/pkg/client"
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// defaultServeAddr is the address serve listens on when --addr is not
+// given.
+const defaultServeAddr = "localhost:8080"
+
+// The requests and responses of serve are the types of its client, so the
+// two cannot drift apart.
+type (
+	serveRequest  = client.Request
+	serveResponse = client.Response
+)
+
+// runServe implements the serve subcommand: it answers stamp and check
+// requests over HTTP with the settings of the working directory until
+// interrupted, so bots and code review tools apply the same rules as the
+// CLI without a checkout.
+func runServe(cmd *cobra.Command, _ []string) {
+	addr, _ := cmd.Flags().GetString("addr")
+	r := newRunner(cmd, []string{"."})
+
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	server := &http.Server{Handler: r.serveMux(), ReadHeaderTimeout: 10 * time.Second}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = server.Shutdown(shutdown)
+	}()
+
+	fmt.Fprintf(r.out, "Serving on http://%s\n", ln.Addr())
+	if err := server.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// serveMux routes the endpoints of serve.
+func (r *runner) serveMux() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/stamp", func(w http.ResponseWriter, req *http.Request) {
+		r.serveFile(w, req, false)
+	})
+	mux.HandleFunc("POST /v1/check", func(w http.ResponseWriter, req *http.Request) {
+		r.serveFile(w, req, true)
+	})
+	mux.HandleFunc("GET /v1/openapi.yaml", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		_, _ = w.Write(client.OpenAPI)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "ok")
+	})
+	return mux
+}
+
+// maxJSONEscape is the most bytes JSON encodes a byte of content in, as
+// \u00XX.
+const maxJSONEscape = 6
+
+// serveFile answers a stamp or check request. A bad request gets 400, one
+// whose content is over --max-file-size 413 and an unsupported language
+// 422; the verdict itself is always 200.
+func (r *runner) serveFile(w http.ResponseWriter, req *http.Request, check bool) {
+	var body serveRequest
+	if r.maxFileSize > 0 {
+		// Bound the JSON so a huge body is not decoded at all; the limit
+		// itself is on the content, checked below.
+		req.Body = http.MaxBytesReader(w, req.Body, maxJSONEscape*r.maxFileSize+64<<10)
+	}
+	dec := json.NewDecoder(req.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&body); err != nil {
+		code := http.StatusBadRequest
+		if tooLarge := (*http.MaxBytesError)(nil); errors.As(err, &tooLarge) {
+			code = http.StatusRequestEntityTooLarge
+		}
+		writeServeResponse(w, code, serveResponse{Status: "failed", Error: "invalid request: " + err.Error()})
+		return
+	}
+	if r.maxFileSize > 0 && int64(len(body.Content)) > r.maxFileSize {
+		writeServeResponse(w, http.StatusRequestEntityTooLarge, serveResponse{Status: "failed", Error: fmt.Sprintf("content of %d bytes is over the limit of %d", len(body.Content), r.maxFileSize)})
+		return
+	}
+	path, err := servePath(body)
+	if err != nil {
+		writeServeResponse(w, http.StatusBadRequest, serveResponse{Status: "failed", Error: err.Error()})
+		return
+	}
+	resp, err := r.verdict(path, body.Content, check)
+	switch {
+	case errors.Is(err, copyrighter.ErrUnsupported):
+		writeServeResponse(w, http.StatusUnprocessableEntity, serveResponse{Status: "failed", Error: err.Error()})
+	case err != nil:
+		writeServeResponse(w, http.StatusInternalServerError, serveResponse{Status: "failed", Error: err.Error()})
+	default:
+		writeServeResponse(w, http.StatusOK, resp)
+	}
+}
+
+// servePath returns the path the content of a request is stamped as: its
+// path, cleaned, or a file name with the extension of its language. The
+// path is matched against the holders, exceptions and .gitattributes of
+// the working directory, so one that is absolute or climbs out of it is
+// refused.
+func servePath(body serveRequest) (string, error) {
+	if body.Path != "" {
+		path := filepath.Clean(filepath.FromSlash(body.Path))
+		if !filepath.IsLocal(path) {
+			return "", fmt.Errorf("path %q is not inside the working directory", body.Path)
+		}
+		return path, nil
+	}
+	if body.Language == "" {
+		return "", errors.New("request needs a path or a language")
+	}
+	for _, lang := range copyrighter.Languages {
+		if strings.EqualFold(lang.Name, body.Language) {
+			return "file" + lang.Extensions[0], nil
+		}
+	}
+	if strings.ContainsAny(body.Language, `/\`) {
+		return "", fmt.Errorf("invalid language %q", body.Language)
+	}
+	return "file." + strings.TrimPrefix(body.Language, "."), nil
+}
+
+// verdict runs the content of the file at path through the same steps as
+// processFile, without reading or writing anything and without touching
+// the summary of the runner, which requests share.
+func (r *runner) verdict(path, content string, check bool) (serveResponse, error) {
+	h := r.holderFor(path)
+	if h != nil && h.Skip {
+		return serveResponse{Status: "skipped"}, nil
+	}
+	if r.dep5 != nil && r.dep5.covers(path) != nil {
+		return serveResponse{Status: "skipped"}, nil
+	}
+	opts, err := r.processor.OptionsFor(path)
+	if err != nil {
+		return serveResponse{}, err
+	}
+	if h != nil {
+		opts.Copyright = string(h.Copyright)
+		if h.SPDX != "" {
+			opts.SPDX = h.SPDX
+		}
+	}
+	if !r.includeGenerated && (copyrighter.IsGenerated(content, r.generated) || r.attributes.set(path, linguistGenerated)) {
+		return serveResponse{Status: "generated"}, nil
+	}
+	if copyrighter.IsIgnored(content) {
+		return serveResponse{Status: "ignored"}, nil
+	}
+	if r.exceptionFor(path) != nil {
+		return serveResponse{Status: "excepted"}, nil
+	}
+
+	st, err := r.stamp(content, opts, check)
+	if err != nil {
+		return serveResponse{}, err
+	}
+	if st.foreign != "" {
+		return serveResponse{Status: "foreign", Problems: []string{"foreign header"}, ForeignHeader: st.foreign}, nil
+	}
+
+	resp := serveResponse{Status: "up_to_date", Problems: problemList(st.result), Warnings: st.warnings}
+	if check {
+		if st.result.Changed() {
+			resp.Status = "outdated"
+		}
+		return resp, nil
+	}
+	if st.result.Changed() {
+		resp.Status = "modified"
+		resp.Actions = actionList(st.result)
+	}
+	resp.Content = &st.content
+	return resp, nil
+}
+
+func writeServeResponse(w http.ResponseWriter, code int, resp serveResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
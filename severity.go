@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// severity ranks a finding. Check fails on errors and only reports
+// warnings; fix mode acts on findings at or above the fix severity.
+type severity int
+
+const (
+	severityOff severity = iota
+	severityWarning
+	severityError
+)
+
+var severityNames = map[string]severity{
+	"off":     severityOff,
+	"warning": severityWarning,
+	"error":   severityError,
+}
+
+func (s severity) String() string {
+	switch s {
+	case severityOff:
+		return "off"
+	case severityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+func parseSeverity(value string) (severity, error) {
+	s, ok := severityNames[strings.ToLower(strings.TrimSpace(value))]
+	if !ok {
+		return 0, fmt.Errorf("invalid severity %q (want off, warning or error)", value)
+	}
+	return s, nil
+}
+
+// findingKinds are the findings a severity can be configured for, named
+// as in the config file and the SARIF rule IDs. A stale year is an
+// outdated header or footer that differs from the notice only in its
+// years.
+var findingKinds = []string{"missing-header", "outdated-header", "missing-footer", "outdated-footer", "stale-year"}
+
+// severityPolicy maps every finding to a severity. Findings not listed
+// are errors, and fix mode fixes every finding that is not off, unless
+// configured otherwise.
+type severityPolicy struct {
+	levels map[string]severity
+	fix    severity
+}
+
+// newSeverityPolicy validates the severity and fix_severity settings.
+func newSeverityPolicy(levels map[string]string, fix string) (severityPolicy, error) {
+	p := severityPolicy{levels: make(map[string]severity, len(levels)), fix: severityWarning}
+	kinds := make([]string, 0, len(levels))
+	for kind := range levels {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		if !slices.Contains(findingKinds, kind) {
+			return p, fmt.Errorf("severity: unknown finding %q (known: %s)", kind, strings.Join(findingKinds, ", "))
+		}
+		s, err := parseSeverity(levels[kind])
+		if err != nil {
+			return p, fmt.Errorf("severity of %s: %w", kind, err)
+		}
+		p.levels[kind] = s
+	}
+	if fix != "" {
+		s, err := parseSeverity(fix)
+		if err != nil {
+			return p, fmt.Errorf("fix_severity: %w", err)
+		}
+		if s == severityOff {
+			return p, fmt.Errorf("fix_severity: %q would fix nothing; use check instead", fix)
+		}
+		p.fix = s
+	}
+	return p, nil
+}
+
+// of returns the severity of a finding.
+func (p severityPolicy) of(finding string) severity {
+	if s, ok := p.levels[finding]; ok {
+		return s
+	}
+	return severityError
+}
+
+// partFinding names what is wrong with a header or footer given the
+// action stamping took on it, or returns "" when it is up to date.
+func partFinding(part string, action copyrighter.Action, existing, expected []string) string {
+	switch action {
+	case copyrighter.Added:
+		return "missing-" + part
+	case copyrighter.Updated:
+		if sameIgnoringYears(existing, expected) && copyrighter.NoticeHash(existing) != copyrighter.NoticeHash(expected) {
+			return "stale-year"
+		}
+		return "outdated-" + part
+	}
+	return ""
+}
+
+// sameIgnoringYears reports whether two notices that mention a year
+// differ in their years only.
+func sameIgnoringYears(a, b []string) bool {
+	if len(a) != len(b) || !copyrighter.YearRangePattern.MatchString(strings.Join(b, "\n")) {
+		return false
+	}
+	mask := func(lines []string) []string {
+		masked := make([]string, len(lines))
+		for i, line := range lines {
+			masked[i] = copyrighter.YearRangePattern.ReplaceAllString(line, "YEAR")
+		}
+		return masked
+	}
+	return copyrighter.NoticeHash(mask(a)) == copyrighter.NoticeHash(mask(b))
+}
+
+// triage applies the severity policy to the findings stamping content
+// reported in result. The returned options leave the findings below
+// threshold in place; they are returned as warnings unless they are off.
+func (p severityPolicy) triage(content string, opts copyrighter.Options, threshold severity, result copyrighter.Result) (copyrighter.Options, []string) {
+	if !result.Changed() {
+		return opts, nil
+	}
+	_, body := copyrighter.SplitContent(content, opts)
+	header, footer := copyrighter.ExpectedNotice(body, opts)
+	headerFinding := partFinding("header", result.Header, body[:opts.Style.LeadingComment(body, len(header))], header)
+	footerFinding := partFinding("footer", result.Footer, body[len(body)-opts.Style.TrailingComment(body, len(footer)):], footer)
+
+	var warnings []string
+	leave := func(finding string) bool {
+		if finding == "" || p.of(finding) >= threshold {
+			return false
+		}
+		if p.of(finding) != severityOff {
+			warnings = append(warnings, strings.ReplaceAll(finding, "-", " "))
+		}
+		return true
+	}
+	opts.LeaveHeader = opts.LeaveHeader || leave(headerFinding)
+	opts.LeaveFooter = opts.LeaveFooter || leave(footerFinding)
+	return opts, warnings
+}
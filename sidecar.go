@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// defaultSidecar is the manifest sidecar stamps are kept in when
+// sidecar_extensions is set without a sidecar path.
+const defaultSidecar = ".copyrighter-stamps.json"
+
+// sidecarManifest records the stamps of files that cannot carry a comment,
+// such as strict JSON, so the policy still covers them. Each file is keyed
+// by its path relative to the manifest and stamped with the hash of the
+// notice that applies to it.
+type sidecarManifest struct {
+	Files map[string]string `json:"files"`
+
+	path     string
+	original []byte
+	dirty    bool
+}
+
+// loadSidecar reads the manifest at path; a missing one is empty.
+func loadSidecar(path string) (*sidecarManifest, error) {
+	m := &sidecarManifest{path: path, Files: make(map[string]string)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("parsing sidecar %s: %w", path, err)
+	}
+	if m.Files == nil {
+		m.Files = make(map[string]string)
+	}
+	m.original = data
+	return m, nil
+}
+
+// key returns the manifest key of filePath.
+func (m *sidecarManifest) key(filePath string) (string, error) {
+	abs, err := filepath.Abs(filePath)
+	if err != nil {
+		return "", err
+	}
+	dir, err := filepath.Abs(filepath.Dir(m.path))
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(dir, abs)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
+
+// sidecarStamp is the stamp recorded for a file under opts: the hash of
+// the notice with its SPDX identifier and annotations, so a change to
+// any of them outdates every stamp.
+func sidecarStamp(opts copyrighter.Options) string {
+	return copyrighter.NoticeHash([]string{copyrighter.HeaderText(opts.Copyright, opts.SPDX, opts.MaintainedBy, opts.TemplateVersion)})
+}
+
+// usesSidecar reports whether filePath is stamped in the sidecar manifest.
+func (r *runner) usesSidecar(filePath string) bool {
+	return r.sidecar != nil && slices.Contains(r.sidecarExtensions, strings.ToLower(filepath.Ext(filePath)))
+}
+
+// processSidecar stamps, checks or removes the sidecar stamp of filePath,
+// leaving the file itself untouched.
+func (r *runner) processSidecar(filePath string) (bool, error) {
+	key, err := r.sidecar.key(filePath)
+	if err != nil {
+		return false, err
+	}
+	if key == filepath.Base(r.sidecar.path) {
+		r.logf(logVerbose, "Skipping the sidecar manifest: %s\n", filePath)
+		r.skip(filePath)
+		return false, nil
+	}
+	opts := r.processor.Options
+	if opts.Copyright, err = r.processor.Notice(filePath); err != nil {
+		return false, err
+	}
+	stamp := sidecarStamp(opts)
+	current, stamped := r.sidecar.Files[key]
+	action := copyrighter.UpToDate
+	switch {
+	case r.remove:
+		if stamped {
+			action = copyrighter.Removed
+		}
+	case !stamped:
+		action = copyrighter.Added
+	case current != stamp:
+		action = copyrighter.Updated
+	}
+
+	r.summary.Scanned++
+	outcome := fileOutcome{Path: filePath, Status: "up_to_date"}
+	var problem string
+	status := auditOK
+	switch action {
+	case copyrighter.Added:
+		problem, status = "missing sidecar stamp", auditMissing
+	case copyrighter.Updated:
+		problem, status = "outdated sidecar stamp", auditOutdated
+	}
+	if problem != "" {
+		outcome.Problems = []string{problem}
+	}
+	switch {
+	case r.audit != nil:
+		r.audit.add(filePath, status, problem)
+		outcome.Status = status.String()
+		if status == auditOK {
+			r.summary.UpToDate++
+		} else {
+			r.summary.Outdated++
+		}
+	case action == copyrighter.UpToDate:
+		if r.remove {
+			r.logf(logVerbose, "No sidecar stamp to remove for: %s\n", filePath)
+		} else {
+			r.logf(logVerbose, "Sidecar stamp already up to date for: %s\n", filePath)
+		}
+		r.summary.UpToDate++
+	case r.check:
+		r.logf(logQuiet, "%s: %s\n", filePath, problem)
+		r.summary.Outdated++
+		outcome.Status = "outdated"
+	default:
+		switch action {
+		case copyrighter.Added:
+			r.logf(logNormal, "Adding sidecar stamp for: %s\n", filePath)
+			outcome.Actions = []string{"sidecar stamp added"}
+			r.summary.Added++
+		case copyrighter.Updated:
+			r.logf(logNormal, "Updating sidecar stamp for: %s\n", filePath)
+			outcome.Actions = []string{"sidecar stamp updated"}
+			r.summary.Updated++
+		case copyrighter.Removed:
+			r.logf(logNormal, "Removing sidecar stamp for: %s\n", filePath)
+			outcome.Actions = []string{"sidecar stamp removed"}
+		}
+		r.summary.Modified++
+		if r.dryRun {
+			r.logf(logNormal, "Dry run, not writing: %s\n", r.sidecar.path)
+			outcome.Status = "would_modify"
+			break
+		}
+		if action == copyrighter.Removed {
+			delete(r.sidecar.Files, key)
+		} else {
+			r.sidecar.Files[key] = stamp
+		}
+		r.sidecar.dirty = true
+		outcome.Status = "modified"
+	}
+	r.record(outcome)
+	return outcome.Status == "modified", nil
+}
+
+// saveSidecar writes the manifest if the run changed it.
+func (r *runner) saveSidecar() error {
+	if r.sidecar == nil || !r.sidecar.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(r.sidecar, "", "  ")
+	if err != nil {
+		return err
+	}
+	return r.writeFile(r.sidecar.path, r.sidecar.original, append(data, '\n'))
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"unicode/utf8"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// source is the content of a file being processed. A large file is held
+// as the view of its head and tail, see copyrighter.Windows: its middle
+// stays on disk and is copied through when the file is written, so memory
+// does not grow with the size of data files and bundles.
+type source struct {
+	path    string
+	enc     sourceEncoding
+	content string
+	// hash and lines are those of the whole file
+	hash  string
+	lines int
+	// windows is nil for a file read whole
+	windows *copyrighter.Windows
+	size    int64
+}
+
+// openSource reads the file at path for processing, as windows when it is
+// large unless whole is set. Patches and the review need the whole content,
+// so their runs read every file whole.
+func (r *runner) openSource(path string, whole bool) (*source, error) {
+	if !whole && r.patch == nil && r.review == nil {
+		src, err := openLargeSource(path)
+		if err != nil || src != nil {
+			return src, err
+		}
+	}
+	data, enc, err := r.readSource(path)
+	if err != nil {
+		return nil, err
+	}
+	content := string(data)
+	return &source{path: path, enc: enc, content: content, hash: hashString(content), lines: countLines(content)}, nil
+}
+
+// openLargeSource returns the windows of the file at path, or nil when it
+// is to be read whole: when it is small, or not UTF-8 without carriage
+// returns, which are decoded or normalised throughout.
+func openLargeSource(path string) (*source, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	w, err := copyrighter.ReadWindows(f, info.Size())
+	if err != nil || w == nil {
+		return nil, err
+	}
+	hash, lines, ok, err := scanSource(f)
+	if err != nil || !ok {
+		return nil, err
+	}
+	return &source{path: path, enc: utf8Encoding, content: w.View(), hash: hash, lines: lines, windows: w, size: info.Size()}, nil
+}
+
+// scanSource reads r once for the hash and line count of a large file,
+// and reports whether it is UTF-8 without carriage returns.
+func scanSource(r io.Reader) (hash string, lines int, ok bool, err error) {
+	h := sha256.New()
+	// A rune cut by the end of a chunk is carried over to the next one
+	buf := make([]byte, utf8.UTFMax+32<<10)
+	var carry []byte
+	var last byte
+	for {
+		c := copy(buf, carry)
+		n, err := r.Read(buf[c:])
+		chunk := buf[c : c+n]
+		if bytes.IndexByte(chunk, '\r') >= 0 {
+			return "", 0, false, nil
+		}
+		h.Write(chunk)
+		lines += bytes.Count(chunk, []byte{'\n'})
+		if n > 0 {
+			last = chunk[n-1]
+		}
+		data := buf[:c+n]
+		end := len(data)
+		for i := len(data) - 1; i >= 0 && i > len(data)-utf8.UTFMax; i-- {
+			if utf8.RuneStart(data[i]) {
+				if !utf8.FullRune(data[i:]) {
+					end = i
+				}
+				break
+			}
+		}
+		if !utf8.Valid(data[:end]) {
+			return "", 0, false, nil
+		}
+		carry = data[end:]
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", 0, false, err
+		}
+	}
+	if len(carry) > 0 {
+		return "", 0, false, nil
+	}
+	if last != '\n' {
+		lines++
+	}
+	return hex.EncodeToString(h.Sum(nil)), lines, true, nil
+}
+
+// fits reports whether content, the view of the source as Stamp or Remove
+// rewrote it, still has the middle of the file in place.
+func (s *source) fits(content string) bool {
+	if s.windows == nil {
+		return true
+	}
+	_, _, ok := copyrighter.SplitView(content)
+	return ok
+}
+
+// write writes content, the source as Stamp or Remove rewrote it, over its
+// file and returns the hash of its new content. The middle of a large file
+// is copied from the file into place.
+func (r *runner) write(s *source, content string) (string, error) {
+	if s.windows == nil {
+		if err := r.writeSource(s.path, s.enc, s.content, content); err != nil {
+			return "", err
+		}
+		return hashString(content), nil
+	}
+	before, after, ok := copyrighter.SplitView(content)
+	if !ok {
+		return "", errWindowLost
+	}
+	f, err := os.Open(s.path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err != nil {
+		return "", err
+	} else if info.Size() != s.size {
+		return "", errChanged
+	}
+	copyMiddle := func(w io.Writer) error {
+		if _, err := io.WriteString(w, before); err != nil {
+			return err
+		}
+		middle := io.NewSectionReader(f, s.windows.MiddleStart, s.windows.MiddleEnd-s.windows.MiddleStart)
+		if _, err := io.Copy(w, middle); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, after)
+		return err
+	}
+	h := sha256.New()
+	if err := copyMiddle(h); err != nil {
+		return "", err
+	}
+	hash := hex.EncodeToString(h.Sum(nil))
+	if err := r.journal.recordFrom(s.path, io.NewSectionReader(f, 0, s.size), s.hash, hash); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomicFunc(s.path, copyMiddle); err != nil {
+		return "", err
+	}
+	return hash, nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxStatBarWidth caps the +/- graph printed for each file, as git does.
+const maxStatBarWidth = 50
+
+// fileStat is the number of lines a run inserted into and deleted from one file.
+type fileStat struct {
+	path       string
+	insertions int
+	deletions  int
+}
+
+// diffStat accumulates a git-style diffstat over a run, together with the
+// totals needed to estimate how much of the scanned tree a rollout touches.
+type diffStat struct {
+	files        []fileStat
+	scannedFiles int
+	scannedLines int
+	byteDelta    int
+}
+
+// splitForDiff splits content into lines that keep their terminating
+// newline, so a missing final newline shows up as a changed line like git.
+func splitForDiff(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+func (s *diffStat) add(path, before, after string) {
+	beforeLines := splitForDiff(before)
+	s.scannedFiles++
+	s.scannedLines += len(beforeLines)
+	if before == after {
+		return
+	}
+
+	fs := fileStat{path: path}
+	for _, op := range diffLines(beforeLines, splitForDiff(after)) {
+		switch op.kind {
+		case '+':
+			fs.insertions++
+		case '-':
+			fs.deletions++
+		}
+	}
+	s.files = append(s.files, fs)
+	s.byteDelta += len(after) - len(before)
+}
+
+func (s *diffStat) print(w io.Writer) {
+	nameWidth, maxChanges := 0, 0
+	insertions, deletions := 0, 0
+	for _, f := range s.files {
+		nameWidth = max(nameWidth, len(f.path))
+		maxChanges = max(maxChanges, f.insertions+f.deletions)
+		insertions += f.insertions
+		deletions += f.deletions
+	}
+	countWidth := len(fmt.Sprint(maxChanges))
+
+	for _, f := range s.files {
+		plus, minus := f.insertions, f.deletions
+		if maxChanges > maxStatBarWidth {
+			plus = scaleStat(plus, maxChanges)
+			minus = scaleStat(minus, maxChanges)
+		}
+		fmt.Fprintf(w, " %-*s | %*d %s%s\n", nameWidth, f.path, countWidth, f.insertions+f.deletions,
+			strings.Repeat("+", plus), strings.Repeat("-", minus))
+	}
+
+	summary := fmt.Sprintf(" %d %s changed", len(s.files), plural(len(s.files), "file", "files"))
+	if insertions > 0 {
+		summary += fmt.Sprintf(", %d %s(+)", insertions, plural(insertions, "insertion", "insertions"))
+	}
+	if deletions > 0 {
+		summary += fmt.Sprintf(", %d %s(-)", deletions, plural(deletions, "deletion", "deletions"))
+	}
+	fmt.Fprintln(w, summary)
+
+	percent := 0.0
+	if s.scannedLines > 0 {
+		percent = float64(insertions+deletions) * 100 / float64(s.scannedLines)
+	}
+	fmt.Fprintf(w, "Estimated churn: %d of %d scanned lines (%.1f%%) in %d of %d scanned files, %+d bytes\n",
+		insertions+deletions, s.scannedLines, percent, len(s.files), s.scannedFiles, s.byteDelta)
+}
+
+// scaleStat scales a change count so the largest file's bar fits the width,
+// keeping at least one character for any non-zero count.
+func scaleStat(n, maxChanges int) int {
+	if n == 0 {
+		return 0
+	}
+	return max(1, n*maxStatBarWidth/maxChanges)
+}
+
+func plural(n int, singular, pluralForm string) string {
+	if n == 1 {
+		return singular
+	}
+	return pluralForm
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// runSummary is the machine-readable outcome of a run. It is what
+// notification hooks receive.
+type runSummary struct {
+	Command  string `json:"command"`
+	Scanned  int    `json:"scanned"`
+	Modified int    `json:"modified"`
+	UpToDate int    `json:"up_to_date"`
+	Outdated int    `json:"outdated"`
+	Failed   int    `json:"failed"`
+	// Added and Updated split Modified by fix runs into files that only
+	// gained a notice and files whose notice was rewritten.
+	Added   int `json:"added"`
+	Updated int `json:"updated"`
+	// Skipped counts files left alone without being scanned: generated,
+	// excluded, not included or of an unsupported type.
+	Skipped int `json:"skipped"`
+	// Repositories splits the counts by git repository when a run spans
+	// several.
+	Repositories []repoSummary `json:"repositories,omitempty"`
+	// Anomalies counts lines of license text found outside the header and
+	// footer by check.
+	Anomalies int `json:"anomalies,omitempty"`
+	// ExpiredReviews counts headers whose Maintained-by review date has
+	// passed, found by check.
+	ExpiredReviews int `json:"expired_reviews,omitempty"`
+	// Excepted counts the files whose findings an exception suppressed,
+	// and ExpiredExceptions the exceptions check found expired.
+	Excepted          int `json:"excepted,omitempty"`
+	ExpiredExceptions int `json:"expired_exceptions,omitempty"`
+	// PackagesWithoutNotice counts the Go packages lacking the file that
+	// package_notice says carries their notice.
+	PackagesWithoutNotice int `json:"packages_without_notice,omitempty"`
+	// Warnings counts findings reported below the severity that fails
+	// check or that fix mode acts on.
+	Warnings int `json:"warnings,omitempty"`
+	// Foreign counts the files whose header belongs to another party and
+	// was left in place.
+	Foreign int `json:"foreign,omitempty"`
+	// Baselined counts the outdated files check tolerated because the
+	// baseline lists them.
+	Baselined int `json:"baselined,omitempty"`
+	// LegacyHeaders counts the files check accepted for a header in the
+	// format of the tool the config was imported from.
+	LegacyHeaders int `json:"legacy_headers,omitempty"`
+	// TemplateVersions counts the checked files by the template version
+	// of their header, "none" for files without one.
+	TemplateVersions map[string]int `json:"template_versions,omitempty"`
+	DryRun           bool           `json:"dry_run"`
+	// EnforcementSkipped records that COPYRIGHTER_SKIP bypassed the check.
+	EnforcementSkipped bool   `json:"enforcement_skipped,omitempty"`
+	Elapsed            string `json:"elapsed"`
+	// Text is a one-line human readable summary. Chat webhooks such as
+	// Slack and Teams display this field.
+	Text string `json:"text"`
+
+	started time.Time
+	// frozen reports a run with a pinned clock, whose elapsed time is
+	// left out so its summary is byte-identical across runs.
+	frozen bool
+}
+
+// Exit codes of fix, check and remove runs, so scripts can tell a tree
+// that needed changes from a run that failed.
+const (
+	// exitOK means no file needed a change.
+	exitOK = 0
+	// exitChanged means files were changed, or in check mode and with
+	// --dry-run would have to be.
+	exitChanged = 1
+	// exitError means a file, the configuration or the command line
+	// could not be processed.
+	exitError = 2
+)
+
+// exitCode returns the exit code for the outcome of the run.
+func (s *runSummary) exitCode() int {
+	switch {
+	case s.Failed > 0:
+		return exitError
+	case s.Modified > 0 || !s.ok():
+		return exitChanged
+	}
+	return exitOK
+}
+
+// ok reports whether the run found nothing to complain about.
+func (s *runSummary) ok() bool {
+	return s.Failed == 0 && s.Outdated == 0 && s.Foreign == 0 && s.ExpiredReviews == 0 && s.ExpiredExceptions == 0 && s.PackagesWithoutNotice == 0
+}
+
+// complete fills in the derived fields once the run has finished.
+func (s *runSummary) complete() {
+	s.Elapsed = time.Since(s.started).Round(time.Millisecond).String()
+	if s.frozen {
+		s.Elapsed = "0s"
+	}
+	status := "succeeded"
+	if !s.ok() {
+		status = "failed"
+	}
+	if s.EnforcementSkipped {
+		status = "bypassed by " + skipEnv
+	}
+	s.Text = fmt.Sprintf("copy-righter %s %s: %d scanned, %d modified, %d up to date, %d outdated, %d failed (%s)",
+		s.Command, status, s.Scanned, s.Modified, s.UpToDate, s.Outdated, s.Failed, s.Elapsed)
+}
+
+// console returns the summary printed at the end of a run, worded for the
+// command that ran.
+func (s *runSummary) console() string {
+	var counts string
+	switch s.Command {
+	case "check", "audit":
+		counts = fmt.Sprintf("%d up to date, %d outdated", s.UpToDate, s.Outdated)
+		if s.Baselined > 0 {
+			counts += fmt.Sprintf(", %d baselined", s.Baselined)
+		}
+	case "remove":
+		counts = fmt.Sprintf("%d removed, %d without a notice", s.Modified, s.UpToDate)
+	default:
+		counts = fmt.Sprintf("%d added, %d updated, %d already up to date", s.Added, s.Updated, s.UpToDate)
+		if s.Foreign > 0 {
+			counts += fmt.Sprintf(", %d foreign", s.Foreign)
+		}
+	}
+	dryRun := ""
+	if s.DryRun {
+		dryRun = ", dry run"
+	}
+	lines := []string{fmt.Sprintf("Summary: %d scanned, %s, %d skipped, %d errored (%s%s)", s.Scanned, counts, s.Skipped, s.Failed, s.Elapsed, dryRun)}
+	for _, repo := range s.Repositories {
+		lines = append(lines, repo.console())
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// templateVersionPattern matches a template version line such as
+// "Template-Version: 3", capturing the version.
+var templateVersionPattern = regexp.MustCompile(`^Template-Version:\s*(\S+)$`)
+
+// validTemplateVersion matches a version made of dot-separated numbers,
+// such as "3" or "2.1".
+var validTemplateVersion = regexp.MustCompile(`^[0-9]+(\.[0-9]+)*$`)
+
+// checkTemplateVersion refuses a version that cannot be compared.
+func checkTemplateVersion(setting, version string) error {
+	if version != "" && !validTemplateVersion.MatchString(version) {
+		return fmt.Errorf("invalid %s %q, want a version such as 3 or 2.1", setting, version)
+	}
+	return nil
+}
+
+// compareVersions compares two dot-separated versions numerically,
+// returning -1, 0 or 1. Missing components count as zero, so 2 and 2.0
+// are equal.
+func compareVersions(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < max(len(as), len(bs)); i++ {
+		var x, y int
+		if i < len(as) {
+			x, _ = strconv.Atoi(as[i])
+		}
+		if i < len(bs) {
+			y, _ = strconv.Atoi(bs[i])
+		}
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// findTemplateVersion returns the template version in the header of
+// content, if there is one.
+func findTemplateVersion(content string, opts copyrighter.Options) (string, bool) {
+	_, m := findAnnotation(content, opts, templateVersionPattern)
+	if m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// tracksTemplateVersions reports whether a check, when check is set,
+// looks for the template versions of headers, to measure the adoption of
+// a new template.
+func (r *runner) tracksTemplateVersions(check bool) bool {
+	return check && (r.processor.Options.TemplateVersion != "" || r.minTemplateVersion != "")
+}
+
+// countTemplateVersion records the template version of a checked file in
+// the run summary and returns it, or "" when the header carries none.
+func (r *runner) countTemplateVersion(content string, opts copyrighter.Options) string {
+	version, _ := findTemplateVersion(content, opts)
+	r.tallyTemplateVersion(version)
+	return version
+}
+
+// tallyTemplateVersion records version, "" for none, in the run summary.
+func (r *runner) tallyTemplateVersion(version string) {
+	key := version
+	if key == "" {
+		key = "none"
+	}
+	if r.summary.TemplateVersions == nil {
+		r.summary.TemplateVersions = make(map[string]int)
+	}
+	r.summary.TemplateVersions[key]++
+}
+
+// acceptTemplateVersion implements --min-template-version: a header that
+// differs from the current template but carries a template version at or
+// above the minimum passes the check, and so does the footer that goes
+// with it. It returns the options that leave both in place, and whether
+// the file was accepted that way.
+func (r *runner) acceptTemplateVersion(version string, opts copyrighter.Options, result copyrighter.Result) (copyrighter.Options, bool) {
+	if r.minTemplateVersion == "" || version == "" || result.Header != copyrighter.Updated {
+		return opts, false
+	}
+	if !validTemplateVersion.MatchString(version) || compareVersions(version, r.minTemplateVersion) < 0 {
+		return opts, false
+	}
+	opts.LeaveHeader = true
+	opts.LeaveFooter = opts.LeaveFooter || result.Footer == copyrighter.Updated
+	return opts, true
+}
+
+// templateAdoption describes how many checked files carry each template
+// version, newest first, with the files without one last.
+func templateAdoption(versions map[string]int) string {
+	keys := make([]string, 0, len(versions))
+	for v := range versions {
+		if v != "none" {
+			keys = append(keys, v)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return compareVersions(keys[i], keys[j]) > 0 })
+	if versions["none"] > 0 {
+		keys = append(keys, "none")
+	}
+	parts := make([]string, len(keys))
+	for i, v := range keys {
+		parts[i] = fmt.Sprintf("%s: %d file(s)", v, versions[v])
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,4 @@
+// Engine implementation.
+#include "engine.hpp"
+
+int Engine::run() { return 0; }
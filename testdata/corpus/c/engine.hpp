@@ -0,0 +1,6 @@
+#pragma once
+
+class Engine {
+ public:
+  int run();
+};
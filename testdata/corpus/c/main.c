@@ -0,0 +1,11 @@
+/*
+ * Copyright (c) 2015 Old Holder.
+ * All rights reserved.
+ */
+
+#include <stdio.h>
+
+int main(void) {
+    printf("hello\n");
+    return 0;
+}
@@ -0,0 +1,9 @@
+// Copyright 2018 Old Holder
+// SPDX-License-Identifier: MIT
+
+#ifndef UTIL_H
+#define UTIL_H
+
+int add(int a, int b);
+
+#endif /* UTIL_H */
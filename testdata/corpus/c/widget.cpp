@@ -0,0 +1,6 @@
+#include <string>
+
+/** A widget with a name. */
+class Widget {
+  std::string name_;
+};
@@ -0,0 +1,5 @@
+// Package corpus exercises header placement above a package doc comment.
+package corpus
+
+// Answer returns the answer.
+func Answer() int { return 42 }
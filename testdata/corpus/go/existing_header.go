@@ -0,0 +1,5 @@
+// Copyright (c) 2019 Someone Else.
+
+package corpus
+
+type T struct{}
@@ -0,0 +1,5 @@
+// Copyright (c) 2019 Someone Else.
+// Package corpus has its old header glued to the package doc comment.
+package corpus
+
+var glued = true
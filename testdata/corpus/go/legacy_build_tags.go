@@ -0,0 +1,7 @@
+//go:build linux && !cgo
+// +build linux,!cgo
+
+// Package corpus is a build-constrained package with a doc comment.
+package corpus
+
+const pure = true
@@ -0,0 +1,3 @@
+package corpus
+
+func noNewline() {}
\ No newline at end of file
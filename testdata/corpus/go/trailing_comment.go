@@ -0,0 +1,5 @@
+package corpus
+
+var x = 1
+
+// End of file.
@@ -0,0 +1,8 @@
+/**
+ * Entry point.
+ */
+public class Main {
+    public static void main(String[] args) {
+        System.out.println("hello");
+    }
+}
@@ -0,0 +1,3 @@
+type Props = { title: string };
+
+export const Card = ({ title }: Props) => <h2>{title}</h2>;
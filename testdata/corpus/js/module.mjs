@@ -0,0 +1,3 @@
+export default function double(x) {
+  return x * 2;
+}
@@ -0,0 +1,5 @@
+"""Helpers without a shebang."""
+
+
+def add(a, b):
+    return a + b
@@ -0,0 +1,13 @@
+#!/usr/bin/env python3
+# -*- coding: utf-8 -*-
+"""Print a greeting."""
+
+import sys
+
+
+def main():
+    print("hello", sys.argv[1:])
+
+
+if __name__ == "__main__":
+    main()
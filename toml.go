@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseTOML reads the subset of TOML that tool configs such as hawkeye's
+// licenserc.toml use: tables, and keys set to strings, integers, booleans
+// and arrays of them, on one line or spread over several. Keys of a table
+// are found under its name, as a nested map.
+func parseTOML(data string) (map[string]any, error) {
+	root := make(map[string]any)
+	table := root
+	p := &tomlParser{src: data, line: 1}
+	for {
+		p.skipBlank()
+		if p.done() {
+			return root, nil
+		}
+		if p.peek() == '[' {
+			name, err := p.tableHeader()
+			if err != nil {
+				return nil, err
+			}
+			table = root
+			for _, part := range strings.Split(name, ".") {
+				next, ok := table[part].(map[string]any)
+				if !ok {
+					next = make(map[string]any)
+					table[part] = next
+				}
+				table = next
+			}
+			continue
+		}
+		key, err := p.key()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		if p.done() || p.peek() != '=' {
+			return nil, p.errorf("expected = after %s", key)
+		}
+		p.pos++
+		p.skipSpace()
+		value, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		table[key] = value
+		p.skipSpace()
+		if !p.done() && p.peek() != '\n' && p.peek() != '#' && p.peek() != '\r' {
+			return nil, p.errorf("unexpected %q after the value of %s", p.peek(), key)
+		}
+	}
+}
+
+type tomlParser struct {
+	src  string
+	pos  int
+	line int
+}
+
+func (p *tomlParser) done() bool { return p.pos >= len(p.src) }
+func (p *tomlParser) peek() byte { return p.src[p.pos] }
+
+func (p *tomlParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("line %d: %s", p.line, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips spaces and tabs on the current line.
+func (p *tomlParser) skipSpace() {
+	for !p.done() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipBlank skips whitespace, line breaks and comments.
+func (p *tomlParser) skipBlank() {
+	for !p.done() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.pos++
+			p.line++
+		case '#':
+			for !p.done() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (p *tomlParser) tableHeader() (string, error) {
+	end := strings.IndexByte(p.src[p.pos:], ']')
+	if end < 0 || strings.HasPrefix(p.src[p.pos:], "[[") {
+		return "", p.errorf("unsupported table header")
+	}
+	name := strings.TrimSpace(p.src[p.pos+1 : p.pos+end])
+	p.pos += end + 1
+	return name, nil
+}
+
+func (p *tomlParser) key() (string, error) {
+	if p.peek() == '"' || p.peek() == '\'' {
+		v, err := p.value()
+		s, ok := v.(string)
+		if err != nil || !ok {
+			return "", p.errorf("invalid key")
+		}
+		return s, nil
+	}
+	start := p.pos
+	for !p.done() && isKeyByte(p.peek()) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", p.errorf("expected a key, found %q", p.peek())
+	}
+	return p.src[start:p.pos], nil
+}
+
+func isKeyByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) value() (any, error) {
+	if p.done() {
+		return nil, p.errorf("missing value")
+	}
+	rest := p.src[p.pos:]
+	switch {
+	case strings.HasPrefix(rest, `"""`), strings.HasPrefix(rest, "'''"):
+		delim := rest[:3]
+		end := strings.Index(rest[3:], delim)
+		if end < 0 {
+			return nil, p.errorf("unterminated string")
+		}
+		s := strings.TrimPrefix(strings.TrimPrefix(rest[3:3+end], "\r"), "\n")
+		p.line += strings.Count(rest[:6+end], "\n")
+		p.pos += 6 + end
+		if delim == `"""` {
+			return unescapeTOML(s)
+		}
+		return s, nil
+	case rest[0] == '"':
+		end := 1
+		for end < len(rest) && rest[end] != '"' && rest[end] != '\n' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		if end >= len(rest) || rest[end] != '"' {
+			return nil, p.errorf("unterminated string")
+		}
+		p.pos += end + 1
+		return unescapeTOML(rest[1:end])
+	case rest[0] == '\'':
+		end := strings.IndexAny(rest[1:], "'\n")
+		if end < 0 || rest[1+end] != '\'' {
+			return nil, p.errorf("unterminated string")
+		}
+		p.pos += end + 2
+		return rest[1 : 1+end], nil
+	case rest[0] == '[':
+		p.pos++
+		var items []any
+		for {
+			p.skipBlank()
+			if p.done() {
+				return nil, p.errorf("unterminated array")
+			}
+			if p.peek() == ']' {
+				p.pos++
+				return items, nil
+			}
+			item, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			p.skipBlank()
+			if !p.done() && p.peek() == ',' {
+				p.pos++
+			}
+		}
+	}
+	end := strings.IndexAny(rest, ",]#\r\n")
+	if end < 0 {
+		end = len(rest)
+	}
+	word := strings.TrimSpace(rest[:end])
+	p.pos += end
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	n, err := strconv.ParseInt(strings.ReplaceAll(word, "_", ""), 0, 64)
+	if err != nil {
+		return nil, p.errorf("unsupported value %q", word)
+	}
+	return n, nil
+}
+
+// unescapeTOML resolves the escapes of a basic string. A backslash at the
+// end of a line trims the line break and the whitespace that follows it.
+func unescapeTOML(s string) (string, error) {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 == len(s) {
+			return "", fmt.Errorf("invalid escape at the end of %q", s)
+		}
+		i++
+		switch c := s[i]; c {
+		case 'b':
+			b.WriteByte('\b')
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'f':
+			b.WriteByte('\f')
+		case 'r':
+			b.WriteByte('\r')
+		case '"', '\\':
+			b.WriteByte(c)
+		case 'u', 'U':
+			n := 4
+			if c == 'U' {
+				n = 8
+			}
+			if i+n >= len(s) {
+				return "", fmt.Errorf("invalid escape in %q", s)
+			}
+			r, err := strconv.ParseUint(s[i+1:i+1+n], 16, 32)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape in %q", s)
+			}
+			b.WriteRune(rune(r))
+			i += n
+		case ' ', '\t', '\r', '\n':
+			for i+1 < len(s) && strings.IndexByte(" \t\r\n", s[i+1]) >= 0 {
+				i++
+			}
+		default:
+			return "", fmt.Errorf("invalid escape \\%c in %q", c, s)
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,366 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+// diffContext is the number of unchanged lines the diff preview shows
+// around each change.
+const diffContext = 3
+
+// reviewItem is one candidate file of a review session, with the content
+// a fix would write.
+type reviewItem struct {
+	path          string
+	before, after string
+	// status describes the problems of the file, "up to date" if none.
+	status   string
+	selected bool
+}
+
+func (it *reviewItem) changed() bool { return it.before != it.after }
+
+// reviewSession is the state of the tui subcommand: the candidate files of
+// a dry run, which of them to stamp, and what is on screen. It is driven
+// by press and drawn by render, so it works without a terminal.
+type reviewSession struct {
+	items []reviewItem
+	// cursor is the item under the cursor and top the first one on screen.
+	cursor, top int
+	// diff holds the preview of the item under the cursor while it is
+	// shown, nil in the file list; diffTop is its first line on screen.
+	diff    []string
+	diffTop int
+	// apply and quit end the session, with or without stamping the
+	// selection.
+	apply, quit bool
+}
+
+// add records a file processed by the dry run. Files that need a change
+// start out selected.
+func (s *reviewSession) add(path, before, after string, result copyrighter.Result) {
+	status := "up to date"
+	if result.Changed() {
+		status = describeProblems(result)
+	}
+	s.items = append(s.items, reviewItem{path: path, before: before, after: after, status: status, selected: result.Changed()})
+}
+
+// counts returns the number of files that need a change and of those
+// selected.
+func (s *reviewSession) counts() (changed, selected int) {
+	for _, it := range s.items {
+		if it.changed() {
+			changed++
+			if it.selected {
+				selected++
+			}
+		}
+	}
+	return changed, selected
+}
+
+// press handles a key, as returned by parseKey, on a screen of the given
+// height.
+func (s *reviewSession) press(key string, height int) {
+	page := max(height-1, 1)
+	if s.diff != nil {
+		switch key {
+		case "up", "k":
+			s.diffTop--
+		case "down", "j":
+			s.diffTop++
+		case "pgup":
+			s.diffTop -= page
+		case "pgdown":
+			s.diffTop += page
+		case "space":
+			s.toggle()
+		case "n":
+			s.moveCursor(1)
+			s.preview()
+		case "p":
+			s.moveCursor(-1)
+			s.preview()
+		case "q", "esc", "enter", "d":
+			s.diff = nil
+		case "ctrl-c":
+			s.quit = true
+		}
+		s.diffTop = max(min(s.diffTop, len(s.diff)-page), 0)
+		return
+	}
+	switch key {
+	case "up", "k":
+		s.moveCursor(-1)
+	case "down", "j":
+		s.moveCursor(1)
+	case "pgup":
+		s.moveCursor(-page)
+	case "pgdown":
+		s.moveCursor(page)
+	case "home", "g":
+		s.moveCursor(-len(s.items))
+	case "end", "G":
+		s.moveCursor(len(s.items))
+	case "space":
+		s.toggle()
+	case "a":
+		changed, selected := s.counts()
+		for i := range s.items {
+			s.items[i].selected = s.items[i].changed() && selected < changed
+		}
+	case "enter", "d":
+		s.preview()
+	case "w":
+		s.apply = true
+	case "q", "esc", "ctrl-c":
+		s.quit = true
+	}
+}
+
+func (s *reviewSession) moveCursor(delta int) {
+	s.cursor = max(min(s.cursor+delta, len(s.items)-1), 0)
+}
+
+// toggle selects or deselects the file under the cursor; files that are up
+// to date have nothing to apply.
+func (s *reviewSession) toggle() {
+	if it := &s.items[s.cursor]; it.changed() {
+		it.selected = !it.selected
+	}
+}
+
+func (s *reviewSession) preview() {
+	it := s.items[s.cursor]
+	s.diff, s.diffTop = unifiedDiff(it.before, it.after, diffContext), 0
+	if len(s.diff) == 0 {
+		s.diff = []string{" (no changes)"}
+	}
+}
+
+// render returns the screen for a terminal of the given size, one string
+// per line.
+func (s *reviewSession) render(width, height int) []string {
+	height = max(height, 2)
+	var lines []string
+	if s.diff != nil {
+		it := s.items[s.cursor]
+		mark := "not selected"
+		if it.selected {
+			mark = "selected"
+		}
+		lines = append(lines, fit(fmt.Sprintf("%s: %s, %s | up/down scroll, space toggle, n/p next/previous file, q back", it.path, it.status, mark), width))
+		for _, line := range s.diff[s.diffTop:min(s.diffTop+height-1, len(s.diff))] {
+			line = fit(line, width)
+			switch line[0] {
+			case '+':
+				line = "\x1b[32m" + line + "\x1b[0m"
+			case '-':
+				line = "\x1b[31m" + line + "\x1b[0m"
+			}
+			lines = append(lines, line)
+		}
+		return lines
+	}
+
+	changed, selected := s.counts()
+	lines = append(lines, fit(fmt.Sprintf("%d files, %d to change, %d selected | space toggle, a all, enter diff, w apply, q quit", len(s.items), changed, selected), width))
+	rows := height - 1
+	if s.cursor < s.top {
+		s.top = s.cursor
+	}
+	if s.cursor >= s.top+rows {
+		s.top = s.cursor - rows + 1
+	}
+	for i := s.top; i < min(s.top+rows, len(s.items)); i++ {
+		it := s.items[i]
+		box := "[ ]"
+		switch {
+		case !it.changed():
+			box = "   "
+		case it.selected:
+			box = "[x]"
+		}
+		line := fit(fmt.Sprintf("%s %s  %s", box, it.path, it.status), width)
+		if i == s.cursor {
+			line = "\x1b[7m" + line + "\x1b[0m"
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// fit cuts line to width runes.
+func fit(line string, width int) string {
+	if width <= 0 || utf8.RuneCountInString(line) <= width {
+		return line
+	}
+	return string([]rune(line)[:width])
+}
+
+// unifiedDiff returns the lines of a diff turning before into after, with
+// context unchanged lines around each change and "..." for the lines
+// left out.
+func unifiedDiff(before, after string, context int) []string {
+	ops := diffLines(splitForDiff(before), splitForDiff(after))
+	keep := make([]bool, len(ops))
+	for i, op := range ops {
+		if op.kind != ' ' {
+			for j := max(i-context, 0); j <= min(i+context, len(ops)-1); j++ {
+				keep[j] = true
+			}
+		}
+	}
+	var lines []string
+	for i, op := range ops {
+		if !keep[i] {
+			if i > 0 && keep[i-1] || i == 0 {
+				lines = append(lines, "...")
+			}
+			continue
+		}
+		lines = append(lines, string(op.kind)+strings.TrimRight(op.line, "\r\n"))
+	}
+	return lines
+}
+
+// parseKey names the key a read from a raw terminal returned.
+func parseKey(b []byte) string {
+	switch string(b) {
+	case "\x1b[A", "\x1bOA":
+		return "up"
+	case "\x1b[B", "\x1bOB":
+		return "down"
+	case "\x1b[5~":
+		return "pgup"
+	case "\x1b[6~":
+		return "pgdown"
+	case "\x1b[H", "\x1b[1~", "\x1bOH":
+		return "home"
+	case "\x1b[F", "\x1b[4~", "\x1bOF":
+		return "end"
+	case "\x1b":
+		return "esc"
+	case "\r", "\n":
+		return "enter"
+	case " ":
+		return "space"
+	case "\x03":
+		return "ctrl-c"
+	}
+	return string(b)
+}
+
+// runTUI implements the tui subcommand: a dry run lists the candidate
+// files, the user reviews their diffs and picks the ones to stamp, and
+// only those are written, in one journaled run that undo can revert.
+func runTUI(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	if !term.IsTerminal(int(os.Stdin.Fd())) || !term.IsTerminal(int(os.Stdout.Fd())) {
+		fmt.Fprintln(os.Stderr, "Error: tui needs an interactive terminal; use --dry-run --stat for a report")
+		os.Exit(exitError)
+	}
+	r := newRunner(cmd, args)
+	r.dryRun = true
+	r.review = &reviewSession{}
+	level := r.logLevel
+	r.logLevel = logQuiet
+	r.runArgs(args)
+	r.logLevel = level
+
+	s := r.review
+	if changed, _ := s.counts(); changed == 0 {
+		fmt.Fprintf(r.out, "All %d %s up to date, nothing to review\n", len(s.items), plural(len(s.items), "file is", "files are"))
+		return
+	}
+	if err := s.interact(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if !s.apply {
+		fmt.Fprintln(r.out, "Nothing written")
+		return
+	}
+	r.dryRun = false
+	r.applyReview()
+	if err := r.journal.close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing run journal: %v\n", err)
+	}
+	if r.journal.written() {
+		r.logf(logNormal, "Undo this run with: copy-righter undo --run=%s\n", r.journal.id)
+	}
+	if r.summary.Failed > 0 {
+		os.Exit(exitError)
+	}
+}
+
+// interact runs the session on a terminal until the user applies or quits.
+func (s *reviewSession) interact(in, out *os.File) error {
+	state, err := term.MakeRaw(int(in.Fd()))
+	if err != nil {
+		return err
+	}
+	defer term.Restore(int(in.Fd()), state)
+	// The alternate screen leaves the shell's scrollback as it was
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	buf := make([]byte, 32)
+	for !s.apply && !s.quit {
+		width, height, err := term.GetSize(int(out.Fd()))
+		if err != nil || width == 0 || height == 0 {
+			width, height = 80, 24
+		}
+		fmt.Fprint(out, "\x1b[H\x1b[2J"+strings.Join(s.render(width, height), "\r\n"))
+		n, err := in.Read(buf)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		s.press(parseKey(buf[:n]), height)
+	}
+	return nil
+}
+
+// applyReview writes the selected files of the review, skipping any that
+// changed on disk since the dry run.
+func (r *runner) applyReview() {
+	applied, selected := 0, 0
+	for _, it := range r.review.items {
+		if !it.changed() || !it.selected {
+			continue
+		}
+		selected++
+		data, enc, err := r.readSource(it.path)
+		if err == nil && string(data) != it.before {
+			err = fmt.Errorf("%s changed since it was reviewed, not stamped", it.path)
+		}
+		if err == nil {
+			err = r.writeSource(it.path, enc, it.before, it.after)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			r.fail(it.path, err)
+			continue
+		}
+		r.logf(logVerbose, "Stamped: %s\n", it.path)
+		applied++
+	}
+	changed, _ := r.review.counts()
+	r.logf(logNormal, "Stamped %d of %d selected %s; %d of the %d %s needing a change left as they were\n",
+		applied, selected, plural(selected, "file", "files"), changed-selected, changed, plural(changed, "file", "files"))
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/earik87/copy-righter/pkg/copyrighter"
+)
+
+func TestReviewSession(t *testing.T) {
+	s := &reviewSession{}
+	missing := copyrighter.Result{Header: copyrighter.Added}
+	s.add("a.go", "package a\n", "// C\n\npackage a\n", missing)
+	s.add("b.go", "// C\n\npackage b\n", "// C\n\npackage b\n", copyrighter.Result{})
+	s.add("c.go", "package c\n", "// C\n\npackage c\n", missing)
+
+	for _, key := range []string{"down", "space", "down", "space"} {
+		s.press(key, 10)
+	}
+	if got := []bool{s.items[0].selected, s.items[1].selected, s.items[2].selected}; !reflect.DeepEqual(got, []bool{true, false, false}) {
+		t.Errorf("selection after toggling b.go and c.go = %v", got)
+	}
+	screen := strings.Join(s.render(80, 10), "\n")
+	for _, want := range []string{"3 files, 2 to change, 1 selected", "[x] a.go  missing header", "    b.go  up to date", "[ ] c.go  missing header"} {
+		if !strings.Contains(screen, want) {
+			t.Errorf("list missing %q:\n%s", want, screen)
+		}
+	}
+
+	s.press("enter", 10)
+	if want := []string{"+// C", "+", " package c"}; !reflect.DeepEqual(s.diff, want) {
+		t.Errorf("diff of c.go = %q, want %q", s.diff, want)
+	}
+	s.press("q", 10)
+	s.press("a", 10)
+	if _, selected := s.counts(); selected != 2 || s.diff != nil {
+		t.Errorf("a after leaving the diff selected %d files, diff %q", selected, s.diff)
+	}
+	s.press("w", 10)
+	if !s.apply {
+		t.Error("w did not apply the selection")
+	}
+}
+
+func TestUnifiedDiffContext(t *testing.T) {
+	before := "package a\n\n1\n2\n3\n4\n5\n6\n7\n8\n"
+	after := "// C\n\n" + before + "\n// C\n"
+	want := []string{"+// C", "+", " package a", " ", " 1", "...", " 6", " 7", " 8", "+", "+// C"}
+	if got := unifiedDiff(before, after, 3); !reflect.DeepEqual(got, want) {
+		t.Errorf("unifiedDiff = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,604 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/cobra"
+)
+
+// defaultDebounce is how long watch waits after the last event on a file
+// before stamping it, so an editor saving in several writes, or a burst
+// of files from a checkout, is processed once.
+const defaultDebounce = 300 * time.Millisecond
+
+// defaultPollInterval is how often watch scans the directories it has no
+// inotify watch for.
+const defaultPollInterval = 2 * time.Second
+
+// watchRetries is how many times watch tries to stamp a file before it
+// reports the error, so a file an editor or a build holds locked for a
+// moment is not left unstamped.
+const watchRetries = 3
+
+// watchSaveDelay is how long watch gathers changes to its queue before it
+// saves it, so a burst of events rewrites the state file once.
+const watchSaveDelay = 100 * time.Millisecond
+
+// watcher stamps the files created or saved beneath the watched roots.
+type watcher struct {
+	r        *runner
+	fs       *fsnotify.Watcher
+	debounce time.Duration
+	// maxWatches is how many directories get an inotify watch, 0 for as
+	// many as the system allows; the others are polled every interval.
+	// Once the system runs out, outOfWatches has every new directory
+	// polled.
+	maxWatches   int
+	outOfWatches bool
+	interval     time.Duration
+	// roots maps each watched directory to the root it was found under,
+	// which the include and exclude patterns are relative to.
+	roots map[string]string
+	// polled maps the directories without a watch to what was in them at
+	// the last scan.
+	polled map[string]*polledDir
+	// pending maps the files with events still being debounced, or
+	// waiting for another attempt, to their root.
+	pending  map[string]string
+	attempts map[string]int
+	// statePath is the file pending is kept in, so the files of a session
+	// that is killed are stamped by the next one; "" without a user cache
+	// directory.
+	statePath string
+	dirty     bool
+}
+
+// polledDir is a directory watch scans for changes.
+type polledDir struct {
+	root  string
+	files map[string]fileStamp
+}
+
+// fileStamp is what a scan compares to tell a file has been saved.
+type fileStamp struct {
+	modTime int64
+	size    int64
+}
+
+// watchState is the state of a watch session kept in its state file, for
+// the next session and for watch --status.
+type watchState struct {
+	// PID is that of the running session, 0 once it stopped
+	PID     int            `json:"pid,omitempty"`
+	Roots   []string       `json:"roots"`
+	Watches int            `json:"watches"`
+	Polled  int            `json:"polled"`
+	Pending []watchPending `json:"pending"`
+}
+
+// watchPending is a queued file, with absolute paths so the next session
+// finds it from any directory.
+type watchPending struct {
+	Path     string `json:"path"`
+	Root     string `json:"root"`
+	Attempts int    `json:"attempts,omitempty"`
+}
+
+// runWatch implements the watch subcommand: it stamps source files as
+// they are created or saved until interrupted, then prints the summary of
+// the session like any other run.
+func runWatch(cmd *cobra.Command, args []string) {
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	if status, _ := cmd.Flags().GetBool("status"); status {
+		printWatchStatus(watchStatePath(args), strings.Join(args, ", "))
+		return
+	}
+	r := newRunner(cmd, args)
+	if r.since != "" || r.staged || r.readStdin || r.filesFrom != "" {
+		fmt.Fprintln(os.Stderr, "Error: watch takes directories to watch, not --since, --staged, -0 or --files-from")
+		os.Exit(exitError)
+	}
+	roots := make([]string, 0, len(args))
+	for _, arg := range args {
+		root, err := r.resolveArg(trimRecursivePattern(arg))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+		roots = append(roots, filepath.Clean(root))
+	}
+
+	debounce, _ := cmd.Flags().GetDuration("debounce")
+	interval, _ := cmd.Flags().GetDuration("poll-interval")
+	maxWatches, _ := cmd.Flags().GetInt("max-watches")
+	w, err := newWatcher(r, debounce, interval, maxWatches)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	defer w.fs.Close()
+	for _, root := range roots {
+		if err := w.addTree(root, root, false); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	w.statePath = watchStatePath(roots)
+	resumed := w.load(roots)
+	dirs := len(w.roots) + len(w.polled)
+	polled := ""
+	if len(w.polled) > 0 {
+		polled = fmt.Sprintf(" (%d polled every %s)", len(w.polled), w.interval)
+	}
+	r.logf(logNormal, "Watching %d %s in %s%s for new and saved files; press Ctrl-C to stop\n", dirs, plural(dirs, "directory", "directories"), strings.Join(args, ", "), polled)
+	if resumed > 0 {
+		r.logf(logNormal, "Resuming %d %s queued by the last session\n", resumed, plural(resumed, "file", "files"))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	w.loop(ctx)
+	w.stop()
+	r.finish()
+	os.Exit(r.summary.exitCode())
+}
+
+func newWatcher(r *runner, debounce, interval time.Duration, maxWatches int) (*watcher, error) {
+	if debounce < 0 {
+		return nil, fmt.Errorf("invalid --debounce %s", debounce)
+	}
+	if interval <= 0 {
+		return nil, fmt.Errorf("invalid --poll-interval %s", interval)
+	}
+	if maxWatches < 0 {
+		return nil, fmt.Errorf("invalid --max-watches %d", maxWatches)
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	return &watcher{
+		r:          r,
+		fs:         fsw,
+		debounce:   debounce,
+		maxWatches: maxWatches,
+		interval:   interval,
+		roots:      make(map[string]string),
+		polled:     make(map[string]*polledDir),
+		pending:    make(map[string]string),
+		attempts:   make(map[string]int),
+	}, nil
+}
+
+// addTree watches dir and every directory beneath it that a run would
+// walk into. With queue, the files already in them are queued too, for a
+// directory created, or moved in, while watching.
+func (w *watcher) addTree(root, dir string, queue bool) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error accessing path %s: %v\n", path, err)
+			return nil
+		}
+		if !d.IsDir() {
+			if queue {
+				w.queue(root, path)
+			}
+			return nil
+		}
+		if path != root && (w.r.isExcluded(root, path) || !w.r.nestedRepos && isRepoRoot(path)) {
+			return filepath.SkipDir
+		}
+		if _, ok := w.polled[path]; ok {
+			return nil
+		}
+		if w.outOfWatches || w.maxWatches > 0 && len(w.roots) >= w.maxWatches {
+			w.poll(root, path)
+			return nil
+		}
+		if err := w.fs.Add(path); err != nil {
+			if !errors.Is(err, syscall.ENOSPC) {
+				return fmt.Errorf("watching %s: %w", path, err)
+			}
+			// The directories after this one would fail too, so they
+			// go straight to polling
+			w.outOfWatches = true
+			w.r.errorf("Warning: out of inotify watches at %d directories; polling %s and the directories after it every %s instead. Raise fs.inotify.max_user_watches to watch them\n", len(w.roots), path, w.interval)
+			w.poll(root, path)
+			return nil
+		}
+		w.roots[path] = root
+		w.dirty = true
+		return nil
+	})
+}
+
+// poll has dir scanned every interval instead of watched, starting from
+// what is in it now.
+func (w *watcher) poll(root, dir string) {
+	p := &polledDir{root: root}
+	p.files, _ = scanDir(dir)
+	w.polled[dir] = p
+	w.dirty = true
+}
+
+// scanDir returns the stamps of the files in dir.
+func scanDir(dir string) (map[string]fileStamp, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]fileStamp, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files[filepath.Join(dir, entry.Name())] = fileStamp{modTime: info.ModTime().UnixNano(), size: info.Size()}
+	}
+	return files, nil
+}
+
+// scan queues the files of the polled directories that appeared or
+// changed since the last scan, and adds the directories created in them.
+// It reports whether it found any.
+func (w *watcher) scan() bool {
+	changed := false
+	dirs := make([]string, 0, len(w.polled))
+	for dir := range w.polled {
+		dirs = append(dirs, dir)
+	}
+	slices.Sort(dirs)
+	for _, dir := range dirs {
+		p := w.polled[dir]
+		files, err := scanDir(dir)
+		if err != nil {
+			delete(w.polled, dir)
+			continue
+		}
+		for path, stamp := range files {
+			if old, ok := p.files[path]; !ok || old != stamp {
+				w.queue(p.root, path)
+				changed = true
+			}
+		}
+		p.files = files
+		entries, _ := os.ReadDir(dir)
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if _, watched := w.roots[path]; !entry.IsDir() || watched {
+				continue
+			}
+			if _, ok := w.polled[path]; ok {
+				continue
+			}
+			if err := w.addTree(p.root, path, true); err != nil {
+				w.r.errorf("Error: %v\n", err)
+			}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// queue schedules a file for stamping once its events settle, if a run
+// would select it.
+func (w *watcher) queue(root, path string) {
+	if verdict, _ := w.r.selectFile(root, path); verdict == matchSelected {
+		w.pending[path] = root
+		w.dirty = true
+	}
+}
+
+// loop handles events until ctx is done. Every event on a file restarts
+// the debounce timer; when it fires, the queued files are stamped. The
+// queue is saved watchSaveDelay after it changes.
+func (w *watcher) loop(ctx context.Context) {
+	timer := time.NewTimer(w.debounce)
+	if len(w.pending) == 0 {
+		timer.Stop()
+	}
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	w.save(true)
+	saveTimer := time.NewTimer(watchSaveDelay)
+	saveTimer.Stop()
+	saving := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			w.handle(event)
+			if len(w.pending) > 0 {
+				timer.Reset(w.debounce)
+			}
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			w.r.errorf("Error watching files: %v\n", err)
+		case <-ticker.C:
+			if len(w.polled) == 0 {
+				continue
+			}
+			if w.scan() && len(w.pending) > 0 {
+				timer.Reset(w.debounce)
+			}
+		case <-timer.C:
+			// Files that failed are tried again after a pause
+			if w.flush(); len(w.pending) > 0 {
+				timer.Reset(max(w.debounce, time.Second))
+			}
+		case <-saveTimer.C:
+			saving = false
+			w.save(true)
+		}
+		if w.dirty && !saving {
+			saveTimer.Reset(watchSaveDelay)
+			saving = true
+		}
+	}
+}
+
+func (w *watcher) handle(event fsnotify.Event) {
+	// fsnotify joins the names without cleaning them, "./a.go" in "."
+	path := filepath.Clean(event.Name)
+	if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+		if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+			if _, ok := w.pending[path]; ok {
+				delete(w.pending, path)
+				delete(w.attempts, path)
+				w.dirty = true
+			}
+			delete(w.roots, path)
+		}
+		return
+	}
+	root, ok := w.roots[filepath.Dir(path)]
+	if !ok {
+		return
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return
+	}
+	if !info.IsDir() {
+		w.queue(root, path)
+		return
+	}
+	// Files created in the new directory before it was watched have no
+	// events of their own, so its content is queued as well; a directory
+	// the walk of its parent already added is walked again for the same
+	// reason
+	if err := w.addTree(root, path, true); err != nil {
+		w.r.errorf("Error: %v\n", err)
+	}
+}
+
+// flush stamps the queued files that are still regular files. Stamping
+// writes them again, which queues them once more; the second pass finds
+// them up to date and leaves them alone. A file that fails stays queued
+// for another attempt, up to watchRetries, before its error is reported.
+func (w *watcher) flush() {
+	paths := make([]string, 0, len(w.pending))
+	for path := range w.pending {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	w.dirty = true
+	for _, path := range paths {
+		root := w.pending[path]
+		delete(w.pending, path)
+		if info, err := os.Lstat(path); err != nil || !info.Mode().IsRegular() {
+			delete(w.attempts, path)
+			continue
+		}
+		err := w.r.visit(root, path)
+		if err == nil {
+			delete(w.attempts, path)
+			continue
+		}
+		if w.attempts[path]++; w.attempts[path] < watchRetries {
+			w.r.logf(logVerbose, "Error processing file %s: %v; trying again\n", path, err)
+			w.pending[path] = root
+			continue
+		}
+		delete(w.attempts, path)
+		w.r.errorf("Error processing file %s: %v\n", path, err)
+		w.r.fail(path, err)
+	}
+}
+
+// watchStatePath returns the state file of a watch over roots, one for
+// each set of directories however they are named, or "" without a user
+// cache directory.
+func watchStatePath(roots []string) string {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return ""
+	}
+	abs := make([]string, 0, len(roots))
+	for _, root := range roots {
+		a, err := filepath.Abs(trimRecursivePattern(root))
+		if err != nil {
+			continue
+		}
+		if target, err := filepath.EvalSymlinks(a); err == nil {
+			a = target
+		}
+		abs = append(abs, a)
+	}
+	slices.Sort(abs)
+	return filepath.Join(cache, "copy-righter", "watch", hashString(strings.Join(abs, "\x00"))[:16]+".json")
+}
+
+// readWatchState reads the state file at path, nil when there is none.
+func readWatchState(path string) (*watchState, error) {
+	if path == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state watchState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("reading %s: %w", path, err)
+	}
+	return &state, nil
+}
+
+// load queues the files the last session over roots left queued, and
+// returns how many.
+func (w *watcher) load(roots []string) int {
+	state, err := readWatchState(w.statePath)
+	if err != nil {
+		w.r.errorf("Warning: %v\n", err)
+	}
+	if state == nil {
+		return 0
+	}
+	// The files are queued under the roots as given this time, so their
+	// events and those the state names are the same paths
+	given := make(map[string]string, len(roots))
+	for _, root := range roots {
+		if abs, err := filepath.Abs(root); err == nil {
+			given[abs] = root
+		}
+	}
+	n := 0
+	for _, p := range state.Pending {
+		root, ok := given[p.Root]
+		if !ok {
+			continue
+		}
+		rel, err := filepath.Rel(p.Root, p.Path)
+		if err != nil {
+			continue
+		}
+		path := filepath.Join(root, rel)
+		if _, ok := w.pending[path]; ok {
+			continue
+		}
+		if w.queue(root, path); w.pending[path] != "" {
+			w.attempts[path] = p.Attempts
+			n++
+		}
+	}
+	return n
+}
+
+// save writes the state of the session to its state file, with the pid
+// while running.
+func (w *watcher) save(running bool) {
+	w.dirty = false
+	if w.statePath == "" {
+		return
+	}
+	state := watchState{Watches: len(w.roots), Polled: len(w.polled), Pending: []watchPending{}}
+	if running {
+		state.PID = os.Getpid()
+	}
+	roots := make(map[string]bool)
+	for _, root := range w.roots {
+		roots[root] = true
+	}
+	for _, p := range w.polled {
+		roots[p.root] = true
+	}
+	for root := range roots {
+		if abs, err := filepath.Abs(root); err == nil {
+			state.Roots = append(state.Roots, abs)
+		}
+	}
+	slices.Sort(state.Roots)
+	for path, root := range w.pending {
+		absPath, err1 := filepath.Abs(path)
+		absRoot, err2 := filepath.Abs(root)
+		if err1 == nil && err2 == nil {
+			state.Pending = append(state.Pending, watchPending{Path: absPath, Root: absRoot, Attempts: w.attempts[path]})
+		}
+	}
+	slices.SortFunc(state.Pending, func(a, b watchPending) int { return strings.Compare(a.Path, b.Path) })
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(w.statePath), 0755)
+	}
+	if err == nil {
+		err = writeFileAtomic(w.statePath, append(data, '\n'))
+	}
+	if err != nil {
+		w.r.errorf("Warning: saving the watch queue: %v\n", err)
+	}
+}
+
+// stop records the end of the session: the files still queued are kept
+// for the next one, and with none the state file is removed.
+func (w *watcher) stop() {
+	if len(w.pending) > 0 {
+		w.save(false)
+		return
+	}
+	if w.statePath != "" {
+		os.Remove(w.statePath)
+	}
+}
+
+// processAlive reports whether the process pid is running. On Unix,
+// FindProcess always succeeds and signal 0 probes the process; on
+// Windows, FindProcess fails for a process that is gone and other signals
+// are not supported.
+func processAlive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	defer p.Release()
+	err = p.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, syscall.EPERM) || runtime.GOOS == "windows"
+}
+
+// printWatchStatus prints the state of the watch of dirs kept at path:
+// its watches and the files it has queued.
+func printWatchStatus(path, dirs string) {
+	state, err := readWatchState(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(exitError)
+	}
+	if state == nil {
+		fmt.Printf("No watch of %s\n", dirs)
+		return
+	}
+	queued := fmt.Sprintf("%d %s queued", len(state.Pending), plural(len(state.Pending), "file", "files"))
+	// A session that was killed left its pid behind
+	if state.PID == 0 || !processAlive(state.PID) {
+		fmt.Printf("No watch of %s running; %s for the next one\n", dirs, queued)
+		return
+	}
+	fmt.Printf("Watch of %s (pid %d): %d %s watched, %d polled, %s\n", dirs, state.PID, state.Watches, plural(state.Watches, "directory", "directories"), state.Polled, queued)
+}